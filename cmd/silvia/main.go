@@ -7,7 +7,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"silvia/internal/bsky"
@@ -15,7 +18,11 @@ import (
 	"silvia/internal/graph"
 	"silvia/internal/llm"
 	"silvia/internal/mcp"
+	"silvia/internal/operations"
+	"silvia/internal/prompts"
+	"silvia/internal/resilience"
 	"silvia/internal/server"
+	"silvia/internal/sources"
 )
 
 func main() {
@@ -24,12 +31,23 @@ func main() {
 		bskyHandle    string
 		bskyPassword  string
 		openrouterKey string
+		llmConfig     = llm.ConfigFromEnv()
+		taskModels    []string
+		llmCache      string
+		llmCacheDir   string
 		dataDir       string
 		serverPort    int
 		serverToken   string
 		noServer      bool
 		debug         bool
 		mcpMode       bool
+		recheckEvery  time.Duration
+		llmRetries    int
+		llmRate       float64
+		fetchRate     float64
+		jobWorkers    int
+		origins       []string
+		hosts         []string
 	)
 
 	flag.BoolVar(&help, "help", false, "Show help message")
@@ -37,12 +55,34 @@ func main() {
 	flag.StringVar(&bskyHandle, "bsky-handle", os.Getenv("BSKY_HANDLE"), "Bluesky handle (can also use BSKY_HANDLE env var)")
 	flag.StringVar(&bskyPassword, "bsky-password", os.Getenv("BSKY_PASSWORD"), "Bluesky app password (can also use BSKY_PASSWORD env var)")
 	flag.StringVar(&openrouterKey, "openrouter-key", os.Getenv("OPENROUTER_API_KEY"), "OpenRouter API key (can also use OPENROUTER_API_KEY env var)")
+	flag.StringVar(&llmConfig.Provider, "llm-provider", llmConfig.Provider, "LLM provider: openrouter, openai (any OpenAI-compatible endpoint), or fake (can also use SILVIA_LLM_PROVIDER env var)")
+	flag.StringVar(&llmConfig.BaseURL, "llm-base-url", llmConfig.BaseURL, "Base URL for the openai provider, e.g. http://localhost:11434/v1 (can also use SILVIA_LLM_BASE_URL env var)")
+	flag.StringVar(&llmConfig.Model, "llm-model", llmConfig.Model, "Model to use for every request instead of the built-in defaults (can also use SILVIA_LLM_MODEL env var)")
+	flag.Func("model", "Model for a task as task=model, repeatable (tasks: default, extraction, summary, merge, refine, chat-tools, chat-answer)", func(value string) error {
+		taskModels = append(taskModels, value)
+		return nil
+	})
+	flag.StringVar(&llmCache, "llm-cache", os.Getenv("SILVIA_LLM_CACHE"), "LLM response cache: off, rw (read-write), or replay (fail on miss, no network) (can also use SILVIA_LLM_CACHE env var)")
+	flag.StringVar(&llmCacheDir, "llm-cache-dir", os.Getenv("SILVIA_LLM_CACHE_DIR"), "Directory for cached LLM responses (default <data>/.silvia/llm-cache)")
+	flag.IntVar(&llmRetries, "llm-retries", resilience.DefaultPolicy.MaxRetries, "Retries for LLM calls and fetches that fail with a timeout, 429 or 5xx (0 disables)")
+	flag.Float64Var(&llmRate, "llm-rate", 0, "Maximum LLM requests per minute (0 for no limit)")
+	flag.Float64Var(&fetchRate, "fetch-rate", 30, "Maximum fetches per minute to any one host (0 for no limit)")
 	flag.StringVar(&dataDir, "data", "./data", "Data directory for storing the knowledge graph")
 	flag.IntVar(&serverPort, "port", 8765, "Port for browser extension API server")
 	flag.StringVar(&serverToken, "token", os.Getenv("SILVIA_TOKEN"), "Optional auth token for extension API (can also use SILVIA_TOKEN env var)")
+	flag.Func("allow-origin", "Browser origin allowed to call the API, e.g. chrome-extension://<id>; repeatable, a trailing * matches a prefix (can also use SILVIA_ALLOWED_ORIGINS env var, comma-separated)", func(value string) error {
+		origins = append(origins, value)
+		return nil
+	})
+	flag.Func("allow-host", "Host name, besides localhost, that API requests may be addressed to, e.g. a reverse proxy's; repeatable (can also use SILVIA_ALLOWED_HOSTS env var, comma-separated)", func(value string) error {
+		hosts = append(hosts, value)
+		return nil
+	})
+	flag.IntVar(&jobWorkers, "jobs", operations.DefaultJobWorkers, "Background jobs (ingest, refine, merge, queue) to run at once")
 	flag.BoolVar(&noServer, "no-server", false, "Disable the extension API server")
 	flag.BoolVar(&debug, "debug", false, "Enable debug output for troubleshooting")
-	flag.BoolVar(&mcpMode, "mcp", false, "Run as MCP server for AI assistants (requires stdio connection)")
+	flag.BoolVar(&mcpMode, "mcp", false, "Serve MCP to an AI assistant over stdin/stdout instead of running the CLI")
+	flag.DurationVar(&recheckEvery, "recheck-interval", 0, "Recheck processed sources for changes at this interval, e.g. 24h (0 disables)")
 	flag.Parse()
 
 	if help {
@@ -50,6 +90,7 @@ func main() {
 		fmt.Println()
 		fmt.Println("Usage:")
 		fmt.Printf("  %s [flags]\n", os.Args[0])
+		fmt.Printf("  %s [-data dir] token create|revoke|list   Manage API tokens\n", os.Args[0])
 		fmt.Println()
 		fmt.Println("Flags:")
 		flag.PrintDefaults()
@@ -58,21 +99,34 @@ func main() {
 		fmt.Println("  BSKY_HANDLE          Bluesky handle")
 		fmt.Println("  BSKY_PASSWORD        Bluesky app password")
 		fmt.Println("  OPENROUTER_API_KEY   OpenRouter API key")
+		fmt.Println("  SILVIA_LLM_PROVIDER  LLM provider (openrouter, openai, fake)")
+		fmt.Println("  SILVIA_LLM_BASE_URL  Base URL of an OpenAI-compatible endpoint")
+		fmt.Println("  SILVIA_LLM_API_KEY   API key for the OpenAI-compatible endpoint")
+		fmt.Println("  SILVIA_LLM_MODEL     Model to use for every request")
+		fmt.Println("  SILVIA_MODEL_<TASK>  Model for a task, e.g. SILVIA_MODEL_EXTRACTION")
+		fmt.Println("  SILVIA_LLM_CACHE     LLM response cache mode (off, rw, replay)")
+		fmt.Println("  SILVIA_LLM_CACHE_DIR Directory for cached LLM responses")
 		fmt.Println("  SILVIA_TOKEN         Optional auth token for extension API")
+		fmt.Println("  SILVIA_ALLOWED_ORIGINS Comma-separated browser origins allowed to call the API")
 		fmt.Println()
-		fmt.Println("MCP Server Mode:")
-		fmt.Println("  Run with -mcp flag to start as an MCP server for AI assistants.")
-		fmt.Println("  This mode requires stdin/stdout to be connected (not a terminal).")
-		fmt.Println("  Example: silvia -mcp < /dev/null")
+		fmt.Println("MCP:")
+		fmt.Printf("  The API server serves MCP over streamable HTTP at http://localhost:%d/mcp,\n", serverPort)
+		fmt.Println("  using the same tokens as the API.")
+		fmt.Println("  Run with -mcp to serve MCP over stdin/stdout instead, for assistants that")
+		fmt.Println("  launch silvia themselves. stdin/stdout must not be a terminal.")
 		os.Exit(0)
 	}
 
-	// If MCP mode is requested, run as MCP server
+	exitOnTokenCommand(dataDir, flag.Args())
+
+	// In MCP mode stdout carries the protocol, so anything else printed
+	// goes to stderr
+	protocolOut := os.Stdout
 	if mcpMode {
-		if err := mcp.RunMCPServer(); err != nil {
-			log.Fatalf("MCP server error: %v", err)
+		if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+			log.Fatal("Error: -mcp needs stdin/stdout connected to an MCP client, not a terminal")
 		}
-		return
+		os.Stdout = os.Stderr
 	}
 
 	// Initialize graph manager
@@ -93,12 +147,70 @@ func main() {
 		}
 	}
 
-	// Initialize OpenRouter client (required)
-	if openrouterKey == "" {
-		log.Fatal("Error: OPENROUTER_API_KEY environment variable is required")
+	// Initialize LLM client (required)
+	cacheMode, err := llm.ParseCacheMode(llmCache)
+	if err != nil {
+		log.Fatalf("Invalid -llm-cache: %v", err)
+	}
+	if cacheMode == llm.CacheReplay && llmConfig.Provider == llm.ProviderOpenRouter && openrouterKey == "" && llmConfig.APIKey == "" {
+		// Replay never reaches the provider, so no key is needed
+		llmConfig.Provider = llm.ProviderFake
+	}
+	if llmConfig.Provider == llm.ProviderOpenRouter {
+		if openrouterKey != "" {
+			llmConfig.APIKey = openrouterKey
+		}
+		if llmConfig.APIKey == "" {
+			log.Fatal("Error: OPENROUTER_API_KEY environment variable is required (or use -llm-provider openai with a local endpoint)")
+		}
+	}
+	llmClient, err := llm.NewClientFromConfig(llmConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM client: %v", err)
+	}
+	log.Printf("LLM client initialized (provider: %s)", llmClient.Provider().Name())
+
+	// Retry transient failures with backoff and stay under rate limits
+	retryPolicy := resilience.DefaultPolicy
+	retryPolicy.MaxRetries = llmRetries
+	llmClient.SetRetryPolicy(retryPolicy)
+	llmClient.SetRateLimit(llmRate, 5)
+	sources.SetFetchRetryPolicy(retryPolicy)
+	sources.SetFetchRateLimit(fetchRate, 3)
+
+	// Per-task models: config file, then environment, then flags
+	if err := llmClient.LoadModelConfig(filepath.Join(dataDir, ".silvia", "models.json")); err != nil {
+		log.Fatalf("Failed to load model config: %v", err)
+	}
+	llmClient.SetModelOverrides(llm.ModelOverridesFromEnv())
+	flagModels, err := llm.ParseModelOverrides(taskModels)
+	if err != nil {
+		log.Fatalf("Invalid -model flag: %v", err)
+	}
+	llmClient.SetModelOverrides(flagModels)
+
+	// Record token usage and cost of every call, enforcing any budget
+	usageLedger, err := llm.NewUsageLedger(filepath.Join(dataDir, ".silvia"))
+	if err != nil {
+		log.Fatalf("Failed to open usage ledger: %v", err)
+	}
+	llmClient.SetUsageLedger(usageLedger)
+
+	// Cache responses on disk so repeated calls are free and runs can be replayed
+	if cacheMode != llm.CacheOff {
+		if llmCacheDir == "" {
+			llmCacheDir = filepath.Join(dataDir, ".silvia", "llm-cache")
+		}
+		llmClient.SetCache(llm.NewCache(llmCacheDir, cacheMode))
+		log.Printf("LLM response cache enabled (mode: %s, dir: %s)", cacheMode, llmCacheDir)
+	}
+
+	// Prompt templates, with any edited copies in the data directory
+	promptLibrary, err := prompts.LoadLibrary(filepath.Join(dataDir, ".silvia", "prompts"))
+	if err != nil {
+		log.Fatalf("Failed to load prompts: %v", err)
 	}
-	llmClient := llm.NewClient(openrouterKey)
-	log.Println("OpenRouter client initialized")
+	prompts.Use(promptLibrary)
 
 	// Store clients in context for later use
 	ctx := context.Background()
@@ -115,10 +227,25 @@ func main() {
 		cliInterface.SetDebug(true)
 	}
 
-	// Load queue from disk
-	queuePath := filepath.Join(dataDir, ".silvia", "queue.json")
-	if err := cliInterface.LoadQueue(queuePath); err != nil {
-		log.Printf("Warning: Failed to load queue: %v", err)
+	// Run background jobs on a bounded pool, cancelling any still going at exit
+	if ops := cliInterface.GetOperations(); ops != nil {
+		ops.Jobs.SetWorkers(jobWorkers)
+		defer ops.Jobs.CancelAll()
+	}
+
+	// Serve MCP on stdio instead of running the CLI, until the client
+	// closes stdin or the process is told to stop
+	if mcpMode {
+		ops := cliInterface.GetOperations()
+		if ops == nil {
+			log.Fatal("Operations layer not available")
+		}
+		mcpCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := mcp.RunStdio(mcpCtx, ops, dataDir, os.Stdin, protocolOut); err != nil {
+			log.Printf("MCP server error: %v", err)
+		}
+		return
 	}
 
 	// Start extension API server if enabled
@@ -128,13 +255,42 @@ func main() {
 			log.Fatal("Operations layer not available")
 		}
 
+		// Serve MCP at /mcp from the same graph, stopped after the API
+		// server has finished its requests
+		mcpTransport := mcp.NewHTTPTransport()
+		mcpServer, err := mcp.NewServer(ops, dataDir, mcpTransport)
+		if err != nil {
+			log.Fatalf("Failed to create MCP server: %v", err)
+		}
+		mcpCtx, stopMCP := context.WithCancel(ctx)
+		defer stopMCP()
+		go func() {
+			if err := mcpServer.Serve(mcpCtx); err != nil {
+				log.Printf("MCP server error: %v", err)
+			}
+		}()
+
 		apiServer := server.NewServer(serverPort, serverToken, ops)
+		apiServer.UseTokens(server.NewTokenStore(dataDir))
+		for _, origin := range strings.Split(os.Getenv("SILVIA_ALLOWED_ORIGINS"), ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		apiServer.AllowOrigins(origins)
+		for _, host := range strings.Split(os.Getenv("SILVIA_ALLOWED_HOSTS"), ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+		apiServer.AllowHosts(hosts)
+		apiServer.UseMCP(mcpTransport)
 		go func() {
 			if err := apiServer.Start(); err != nil && err != http.ErrServerClosed {
 				log.Printf("API server error: %v", err)
 			}
 		}()
-		log.Printf("API server started on port %d", serverPort)
+		log.Printf("API server started on port %d; web UI at http://localhost:%d/, MCP at http://localhost:%d/mcp", serverPort, serverPort, serverPort)
 
 		// Ensure server stops on exit
 		defer func() {
@@ -148,8 +304,44 @@ func main() {
 		log.Println("Extension API server disabled")
 	}
 
+	// Periodically recheck processed sources for changes
+	if recheckEvery > 0 {
+		if ops := cliInterface.GetOperations(); ops != nil {
+			go runScheduledRechecks(ctx, ops, recheckEvery)
+			log.Printf("Source recheck scheduled every %s", recheckEvery)
+		}
+	}
+
 	// Run interactive CLI
 	if err := cliInterface.Run(ctx); err != nil {
 		log.Fatalf("CLI error: %v", err)
 	}
 }
+
+// runScheduledRechecks rechecks sources that have not been checked within interval
+func runScheduledRechecks(ctx context.Context, ops *operations.Operations, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := ops.Source.RecheckSources(ctx, false, interval, 0)
+			if err != nil {
+				log.Printf("Source recheck failed: %v", err)
+				continue
+			}
+			changed := 0
+			for _, result := range report.Results {
+				if result.Status == operations.RecheckChanged {
+					changed++
+				}
+			}
+			if len(report.Results) > 0 {
+				log.Printf("Rechecked %d sources, %d changed (report: %s)", len(report.Results), changed, report.ReportPath)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"silvia/internal/server"
+)
+
+const tokenUsage = `Usage:
+  silvia [-data dir] token create <name> [-scopes read,write,ingest,admin]
+  silvia [-data dir] token revoke <name>
+  silvia [-data dir] token list
+
+Tokens authenticate clients of the API server. Once one exists, every API
+request needs a token; changes are attributed to the token's name. Scopes:
+  read    read entities, search, queue, jobs and events
+  write   edit entities and the queue, chat, refine and merge
+  ingest  ingest sources, process the queue, poll feeds and recheck sources
+  admin   everything, including feeds, clearing the queue and usage`
+
+// runTokenCommand manages the API tokens in the data directory
+func runTokenCommand(dataDir string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing token command\n\n%s", tokenUsage)
+	}
+	store := server.NewTokenStore(dataDir)
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		scopeList := fs.String("scopes", string(server.ScopeRead), "Comma-separated scopes")
+		// Allow the flag before or after the name
+		var names []string
+		rest := args[1:]
+		for {
+			if err := fs.Parse(rest); err != nil {
+				return fmt.Errorf("%v\n\n%s", err, tokenUsage)
+			}
+			if fs.NArg() == 0 {
+				break
+			}
+			names = append(names, fs.Arg(0))
+			rest = fs.Args()[1:]
+		}
+		if len(names) != 1 {
+			return fmt.Errorf("token create needs exactly one name\n\n%s", tokenUsage)
+		}
+
+		scopes, err := server.ParseScopes(*scopeList)
+		if err != nil {
+			return err
+		}
+		token, secret, err := store.Create(names[0], scopes)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created token %q with scopes %s\n\n", token.Name, joinScopes(token.Scopes))
+		fmt.Fprintf(out, "  %s\n\n", secret)
+		fmt.Fprintln(out, "Store it now; it cannot be shown again. Send it as 'Authorization: Bearer <token>'.")
+		return nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("token revoke needs exactly one name\n\n%s", tokenUsage)
+		}
+		if err := store.Revoke(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Revoked token %q\n", args[1])
+		return nil
+
+	case "list":
+		tokens, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			fmt.Fprintln(out, "No tokens. Create one with: silvia token create <name> -scopes read")
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCOPES\tCREATED\tLAST USED")
+		for _, token := range tokens {
+			lastUsed := "never"
+			if !token.LastUsed.IsZero() {
+				lastUsed = token.LastUsed.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", token.Name, joinScopes(token.Scopes), token.Created.Format(time.DateTime), lastUsed)
+		}
+		return w.Flush()
+
+	case "help", "-h", "--help":
+		fmt.Fprintln(out, tokenUsage)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown token command %q\n\n%s", args[0], tokenUsage)
+	}
+}
+
+func joinScopes(scopes []server.Scope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
+
+// exitOnTokenCommand runs `silvia token ...` and exits if that is what was
+// asked for
+func exitOnTokenCommand(dataDir string, args []string) {
+	if len(args) == 0 || args[0] != "token" {
+		return
+	}
+	if err := runTokenCommand(dataDir, args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
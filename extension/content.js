@@ -6,7 +6,7 @@ function extractPageContent() {
   const pageInfo = {
     url: window.location.href,
     title: document.title || '',
-    html: document.documentElement.outerHTML,
+    html: pageHTML(),
     text: document.body ? document.body.innerText : '',
     metadata: {},
     links: [],
@@ -116,14 +116,300 @@ function extractPageContent() {
   return pageInfo;
 }
 
+// The page's HTML without the marks added to show highlights
+function pageHTML() {
+  const clone = document.documentElement.cloneNode(true);
+  clone.querySelectorAll('mark[data-silvia-highlight], mark[data-silvia-entity]').forEach(mark => {
+    mark.replaceWith(...mark.childNodes);
+  });
+  return clone.outerHTML;
+}
+
+// Highlights
+
+// Characters of surrounding text kept to find a highlight again
+const ANCHOR_CONTEXT = 32;
+
+// XPath of a node from the document root, e.g. /html[1]/body[1]/p[3]/text()[1]
+function xpathFor(node) {
+  const parts = [];
+  for (; node && node.nodeType !== Node.DOCUMENT_NODE; node = node.parentNode) {
+    let index = 1;
+    for (let sibling = node.previousSibling; sibling; sibling = sibling.previousSibling) {
+      if (sibling.nodeType === node.nodeType && sibling.nodeName === node.nodeName) {
+        index++;
+      }
+    }
+    const step = node.nodeType === Node.TEXT_NODE ? 'text()' : node.nodeName.toLowerCase();
+    parts.unshift(`${step}[${index}]`);
+  }
+  return '/' + parts.join('/');
+}
+
+function nodeAt(path) {
+  try {
+    return document.evaluate(path, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue;
+  } catch (error) {
+    return null;
+  }
+}
+
+// Elements whose text is not shown on the page
+const HIDDEN_TEXT = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE']);
+
+// The visible text of the page, with the text node each character is in
+function pageText() {
+  const walker = document.createTreeWalker(document.body, NodeFilter.SHOW_TEXT, {
+    acceptNode: node => HIDDEN_TEXT.has(node.parentNode.nodeName) ? NodeFilter.FILTER_REJECT : NodeFilter.FILTER_ACCEPT
+  });
+  const nodes = [];
+  let text = '';
+  for (let node = walker.nextNode(); node; node = walker.nextNode()) {
+    nodes.push({ node, start: text.length });
+    text += node.nodeValue;
+  }
+  return { text, nodes };
+}
+
+// The text node and offset at a position in pageText
+function pointAt(page, position) {
+  for (let i = page.nodes.length - 1; i >= 0; i--) {
+    if (page.nodes[i].start <= position) {
+      return { node: page.nodes[i].node, offset: position - page.nodes[i].start };
+    }
+  }
+  return null;
+}
+
+// Describe the current selection as a highlight: its text and an anchor of
+// DOM positions plus the text around it
+function selectionHighlight(note, kind) {
+  const selection = window.getSelection();
+  if (!selection || selection.rangeCount === 0 || selection.isCollapsed) {
+    return null;
+  }
+  const range = selection.getRangeAt(0);
+  const text = range.toString().trim();
+  if (!text) {
+    return null;
+  }
+
+  const before = document.createRange();
+  before.setStart(document.body, 0);
+  before.setEnd(range.startContainer, range.startOffset);
+  const after = document.createRange();
+  after.setStart(range.endContainer, range.endOffset);
+  after.setEndAfter(document.body.lastChild || document.body);
+
+  return {
+    text,
+    note: note || '',
+    kind: kind || 'quote',
+    anchor: {
+      start_path: xpathFor(range.startContainer),
+      start_offset: range.startOffset,
+      end_path: xpathFor(range.endContainer),
+      end_offset: range.endOffset,
+      prefix: before.toString().slice(-ANCHOR_CONTEXT),
+      suffix: after.toString().slice(0, ANCHOR_CONTEXT)
+    }
+  };
+}
+
+// Find a saved highlight on the page: at its DOM position if the text there
+// still matches, otherwise by searching for the text, preferring the
+// occurrence with the same surrounding text
+function findHighlight(highlight, page) {
+  const anchor = highlight.anchor || {};
+  const normalize = value => value.replace(/\s+/g, ' ').trim();
+
+  const start = anchor.start_path && nodeAt(anchor.start_path);
+  const end = anchor.end_path && nodeAt(anchor.end_path);
+  if (start && end) {
+    try {
+      const range = document.createRange();
+      range.setStart(start, anchor.start_offset);
+      range.setEnd(end, anchor.end_offset);
+      if (normalize(range.toString()) === normalize(highlight.text)) {
+        return range;
+      }
+    } catch (error) {
+      // Offsets no longer fit the nodes; fall back to the text
+    }
+  }
+
+  let best = -1;
+  for (let at = page.text.indexOf(highlight.text); at !== -1; at = page.text.indexOf(highlight.text, at + 1)) {
+    best = at;
+    const prefix = anchor.prefix || '';
+    if (!prefix || page.text.slice(Math.max(0, at - prefix.length), at) === prefix) {
+      break;
+    }
+  }
+  if (best === -1) {
+    return null;
+  }
+  const from = pointAt(page, best);
+  const to = pointAt(page, best + highlight.text.length);
+  if (!from || !to) {
+    return null;
+  }
+  const range = document.createRange();
+  range.setStart(from.node, from.offset);
+  range.setEnd(to.node, to.offset);
+  return range;
+}
+
+// Wrap the text of a range in marks with the given attribute, tooltip and
+// colour
+function markRange(range, { attribute, value, title, background }) {
+  const walker = document.createTreeWalker(range.commonAncestorContainer, NodeFilter.SHOW_TEXT);
+  const nodes = [];
+  if (range.commonAncestorContainer.nodeType === Node.TEXT_NODE) {
+    nodes.push(range.commonAncestorContainer);
+  }
+  for (let node = walker.nextNode(); node; node = walker.nextNode()) {
+    if (range.intersectsNode(node)) {
+      nodes.push(node);
+    }
+  }
+
+  nodes.forEach(node => {
+    const start = node === range.startContainer ? range.startOffset : 0;
+    const end = node === range.endContainer ? range.endOffset : node.nodeValue.length;
+    if (start >= end || !node.nodeValue.slice(start, end).trim()) {
+      return;
+    }
+    let target = node;
+    if (start > 0) {
+      target = target.splitText(start);
+    }
+    if (end - start < target.nodeValue.length) {
+      target.splitText(end - start);
+    }
+    const mark = document.createElement('mark');
+    mark.setAttribute(attribute, value);
+    mark.title = title;
+    mark.style.background = background;
+    target.parentNode.insertBefore(mark, target);
+    mark.appendChild(target);
+  });
+}
+
+// Mark saved highlights on the page, returning how many were found. Ranges
+// are all found first and marked last to first, so splitting text nodes for
+// one does not move the others.
+function showHighlights(highlights) {
+  removeMarks('data-silvia-highlight');
+
+  const page = pageText();
+  const found = [];
+  highlights.forEach(highlight => {
+    const range = findHighlight(highlight, page);
+    if (range) {
+      found.push({ range, highlight });
+    }
+  });
+  found.sort((a, b) => b.range.compareBoundaryPoints(Range.START_TO_START, a.range));
+  found.forEach(({ range, highlight }) => markRange(range, {
+    attribute: 'data-silvia-highlight',
+    value: highlight.id || '',
+    title: highlight.note || (highlight.kind === 'claim' ? 'Claim' : 'Quote'),
+    background: highlight.kind === 'claim' ? '#ffd8a8' : '#fff3a3'
+  }));
+  return found.length;
+}
+
+function removeMarks(attribute) {
+  document.querySelectorAll(`mark[${attribute}]`).forEach(mark => {
+    const parent = mark.parentNode;
+    mark.replaceWith(...mark.childNodes);
+    parent.normalize();
+  });
+}
+
+// Known entities
+
+// Index in a JavaScript (UTF-16) string of each character of text, as the
+// server counts offsets in characters
+function utf16Offsets(text) {
+  const offsets = [];
+  let index = 0;
+  for (const ch of text) {
+    offsets.push(index);
+    index += ch.length;
+  }
+  offsets.push(index);
+  return offsets;
+}
+
+// Mark the mentions of known entities the server found in the page text,
+// last first so splitting text nodes does not move the others
+function showEntities(matches) {
+  removeMarks('data-silvia-entity');
+  const page = pageText();
+  const offsets = utf16Offsets(page.text);
+  const ranges = [];
+  matches.forEach(match => {
+    const from = pointAt(page, offsets[match.start]);
+    const to = pointAt(page, offsets[match.end]);
+    if (!from || !to) {
+      return;
+    }
+    const range = document.createRange();
+    range.setStart(from.node, from.offset);
+    range.setEnd(to.node, to.offset);
+    ranges.push({ range, match });
+  });
+  ranges.reverse().forEach(({ range, match }) => markRange(range, {
+    attribute: 'data-silvia-entity',
+    value: match.entity_id,
+    title: `${match.title} (${match.type})`,
+    background: '#d0ebff'
+  }));
+  return ranges.length;
+}
+
+// The first link on the page whose text names noun
+function linkFor(noun) {
+  const lower = noun.toLowerCase();
+  const link = Array.from(document.querySelectorAll('a[href]')).find(a =>
+    a.textContent.toLowerCase().includes(lower) && /^https?:/.test(a.href));
+  return link ? link.href : '';
+}
+
+// Highlights made since the page loaded, sent with the next capture
+let pendingHighlights = [];
+
 // Listen for messages from popup
 chrome.runtime.onMessage.addListener((request, sender, sendResponse) => {
   if (request.action === 'extractContent') {
     const content = extractPageContent();
+    content.highlights = pendingHighlights;
     sendResponse(content);
   } else if (request.action === 'getSelection') {
     const selection = window.getSelection().toString();
     sendResponse({ selection });
+  } else if (request.action === 'addHighlight') {
+    const highlight = selectionHighlight(request.note, request.kind);
+    if (highlight) {
+      pendingHighlights.push(highlight);
+    }
+    sendResponse({ highlight, pending: pendingHighlights.length });
+  } else if (request.action === 'getHighlights') {
+    sendResponse({ pending: pendingHighlights });
+  } else if (request.action === 'clearHighlights') {
+    pendingHighlights = [];
+    sendResponse({ pending: 0 });
+  } else if (request.action === 'showHighlights') {
+    sendResponse({ shown: showHighlights(request.highlights || []) });
+  } else if (request.action === 'getPageText') {
+    removeMarks('data-silvia-entity');
+    sendResponse({ url: window.location.href, text: pageText().text });
+  } else if (request.action === 'showEntities') {
+    const shown = showEntities(request.matches || []);
+    const unknown = (request.unknown || []).map(noun => ({ ...noun, url: noun.url || linkFor(noun.text) }));
+    sendResponse({ shown, unknown });
   }
   return true; // Keep message channel open for async response
 });
\ No newline at end of file
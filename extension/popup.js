@@ -30,9 +30,176 @@ document.addEventListener('DOMContentLoaded', async () => {
   // Setup event listeners
   document.getElementById('capture-btn').addEventListener('click', captureContent);
   document.getElementById('save-settings').addEventListener('click', saveSettings);
-  document.getElementById('capture-selection').addEventListener('change', toggleSelectionMode);
+  document.getElementById('highlight-btn').addEventListener('click', addHighlight);
+  
+  // Show the highlights already saved for this page and the entities it
+  // mentions
+  if (tab && isConnected) {
+    await showSavedHighlights(tab);
+    await showKnownEntities(tab);
+  }
 });
 
+// Ask Silvia which known entities the page mentions, mark them on the page
+// and list them, with names Silvia does not know yet
+async function showKnownEntities(tab) {
+  try {
+    const page = await chrome.tabs.sendMessage(tab.id, { action: 'getPageText' });
+    if (!page || !page.text) {
+      return;
+    }
+    const response = await fetch(`${serverUrl}/api/annotate`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...(authToken ? { 'Authorization': `Bearer ${authToken}` } : {})
+      },
+      body: JSON.stringify({ url: page.url, text: page.text })
+    });
+    if (!response.ok) {
+      throw new Error(`Server returned ${response.status}`);
+    }
+    const result = await response.json();
+    const shown = await chrome.tabs.sendMessage(tab.id, {
+      action: 'showEntities',
+      matches: result.matches,
+      unknown: result.unknown
+    });
+    renderKnownEntities(result.entities, shown ? shown.unknown : result.unknown, tab);
+  } catch (error) {
+    console.error('Failed to find known entities:', error);
+  }
+}
+
+function renderKnownEntities(entities, unknown, tab) {
+  const knownList = document.getElementById('known-entities');
+  const unknownList = document.getElementById('unknown-names');
+  knownList.innerHTML = '';
+  unknownList.innerHTML = '';
+  
+  document.getElementById('known-title').textContent = entities.length > 0
+    ? `Known on this page (${entities.length})`
+    : 'No known entities on this page';
+  entities.forEach(entity => {
+    const item = document.createElement('li');
+    const name = document.createElement('span');
+    name.textContent = `${entity.title} `;
+    const type = document.createElement('span');
+    type.className = 'entity-type';
+    type.textContent = `${entity.type} · ${entity.mentions}×`;
+    name.appendChild(type);
+    item.appendChild(name);
+    knownList.appendChild(item);
+  });
+  
+  document.getElementById('unknown-title').textContent = unknown.length > 0 ? 'Not in Silvia yet' : '';
+  unknown.forEach(noun => {
+    const item = document.createElement('li');
+    const name = document.createElement('span');
+    name.textContent = noun.text;
+    item.appendChild(name);
+    const queueBtn = document.createElement('button');
+    queueBtn.textContent = 'Add to queue';
+    queueBtn.title = noun.url || wikipediaURL(noun.text);
+    queueBtn.addEventListener('click', () => queueName(noun, tab, queueBtn));
+    item.appendChild(queueBtn);
+    unknownList.appendChild(item);
+  });
+  
+  document.getElementById('known').style.display = 'block';
+}
+
+// Where to read about a name the page does not link
+function wikipediaURL(name) {
+  return `https://en.wikipedia.org/wiki/${encodeURIComponent(name.replace(/ /g, '_'))}`;
+}
+
+// Queue the page the name links to, or its Wikipedia article
+async function queueName(noun, tab, button) {
+  button.disabled = true;
+  try {
+    const response = await fetch(`${serverUrl}/api/queue/add`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...(authToken ? { 'Authorization': `Bearer ${authToken}` } : {})
+      },
+      body: JSON.stringify({
+        url: noun.url || wikipediaURL(noun.text),
+        priority: 1,
+        from_source: tab.url,
+        description: `${noun.text}, mentioned on ${tab.title || tab.url}`
+      })
+    });
+    if (!response.ok) {
+      const data = await response.json().catch(() => ({}));
+      throw new Error(data.error || `Server returned ${response.status}`);
+    }
+    button.textContent = 'Queued';
+  } catch (error) {
+    button.disabled = false;
+    showMessage('error', error.message || 'Failed to queue');
+  }
+}
+
+let pendingCount = 0;
+let savedCount = 0;
+
+function updateHighlightCount() {
+  const parts = [];
+  if (pendingCount > 0) {
+    parts.push(`${pendingCount} new highlight${pendingCount === 1 ? '' : 's'} to capture`);
+  }
+  if (savedCount > 0) {
+    parts.push(`${savedCount} saved highlight${savedCount === 1 ? '' : 's'} shown`);
+  }
+  document.getElementById('highlight-count').textContent = parts.join(' · ');
+}
+
+// Fetch the highlights saved for the page and mark them on it
+async function showSavedHighlights(tab) {
+  try {
+    const pending = await chrome.tabs.sendMessage(tab.id, { action: 'getHighlights' });
+    pendingCount = pending && pending.pending ? pending.pending.length : 0;
+    
+    const response = await fetch(`${serverUrl}/api/sources/highlights?url=${encodeURIComponent(tab.url)}`, {
+      headers: authToken ? { 'Authorization': `Bearer ${authToken}` } : {}
+    });
+    if (response.ok) {
+      const highlights = await response.json();
+      if (highlights.length > 0) {
+        const result = await chrome.tabs.sendMessage(tab.id, { action: 'showHighlights', highlights });
+        savedCount = result ? result.shown : 0;
+      }
+    }
+  } catch (error) {
+    console.error('Failed to load highlights:', error);
+  }
+  updateHighlightCount();
+}
+
+// Keep the selected passage, with the note, to send with the next capture
+async function addHighlight() {
+  const [tab] = await chrome.tabs.query({ active: true, currentWindow: true });
+  if (!tab) {
+    return;
+  }
+  const noteEl = document.getElementById('highlight-note');
+  const result = await chrome.tabs.sendMessage(tab.id, {
+    action: 'addHighlight',
+    note: noteEl.value,
+    kind: document.getElementById('highlight-kind').value
+  });
+  if (!result || !result.highlight) {
+    showMessage('error', 'Select some text on the page first');
+    return;
+  }
+  noteEl.value = '';
+  pendingCount = result.pending;
+  updateHighlightCount();
+  showMessage('info', 'Highlight added; it is saved when you capture the page');
+}
+
 // Check connection to Silvia server
 async function checkConnection() {
   const statusEl = document.getElementById('status');
@@ -82,18 +249,6 @@ async function saveSettings() {
   await checkConnection();
 }
 
-// Toggle selection mode
-function toggleSelectionMode(event) {
-  const captureLinks = document.getElementById('capture-links');
-  if (event.target.checked) {
-    captureLinks.disabled = true;
-    captureLinks.checked = false;
-  } else {
-    captureLinks.disabled = false;
-    captureLinks.checked = true;
-  }
-}
-
 // Capture content from the current page
 async function captureContent() {
   const captureBtn = document.getElementById('capture-btn');
@@ -127,30 +282,36 @@ async function captureContent() {
     const requestData = {
       url: results.url,
       title: results.title,
-      metadata: results.metadata || {}
+      metadata: results.metadata || {},
+      html: results.html,
+      text: results.text,
+      links: captureLinks ? results.links : [],
+      highlights: results.highlights || []
     };
     
-    // Handle selection-only mode
-    if (captureSelection && results.selection) {
-      requestData.text = results.selection;
-      requestData.html = ''; // Don't send full HTML in selection mode
-      requestData.links = []; // No links in selection mode
-    } else {
-      requestData.html = results.html;
-      requestData.text = results.text;
-      requestData.links = captureLinks ? results.links : [];
-      
-      // Debug logging
-      console.log('Capture links enabled:', captureLinks);
-      console.log('Number of links found:', results.links ? results.links.length : 0);
-      if (results.links && results.links.length > 0) {
-        console.log('Sample links:', results.links.slice(0, 3));
+    // Debug logging
+    console.log('Capture links enabled:', captureLinks);
+    console.log('Number of links found:', results.links ? results.links.length : 0);
+    if (results.links && results.links.length > 0) {
+      console.log('Sample links:', results.links.slice(0, 3));
+    }
+    
+    // The current selection becomes a highlight too, unless it already is one
+    const selection = (results.selection || '').trim();
+    if (captureSelection && selection && !requestData.highlights.some(h => h.text === selection)) {
+      const selected = await chrome.tabs.sendMessage(tab.id, {
+        action: 'addHighlight',
+        note: document.getElementById('highlight-note').value,
+        kind: document.getElementById('highlight-kind').value
+      });
+      if (selected && selected.highlight) {
+        requestData.highlights.push(selected.highlight);
       }
     }
     
     // Add capture metadata
     requestData.metadata['captured_at'] = new Date().toISOString();
-    requestData.metadata['capture_mode'] = captureSelection ? 'selection' : 'full';
+    requestData.metadata['capture_mode'] = requestData.highlights.length > 0 ? 'highlights' : 'full';
     
     // Add force flag if enabled
     if (forceUpdate) {
@@ -172,6 +333,7 @@ async function captureContent() {
     
     if (response.ok && responseData.success) {
       showMessage('success', responseData.message || 'Content captured successfully!');
+      await chrome.tabs.sendMessage(tab.id, { action: 'clearHighlights' });
       
       // Show stats if available
       if (responseData.stats) {
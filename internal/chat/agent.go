@@ -0,0 +1,314 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"silvia/internal/llm"
+	"silvia/internal/tools"
+)
+
+// DefaultMaxSteps bounds how many times the agent asks the model for tool
+// calls before it has to answer with what it found
+const DefaultMaxSteps = 6
+
+// maxToolResultChars caps how much of a single tool result the model sees
+const maxToolResultChars = 12000
+
+// Step is one tool call made while answering a message
+type Step struct {
+	Round    int            // Which model turn requested the call, from 1
+	Tool     string         // Tool name
+	Args     map[string]any // Arguments as sent by the model
+	Result   tools.ToolResult
+	Declined bool // A mutating tool the user did not confirm
+	Duration time.Duration
+}
+
+// ConfirmFunc asks the user whether a mutating tool call may run
+type ConfirmFunc func(tool string, args map[string]any) bool
+
+// Agent answers messages with native function calling: the model calls tools,
+// sees their results, calls more as needed, and then an answer is written
+// from everything it found. Mutating tools only run if confirmed.
+type Agent struct {
+	tools    *tools.Manager
+	llm      *llm.Client
+	maxSteps int
+	confirm  ConfirmFunc
+	onStep   func(Step)
+	onReset  func()
+}
+
+// NewAgent creates an agent using the given tools and LLM client
+func NewAgent(toolManager *tools.Manager, llmClient *llm.Client) *Agent {
+	return &Agent{
+		tools:    toolManager,
+		llm:      llmClient,
+		maxSteps: DefaultMaxSteps,
+	}
+}
+
+// SetMaxSteps sets how many rounds of tool calls are allowed per message
+func (a *Agent) SetMaxSteps(n int) {
+	if n < 1 {
+		n = 1
+	}
+	a.maxSteps = n
+}
+
+// SetConfirm sets the function that approves mutating tool calls. Without
+// one, mutating tools are never run.
+func (a *Agent) SetConfirm(confirm ConfirmFunc) {
+	a.confirm = confirm
+}
+
+// OnStep sets a function called after each tool call, for showing a trace
+func (a *Agent) OnStep(fn func(Step)) {
+	a.onStep = fn
+}
+
+// OnReset sets a function called when the answer streamed so far is
+// abandoned, before the list of results that replaces it is streamed. Without
+// one, that list is only returned once part of an answer was streamed.
+func (a *Agent) OnReset(fn func()) {
+	a.onReset = fn
+}
+
+const agentSystemPrompt = `You are a knowledge graph assistant that ONLY uses information from the user's local knowledge graph.
+Use the tools to look things up. Call them as often as you need: search first, then read entities or follow relationships to fill any gaps.
+Do NOT answer from general knowledge or training data.
+Only change the graph or queue (merge, rename, delete, create, refine, queue changes, ingest) when the user explicitly asks for it.
+When you have gathered what you need, stop calling tools and reply with a short summary of what you found.`
+
+// Run answers message, given the earlier turns of the conversation, and
+// returns the answer along with the tool calls made. The answer is passed to
+// onDelta as it is generated if onDelta is not nil.
+func (a *Agent) Run(ctx context.Context, history []llm.Message, message string, onDelta func(string)) (string, []Step, error) {
+	messages := []llm.Message{{Role: "system", Content: agentSystemPrompt}}
+	messages = append(messages, history...)
+	messages = append(messages, llm.Message{Role: "user", Content: message})
+
+	definitions := a.toolDefinitions()
+	planner := a.llm.ForTask(llm.TaskChatTools)
+
+	var steps []Step
+	limitReached := true
+	for round := 1; round <= a.maxSteps; round++ {
+		response, err := planner.CompleteWithFunctions(ctx, llm.FunctionCallRequest{
+			Messages:   messages,
+			Tools:      definitions,
+			ToolChoice: "auto",
+		})
+		if err != nil {
+			return "", steps, err
+		}
+		if len(response.ToolCalls) == 0 {
+			limitReached = false
+			break
+		}
+
+		messages = append(messages, llm.Message{
+			Role:      "assistant",
+			Content:   response.Content,
+			ToolCalls: response.ToolCalls,
+		})
+		for _, call := range response.ToolCalls {
+			step := a.runTool(ctx, round, call)
+			if err := ctx.Err(); err != nil {
+				return "", steps, err
+			}
+			steps = append(steps, step)
+			if a.onStep != nil {
+				a.onStep(step)
+			}
+			messages = append(messages, llm.Message{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    toolResultContent(step),
+			})
+		}
+	}
+
+	answer, err := a.answer(ctx, history, message, steps, limitReached, onDelta)
+	return answer, steps, err
+}
+
+// toolDefinitions converts the tool schemas for function calling
+func (a *Agent) toolDefinitions() []llm.Tool {
+	schemas := a.tools.GetToolSchemas()
+	definitions := make([]llm.Tool, 0, len(schemas))
+	for _, schema := range schemas {
+		name, _ := schema["name"].(string)
+		description, _ := schema["description"].(string)
+		definitions = append(definitions, llm.Tool{
+			Type: "function",
+			Function: llm.Function{
+				Name:        name,
+				Description: description,
+				Parameters:  schema["parameters"],
+			},
+		})
+	}
+	return definitions
+}
+
+// runTool executes one requested call, asking for confirmation first if the
+// tool changes state
+func (a *Agent) runTool(ctx context.Context, round int, call llm.ToolCall) (step Step) {
+	step = Step{Round: round, Tool: call.Function.Name, Args: map[string]any{}}
+	start := time.Now()
+	defer func() { step.Duration = time.Since(start) }()
+
+	if args := strings.TrimSpace(call.Function.Arguments); args != "" {
+		if err := json.Unmarshal([]byte(args), &step.Args); err != nil {
+			step.Result = tools.ToolResult{Error: fmt.Sprintf("invalid arguments: %v", err)}
+			return step
+		}
+	}
+
+	if a.tools.IsMutating(step.Tool) && (a.confirm == nil || !a.confirm(step.Tool, step.Args)) {
+		step.Declined = true
+		step.Result = tools.ToolResult{Error: "the user did not allow this action"}
+		return step
+	}
+
+	result, err := a.tools.Execute(ctx, step.Tool, step.Args)
+	if err != nil && result.Error == "" {
+		result.Success = false
+		result.Error = err.Error()
+	}
+	step.Result = result
+	return step
+}
+
+// toolResultContent renders a tool result for the model, truncating large data
+func toolResultContent(step Step) string {
+	var payload any
+	if step.Result.Success {
+		payload = map[string]any{"success": true, "data": step.Result.Data}
+	} else {
+		payload = map[string]any{"success": false, "error": step.Result.Error}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf(`{"success": false, "error": %q}`, err.Error())
+	}
+	content := []rune(string(data))
+	if len(content) > maxToolResultChars {
+		return string(content[:maxToolResultChars]) + "... (truncated)"
+	}
+	return string(content)
+}
+
+// answer writes the reply from the tool results with the chat-answer model.
+// The trace is given as text rather than tool messages so any model can
+// write it, whether or not it supports function calling.
+func (a *Agent) answer(ctx context.Context, history []llm.Message, message string, steps []Step, limitReached bool, onDelta func(string)) (string, error) {
+	systemPrompt := `You are a knowledge graph assistant that ONLY reports information found in the local knowledge graph.
+Base your response ONLY on the tool results provided. Do not add information from general knowledge.
+Rules:
+1. Only mention information that appears in the tool results
+2. If a search returned results, that means the information WAS found - summarize it
+3. If the requested information is not in the results, say clearly that it is not in the knowledge graph
+4. Be concise but informative
+5. If an action was not allowed by the user, say that it was not performed
+6. Cite the entity and source IDs each statement comes from as [[id]] links, e.g. [[people/jane-doe]]
+7. Only cite IDs that appear in the tool results; every factual sentence needs at least one citation`
+
+	var prompt strings.Builder
+	if len(history) > 0 {
+		prompt.WriteString("Recent conversation:\n")
+		for _, turn := range history {
+			content := []rune(turn.Content)
+			if len(content) > 300 {
+				content = append(content[:300], []rune("...")...)
+			}
+			fmt.Fprintf(&prompt, "%s: %s\n", turn.Role, string(content))
+		}
+		prompt.WriteString("\n")
+	}
+	fmt.Fprintf(&prompt, "The user asked: %q\n\n", message)
+
+	if len(steps) == 0 {
+		prompt.WriteString("No tools were called, so nothing was looked up in the knowledge graph.\n")
+	} else {
+		prompt.WriteString("Tool calls and results:\n")
+		for _, step := range steps {
+			args, _ := json.Marshal(step.Args)
+			fmt.Fprintf(&prompt, "\nTool: %s\nArguments: %s\nResult: %s\n", step.Tool, args, toolResultContent(step))
+		}
+		if ids := sortedIDs(collectIDs(steps)); len(ids) > 0 {
+			fmt.Fprintf(&prompt, "\nIDs you may cite: %s\n", strings.Join(ids, ", "))
+		}
+	}
+	if limitReached {
+		prompt.WriteString("\nThe lookup stopped at the step limit; answer with what was found and say what is missing.\n")
+	}
+	prompt.WriteString("\nYour response:")
+
+	streamed := false
+	stream := onDelta
+	if onDelta != nil {
+		stream = func(delta string) {
+			streamed = true
+			onDelta(delta)
+		}
+	}
+	response, err := a.llm.ForTask(llm.TaskChatAnswer).CompleteWithSystemStream(ctx, systemPrompt, prompt.String(), "", stream)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		// Fall back to listing the results, which must not be appended to
+		// a partial answer
+		fallback := basicFormatResponse(steps)
+		if streamed && a.onReset != nil {
+			a.onReset()
+			streamed = false
+		}
+		if onDelta != nil && !streamed {
+			onDelta(fallback)
+		}
+		return fallback, nil
+	}
+	return response, nil
+}
+
+// basicFormatResponse lists tool results when the answer model is unavailable
+func basicFormatResponse(steps []Step) string {
+	var response strings.Builder
+
+	for _, step := range steps {
+		result := step.Result
+		if !result.Success {
+			response.WriteString(fmt.Sprintf("%s: %s\n", step.Tool, result.Error))
+			continue
+		}
+		switch step.Tool {
+		case "search_entities":
+			response.WriteString("Search results:\n")
+			if matches, ok := result.Data.([]map[string]any); ok {
+				for _, match := range matches {
+					response.WriteString(fmt.Sprintf("- %s [[%s]]\n", match["title"], match["id"]))
+				}
+			}
+		case "read_entity":
+			response.WriteString("Entity details:\n")
+			dataStr, _ := json.MarshalIndent(result.Data, "", "  ")
+			response.Write(dataStr)
+			response.WriteString("\n")
+		default:
+			response.WriteString(fmt.Sprintf("Tool %s completed successfully.\n", step.Tool))
+		}
+	}
+
+	if response.Len() == 0 {
+		return "Nothing was found in the knowledge graph."
+	}
+	return response.String()
+}
@@ -0,0 +1,209 @@
+package chat
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CitationCheck records which IDs an answer cites and where it falls short
+type CitationCheck struct {
+	Cited   []string `json:"cited,omitempty"`   // IDs cited that the tools returned
+	Unknown []string `json:"unknown,omitempty"` // IDs cited that no tool returned
+	Uncited []string `json:"uncited,omitempty"` // Sentences that cite nothing
+}
+
+// OK reports whether every citation is grounded and every sentence cites something
+func (c *CitationCheck) OK() bool {
+	return c == nil || (len(c.Unknown) == 0 && len(c.Uncited) == 0)
+}
+
+var (
+	// idPattern matches entity and source IDs such as people/jane-doe
+	idPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*/[a-z0-9][a-z0-9._/-]*$`)
+	// wikiLinkPattern matches [[id]] and [[id|label]] links
+	wikiLinkPattern = regexp.MustCompile(`\[\[([^\]|]+)(?:\|[^\]]*)?\]\]`)
+	// sentenceEnd splits a line into sentences
+	sentenceEnd = regexp.MustCompile(`[.!?](\s+|$)`)
+	// absencePattern matches sentences saying something was not found, which
+	// have nothing to cite
+	absencePattern = regexp.MustCompile(`(?i)\b(not|no|nothing|none|couldn't|could not)\b.*\b(found|graph|information|results?|mention\w*)\b`)
+)
+
+// minCitedWords is the shortest sentence that must carry a citation
+const minCitedWords = 5
+
+// collectIDs returns the entity and source IDs that appear anywhere in the
+// results of the successful tool calls
+func collectIDs(steps []Step) map[string]bool {
+	ids := make(map[string]bool)
+	for _, step := range steps {
+		if !step.Result.Success || step.Result.Data == nil {
+			continue
+		}
+		// Round-trip through JSON so structs and maps are walked alike
+		data, err := json.Marshal(step.Result.Data)
+		if err != nil {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			continue
+		}
+		walkIDs(value, ids)
+	}
+	return ids
+}
+
+func walkIDs(value any, ids map[string]bool) {
+	switch v := value.(type) {
+	case string:
+		if idPattern.MatchString(v) {
+			ids[v] = true
+		}
+	case []any:
+		for _, item := range v {
+			walkIDs(item, ids)
+		}
+	case map[string]any:
+		for _, item := range v {
+			walkIDs(item, ids)
+		}
+	}
+}
+
+// sortedIDs returns the keys of ids in order
+func sortedIDs(ids map[string]bool) []string {
+	list := make([]string, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// linkCitations turns bare or backquoted mentions of known IDs into [[id]]
+// links, leaving existing links alone
+func linkCitations(answer string, ids map[string]bool) string {
+	// Longest first so people/jane-doe-jr is not linked as people/jane-doe
+	list := sortedIDs(ids)
+	sort.SliceStable(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+
+	for _, id := range list {
+		answer = strings.ReplaceAll(answer, "`"+id+"`", "[["+id+"]]")
+		answer = linkBare(answer, id)
+	}
+	return answer
+}
+
+// linkBare wraps occurrences of id that are not part of a longer ID or link
+func linkBare(text, id string) string {
+	var out strings.Builder
+	for {
+		i := strings.Index(text, id)
+		if i < 0 {
+			out.WriteString(text)
+			return out.String()
+		}
+		end := i + len(id)
+		before := byte(' ')
+		if i > 0 {
+			before = text[i-1]
+		}
+		after := byte(' ')
+		if end < len(text) {
+			after = text[end]
+		}
+		out.WriteString(text[:i])
+		if isIDChar(before) || before == '[' || before == '|' || isIDChar(after) || after == ']' || after == '|' {
+			out.WriteString(id)
+		} else {
+			out.WriteString("[[" + id + "]]")
+		}
+		text = text[end:]
+	}
+}
+
+func isIDChar(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '_' || b == '/'
+}
+
+// checkCitations checks an answer's [[id]] links against the IDs the tools
+// returned and lists the sentences that cite nothing. When nothing was
+// looked up, only invented citations are reported.
+func checkCitations(answer string, ids map[string]bool) *CitationCheck {
+	check := &CitationCheck{}
+	seen := make(map[string]bool)
+	for _, match := range wikiLinkPattern.FindAllStringSubmatch(answer, -1) {
+		id := strings.TrimSpace(match[1])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if ids[id] {
+			check.Cited = append(check.Cited, id)
+		} else {
+			check.Unknown = append(check.Unknown, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return check
+	}
+
+	inCode := false
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || line == "" || strings.HasPrefix(line, "#") || strings.HasSuffix(line, ":") {
+			continue
+		}
+		line = strings.TrimLeft(line, "-*> 0123456789.")
+		for _, sentence := range splitSentences(line) {
+			if strings.Contains(sentence, "[[") || len(strings.Fields(sentence)) < minCitedWords {
+				continue
+			}
+			if absencePattern.MatchString(sentence) {
+				continue
+			}
+			check.Uncited = append(check.Uncited, sentence)
+		}
+	}
+	return check
+}
+
+// splitSentences splits text after sentence-ending punctuation. A citation
+// just after the full stop belongs to the sentence before it.
+func splitSentences(text string) []string {
+	var sentences []string
+	for text != "" {
+		loc := sentenceEnd.FindStringIndex(text)
+		if loc == nil {
+			sentences = append(sentences, strings.TrimSpace(text))
+			break
+		}
+		end := loc[1]
+		rest := text[end:]
+		for strings.HasPrefix(rest, "[[") {
+			close := strings.Index(rest, "]]")
+			if close < 0 {
+				break
+			}
+			next := close + 2
+			for next < len(rest) && rest[next] == ' ' {
+				next++
+			}
+			end += next
+			rest = text[end:]
+		}
+		if sentence := strings.TrimSpace(text[:end]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		text = text[end:]
+	}
+	return sentences
+}
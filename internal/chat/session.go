@@ -0,0 +1,229 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"silvia/internal/graph"
+)
+
+// sessionIDFormat names sessions by when they started
+const sessionIDFormat = "20060102-150405"
+
+// Session is a saved conversation with the tool calls behind each answer
+type Session struct {
+	ID      string             `json:"id"`
+	Title   string             `json:"title"` // The first question, shortened
+	Created time.Time          `json:"created"`
+	Updated time.Time          `json:"updated"`
+	Turns   []ConversationTurn `json:"turns"`
+}
+
+// NewSession starts an empty session
+func NewSession() *Session {
+	now := time.Now()
+	return &Session{
+		ID:      now.Format(sessionIDFormat),
+		Created: now,
+		Updated: now,
+	}
+}
+
+// SessionInfo summarizes a stored session for listing
+type SessionInfo struct {
+	ID      string
+	Title   string
+	Created time.Time
+	Updated time.Time
+	Turns   int
+}
+
+// SessionStore keeps sessions as one JSON file each in a directory
+type SessionStore struct {
+	dir string
+}
+
+// NewSessionStore creates a store for sessions under dir
+func NewSessionStore(dir string) *SessionStore {
+	return &SessionStore{dir: dir}
+}
+
+func (s *SessionStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes a session, replacing any earlier copy
+func (s *SessionStore) Save(session *Session) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create chat directory: %w", err)
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	// Write then rename so a crash never leaves half a session
+	tmp := s.path(session.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(session.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// Load reads a session by ID
+func (s *SessionStore) Load(id string) (*Session, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid session ID: %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("session not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	return &session, nil
+}
+
+// List returns the stored sessions, most recently used first
+func (s *SessionStore) List() ([]SessionInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read chat directory: %w", err)
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		session, err := s.Load(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue // Skip unreadable files rather than failing the listing
+		}
+		sessions = append(sessions, SessionInfo{
+			ID:      session.ID,
+			Title:   session.Title,
+			Created: session.Created,
+			Updated: session.Updated,
+			Turns:   len(session.Turns),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+// Citations returns every grounded ID cited in the session, in order
+func (s *Session) Citations() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, turn := range s.Turns {
+		if turn.Citations == nil {
+			continue
+		}
+		for _, id := range turn.Citations.Cited {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Entity renders the session as a work entity whose content is the
+// transcript, so an exported chat can be saved into the graph as a note
+func (s *Session) Entity() *graph.Entity {
+	title := s.Title
+	if title == "" {
+		title = s.ID
+	}
+
+	entity := &graph.Entity{
+		Metadata: graph.Metadata{
+			ID:      "works/chat-" + s.ID,
+			Type:    graph.EntityWork,
+			Created: s.Created,
+			Updated: s.Updated,
+			Tags:    []string{"chat"},
+		},
+		Title:   "Chat: " + title,
+		Content: s.Transcript(),
+	}
+	for _, id := range s.Citations() {
+		if strings.HasPrefix(id, "sources/") {
+			entity.Metadata.Sources = append(entity.Metadata.Sources, id)
+		}
+	}
+	return entity
+}
+
+// Markdown renders the session as an entity markdown file
+func (s *Session) Markdown() string {
+	return graph.FormatEntityMarkdown(s.Entity())
+}
+
+// Transcript renders the questions, answers, tool calls and citation
+// warnings as markdown
+func (s *Session) Transcript() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Chat session started %s.\n", s.Created.Format("2 January 2006 15:04"))
+
+	for _, turn := range s.Turns {
+		if turn.Role == "user" {
+			fmt.Fprintf(&out, "\n## %s\n\n", singleLine(turn.Message))
+			continue
+		}
+
+		out.WriteString(strings.TrimSpace(turn.Message))
+		out.WriteString("\n")
+
+		if len(turn.ToolCalls) > 0 {
+			out.WriteString("\n**Tools used**\n\n")
+			for i, call := range turn.ToolCalls {
+				args, _ := json.Marshal(call.Args)
+				status := "ok"
+				if i < len(turn.ToolResults) && !turn.ToolResults[i].Success {
+					status = "failed: " + turn.ToolResults[i].Error
+				}
+				fmt.Fprintf(&out, "- `%s %s` (%s)\n", call.Tool, args, status)
+			}
+		}
+
+		if check := turn.Citations; !check.OK() {
+			out.WriteString("\n**Citation warnings**\n\n")
+			for _, id := range check.Unknown {
+				fmt.Fprintf(&out, "- Cites `%s`, which no tool returned\n", id)
+			}
+			for _, sentence := range check.Uncited {
+				fmt.Fprintf(&out, "- Cites nothing: %q\n", sentence)
+			}
+		}
+	}
+	return out.String()
+}
+
+// singleLine collapses whitespace so a message fits in a heading
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
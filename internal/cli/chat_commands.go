@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"silvia/internal/chat"
+)
+
+const chatUsage = "usage: /chat [list | new | resume <id> | export [id] [--save | <file>]]"
+
+// handleChat lists, resumes and exports saved chat sessions
+func handleChat(ctx context.Context, c *CLI, args []string) error {
+	conversation, err := c.chatInterface()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		session := conversation.Session()
+		fmt.Printf("\nCurrent chat %s (%d messages)\n", HighlightStyle.Render(session.ID), len(session.Turns))
+		fmt.Println(DimStyle.Render(chatUsage))
+		return nil
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.listChats()
+	case "new":
+		conversation.NewSession()
+		fmt.Println(FormatSuccess("Started a new chat"))
+		return nil
+	case "resume":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", chatUsage)
+		}
+		if err := conversation.ResumeSession(args[1]); err != nil {
+			return err
+		}
+		session := conversation.Session()
+		fmt.Println(FormatSuccess(fmt.Sprintf("Resumed %s: %s (%d messages)", session.ID, session.Title, len(session.Turns))))
+		return nil
+	case "export":
+		return c.exportChat(args[1:])
+	default:
+		return fmt.Errorf("%s", chatUsage)
+	}
+}
+
+// chatInterface returns the conversation used for natural language queries,
+// creating it on first use
+func (c *CLI) chatInterface() (*chat.ChatInterface, error) {
+	if c.tools == nil {
+		return nil, fmt.Errorf("chat is not available without tools")
+	}
+	if c.chat == nil {
+		c.chat = chat.NewChatInterface(c.tools, c.llm)
+		c.chat.EnableLogging(c.debug)
+		c.chat.SetSessionStore(c.chatStore())
+		c.chat.Agent().SetConfirm(c.confirmToolCall)
+		c.chat.Agent().OnStep(printAgentStep)
+	}
+	return c.chat, nil
+}
+
+// chatStore returns the store for saved chat sessions
+func (c *CLI) chatStore() *chat.SessionStore {
+	return chat.NewSessionStore(filepath.Join(c.dataDir, ".silvia", "chats"))
+}
+
+// listChats shows saved sessions, most recent first
+func (c *CLI) listChats() error {
+	sessions, err := c.chatStore().List()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println(FormatInfo("No saved chats"))
+		return nil
+	}
+
+	fmt.Printf("\n%s\n\n", HeaderStyle.Render("Saved chats"))
+	current := ""
+	if c.chat != nil {
+		current = c.chat.Session().ID
+	}
+	for _, session := range sessions {
+		marker := " "
+		if session.ID == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %s  %s  %s\n", marker,
+			HighlightStyle.Render(session.ID),
+			DimStyle.Render(fmt.Sprintf("%s %3d msgs", session.Updated.Local().Format("2006-01-02 15:04"), session.Turns)),
+			session.Title)
+	}
+	fmt.Println(DimStyle.Render("\nUse /chat resume <id> to continue one"))
+	return nil
+}
+
+// exportChat writes a session as entity markdown to stdout or a file, or
+// saves it into the graph as a work entity
+func (c *CLI) exportChat(args []string) error {
+	var id, file string
+	save := false
+	for _, arg := range args {
+		switch {
+		case arg == "--save":
+			save = true
+		case id == "" && looksLikeSessionID(arg):
+			id = arg
+		case file == "":
+			file = arg
+		default:
+			return fmt.Errorf("%s", chatUsage)
+		}
+	}
+
+	var session *chat.Session
+	if id == "" {
+		session = c.chat.Session()
+	} else {
+		loaded, err := c.chatStore().Load(id)
+		if err != nil {
+			return err
+		}
+		session = loaded
+	}
+	if len(session.Turns) == 0 {
+		return fmt.Errorf("chat %s has no messages to export", session.ID)
+	}
+
+	switch {
+	case save:
+		entity := session.Entity()
+		if err := c.graph.SaveEntity(entity); err != nil {
+			return fmt.Errorf("failed to save chat: %w", err)
+		}
+		fmt.Println(FormatSuccess(fmt.Sprintf("Saved chat as %s", entity.Metadata.ID)))
+	case file != "":
+		if err := os.WriteFile(file, []byte(session.Markdown()), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		fmt.Println(FormatSuccess(fmt.Sprintf("Exported chat to %s", file)))
+	default:
+		fmt.Println(session.Markdown())
+	}
+	return nil
+}
+
+// looksLikeSessionID reports whether s is a session ID rather than a file name
+func looksLikeSessionID(s string) bool {
+	_, err := time.Parse("20060102-150405", s)
+	return err == nil
+}
+
+// printCitationCheck warns about answer sentences that cite nothing and
+// citations that no tool returned
+func printCitationCheck(check *chat.CitationCheck) {
+	if check == nil {
+		return
+	}
+	if len(check.Cited) > 0 {
+		fmt.Println(DimStyle.Render(fmt.Sprintf("Sources: %d cited", len(check.Cited))))
+	}
+	for _, id := range check.Unknown {
+		fmt.Println(FormatWarning(fmt.Sprintf("Cites [[%s]], which no tool returned", id)))
+	}
+	for _, sentence := range check.Uncited {
+		fmt.Println(FormatWarning("Cites nothing: " + truncateString(sentence, 100)))
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/chzyer/readline"
+	"silvia/internal/chat"
 	"silvia/internal/graph"
 	"silvia/internal/llm"
 	"silvia/internal/operations"
@@ -24,7 +26,7 @@ import (
 type CLI struct {
 	graph      *graph.Manager
 	llm        *llm.Client
-	queue      *SourceQueue
+	queue      *operations.QueueOps // The shared source queue, from ops
 	readline   *readline.Instance
 	sources    *sources.Manager
 	extractor  *sources.Extractor
@@ -32,6 +34,7 @@ type CLI struct {
 	registry   *CommandRegistry
 	tools      *tools.Manager         // Tool manager for operations
 	ops        *operations.Operations // Unified operations layer
+	chat       *chat.ChatInterface    // Conversation for natural language queries
 	termWriter *term.OSCWriter
 	dataDir    string
 	debug      bool
@@ -39,7 +42,7 @@ type CLI struct {
 
 // NewCLI creates a new CLI instance
 func NewCLI(graphManager *graph.Manager, llmClient *llm.Client) *CLI {
-	dataDir := "data" // Default data directory
+	dataDir := graphManager.BaseDir()
 	sourcesManager := sources.NewManager()
 
 	// Create operations layer
@@ -47,16 +50,18 @@ func NewCLI(graphManager *graph.Manager, llmClient *llm.Client) *CLI {
 
 	// Create tool manager
 	var toolsMgr *tools.Manager
+	var queue *operations.QueueOps
 	if ops != nil {
 		toolsMgr = tools.NewManager(ops)
+		queue = ops.Queue
 	}
 
 	return &CLI{
 		graph:      graphManager,
 		llm:        llmClient,
-		queue:      NewSourceQueue(),
+		queue:      queue,
 		sources:    sourcesManager,
-		extractor:  sources.NewExtractor(llmClient),
+		extractor:  newExtractor(llmClient),
 		tracker:    NewSourceTracker(dataDir),
 		registry:   NewCommandRegistry(),
 		tools:      toolsMgr,
@@ -68,21 +73,23 @@ func NewCLI(graphManager *graph.Manager, llmClient *llm.Client) *CLI {
 
 // NewCLIWithOperations creates a new CLI instance with explicit operations
 func NewCLIWithOperations(ops *operations.Operations, graphManager *graph.Manager, llmClient *llm.Client) *CLI {
-	dataDir := "data" // Default data directory
+	dataDir := graphManager.BaseDir()
 	sourcesManager := sources.NewManager()
 
 	// Create tool manager
 	var toolsMgr *tools.Manager
+	var queue *operations.QueueOps
 	if ops != nil {
 		toolsMgr = tools.NewManager(ops)
+		queue = ops.Queue
 	}
 
 	return &CLI{
 		graph:      graphManager,
 		llm:        llmClient,
-		queue:      NewSourceQueue(),
+		queue:      queue,
 		sources:    sourcesManager,
-		extractor:  sources.NewExtractor(llmClient),
+		extractor:  newExtractor(llmClient),
 		tracker:    NewSourceTracker(dataDir),
 		registry:   NewCommandRegistry(),
 		tools:      toolsMgr,
@@ -92,14 +99,72 @@ func NewCLIWithOperations(ops *operations.Operations, graphManager *graph.Manage
 	}
 }
 
+// newExtractor creates an extractor that reports chunk progress on the terminal
+func newExtractor(llmClient *llm.Client) *sources.Extractor {
+	extractor := sources.NewExtractor(llmClient)
+	extractor.SetProgressFunc(printExtractionProgress)
+	return extractor
+}
+
+// printExtractionProgress shows per-chunk progress for long sources
+func printExtractionProgress(p sources.ExtractionProgress) {
+	if p.Total <= 1 {
+		return
+	}
+
+	label := "Extracting"
+	if p.Stage == "summarize" {
+		label = "Summarizing"
+	}
+
+	section := ""
+	if p.Heading != "" {
+		section = DimStyle.Render(" — " + p.Heading)
+	}
+
+	switch {
+	case !p.Done:
+		fmt.Printf("  %s chunk %d/%d%s\n", InfoStyle.Render(label), p.Chunk, p.Total, section)
+	case p.Err != nil:
+		fmt.Printf("    %s\n", FormatWarning(fmt.Sprintf("chunk %d failed: %v", p.Chunk, p.Err)))
+	case p.Stage == "extract":
+		fmt.Printf("    %s\n", DimStyle.Render(fmt.Sprintf("%d entities", p.Entities)))
+	}
+}
+
+// formatChunks describes which chunks of a long source an entity came from
+func formatChunks(chunks []int, total int) string {
+	if total <= 1 || len(chunks) == 0 {
+		return ""
+	}
+	parts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		parts[i] = fmt.Sprintf("%d", chunk)
+	}
+	return DimStyle.Render(fmt.Sprintf(" [chunks %s/%d]", strings.Join(parts, ","), total))
+}
+
 // GetOperations returns the operations layer
 func (c *CLI) GetOperations() *operations.Operations {
 	return c.ops
 }
 
-// LoadQueue loads the queue from a file
-func (c *CLI) LoadQueue(filePath string) error {
-	return c.queue.LoadFromFile(filePath)
+// events returns the event log that followers of the data directory read,
+// or nil, which ignores events, without an operations layer
+func (c *CLI) events() *operations.EventLog {
+	if c.ops == nil {
+		return nil
+	}
+	return c.ops.Events
+}
+
+// publishIngested records that a source was ingested, as SourceOps does
+func (c *CLI) publishIngested(url string, extraction *sources.ExtractionResult) {
+	c.events().Publish(operations.EventSourceIngested, url, map[string]any{
+		"entities":      len(extraction.Entities),
+		"links":         len(extraction.Links),
+		"failed_chunks": extraction.FailedChunks,
+	})
 }
 
 // SetDebug enables or disables debug mode
@@ -160,8 +225,16 @@ func (c *CLI) Run(ctx context.Context) error {
 			return nil
 		}
 
-		// Process the command
-		if err := c.processInput(ctx, line); err != nil {
+		// Process the command. Ctrl-C cancels it rather than exiting:
+		// readline only sees the key while it is reading a line.
+		cmdCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err = c.processInput(cmdCtx, line)
+		cancelled := cmdCtx.Err() != nil && ctx.Err() == nil
+		stop()
+		if cancelled {
+			fmt.Println()
+			fmt.Println(FormatWarning("Cancelled"))
+		} else if err != nil {
 			fmt.Printf("Error: %v\n", err)
 		}
 	}
@@ -600,23 +673,9 @@ func (c *CLI) createEntity(entityType, id string) error {
 
 // createLink creates a relationship between two entities
 func (c *CLI) createLink(sourceID, relType, targetID string) error {
-	// Load source entity
-	source, err := c.graph.LoadEntity(sourceID)
-	if err != nil {
-		return fmt.Errorf("source entity not found: %w", err)
-	}
-
-	// Check if target exists
-	if !c.graph.EntityExists(targetID) {
-		return fmt.Errorf("target entity not found: %s", targetID)
-	}
-
-	// Add relationship
-	source.AddRelationship(relType, targetID, nil, "")
-
-	// Save entity (this will also update back-references)
-	if err := c.graph.SaveEntity(source); err != nil {
-		return fmt.Errorf("failed to save relationship: %w", err)
+	// Add the relationship; saving also updates back-references
+	if _, err := c.ops.Entity.LinkEntities(sourceID, relType, targetID); err != nil {
+		return err
 	}
 
 	fmt.Printf("✅ Created link: %s → %s → %s\n", sourceID, relType, targetID)
@@ -732,200 +791,97 @@ func (c *CLI) moveEntity(oldID, newID string) error {
 		return nil
 	}
 	// Perform the move
-	if err := c.graph.MoveEntity(oldID, newID); err != nil {
+	if _, err := c.ops.Entity.MoveEntity(oldID, newID); err != nil {
 		return fmt.Errorf("move failed: %w", err)
 	}
 	fmt.Printf("\n✅ Successfully moved %s to %s\n", oldID, newID)
 	return nil
 }
 
-// handleNaturalQuery processes natural language queries using tools and LLM
+// handleNaturalQuery answers a natural language query with the chat agent,
+// which looks things up with tools over as many steps as it needs
 func (c *CLI) handleNaturalQuery(ctx context.Context, query string) error {
-	fmt.Println("🔍 Analyzing your query...")
-
-	// Use the LLM to determine which tools to use
-	toolCalls, err := c.extractToolCallsForQuery(ctx, query)
+	conversation, err := c.chatInterface()
 	if err != nil {
-		// Fallback to simple search if LLM fails
 		return c.fallbackSearch(query)
 	}
 
-	// Execute the tools
-	var allResults []map[string]any
-	for _, call := range toolCalls {
-		result, err := c.tools.Execute(ctx, call.Tool, call.Args)
-		if err != nil {
-			fmt.Printf("Warning: Tool %s failed: %v\n", call.Tool, err)
-			continue
-		}
-		if result.Success && result.Data != nil {
-			// Store result with tool context
-			allResults = append(allResults, map[string]any{
-				"tool": call.Tool,
-				"args": call.Args,
-				"data": result.Data,
-			})
-		}
-	}
-
-	// Build context from tool results ONLY
-	var context strings.Builder
-	context.WriteString("You are a knowledge graph assistant that ONLY uses information from the local knowledge graph.\n")
-	context.WriteString("IMPORTANT: Base your response ONLY on the tool results below. Do not add information from general knowledge.\n")
-	context.WriteString("If the requested information is not in the tool results, say so clearly.\n\n")
-	context.WriteString("The user asked: \"" + query + "\"\n\n")
+	fmt.Println("🔍 Analyzing your query...")
 
-	if len(allResults) > 0 {
-		context.WriteString("Tool results from the knowledge graph:\n")
-		for _, result := range allResults {
-			context.WriteString(fmt.Sprintf("\nTool: %s\n", result["tool"]))
-			// Format the data based on tool type
-			c.formatToolResultForContext(&context, result["tool"].(string), result["data"])
+	started := false
+	answer := newStreamPrinter(os.Stdout, "")
+	// A terminal cannot take back a partial answer, so say it was abandoned
+	// before the results listed in its place
+	conversation.Agent().OnReset(func() {
+		answer.Finish()
+		fmt.Println(FormatWarning("Answer interrupted; listing what was found instead"))
+		answer = newStreamPrinter(os.Stdout, "")
+	})
+	turn, err := conversation.Ask(ctx, query, func(delta string) {
+		if !started {
+			fmt.Println()
+			started = true
 		}
-	} else {
-		context.WriteString("No relevant information found in the knowledge graph.\n")
-	}
-
-	context.WriteString("\nBased ONLY on these tool results, provide a helpful response to the user.\n")
-	context.WriteString("IMPORTANT: The information above IS available in the knowledge graph - summarize it for the user.\n")
-	context.WriteString("Rules:\n")
-	context.WriteString("1. Only mention information that appears in the tool results above\n")
-	context.WriteString("2. If a search returned results, that means the information WAS found\n")
-	context.WriteString("3. Be concise but informative - summarize what you found\n")
-	context.WriteString("4. Do not add facts from your general knowledge - only report what's in the results\n")
-	context.WriteString("5. Do not say information is limited if you found entities - describe what you found\n")
-
-	// Get LLM response
-	if c.debug {
-		fmt.Printf("DEBUG: Sending context to LLM (%d chars)\n", len(context.String()))
-	}
-
-	response, err := c.llm.Complete(ctx, context.String(), "")
+		answer.Write(delta)
+	})
+	answer.Finish()
 	if err != nil {
-		return fmt.Errorf("LLM query failed: %w", err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Println(FormatWarning(fmt.Sprintf("Chat failed (%v); falling back to search", err)))
+		return c.fallbackSearch(query)
 	}
 
 	if c.debug {
-		fmt.Printf("DEBUG: Received response (%d chars)\n", len(response))
+		fmt.Printf("DEBUG: Received response (%d chars)\n", len(turn.Message))
 	}
 
-	fmt.Printf("\n%s\n\n", response)
+	fmt.Println()
+	printCitationCheck(turn.Citations)
 	return nil
 }
 
-// extractToolCallsForQuery determines which tools to use for a natural language query
-func (c *CLI) extractToolCallsForQuery(ctx context.Context, query string) ([]tools.ToolCall, error) {
-	// Use the new LLM operations for structured function calling if available
-	if c.ops != nil && c.ops.LLM != nil && c.tools != nil {
-		// Get tool schemas from the cached tool manager
-		toolSchemas := c.tools.GetToolSchemas()
-
-		response, err := c.ops.LLM.ExecuteFunctionCall(ctx, query, toolSchemas)
-		if err == nil && len(response.ToolCalls) > 0 {
-			// Convert LLM tool calls to our tool format
-			var toolCalls []tools.ToolCall
-			for _, tc := range response.ToolCalls {
-				var args map[string]any
-				if err := json.Unmarshal(tc.Function.Arguments, &args); err != nil {
-					if c.debug {
-						fmt.Printf("Failed to unmarshal tool arguments: %v\n", err)
-					}
-					continue
-				}
-				toolCalls = append(toolCalls, tools.ToolCall{
-					Tool: tc.Function.Name,
-					Args: args,
-				})
-			}
-			if c.debug {
-				fmt.Printf("LLM function calling returned %d tool calls\n", len(toolCalls))
-			}
-			return toolCalls, nil
-		}
-		if c.debug && err != nil {
-			fmt.Printf("LLM function calling failed: %v, falling back to manual parsing\n", err)
-		}
-		// Fall through to legacy method if LLM ops not available or failed
-	}
-
-	// Legacy method: Build prompt for tool extraction
-	var prompt strings.Builder
-	prompt.WriteString("You are a knowledge graph assistant that MUST use tools to access information.\n")
-	prompt.WriteString("Analyze the user's query and determine which tools to use.\n\n")
-
-	prompt.WriteString("Available tools:\n")
-	prompt.WriteString("- search_entities: Search for entities by keyword\n")
-	prompt.WriteString("- read_entity: Read a specific entity by ID\n")
-	prompt.WriteString("- get_related: Get entities related to a specific entity\n\n")
-
-	prompt.WriteString("User query: ")
-	prompt.WriteString(query)
-	prompt.WriteString("\n\n")
+// printAgentStep shows one tool call made while answering a query
+func printAgentStep(step chat.Step) {
+	args, _ := json.Marshal(step.Args)
+	call := fmt.Sprintf("%s %s", step.Tool, truncateString(string(args), 80))
 
-	prompt.WriteString("IMPORTANT: You MUST use tools to retrieve ANY information about entities.\n")
-	prompt.WriteString("Even if you think you know the answer, you MUST use tools.\n\n")
-
-	prompt.WriteString("Respond with tool calls in this format (one per line):\n")
-	prompt.WriteString("TOOL: tool_name ARG: argument_name=value\n")
-	prompt.WriteString("Example:\n")
-	prompt.WriteString("TOOL: search_entities ARG: query=elon musk\n\n")
-	prompt.WriteString("Your response:")
-
-	// Get LLM response
-	response, err := c.llm.Complete(ctx, prompt.String(), "")
-	if err != nil {
-		return nil, err
+	switch {
+	case step.Declined:
+		fmt.Printf("  %s %s %s\n", WarningStyle.Render("⊘"), DimStyle.Render(call), WarningStyle.Render("not run"))
+	case !step.Result.Success:
+		fmt.Printf("  %s %s %s\n", ErrorStyle.Render("✗"), DimStyle.Render(call), ErrorStyle.Render(truncateString(step.Result.Error, 80)))
+	default:
+		fmt.Printf("  %s %s %s\n", SuccessStyle.Render("✓"), DimStyle.Render(call), DimStyle.Render(step.Duration.Round(time.Millisecond).String()))
 	}
-
-	// Parse tool calls from response
-	return c.parseToolCalls(response), nil
 }
 
-// parseToolCalls extracts tool calls from LLM response
-func (c *CLI) parseToolCalls(response string) []tools.ToolCall {
-	var calls []tools.ToolCall
-
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "TOOL:") {
-			parts := strings.Split(line, "ARG:")
-			if len(parts) >= 2 {
-				toolName := strings.TrimSpace(strings.TrimPrefix(parts[0], "TOOL:"))
-				argPart := strings.TrimSpace(parts[1])
-
-				// Parse arguments
-				args := make(map[string]any)
-				argPairs := strings.SplitSeq(argPart, ",")
-				for pair := range argPairs {
-					kv := strings.SplitN(pair, "=", 2)
-					if len(kv) == 2 {
-						args[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
-					}
-				}
-
-				if toolName != "" && len(args) > 0 {
-					calls = append(calls, tools.ToolCall{
-						Tool: toolName,
-						Args: args,
-					})
-				}
-			}
-		}
+// truncateString shortens s to at most max runes, marking the cut with "..."
+func truncateString(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
 	}
+	return string(runes[:max-3]) + "..."
+}
 
-	// If no explicit tools found, try to infer from query
-	if len(calls) == 0 {
-		// Default to searching for key terms
-		calls = append(calls, tools.ToolCall{
-			Tool: "search_entities",
-			Args: map[string]any{
-				"query": response,
-			},
-		})
+// confirmToolCall asks before the agent runs a tool that changes the graph or queue
+func (c *CLI) confirmToolCall(tool string, args map[string]any) bool {
+	if c.readline == nil {
+		return false
 	}
 
-	return calls
+	data, _ := json.Marshal(args)
+	fmt.Printf("\n%s %s %s\n", WarningStyle.Render("⚠️  The assistant wants to run"), HighlightStyle.Render(tool), DimStyle.Render(string(data)))
+	fmt.Print(HighlightStyle.Render("Allow? (y/N): "))
+
+	oldPrompt := c.readline.Config.Prompt
+	c.readline.SetPrompt("")
+	defer c.readline.SetPrompt(oldPrompt)
+
+	answer, err := c.readline.Readline()
+	return err == nil && strings.ToLower(strings.TrimSpace(answer)) == "y"
 }
 
 // fallbackSearch performs a simple search when LLM tool extraction fails
@@ -964,52 +920,6 @@ func (c *CLI) fallbackSearch(query string) error {
 	return nil
 }
 
-// formatToolResultForContext formats tool results for LLM context
-func (c *CLI) formatToolResultForContext(sb *strings.Builder, toolName string, data any) {
-	switch toolName {
-	case "search_entities":
-		if matches, ok := data.([]map[string]any); ok {
-			sb.WriteString("Search results:\n")
-			for _, match := range matches {
-				fmt.Fprintf(sb, "- %s (ID: %s, Type: %s)\n", match["title"], match["id"], match["type"])
-				if excerpt, ok := match["excerpt"].(string); ok && excerpt != "" {
-					fmt.Fprintf(sb, "  Content: %s\n", excerpt)
-				}
-			}
-		}
-
-	case "read_entity":
-		if entity, ok := data.(*graph.Entity); ok {
-			fmt.Fprintf(sb, "Entity: %s (ID: %s, Type: %s)\n", entity.Title, entity.Metadata.ID, entity.Metadata.Type)
-			if entity.Content != "" {
-				fmt.Fprintf(sb, "Content: %s\n", entity.Content)
-			}
-			if len(entity.Metadata.Sources) > 0 {
-				fmt.Fprintf(sb, "Sources: %s\n", strings.Join(entity.Metadata.Sources, ", "))
-			}
-		}
-
-	case "get_related":
-		if result, ok := data.(map[string]any); ok {
-			if entity, ok := result["entity"].(*graph.Entity); ok {
-				fmt.Fprintf(sb, "Related to: %s\n", entity.Title)
-			}
-			if outgoing, ok := result["outgoing"].(map[string][]map[string]any); ok {
-				for relType, entities := range outgoing {
-					fmt.Fprintf(sb, "  %s:\n", relType)
-					for _, e := range entities {
-						fmt.Fprintf(sb, "    - %s (ID: %s)\n", e["title"], e["id"])
-					}
-				}
-			}
-		}
-
-	default:
-		// Generic formatting
-		fmt.Fprintf(sb, "Result: %v\n", data)
-	}
-}
-
 // getEntityIcon returns an icon for the entity type
 func getEntityIcon(entityType graph.EntityType) string {
 	switch entityType {
@@ -1056,7 +966,7 @@ func (c *CLI) generateEntityID(name string, entityType graph.EntityType) string
 }
 
 // saveSource saves the fetched source content to disk
-func (c *CLI) saveSource(source *sources.Source) error {
+func (c *CLI) saveSource(source *sources.Source) (string, error) {
 	// Create filename from URL
 	domain := sources.ExtractDomain(source.URL)
 	timestamp := time.Now().Format("20060102-150405")
@@ -1078,7 +988,24 @@ func (c *CLI) saveSource(source *sources.Source) error {
 	// Ensure directory exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Keep the full original HTML; the markdown only holds the main content
+	rawHTML := ""
+	if rawPath, err := sources.SaveRawHTML(filePath, source); err != nil {
+		if c.debug {
+			fmt.Printf("[DEBUG] Failed to archive raw HTML: %v\n", err)
+		}
+	} else if rawPath != "" {
+		rawHTML = fmt.Sprintf("raw_html: %s\n", filepath.Base(rawPath))
+	}
+
+	// Preserve the exact capture as WARC and record its content hash
+	if _, err := sources.ArchiveCapture(c.dataDir, source); err != nil {
+		fmt.Println(FormatWarning(fmt.Sprintf("Failed to write WARC archive: %v", err)))
+	} else if source.Metadata["warc"] != "" {
+		rawHTML += fmt.Sprintf("warc: %s\ncontent_hash: %s\n", source.Metadata["warc"], source.Metadata["content_hash"])
 	}
 
 	// Create markdown with metadata
@@ -1087,7 +1014,7 @@ url: %s
 title: %s
 fetched_at: %s
 domain: %s
----
+%s---
 
 # %s
 
@@ -1099,6 +1026,7 @@ Source: %s
 		source.Title,
 		time.Now().Format(time.RFC3339),
 		domain,
+		rawHTML,
 		source.Title,
 		source.URL,
 		source.Content,
@@ -1106,11 +1034,15 @@ Source: %s
 
 	// Write file
 	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write source file: %w", err)
+		return "", fmt.Errorf("failed to write source file: %w", err)
 	}
+	return filePath, nil
+}
 
-	// Mark as processed in tracker
-	if c.tracker != nil {
+// markSourceProcessed records a source saved at filePath as ingested. Only
+// a source extracted in full is, so a partial one is retried.
+func (c *CLI) markSourceProcessed(source *sources.Source, filePath string) {
+	if c.tracker != nil && filePath != "" {
 		c.tracker.MarkProcessed(source.URL, source.Title, filePath)
 		// Save tracker state
 		if err := c.tracker.Save(); err != nil {
@@ -1120,8 +1052,6 @@ Source: %s
 			}
 		}
 	}
-
-	return nil
 }
 
 // isSourceProcessed checks if a URL has already been processed
@@ -82,6 +82,14 @@ func (r *CommandRegistry) registerAllCommands() {
 			Handler:     handleExplore,
 			SubCommands: []string{"queue"},
 		},
+		{
+			Name:        "/feeds",
+			Aliases:     []string{"/feed"},
+			Description: "Manage RSS/Atom feed subscriptions",
+			Usage:       "[add|list|remove|poll]",
+			Handler:     handleFeeds,
+			SubCommands: []string{"add", "list", "remove", "poll"},
+		},
 		{
 			Name:        "/related",
 			Aliases:     []string{"/connections"},
@@ -130,6 +138,22 @@ func (r *CommandRegistry) registerAllCommands() {
 			Handler:     handleMove,
 			Dynamic:     true,
 		},
+		{
+			Name:        "/source-raw",
+			Aliases:     []string{},
+			Description: "View or re-extract a source from its archived capture",
+			Usage:       "<source-id> [--html|--extract]",
+			Handler:     handleSourceRaw,
+			Dynamic:     true,
+		},
+		{
+			Name:        "/recheck",
+			Aliases:     []string{},
+			Description: "Recheck processed sources for changes",
+			Usage:       "[all|<url>]",
+			Handler:     handleRecheck,
+			SubCommands: []string{"all"},
+		},
 		{
 			Name:        "/rebuild-refs",
 			Aliases:     []string{},
@@ -144,6 +168,53 @@ func (r *CommandRegistry) registerAllCommands() {
 			Handler:     handleRefine,
 			Dynamic:     true,
 		},
+		{
+			Name:        "/models",
+			Aliases:     []string{"/model"},
+			Description: "Show or switch the model used for each task",
+			Usage:       "[list|set|reset]",
+			Handler:     handleModels,
+			SubCommands: []string{"list", "set", "reset"},
+		},
+		{
+			Name:        "/events",
+			Description: "Show recent changes to the graph, queue and jobs, or follow new ones",
+			Usage:       "[recent [n]|follow] [--types entity,queue,job]",
+			Handler:     handleEvents,
+			SubCommands: []string{"recent", "follow"},
+		},
+		{
+			Name:        "/jobs",
+			Aliases:     []string{"/job"},
+			Description: "Run ingest, refine, merge and queue work in the background, and follow its progress",
+			Usage:       "[list|show <id>|cancel <id>|ingest <url>|refine <id>|merge <keep> <merge>|queue [n]]",
+			Handler:     handleJobs,
+			SubCommands: []string{"list", "show", "cancel", "ingest", "refine", "merge", "queue"},
+		},
+		{
+			Name:        "/prompts",
+			Aliases:     []string{"/prompt"},
+			Description: "List prompt versions, or write editable copies of the prompts",
+			Usage:       "[list|init|reload]",
+			Handler:     handlePrompts,
+			SubCommands: []string{"list", "init", "reload"},
+		},
+		{
+			Name:        "/usage",
+			Aliases:     []string{"/cost"},
+			Description: "Show LLM token usage and cost, or set budgets",
+			Usage:       "[--since <when>] [--by operation|kind|model|source] | budget",
+			Handler:     handleUsage,
+			SubCommands: []string{"budget", "--since", "--by"},
+		},
+		{
+			Name:        "/chat",
+			Aliases:     []string{"/chats"},
+			Description: "List, resume, or export saved chat sessions",
+			Usage:       "[list|new|resume <id>|export [id] [--save|<file>]]",
+			Handler:     handleChat,
+			SubCommands: []string{"list", "new", "resume", "export"},
+		},
 		{
 			Name:        "/clear",
 			Aliases:     []string{},
@@ -276,8 +347,8 @@ func handleMove(ctx context.Context, c *CLI, args []string) error {
 
 func handleRebuildRefs(ctx context.Context, c *CLI, args []string) error {
 	fmt.Println("Rebuilding all back-references in the graph...")
-	if err := c.graph.RebuildAllBackReferences(); err != nil {
-		return fmt.Errorf("failed to rebuild references: %w", err)
+	if err := c.ops.Entity.RebuildReferences(); err != nil {
+		return err
 	}
 	fmt.Println(SuccessStyle.Render("✓ Back-references rebuilt successfully"))
 	return nil
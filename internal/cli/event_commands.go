@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"silvia/internal/operations"
+)
+
+const eventsUsage = "usage: /events [recent [n] | follow] [--types entity,queue,job]"
+
+// handleEvents prints recent changes from the event log, or follows it
+// until Ctrl-C. The log is shared by every silvia process using the data
+// directory, so this shows changes made through the API and MCP server too.
+func handleEvents(ctx context.Context, c *CLI, args []string) error {
+	if c.ops == nil || c.ops.Events == nil {
+		return fmt.Errorf("events not available")
+	}
+
+	mode, count := "recent", 20
+	var types []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--types" || arg == "-t":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--types requires a value")
+			}
+			i++
+			types = strings.Split(args[i], ",")
+		case arg == "recent" || arg == "follow":
+			mode = arg
+		case mode == "recent":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return fmt.Errorf("%s", eventsUsage)
+			}
+			count = n
+		default:
+			return fmt.Errorf("%s", eventsUsage)
+		}
+	}
+
+	if mode == "follow" {
+		fmt.Println(DimStyle.Render("Following events; press Ctrl-C to stop"))
+		for event := range c.ops.Events.Subscribe(ctx, c.ops.Events.LastID()) {
+			if event.Matches(types) {
+				printEvent(event)
+			}
+		}
+		return nil
+	}
+
+	events, err := c.ops.Events.Since(0)
+	if err != nil {
+		return err
+	}
+	var matching []operations.Event
+	for _, event := range events {
+		if event.Matches(types) {
+			matching = append(matching, event)
+		}
+	}
+	if len(matching) == 0 {
+		fmt.Println(FormatInfo("No events recorded"))
+		return nil
+	}
+	if len(matching) > count {
+		matching = matching[len(matching)-count:]
+	}
+
+	fmt.Printf("\n%s\n\n", HeaderStyle.Render("Recent events"))
+	for _, event := range matching {
+		printEvent(event)
+	}
+	fmt.Println(DimStyle.Render("\nUse /events follow to watch for new ones"))
+	return nil
+}
+
+// printEvent prints one event on a line, e.g.
+// "14:02:11 entity.created  people/ada-lovelace  Ada Lovelace (person)"
+func printEvent(event operations.Event) {
+	fmt.Printf("%s %s %s  %s\n",
+		DimStyle.Render(event.Time.Local().Format("15:04:05")),
+		eventTypeStyle(event.Type),
+		HighlightStyle.Render(event.Subject),
+		DimStyle.Render(describeEvent(event)))
+}
+
+func eventTypeStyle(eventType string) string {
+	label := fmt.Sprintf("%-16s", eventType)
+	switch {
+	case strings.HasSuffix(eventType, ".created"), strings.HasSuffix(eventType, ".added"), eventType == operations.EventSourceIngested:
+		return SuccessStyle.Render(label)
+	case strings.HasSuffix(eventType, ".deleted"), strings.HasSuffix(eventType, ".removed"), strings.HasSuffix(eventType, ".cleared"):
+		return WarningStyle.Render(label)
+	default:
+		return InfoStyle.Render(label)
+	}
+}
+
+// describeEvent summarizes an event's data
+func describeEvent(event operations.Event) string {
+	var data struct {
+		Type         string                  `json:"type"`
+		Title        string                  `json:"title"`
+		OldID        string                  `json:"old_id"`
+		MergedID     string                  `json:"merged_id"`
+		Entities     int                     `json:"entities"`
+		Links        int                     `json:"links"`
+		Kind         string                  `json:"kind"`
+		Status       string                  `json:"status"`
+		Error        string                  `json:"error"`
+		Progress     *operations.JobProgress `json:"progress"`
+		Result       *operations.JobResult   `json:"result"`
+		Description  string                  `json:"description"`
+		UpdatedFiles []string                `json:"updated_files"`
+	}
+	if len(event.Data) > 0 {
+		json.Unmarshal(event.Data, &data)
+	}
+
+	switch event.Type {
+	case operations.EventEntityCreated, operations.EventEntityUpdated:
+		return fmt.Sprintf("%s (%s)", data.Title, data.Type)
+	case operations.EventEntityRenamed:
+		return "from " + data.OldID
+	case operations.EventEntityMerged:
+		return fmt.Sprintf("absorbed %s, %d files updated", data.MergedID, len(data.UpdatedFiles))
+	case operations.EventSourceIngested:
+		return fmt.Sprintf("%d entities, %d links", data.Entities, data.Links)
+	case operations.EventQueueAdded:
+		return data.Description
+	case operations.EventQueueUpdated:
+		if data.Error != "" {
+			return data.Status + ": " + data.Error
+		}
+		return data.Status
+	case operations.EventJobProgress:
+		if data.Progress != nil {
+			return data.Kind + ": " + formatJobProgress(*data.Progress)
+		}
+	case operations.EventJobFinished:
+		switch {
+		case data.Error != "":
+			return fmt.Sprintf("%s %s: %s", data.Kind, data.Status, data.Error)
+		case data.Result != nil:
+			return fmt.Sprintf("%s %s: %s", data.Kind, data.Status, data.Result.Summary)
+		default:
+			return data.Kind + " " + data.Status
+		}
+	}
+	return ""
+}
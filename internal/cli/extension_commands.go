@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"silvia/internal/graph"
+	"silvia/internal/llm"
+	"silvia/internal/operations"
+	"silvia/internal/prompts"
 	"silvia/internal/sources"
 )
 
@@ -17,13 +20,18 @@ type ExtensionLinkInfo struct {
 	Context string `json:"context"`
 }
 
-// IngestFromExtension processes content received from the browser extension
-func (c *CLI) IngestFromExtension(ctx context.Context, url string, html string, title string, links []ExtensionLinkInfo, metadata map[string]string, force bool) error {
+// IngestFromExtension processes content received from the browser
+// extension. If the URL is queued, its queue item is marked done or failed.
+func (c *CLI) IngestFromExtension(ctx context.Context, url string, html string, title string, links []ExtensionLinkInfo, metadata map[string]string, force bool) (err error) {
+	ctx = llm.WithOperation(ctx, llm.OpIngest, url)
+
 	// Check if we've already processed this URL (unless force is true)
 	if !force && c.isSourceProcessed(url) {
 		fmt.Printf("⚠️  Source already processed: %s\n", url)
 		return fmt.Errorf("source already ingested: %s", url)
 	}
+	var extraction *sources.ExtractionResult
+	defer func() { c.queue.FinishIngest(url, operations.ExtractionOutcome(extraction, err)) }()
 
 	if force && c.isSourceProcessed(url) {
 		fmt.Printf("🔄 Force update: Re-processing %s\n", url)
@@ -127,17 +135,22 @@ func (c *CLI) IngestFromExtension(ctx context.Context, url string, html string,
 	}
 
 	// Save the source content
-	if err := c.saveSource(source); err != nil {
+	storagePath, err := c.saveSource(source)
+	if err != nil {
 		// Log but don't fail the ingestion
 		fmt.Printf("Warning: Failed to save source content: %v\n", err)
 	}
 
 	// Extract entities and relationships using LLM
 	fmt.Println(InfoStyle.Render("🔍 Extracting entities from extension capture..."))
-	extraction, err := c.extractor.Extract(ctx, source)
+	extraction, err = c.extractor.Extract(ctx, source)
 	if err != nil {
 		return fmt.Errorf("extraction failed: %w", err)
 	}
+	if extraction.FailedChunks > 0 {
+		fmt.Println(FormatWarning(fmt.Sprintf("Extraction failed for %d of %d chunks; the rest of the page is missing from the graph",
+			extraction.FailedChunks, extraction.ChunkCount)))
+	}
 
 	if c.debug {
 		fmt.Printf("[DEBUG] Extraction complete. Found %d entities, %d relationships, %d links\n",
@@ -145,7 +158,14 @@ func (c *CLI) IngestFromExtension(ctx context.Context, url string, html string,
 	}
 
 	// Process the extraction (same as regular ingestion)
-	return c.processExtraction(ctx, source, extraction, linkMap)
+	if err := c.processExtraction(ctx, source, extraction, linkMap); err != nil {
+		return err
+	}
+	if extraction.FailedChunks == 0 {
+		c.markSourceProcessed(source, storagePath)
+	}
+	c.publishIngested(url, extraction)
+	return nil
 }
 
 // processExtraction handles the entity and relationship creation from an extraction
@@ -170,6 +190,8 @@ func (c *CLI) processExtraction(ctx context.Context, source *sources.Source, ext
 				graphEntity.Title = entity.Name
 				graphEntity.Content = entity.Content
 				graphEntity.Metadata.Aliases = entity.Aliases
+				graphEntity.RecordPrompt(prompts.Extraction, extraction.PromptVersion)
+				graphEntity.RecordChunks(source.URL, entity.Chunks, extraction.ChunkCount)
 
 				// Reference source summary if available, otherwise raw URL
 				if sourceSummaryID != "" {
@@ -181,7 +203,8 @@ func (c *CLI) processExtraction(ctx context.Context, source *sources.Source, ext
 				if err := c.graph.SaveEntity(graphEntity); err != nil {
 					fmt.Printf("Warning: Failed to save %s: %v\n", entity.Name, err)
 				} else {
-					fmt.Printf("  ✓ Created: %s (%s)\n", entity.Name, id)
+					c.events().PublishEntity(operations.EventEntityCreated, graphEntity)
+					fmt.Printf("  ✓ Created: %s (%s)%s\n", entity.Name, id, formatChunks(entity.Chunks, extraction.ChunkCount))
 				}
 			} else {
 				// Update existing entity with new source
@@ -192,10 +215,12 @@ func (c *CLI) processExtraction(ctx context.Context, source *sources.Source, ext
 					} else {
 						existing.AddSource(source.URL)
 					}
+					existing.RecordChunks(source.URL, entity.Chunks, extraction.ChunkCount)
 					if err := c.graph.SaveEntity(existing); err != nil {
 						fmt.Printf("Warning: Failed to update %s: %v\n", entity.Name, err)
 					} else {
-						fmt.Printf("  ✓ Updated: %s\n", entity.Name)
+						c.events().PublishEntity(operations.EventEntityUpdated, existing)
+						fmt.Printf("  ✓ Updated: %s%s\n", entity.Name, formatChunks(entity.Chunks, extraction.ChunkCount))
 					}
 				}
 			}
@@ -215,7 +240,7 @@ func (c *CLI) processExtraction(ctx context.Context, source *sources.Source, ext
 		added := 0
 		for _, link := range extraction.Links {
 			// Skip if already in queue or processed
-			if c.queue.Contains(link.URL) || c.isSourceProcessed(link.URL) {
+			if c.isQueued(link.URL) || c.isSourceProcessed(link.URL) {
 				continue
 			}
 
@@ -250,14 +275,13 @@ func (c *CLI) processExtraction(ctx context.Context, source *sources.Source, ext
 				priority = PriorityHigh
 			}
 
-			if c.queue.Add(link.URL, priority, source.URL, desc) {
+			if c.queue.AddToQueue(link.URL, int(priority), source.URL, desc) == nil {
 				added++
 			}
 		}
 
 		if added > 0 {
 			fmt.Printf("Added %d links to queue\n", added)
-			c.queue.SaveToFile()
 		}
 	}
 
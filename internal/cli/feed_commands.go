@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const feedsUsage = "usage: /feeds add <url> [--priority low|med|high] [--keywords a,b] [--entities] | list | remove <url|name> | poll [url|name]"
+
+func handleFeeds(ctx context.Context, c *CLI, args []string) error {
+	if c.ops == nil || c.ops.Feed == nil {
+		return fmt.Errorf("feed operations not available")
+	}
+
+	if len(args) < 1 {
+		return c.listFeeds()
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", feedsUsage)
+		}
+		return c.addFeed(ctx, args[1], args[2:])
+	case "list", "ls":
+		return c.listFeeds()
+	case "remove", "rm":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /feeds remove <url|name>")
+		}
+		return c.removeFeed(strings.Join(args[1:], " "))
+	case "poll":
+		return c.pollFeeds(ctx, strings.Join(args[1:], " "))
+	default:
+		return fmt.Errorf("%s", feedsUsage)
+	}
+}
+
+// addFeed subscribes to a feed, parsing optional filter flags
+func (c *CLI) addFeed(ctx context.Context, url string, flags []string) error {
+	priority := PriorityMedium
+	var keywords []string
+	matchEntities := false
+
+	for i := 0; i < len(flags); i++ {
+		switch flags[i] {
+		case "--priority", "-p":
+			if i+1 >= len(flags) {
+				return fmt.Errorf("--priority requires a value")
+			}
+			i++
+			p, err := parsePriority(flags[i])
+			if err != nil {
+				return err
+			}
+			priority = p
+		case "--keywords", "-k":
+			if i+1 >= len(flags) {
+				return fmt.Errorf("--keywords requires a value")
+			}
+			i++
+			keywords = append(keywords, strings.Split(flags[i], ",")...)
+		case "--entities", "-e":
+			matchEntities = true
+		default:
+			return fmt.Errorf("unknown option: %s", flags[i])
+		}
+	}
+
+	fmt.Printf("Fetching feed %s...\n", URLStyle.Render(url))
+	sub, err := c.ops.Feed.AddFeed(ctx, url, int(priority), keywords, matchEntities)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(FormatSuccess(fmt.Sprintf("Subscribed to %s", sub.Name)))
+	if len(sub.Keywords) > 0 {
+		fmt.Printf("  Keywords: %s\n", strings.Join(sub.Keywords, ", "))
+	}
+	if sub.MatchEntities {
+		fmt.Println("  Matching entries that mention known entities")
+	}
+	fmt.Println(DimStyle.Render("  Run /feeds poll to queue new entries"))
+	return nil
+}
+
+// listFeeds displays all feed subscriptions
+func (c *CLI) listFeeds() error {
+	feeds, err := c.ops.Feed.ListFeeds()
+	if err != nil {
+		return err
+	}
+
+	if len(feeds) == 0 {
+		fmt.Println(FormatInfo("No feed subscriptions. Use /feeds add <url> to subscribe."))
+		return nil
+	}
+
+	fmt.Printf("\n%s\n\n", HeaderStyle.Render(fmt.Sprintf("Feeds (%d)", len(feeds))))
+	for _, feed := range feeds {
+		fmt.Printf("  [%s] %s\n", FormatPriority(SourcePriority(feed.Priority)), HighlightStyle.Render(feed.Name))
+		fmt.Printf("        %s\n", URLStyle.Render(feed.URL))
+
+		var filters []string
+		if len(feed.Keywords) > 0 {
+			filters = append(filters, "keywords: "+strings.Join(feed.Keywords, ", "))
+		}
+		if feed.MatchEntities {
+			filters = append(filters, "entity mentions")
+		}
+		if len(filters) > 0 {
+			fmt.Printf("        %s\n", DimStyle.Render("Filters: "+strings.Join(filters, "; ")))
+		}
+
+		if feed.LastPolled.IsZero() {
+			fmt.Printf("        %s\n", DimStyle.Render("Never polled"))
+		} else {
+			fmt.Printf("        %s\n", DimStyle.Render("Last polled: "+feed.LastPolled.Format("2006-01-02 15:04")))
+		}
+		if feed.LastError != "" {
+			fmt.Printf("        %s\n", FormatWarning(feed.LastError))
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// removeFeed unsubscribes from a feed
+func (c *CLI) removeFeed(identifier string) error {
+	if err := c.ops.Feed.RemoveFeed(identifier); err != nil {
+		return err
+	}
+	fmt.Println(FormatSuccess(fmt.Sprintf("Unsubscribed from %s", identifier)))
+	return nil
+}
+
+// pollFeeds fetches subscribed feeds and queues new entries
+func (c *CLI) pollFeeds(ctx context.Context, identifier string) error {
+	fmt.Println("Polling feeds...")
+	results, err := c.ops.Feed.PollFeeds(ctx, identifier)
+	if err != nil {
+		return err
+	}
+
+	totalQueued := 0
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Println(FormatWarning(fmt.Sprintf("%s: %s", result.Feed, result.Error)))
+			continue
+		}
+
+		fmt.Printf("  %s: %d entries, %s queued, %d already seen, %d filtered out\n",
+			HighlightStyle.Render(result.Feed),
+			result.Entries,
+			SuccessStyle.Render(fmt.Sprintf("%d", len(result.Queued))),
+			result.Duplicates,
+			result.Filtered)
+		for _, item := range result.Queued {
+			fmt.Printf("    + %s\n", DimStyle.Render(item.Description))
+		}
+		totalQueued += len(result.Queued)
+	}
+
+	fmt.Println(FormatSuccess(fmt.Sprintf("Queued %d new sources from %d feeds", totalQueued, len(results))))
+	return nil
+}
+
+// parsePriority converts a priority name to a SourcePriority
+func parsePriority(value string) (SourcePriority, error) {
+	switch strings.ToLower(value) {
+	case "low", "0":
+		return PriorityLow, nil
+	case "med", "medium", "1":
+		return PriorityMedium, nil
+	case "high", "2":
+		return PriorityHigh, nil
+	default:
+		return PriorityLow, fmt.Errorf("invalid priority: %s (use low, med or high)", value)
+	}
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"silvia/internal/operations"
+)
+
+const jobsUsage = "usage: /jobs [list [status] | show <id> | cancel <id> | ingest <url> [--force] | refine <entity-id> [guidance] | merge <keep-id> <merge-id> | queue [limit]]"
+
+// handleJobs lists, inspects, cancels and starts background jobs
+func handleJobs(ctx context.Context, c *CLI, args []string) error {
+	if c.ops == nil || c.ops.Jobs == nil {
+		return fmt.Errorf("jobs not available")
+	}
+	if len(args) == 0 {
+		return c.listJobs("")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		status := ""
+		if len(args) > 1 {
+			status = args[1]
+		}
+		return c.listJobs(operations.JobStatus(status))
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", jobsUsage)
+		}
+		job, err := c.ops.Jobs.GetJob(args[1])
+		if err != nil {
+			return err
+		}
+		printJob(job)
+		return nil
+	case "cancel":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", jobsUsage)
+		}
+		job, err := c.ops.Jobs.CancelJob(args[1])
+		if err != nil {
+			return err
+		}
+		if job.Status == operations.JobCancelled {
+			fmt.Println(FormatSuccess("Cancelled " + job.ID))
+		} else {
+			fmt.Println(FormatInfo("Cancelling " + job.ID + "; it stops at the end of its current step"))
+		}
+		return nil
+	case "ingest":
+		params := operations.JobParams{}
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "--force" || arg == "-f":
+				params.Force = true
+			case params.URL == "":
+				params.URL = arg
+			default:
+				return fmt.Errorf("%s", jobsUsage)
+			}
+		}
+		return c.submitJob(operations.JobIngest, params)
+	case "refine":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", jobsUsage)
+		}
+		return c.submitJob(operations.JobRefine, operations.JobParams{
+			EntityID: args[1],
+			Guidance: strings.Join(args[2:], " "),
+		})
+	case "merge":
+		if len(args) != 3 {
+			return fmt.Errorf("%s", jobsUsage)
+		}
+		return c.submitJob(operations.JobMerge, operations.JobParams{Entity1ID: args[1], Entity2ID: args[2]})
+	case "queue":
+		params := operations.JobParams{}
+		if len(args) > 1 {
+			limit, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid limit: %s", args[1])
+			}
+			params.Limit = limit
+		}
+		return c.submitJob(operations.JobQueue, params)
+	default:
+		return fmt.Errorf("%s", jobsUsage)
+	}
+}
+
+// submitJob starts a job and tells the user how to follow it
+func (c *CLI) submitJob(kind operations.JobKind, params operations.JobParams) error {
+	job, err := c.ops.Jobs.Submit(kind, params)
+	if err != nil {
+		return err
+	}
+	fmt.Println(FormatSuccess(fmt.Sprintf("Started %s job %s", job.Kind, job.ID)))
+	fmt.Println(DimStyle.Render("Follow it with /jobs show " + job.ID))
+	return nil
+}
+
+// listJobs shows jobs newest first
+func (c *CLI) listJobs(status operations.JobStatus) error {
+	jobs, err := c.ops.Jobs.ListJobs(status)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println(FormatInfo("No jobs"))
+		return nil
+	}
+
+	fmt.Printf("\n%s\n\n", HeaderStyle.Render("Jobs"))
+	for _, job := range jobs {
+		fmt.Printf("  %s  %-6s %s  %s\n",
+			HighlightStyle.Render(job.ID),
+			job.Kind,
+			formatJobStatus(job.Status),
+			truncateString(describeJob(job), 70))
+	}
+	fmt.Println(DimStyle.Render("\nUse /jobs show <id> for progress and results"))
+	return nil
+}
+
+// printJob shows one job in full
+func printJob(job *operations.Job) {
+	fmt.Printf("\n%s %s  %s\n\n", HeaderStyle.Render("Job "+job.ID), job.Kind, formatJobStatus(job.Status))
+	fmt.Printf("  Target:   %s\n", describeJob(job))
+	fmt.Printf("  Created:  %s\n", job.Created.Local().Format("2006-01-02 15:04:05"))
+	if job.Started != nil {
+		end := time.Now()
+		if job.Finished != nil {
+			end = *job.Finished
+		}
+		fmt.Printf("  Running:  %s\n", end.Sub(*job.Started).Round(time.Second))
+	}
+	if !job.Status.Finished() && job.Progress.Stage != "" {
+		fmt.Printf("  Progress: %s\n", formatJobProgress(job.Progress))
+	}
+	if job.Error != "" {
+		fmt.Println(FormatError(job.Error))
+	}
+	if job.Result != nil {
+		fmt.Println()
+		fmt.Println(FormatSuccess(job.Result.Summary))
+		for _, id := range job.Result.Entities {
+			fmt.Printf("  %s\n", DimStyle.Render(id))
+		}
+		for _, failure := range job.Result.Failures {
+			fmt.Println(FormatWarning(failure))
+		}
+	}
+	fmt.Println()
+}
+
+// describeJob names what a job is working on
+func describeJob(job *operations.Job) string {
+	params := job.Params
+	switch job.Kind {
+	case operations.JobIngest:
+		return params.URL
+	case operations.JobRefine:
+		return params.EntityID
+	case operations.JobMerge:
+		return params.Entity2ID + " → " + params.Entity1ID
+	case operations.JobQueue:
+		if params.Limit > 0 {
+			return fmt.Sprintf("next %d queued sources", params.Limit)
+		}
+		return "all queued sources"
+	default:
+		return ""
+	}
+}
+
+// formatJobProgress describes a stage, e.g. "source 2/5, extract 3/8"
+func formatJobProgress(progress operations.JobProgress) string {
+	var parts []string
+	if progress.Items > 0 {
+		parts = append(parts, fmt.Sprintf("source %d/%d", progress.Item, progress.Items))
+	}
+	stage := progress.Stage
+	if progress.Total > 0 {
+		stage += fmt.Sprintf(" %d/%d", progress.Current, progress.Total)
+	}
+	parts = append(parts, stage)
+	if progress.Detail != "" {
+		parts = append(parts, truncateString(progress.Detail, 60))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatJobStatus(status operations.JobStatus) string {
+	label := fmt.Sprintf("%-9s", status)
+	switch status {
+	case operations.JobDone:
+		return SuccessStyle.Render(label)
+	case operations.JobFailed:
+		return ErrorStyle.Render(label)
+	case operations.JobRunning:
+		return InfoStyle.Render(label)
+	default:
+		return DimStyle.Render(label)
+	}
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"silvia/internal/llm"
+)
+
+const modelsUsage = "usage: /models [list [filter] | set <task> <model> | reset [task]]"
+
+func handleModels(ctx context.Context, c *CLI, args []string) error {
+	if c.llm == nil {
+		return fmt.Errorf("LLM client not available")
+	}
+
+	if len(args) < 1 {
+		return c.showModelMapping()
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.listAvailableModels(ctx, strings.Join(args[1:], " "))
+	case "set":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: /models set <task> <model>")
+		}
+		return c.setTaskModel(ctx, args[1], args[2])
+	case "reset":
+		var task llm.Task
+		if len(args) > 1 {
+			t, err := llm.ParseTask(args[1])
+			if err != nil {
+				return err
+			}
+			task = t
+		}
+		if err := c.llm.ResetModelConfig(task); err != nil {
+			return err
+		}
+		if task == "" {
+			fmt.Println(FormatSuccess("Restored default models for all tasks"))
+		} else {
+			fmt.Println(FormatSuccess(fmt.Sprintf("Restored default model for %s", task)))
+		}
+		return c.showModelMapping()
+	default:
+		return fmt.Errorf("%s", modelsUsage)
+	}
+}
+
+// showModelMapping prints the active task-to-model mapping
+func (c *CLI) showModelMapping() error {
+	fmt.Printf("\n%s %s\n\n", HeaderStyle.Render("Models"), DimStyle.Render("(provider: "+c.llm.Provider().Name()+")"))
+
+	for _, info := range c.llm.ModelMapping() {
+		line := fmt.Sprintf("  %-12s %s", info.Task, HighlightStyle.Render(info.TaskModel.Model))
+
+		var details []string
+		if info.TaskModel.Temperature != nil {
+			details = append(details, fmt.Sprintf("temperature %.2g", *info.TaskModel.Temperature))
+		}
+		if info.TaskModel.MaxTokens > 0 {
+			details = append(details, fmt.Sprintf("max %d tokens", info.TaskModel.MaxTokens))
+		}
+		if len(info.TaskModel.Fallbacks) > 0 {
+			details = append(details, "fallbacks: "+strings.Join(info.TaskModel.Fallbacks, ", "))
+		}
+		if info.Overridden {
+			details = append(details, "set by flag/env")
+		}
+		if len(details) > 0 {
+			line += " " + DimStyle.Render("("+strings.Join(details, "; ")+")")
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println()
+	fmt.Println(DimStyle.Render("Use /models set <task> <model> to switch, /models list to see available models"))
+	return nil
+}
+
+// listAvailableModels prints the models the provider serves, optionally filtered
+func (c *CLI) listAvailableModels(ctx context.Context, filter string) error {
+	models, err := c.llm.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter = strings.ToLower(filter)
+	var matched []string
+	for _, model := range models {
+		if filter == "" || strings.Contains(strings.ToLower(model), filter) {
+			matched = append(matched, model)
+		}
+	}
+	slices.Sort(matched)
+
+	if len(matched) == 0 {
+		fmt.Println(FormatInfo("No models match"))
+		return nil
+	}
+
+	const maxShown = 50
+	for i, model := range matched {
+		if i == maxShown {
+			fmt.Println(DimStyle.Render(fmt.Sprintf("  ... and %d more (add a filter to narrow the list)", len(matched)-maxShown)))
+			break
+		}
+		fmt.Printf("  %s\n", model)
+	}
+	fmt.Println(DimStyle.Render(fmt.Sprintf("\n%d of %d models", len(matched), len(models))))
+	return nil
+}
+
+// setTaskModel switches a task to a model, warning if the provider does not list it
+func (c *CLI) setTaskModel(ctx context.Context, taskName, model string) error {
+	task, err := llm.ParseTask(taskName)
+	if err != nil {
+		return err
+	}
+
+	if models, err := c.llm.ListModels(ctx); err == nil && !slices.Contains(models, model) {
+		fmt.Println(FormatWarning(fmt.Sprintf("%s is not listed by the provider; setting it anyway", model)))
+	}
+
+	if err := c.llm.SetTaskModel(task, model); err != nil {
+		return err
+	}
+	fmt.Println(FormatSuccess(fmt.Sprintf("%s now uses %s", task, model)))
+
+	for _, info := range c.llm.ModelMapping() {
+		if info.Task == task && info.TaskModel.Model != model {
+			fmt.Println(FormatWarning(fmt.Sprintf("-llm-model is set, so %s still runs on %s", task, info.TaskModel.Model)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"silvia/internal/prompts"
+)
+
+const promptsUsage = "usage: /prompts [list | init | reload]"
+
+func handlePrompts(ctx context.Context, c *CLI, args []string) error {
+	if len(args) < 1 {
+		return c.listPrompts()
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.listPrompts()
+	case "init":
+		written, err := prompts.WriteDefaults(c.promptsDir())
+		if err != nil {
+			return err
+		}
+		if len(written) == 0 {
+			fmt.Println(FormatInfo("All prompts already have editable copies in " + c.promptsDir()))
+			return nil
+		}
+		for _, path := range written {
+			fmt.Printf("  %s %s\n", SuccessStyle.Render("✓"), path)
+		}
+		fmt.Println(FormatSuccess(fmt.Sprintf("Wrote %d prompts; edit them and run /prompts reload", len(written))))
+		return nil
+	case "reload":
+		library, err := prompts.LoadLibrary(c.promptsDir())
+		if err != nil {
+			return err
+		}
+		prompts.Use(library)
+		fmt.Println(FormatSuccess("Reloaded prompts"))
+		return c.listPrompts()
+	default:
+		return fmt.Errorf("%s", promptsUsage)
+	}
+}
+
+// promptsDir returns the directory edited prompts are loaded from
+func (c *CLI) promptsDir() string {
+	if dir := prompts.Default().Dir(); dir != "" {
+		return dir
+	}
+	return filepath.Join(c.dataDir, ".silvia", "prompts")
+}
+
+// listPrompts prints each prompt with its version and whether it was edited
+func (c *CLI) listPrompts() error {
+	fmt.Printf("\n%s %s\n\n", HeaderStyle.Render("Prompts"), DimStyle.Render("("+c.promptsDir()+")"))
+
+	for _, info := range prompts.Default().List() {
+		origin := DimStyle.Render("default")
+		if info.Overridden {
+			origin = WarningStyle.Render("edited")
+		}
+		fmt.Printf("  %-12s %s  %s\n", info.Name, HighlightStyle.Render(info.Version), origin)
+	}
+	fmt.Println()
+	fmt.Println(DimStyle.Render("Generated entities record these versions under prompts: in their frontmatter"))
+	return nil
+}
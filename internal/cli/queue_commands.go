@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	neturl "net/url"
-	"strings"
 	"time"
 
 	"silvia/internal/graph"
+	"silvia/internal/llm"
+	"silvia/internal/operations"
+	"silvia/internal/prompts"
 	"silvia/internal/sources"
 )
 
@@ -17,15 +19,17 @@ func (c *CLI) ingestSource(ctx context.Context, url string) error {
 	return c.ingestSourceWithForce(ctx, url, false)
 }
 
-// ingestSourceWithForce processes a source with optional force flag
-func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool) error {
+// ingestSourceWithForce processes a source with optional force flag. If
+// the URL is queued, its queue item is marked done or failed.
+func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool) (err error) {
+	ctx = llm.WithOperation(ctx, llm.OpIngest, url)
+	var extraction *sources.ExtractionResult
+	defer func() { c.queue.FinishIngest(url, operations.ExtractionOutcome(extraction, err)) }()
+
 	// Check if already processed (unless force is true)
 	if !force && c.isSourceProcessed(url) {
 		fmt.Println(WarningStyle.Render("⚠️  Source already processed: ") + URLStyle.Render(url))
 		fmt.Println(DimStyle.Render("Use /ingest <url> --force to re-process"))
-		// Remove from queue if present
-		c.queue.Remove(url)
-		c.queue.SaveToFile()
 		return nil
 	}
 
@@ -89,16 +93,21 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 	}
 
 	// Save the source content
-	if err := c.saveSource(source); err != nil {
+	storagePath, err := c.saveSource(source)
+	if err != nil {
 		fmt.Println(FormatWarning(fmt.Sprintf("Failed to save source: %v", err)))
 	}
 
 	// Extract entities and relationships
 	fmt.Println(InfoStyle.Render("🔍 Extracting entities..."))
-	extraction, err := c.extractor.Extract(ctx, source)
+	extraction, err = c.extractor.Extract(ctx, source)
 	if err != nil {
 		return fmt.Errorf("extraction failed: %w", err)
 	}
+	if extraction.FailedChunks > 0 {
+		fmt.Println(FormatWarning(fmt.Sprintf("Extraction failed for %d of %d chunks; the source stays unprocessed so /ingest <url> can fill in the rest",
+			extraction.FailedChunks, extraction.ChunkCount)))
+	}
 
 	// Create source summary entity if we have one
 	var sourceSummaryID string
@@ -120,6 +129,8 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 				graphEntity.Title = entity.Name
 				graphEntity.Content = entity.Content // Use rich content
 				graphEntity.Metadata.Aliases = entity.Aliases
+				graphEntity.RecordPrompt(prompts.Extraction, extraction.PromptVersion)
+				graphEntity.RecordChunks(url, entity.Chunks, extraction.ChunkCount)
 				// Reference source summary if available, otherwise raw URL
 				if sourceSummaryID != "" {
 					graphEntity.AddSource(sourceSummaryID) // No wiki-link format in YAML
@@ -130,11 +141,13 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 				if err := c.graph.SaveEntity(graphEntity); err != nil {
 					fmt.Println(FormatWarning(fmt.Sprintf("Failed to save %s: %v", entity.Name, err)))
 				} else {
-					fmt.Printf("  %s %s %s %s\n",
+					c.events().PublishEntity(operations.EventEntityCreated, graphEntity)
+					fmt.Printf("  %s %s %s %s%s\n",
 						SuccessStyle.Render("✓ Created:"),
 						getEntityIcon(entity.Type),
 						HighlightStyle.Render(entity.Name),
-						DimStyle.Render("("+id+")"))
+						DimStyle.Render("("+id+")"),
+						formatChunks(entity.Chunks, extraction.ChunkCount))
 				}
 			} else {
 				// Update existing entity with new source
@@ -146,13 +159,16 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 					} else {
 						existing.AddSource(url)
 					}
+					existing.RecordChunks(url, entity.Chunks, extraction.ChunkCount)
 					if err := c.graph.SaveEntity(existing); err != nil {
 						fmt.Println(FormatWarning(fmt.Sprintf("Failed to update %s: %v", entity.Name, err)))
 					} else {
-						fmt.Printf("  %s %s %s\n",
+						c.events().PublishEntity(operations.EventEntityUpdated, existing)
+						fmt.Printf("  %s %s %s%s\n",
 							SuccessStyle.Render("✓ Updated:"),
 							getEntityIcon(entity.Type),
-							HighlightStyle.Render(entity.Name))
+							HighlightStyle.Render(entity.Name),
+							formatChunks(entity.Chunks, extraction.ChunkCount))
 					}
 				}
 			}
@@ -185,7 +201,7 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 
 		for _, link := range extraction.Links {
 			// Skip if already in queue or processed
-			if c.queue.Contains(link.URL) || c.isSourceProcessed(link.URL) {
+			if c.isQueued(link.URL) || c.isSourceProcessed(link.URL) {
 				continue
 			}
 
@@ -217,7 +233,7 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 				highAdded++
 			}
 
-			if c.queue.Add(link.URL, priority, source.URL, desc) {
+			if c.queue.AddToQueue(link.URL, int(priority), source.URL, desc) == nil {
 				added++
 			}
 		}
@@ -228,104 +244,33 @@ func (c *CLI) ingestSourceWithForce(ctx context.Context, url string, force bool)
 				fmt.Printf(" %s", SuccessStyle.Render(fmt.Sprintf("(%d high priority)", highAdded)))
 			}
 			fmt.Println()
-			c.queue.SaveToFile()
 		}
 	}
 
+	if extraction.FailedChunks == 0 {
+		c.markSourceProcessed(source, storagePath)
+	}
+	c.publishIngested(url, extraction)
 	fmt.Println(FormatSuccess("Source ingestion complete"))
 	return nil
 }
 
 // createSourceSummary creates a source summary entity in the graph
 func (c *CLI) createSourceSummary(source *sources.Source, summary *sources.SourceSummary, url string) string {
-	// Generate ID from URL
-	u, err := neturl.Parse(url)
-	if err != nil {
-		return ""
-	}
-
-	// Create ID like "sources/domain-date"
-	domain := strings.ReplaceAll(u.Hostname(), ".", "-")
-	id := fmt.Sprintf("sources/%s-%s", domain, time.Now().Format("2006-01-02"))
-
-	// Create entity
-	entity := graph.NewEntity(id, graph.EntityWork)
-	entity.Title = summary.Title
-
-	// Build rich content
-	var content strings.Builder
-
-	// Metadata section
-	if summary.Author != "" {
-		content.WriteString(fmt.Sprintf("**Author**: %s\n", summary.Author))
-	}
-	if summary.Publication != "" {
-		content.WriteString(fmt.Sprintf("**Publication**: %s\n", summary.Publication))
-	}
-	if summary.Date != "" {
-		content.WriteString(fmt.Sprintf("**Date**: %s\n", summary.Date))
-	}
-	content.WriteString(fmt.Sprintf("**Source URL**: %s\n", url))
-	content.WriteString(fmt.Sprintf("**Raw Source**: %s\n\n", c.getSourcePath(url)))
-
-	// Key themes
-	if len(summary.KeyThemes) > 0 {
-		content.WriteString("## Key Themes\n\n")
-		for _, theme := range summary.KeyThemes {
-			content.WriteString(fmt.Sprintf("- %s\n", theme))
-		}
-		content.WriteString("\n")
-	}
-
-	// Analysis
-	if summary.Analysis != "" {
-		content.WriteString("## Analysis\n\n")
-		content.WriteString(summary.Analysis)
-		content.WriteString("\n\n")
-	}
-
-	// Key quotes
-	if len(summary.KeyQuotes) > 0 {
-		content.WriteString("## Key Quotes\n\n")
-		for _, quote := range summary.KeyQuotes {
-			content.WriteString(fmt.Sprintf("> %s\n\n", quote))
-		}
-	}
-
-	// Related entities
-	if len(summary.People) > 0 || len(summary.Organizations) > 0 || len(summary.Events) > 0 {
-		content.WriteString("## Related Entities\n\n")
-		if len(summary.People) > 0 {
-			content.WriteString("### People\n")
-			for _, personID := range summary.People {
-				content.WriteString(fmt.Sprintf("- [[%s]]\n", personID))
-			}
-			content.WriteString("\n")
-		}
-		if len(summary.Organizations) > 0 {
-			content.WriteString("### Organizations\n")
-			for _, orgID := range summary.Organizations {
-				content.WriteString(fmt.Sprintf("- [[%s]]\n", orgID))
-			}
-			content.WriteString("\n")
-		}
-		if len(summary.Events) > 0 {
-			content.WriteString("### Events\n")
-			for _, eventID := range summary.Events {
-				content.WriteString(fmt.Sprintf("- [[%s]]\n", eventID))
-			}
-			content.WriteString("\n")
-		}
-	}
-
-	entity.Content = content.String()
-	entity.AddSource(url)
+	entity := sources.NewSourceEntity(source, summary, c.getSourcePath(url))
+	id := entity.Metadata.ID
+	existed := c.graph.EntityExists(id)
 
 	// Save entity
 	if err := c.graph.SaveEntity(entity); err != nil {
 		fmt.Println(FormatWarning(fmt.Sprintf("Failed to save source summary: %v", err)))
 		return ""
 	}
+	if existed {
+		c.events().PublishEntity(operations.EventEntityUpdated, entity)
+	} else {
+		c.events().PublishEntity(operations.EventEntityCreated, entity)
+	}
 
 	fmt.Printf("  %s Created source summary: %s %s\n",
 		SuccessStyle.Render("📄"),
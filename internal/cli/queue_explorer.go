@@ -3,10 +3,12 @@ package cli
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"silvia/internal/operations"
 )
 
 // QueueAction represents an action to take on queue items
@@ -18,11 +20,15 @@ const (
 	QueueActionSkip
 	QueueActionPreview
 	QueueActionReorder
+	QueueActionDefer
 )
 
+// queueDeferPeriod is how long deferring a source puts it aside
+const queueDeferPeriod = 24 * time.Hour
+
 // queueItem represents a queue item for display
 type queueItem struct {
-	source      *QueuedSource
+	source      operations.QueueItem
 	action      QueueAction
 	title       string
 	description string
@@ -48,6 +54,7 @@ type queueExplorerModel struct {
 type queueKeyMap struct {
 	process    key.Binding
 	skip       key.Binding
+	deferItem  key.Binding
 	preview    key.Binding
 	toggleMode key.Binding
 	execute    key.Binding
@@ -65,6 +72,10 @@ func newQueueKeyMap() *queueKeyMap {
 			key.WithKeys("s", "delete"),
 			key.WithHelp("s/del", "skip"),
 		),
+		deferItem: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "defer a day"),
+		),
 		preview: key.NewBinding(
 			key.WithKeys("v"),
 			key.WithHelp("v", "preview"),
@@ -128,6 +139,18 @@ func (m queueExplorerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+		case key.Matches(msg, keys.deferItem):
+			idx := m.list.Index()
+			if idx < len(m.items) {
+				// Mark for deferring
+				m.selectedItems[idx] = QueueActionDefer
+				m.updateItemDisplay(idx)
+				// Move to next item
+				if idx < len(m.items)-1 {
+					m.list.CursorDown()
+				}
+			}
+
 		case key.Matches(msg, keys.preview):
 			idx := m.list.Index()
 			if idx < len(m.items) {
@@ -164,6 +187,7 @@ func (m queueExplorerModel) View() string {
 	// Count actions
 	processCount := 0
 	skipCount := 0
+	deferCount := 0
 	previewCount := 0
 	for _, action := range m.selectedItems {
 		switch action {
@@ -171,6 +195,8 @@ func (m queueExplorerModel) View() string {
 			processCount++
 		case QueueActionSkip:
 			skipCount++
+		case QueueActionDefer:
+			deferCount++
 		case QueueActionPreview:
 			previewCount++
 		}
@@ -178,16 +204,18 @@ func (m queueExplorerModel) View() string {
 
 	header := HeaderStyle.Render(fmt.Sprintf("Queue Explorer (%d items)", len(m.items)))
 
-	status := fmt.Sprintf("\n%s Process: %d | %s Skip: %d | %s Preview: %d",
+	status := fmt.Sprintf("\n%s Process: %d | %s Skip: %d | %s Defer: %d | %s Preview: %d",
 		SuccessStyle.Render("✓"),
 		processCount,
 		WarningStyle.Render("⊘"),
 		skipCount,
+		DimStyle.Render("⏸"),
+		deferCount,
 		InfoStyle.Render("👁"),
 		previewCount,
 	)
 
-	help := helpStyle.Render("\n[p] process • [s] skip • [v] preview • [x] execute • [q] quit")
+	help := helpStyle.Render("\n[p] process • [s] skip • [d] defer • [v] preview • [x] execute • [q] quit")
 
 	return fmt.Sprintf("%s%s\n\n%s%s", header, status, m.list.View(), help)
 }
@@ -211,6 +239,8 @@ func (m *queueExplorerModel) updateItemDisplay(idx int) {
 		item.title = SuccessStyle.Render("✓ ") + URLStyle.Render(baseTitle)
 	case QueueActionSkip:
 		item.title = WarningStyle.Render("⊘ ") + DimStyle.Render(baseTitle)
+	case QueueActionDefer:
+		item.title = DimStyle.Render("⏸ ") + DimStyle.Render(baseTitle)
 	case QueueActionPreview:
 		item.title = InfoStyle.Render("👁 ") + URLStyle.Render(baseTitle)
 	default:
@@ -233,21 +263,34 @@ func (c *CLI) InteractiveQueueExplorer(ctx context.Context) error {
 		defer c.termWriter.ExitInteractive()
 	}
 
-	queueItems := c.queue.GetAll()
-	if len(queueItems) == 0 {
+	// Sources being ingested elsewhere, or finished with, are left out
+	queue, err := c.queue.GetQueue(operations.QueuePending, operations.QueueDeferred, operations.QueueFailed)
+	if err != nil {
+		return err
+	}
+	if len(queue.Items) == 0 {
 		fmt.Println(DimStyle.Render("Queue is empty."))
 		return nil
 	}
 
 	// Create items for display
-	items := make([]queueItem, len(queueItems))
-	for i, source := range queueItems {
+	items := make([]queueItem, len(queue.Items))
+	for i, source := range queue.Items {
 		displayURL := source.URL
 		if len(displayURL) > 60 {
 			displayURL = displayURL[:57] + "..."
 		}
 
-		desc := fmt.Sprintf("[%s]", FormatPriority(source.Priority))
+		desc := fmt.Sprintf("[%s]", FormatPriority(SourcePriority(source.Priority)))
+		switch source.Status {
+		case operations.QueueDeferred:
+			desc += " " + DimStyle.Render("deferred")
+			if !source.DeferredUntil.IsZero() {
+				desc += DimStyle.Render(" until " + source.DeferredUntil.Local().Format("Jan 2 15:04"))
+			}
+		case operations.QueueFailed:
+			desc += " " + ErrorStyle.Render("failed")
+		}
 		if source.Description != "" {
 			desc += " - " + source.Description
 		}
@@ -323,8 +366,10 @@ func (c *CLI) executeQueueActions(ctx context.Context, items []queueItem, action
 
 	processedCount := 0
 	skippedCount := 0
+	deferredCount := 0
 	errors := []string{}
 
+execute:
 	for i, item := range items {
 		action := actions[i]
 		if action == QueueActionNone {
@@ -333,12 +378,26 @@ func (c *CLI) executeQueueActions(ctx context.Context, items []queueItem, action
 
 		switch action {
 		case QueueActionProcess:
+			// Leave the rest queued while the provider keeps failing rather
+			// than burning through every item with the same error
+			if c.llm != nil {
+				if open, until := c.llm.CircuitOpen(); open {
+					fmt.Println(FormatWarning(fmt.Sprintf("LLM provider is failing; paused until %s. Remaining items stay queued.", until.Format("15:04:05"))))
+					break execute
+				}
+			}
+
 			fmt.Printf("%s %s\n",
 				InfoStyle.Render("Processing:"),
 				URLStyle.Render(item.source.URL))
 
-			// Remove from queue
-			c.queue.Remove(item.source.URL)
+			// Claim it so no one else ingests it meanwhile; the ingest marks
+			// it done or failed
+			if err := c.queue.StartItem(item.source.URL); err != nil {
+				fmt.Println(FormatWarning(fmt.Sprintf("Not processed: %v", err)))
+				fmt.Println()
+				continue
+			}
 
 			// Process the source
 			if err := c.ingestSource(ctx, item.source.URL); err != nil {
@@ -354,10 +413,23 @@ func (c *CLI) executeQueueActions(ctx context.Context, items []queueItem, action
 				WarningStyle.Render("Skipping:"),
 				DimStyle.Render(item.source.URL))
 
-			// Remove from queue
-			c.queue.Remove(item.source.URL)
+			if err := c.queue.Skip(item.source.URL); err != nil {
+				fmt.Println(FormatWarning(fmt.Sprintf("Not skipped: %v", err)))
+				continue
+			}
 			skippedCount++
 
+		case QueueActionDefer:
+			fmt.Printf("%s %s\n",
+				DimStyle.Render("Deferring:"),
+				DimStyle.Render(item.source.URL))
+
+			if err := c.queue.Defer(item.source.URL, time.Now().Add(queueDeferPeriod)); err != nil {
+				fmt.Println(FormatWarning(fmt.Sprintf("Not deferred: %v", err)))
+				continue
+			}
+			deferredCount++
+
 		case QueueActionPreview:
 			fmt.Printf("%s %s\n",
 				InfoStyle.Render("Preview:"),
@@ -371,9 +443,6 @@ func (c *CLI) executeQueueActions(ctx context.Context, items []queueItem, action
 		}
 	}
 
-	// Save queue after changes
-	c.queue.SaveToFile()
-
 	// Summary
 	fmt.Println()
 	fmt.Println(HeaderStyle.Render("Summary"))
@@ -383,6 +452,9 @@ func (c *CLI) executeQueueActions(ctx context.Context, items []queueItem, action
 	if skippedCount > 0 {
 		fmt.Println(FormatWarning(fmt.Sprintf("Skipped %d sources", skippedCount)))
 	}
+	if deferredCount > 0 {
+		fmt.Println(DimStyle.Render(fmt.Sprintf("Deferred %d sources until tomorrow", deferredCount)))
+	}
 	if len(errors) > 0 {
 		fmt.Println(FormatError(fmt.Sprintf("Failed %d sources", len(errors))))
 		for _, err := range errors {
@@ -390,7 +462,10 @@ func (c *CLI) executeQueueActions(ctx context.Context, items []queueItem, action
 		}
 	}
 
-	remainingCount := c.queue.Len()
+	remainingCount := 0
+	if pending, err := c.queue.GetQueue(operations.QueuePending); err == nil {
+		remainingCount = pending.TotalCount
+	}
 	if remainingCount > 0 {
 		fmt.Println(InfoStyle.Render(fmt.Sprintf("Queue has %d remaining sources", remainingCount)))
 	} else {
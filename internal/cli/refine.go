@@ -3,17 +3,21 @@ package cli
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"strings"
 	"time"
 
 	"silvia/internal/graph"
+	"silvia/internal/llm"
 	"silvia/internal/prompts"
 )
 
 // refineEntity uses the LLM to enhance an entity based on its sources
 func (c *CLI) refineEntity(ctx context.Context, entityID string, guidance string) error {
+	ctx = llm.WithOperation(ctx, llm.OpRefine, entityID)
+
 	// Load the entity
 	entity, err := c.graph.LoadEntity(entityID)
 	if err != nil {
@@ -45,11 +49,17 @@ func (c *CLI) refineEntity(ctx context.Context, entityID string, guidance string
 	}
 
 	// Build the refinement prompt
-	prompt := c.buildRefinementPrompt(entity, sourceContent, guidance)
+	prompt, err := c.buildRefinementPrompt(entity, sourceContent, guidance)
+	if err != nil {
+		return err
+	}
 
 	// Get LLM refinement
-	fmt.Println(InfoStyle.Render("💭 Analyzing with LLM..."))
-	refinedContent, err := c.llm.Complete(ctx, prompt, "")
+	fmt.Println(InfoStyle.Render("💭 Analyzing with LLM...") + DimStyle.Render(" (Ctrl-C to cancel)"))
+	fmt.Println()
+	draft := newStreamPrinter(os.Stdout, "  ")
+	refinedContent, err := c.llm.ForTask(llm.TaskRefine).CompleteStream(ctx, prompt, "", draft.Write)
+	draft.Finish()
 	if err != nil {
 		return fmt.Errorf("LLM refinement failed: %w", err)
 	}
@@ -69,6 +79,8 @@ func (c *CLI) refineEntity(ctx context.Context, entityID string, guidance string
 		BackRefs:      entity.BackRefs,
 	}
 	newEntity.Metadata.Updated = time.Now()
+	newEntity.Metadata.Prompts = maps.Clone(entity.Metadata.Prompts)
+	newEntity.RecordPrompt(prompts.Refine, prompts.Version(prompts.Refine))
 
 	// Show the diff
 	fmt.Println()
@@ -290,42 +302,15 @@ func (c *CLI) buildContextFromEntity(entity *graph.Entity) string {
 	return content.String()
 }
 
-// buildRefinementPrompt creates the prompt for the LLM
-func (c *CLI) buildRefinementPrompt(entity *graph.Entity, sourceContent string, guidance string) string {
-	var prompt strings.Builder
-
-	prompt.WriteString("You are refining a knowledge graph entity based on its source materials.\n\n")
-
-	prompt.WriteString("CURRENT ENTITY:\n")
-	prompt.WriteString(fmt.Sprintf("Title: %s\n", entity.Title))
-	prompt.WriteString(fmt.Sprintf("Type: %s\n", entity.Metadata.Type))
-	prompt.WriteString(fmt.Sprintf("Current Content:\n%s\n\n", entity.Content))
-
-	prompt.WriteString("SOURCE MATERIALS:\n")
-	prompt.WriteString(sourceContent)
-	prompt.WriteString("\n\n")
-
-	if guidance != "" {
-		prompt.WriteString("REFINEMENT GUIDANCE:\n")
-		prompt.WriteString(guidance)
-		prompt.WriteString("\n\n")
-	}
-
-	prompt.WriteString(prompts.GetCitationGuidelines())
-	prompt.WriteString("\n")
-
-	prompt.WriteString("TASK:\n")
-	prompt.WriteString("Create an enhanced version of this entity that follows these standards:\n")
-	prompt.WriteString(prompts.GetEntityContentGuidelines())
-
-	if guidance != "" {
-		prompt.WriteString("7. Addresses the specific guidance provided\n")
-	}
-
-	prompt.WriteString("\nReturn ONLY the refined content in markdown format, without any preamble or explanation.")
-	prompt.WriteString("\nDo not include YAML frontmatter or title - just the content that goes after the title.")
-
-	return prompt.String()
+// buildRefinementPrompt renders the refine prompt for the entity
+func (c *CLI) buildRefinementPrompt(entity *graph.Entity, sourceContent string, guidance string) (string, error) {
+	return prompts.Render(prompts.Refine, prompts.RefineData{
+		Title:         entity.Title,
+		Type:          string(entity.Metadata.Type),
+		Content:       entity.Content,
+		SourceContent: sourceContent,
+		Guidance:      guidance,
+	})
 }
 
 // extractMarkdownContent extracts clean markdown from LLM response
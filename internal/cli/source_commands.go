@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"silvia/internal/llm"
+	"silvia/internal/operations"
+	"silvia/internal/sources"
+)
+
+func handleSourceRaw(ctx context.Context, c *CLI, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /source-raw <source-id> [--html|--extract]")
+	}
+
+	mode := ""
+	if len(args) > 1 {
+		mode = args[1]
+		if mode != "--html" && mode != "--extract" {
+			return fmt.Errorf("usage: /source-raw <source-id> [--html|--extract]")
+		}
+	}
+
+	return c.showSourceRaw(ctx, args[0], mode)
+}
+
+// showSourceRaw displays or re-extracts a source from its archived WARC capture
+func (c *CLI) showSourceRaw(ctx context.Context, sourceID, mode string) error {
+	if !strings.HasPrefix(sourceID, "sources/") {
+		sourceID = "sources/" + sourceID
+	}
+
+	entity, err := c.graph.LoadEntity(sourceID)
+	if err != nil {
+		return fmt.Errorf("source not found: %s", sourceID)
+	}
+
+	if entity.Metadata.Archive == "" {
+		return fmt.Errorf("no archived capture recorded for %s", sourceID)
+	}
+
+	warcPath := entity.Metadata.Archive
+	if !filepath.IsAbs(warcPath) {
+		warcPath = filepath.Join(c.dataDir, warcPath)
+	}
+
+	capture, err := sources.ReadWARC(warcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	// Print capture details
+	fmt.Printf("\n%s\n", HeaderStyle.Render(entity.Title))
+	fmt.Printf("  URL:       %s\n", URLStyle.Render(capture.URL))
+	fmt.Printf("  Captured:  %s\n", capture.FetchedAt.Local().Format("2006-01-02 15:04:05"))
+	if capture.Resource {
+		fmt.Printf("  Type:      %s\n", DimStyle.Render("resource ("+capture.ContentType+", captured in browser)"))
+	} else {
+		fmt.Printf("  Response:  %s %s\n", capture.Proto, capture.Status)
+		if contentType := capture.ResponseHeaders.Get("Content-Type"); contentType != "" {
+			fmt.Printf("  Type:      %s\n", contentType)
+		}
+	}
+	fmt.Printf("  Size:      %d bytes\n", len(capture.Body))
+	fmt.Printf("  Archive:   %s\n", DimStyle.Render(entity.Metadata.Archive))
+
+	hash := capture.ContentHash()
+	switch {
+	case entity.Metadata.ContentHash == "":
+		fmt.Printf("  Hash:      %s\n", hash)
+	case entity.Metadata.ContentHash == hash:
+		fmt.Printf("  Hash:      %s %s\n", hash, SuccessStyle.Render("✓ verified"))
+	default:
+		fmt.Printf("  Hash:      %s\n", hash)
+		fmt.Println(FormatWarning(fmt.Sprintf("Archive does not match recorded hash %s", entity.Metadata.ContentHash)))
+	}
+	fmt.Println()
+
+	switch mode {
+	case "--html":
+		fmt.Println(string(capture.Body))
+		return nil
+
+	case "--extract":
+		ctx = llm.WithOperation(ctx, llm.OpIngest, capture.URL)
+		source := sources.SourceFromCapture(capture)
+		source.Metadata["warc"] = entity.Metadata.Archive
+		source.Metadata["capture_method"] = "archive"
+
+		fmt.Println(InfoStyle.Render("🔍 Re-extracting from archived capture..."))
+		extraction, err := c.extractor.Extract(ctx, source)
+		if err != nil {
+			return fmt.Errorf("extraction failed: %w", err)
+		}
+		return c.processExtraction(ctx, source, extraction, nil)
+
+	default:
+		if !capture.Resource && len(capture.RequestHeaders) > 0 {
+			fmt.Println(SubheaderStyle.Render("Request headers:"))
+			keys := make([]string, 0, len(capture.RequestHeaders))
+			for key := range capture.RequestHeaders {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("  %s: %s\n", key, DimStyle.Render(strings.Join(capture.RequestHeaders[key], ", ")))
+			}
+			fmt.Println()
+		}
+
+		source := sources.SourceFromCapture(capture)
+		fmt.Println(source.Content)
+		fmt.Println()
+		fmt.Println(DimStyle.Render("Use --html for the raw capture or --extract to re-run extraction from it"))
+		return nil
+	}
+}
+
+func handleRecheck(ctx context.Context, c *CLI, args []string) error {
+	if c.ops == nil {
+		return fmt.Errorf("operations layer not available")
+	}
+
+	if len(args) > 0 && args[0] != "all" {
+		fmt.Println(InfoStyle.Render(fmt.Sprintf("🔄 Rechecking %s...", args[0])))
+		result, err := c.ops.Source.RecheckSource(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		printRecheckResult(*result)
+		return nil
+	}
+
+	all := len(args) > 0 && args[0] == "all"
+	if all {
+		fmt.Println(InfoStyle.Render("🔄 Rechecking all processed sources..."))
+	} else {
+		fmt.Println(InfoStyle.Render("🔄 Rechecking sources not checked in the last 24 hours..."))
+	}
+
+	report, err := c.ops.Source.RecheckSources(ctx, all, 24*time.Hour, 0)
+	if err != nil {
+		return err
+	}
+	if len(report.Results) == 0 {
+		fmt.Println(FormatInfo("No sources due for a recheck"))
+		return nil
+	}
+
+	for _, result := range report.Results {
+		printRecheckResult(result)
+	}
+	if report.ReportPath != "" {
+		fmt.Printf("\n%s %s\n", DimStyle.Render("Report:"), report.ReportPath)
+	}
+	return nil
+}
+
+// printRecheckResult prints a one-source summary of a recheck
+func printRecheckResult(result operations.RecheckResult) {
+	label := result.Title
+	if label == "" {
+		label = result.URL
+	}
+
+	switch result.Status {
+	case operations.RecheckChanged:
+		fmt.Println(FormatWarning(fmt.Sprintf("Changed: %s (%d passages added or changed, %d removed)",
+			label, len(result.Added), len(result.Removed))))
+		for _, entity := range result.ExtractedEntities {
+			fmt.Printf("    %s %s\n", DimStyle.Render("↳"), entity.ID)
+		}
+	case operations.RecheckUnchanged:
+		fmt.Println(DimStyle.Render("  Unchanged: " + label))
+	case operations.RecheckBaseline:
+		fmt.Println(FormatInfo("Snapshot recorded: " + label))
+	case operations.RecheckError:
+		fmt.Println(FormatError(fmt.Sprintf("%s: %s", result.URL, result.Error)))
+	}
+	if result.Error != "" && result.Status != operations.RecheckError {
+		fmt.Println(FormatWarning(result.Error))
+	}
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// streamPrinter writes LLM output to the terminal as it is generated, so
+// long responses show progress instead of a silent wait
+type streamPrinter struct {
+	out     io.Writer
+	indent  string
+	written bool
+	atStart bool // Next write begins a new line
+}
+
+// newStreamPrinter creates a printer that prefixes every line with indent
+func newStreamPrinter(out io.Writer, indent string) *streamPrinter {
+	return &streamPrinter{out: out, indent: indent, atStart: true}
+}
+
+// Write prints a piece of the response. It matches the llm stream callback.
+func (p *streamPrinter) Write(delta string) {
+	if delta == "" {
+		return
+	}
+	p.written = true
+
+	var b strings.Builder
+	for _, r := range delta {
+		if p.atStart {
+			b.WriteString(p.indent)
+			p.atStart = false
+		}
+		b.WriteRune(r)
+		if r == '\n' {
+			p.atStart = true
+		}
+	}
+	fmt.Fprint(p.out, b.String())
+}
+
+// Finish ends the output on a fresh line
+func (p *streamPrinter) Finish() {
+	if p.written && !p.atStart {
+		fmt.Fprintln(p.out)
+	}
+}
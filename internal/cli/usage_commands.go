@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"silvia/internal/llm"
+)
+
+const usageUsage = "usage: /usage [--since 24h|7d|today|2006-01-02] [--by operation|kind|model|source] | budget [daily <usd> | <operation-kind> <usd> | clear]"
+
+func handleUsage(ctx context.Context, c *CLI, args []string) error {
+	if c.ops == nil || c.ops.LLM == nil {
+		return fmt.Errorf("LLM operations not available")
+	}
+
+	if len(args) > 0 && args[0] == "budget" {
+		return c.setBudget(args[1:])
+	}
+
+	sinceValue, by := "", ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since", "-s":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--since requires a value")
+			}
+			i++
+			sinceValue = args[i]
+		case "--by", "-b":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--by requires a value")
+			}
+			i++
+			by = args[i]
+		default:
+			return fmt.Errorf("%s", usageUsage)
+		}
+	}
+
+	since, err := llm.ParseSince(sinceValue)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.ops.LLM.GetUsage(since, by)
+	if err != nil {
+		return err
+	}
+	summary := report.Summary
+
+	title := "LLM usage"
+	if !since.IsZero() {
+		title += " since " + since.Local().Format("2006-01-02 15:04")
+	}
+	fmt.Printf("\n%s\n\n", HeaderStyle.Render(title))
+
+	if summary.Total.Calls == 0 {
+		fmt.Println(FormatInfo("No LLM calls recorded"))
+	} else {
+		fmt.Println(SubheaderStyle.Render("By " + summary.By + ":"))
+		for _, group := range summary.Groups {
+			fmt.Printf("  %s  %s  %s\n",
+				HighlightStyle.Render(formatCost(group.Cost)),
+				DimStyle.Render(fmt.Sprintf("%4d calls %9s tokens", group.Calls, formatTokens(group.TotalTokens))),
+				group.Key)
+		}
+		fmt.Println()
+		fmt.Printf("  Total: %s over %d calls (%s prompt + %s completion tokens)\n",
+			SuccessStyle.Render(formatCost(summary.Total.Cost)), summary.Total.Calls,
+			formatTokens(summary.Total.PromptTokens), formatTokens(summary.Total.CompletionTokens))
+	}
+
+	printBudget(report.Budget, report.SpentToday)
+	return nil
+}
+
+// setBudget updates the daily or per-operation spending limit
+func (c *CLI) setBudget(args []string) error {
+	ledger := c.llm.UsageLedger()
+	if ledger == nil {
+		return fmt.Errorf("usage tracking not enabled")
+	}
+
+	budget, spentToday := ledger.Budget()
+	if len(args) == 0 {
+		printBudget(budget, spentToday)
+		return nil
+	}
+
+	// Copy so the ledger's budget is only changed by SetBudget
+	limits := maps.Clone(budget.OperationLimits)
+	if limits == nil {
+		limits = make(map[string]float64)
+	}
+	budget.OperationLimits = limits
+
+	switch {
+	case args[0] == "clear":
+		budget = llm.Budget{}
+	case len(args) == 2:
+		amount, err := strconv.ParseFloat(strings.TrimPrefix(args[1], "$"), 64)
+		if err != nil || amount < 0 {
+			return fmt.Errorf("invalid amount: %s", args[1])
+		}
+		if args[0] == "daily" {
+			budget.DailyLimit = amount
+		} else if amount == 0 {
+			delete(budget.OperationLimits, args[0])
+		} else {
+			budget.OperationLimits[args[0]] = amount
+		}
+	default:
+		return fmt.Errorf("%s", usageUsage)
+	}
+
+	if err := ledger.SetBudget(budget); err != nil {
+		return err
+	}
+	fmt.Println(FormatSuccess("Budget updated"))
+	printBudget(budget, spentToday)
+	return nil
+}
+
+func printBudget(budget llm.Budget, spentToday float64) {
+	fmt.Println()
+	if budget.DailyLimit > 0 {
+		fmt.Printf("  Today: %s of %s daily budget\n", formatCost(spentToday), formatCost(budget.DailyLimit))
+	} else {
+		fmt.Printf("  Today: %s %s\n", formatCost(spentToday), DimStyle.Render("(no daily budget)"))
+	}
+	for _, kind := range slices.Sorted(maps.Keys(budget.OperationLimits)) {
+		fmt.Printf("  Limit: %s per %s\n", formatCost(budget.OperationLimits[kind]), kind)
+	}
+	fmt.Println()
+}
+
+func formatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+func formatTokens(tokens int) string {
+	if tokens >= 1000000 {
+		return fmt.Sprintf("%.1fM", float64(tokens)/1000000)
+	}
+	if tokens >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	}
+	return strconv.Itoa(tokens)
+}
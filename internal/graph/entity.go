@@ -40,6 +40,32 @@ func extractTitle(id string) string {
 	return strings.Join(parts, " ")
 }
 
+// RecordPrompt notes the version of the prompt that generated the entity's
+// content. An empty version is ignored.
+func (e *Entity) RecordPrompt(name, version string) {
+	if version == "" {
+		return
+	}
+	if e.Metadata.Prompts == nil {
+		e.Metadata.Prompts = make(map[string]string)
+	}
+	e.Metadata.Prompts[name] = version
+}
+
+// RecordChunks records the chunks of a source the entity was extracted
+// from and reports whether they changed. Sources that were not split into
+// chunks are not recorded.
+func (e *Entity) RecordChunks(source string, chunks []int, chunkCount int) bool {
+	if chunkCount <= 1 || len(chunks) == 0 || slices.Equal(e.Metadata.SourceChunks[source], chunks) {
+		return false
+	}
+	if e.Metadata.SourceChunks == nil {
+		e.Metadata.SourceChunks = make(map[string][]int)
+	}
+	e.Metadata.SourceChunks[source] = slices.Clone(chunks)
+	return true
+}
+
 // AddRelationship adds a new relationship to the entity
 func (e *Entity) AddRelationship(relType string, target string, date *time.Time, note string) {
 	rel := Relationship{
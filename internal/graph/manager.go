@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"silvia/internal/llm"
+	"silvia/internal/prompts"
 )
 
 // cacheEntry stores an entity with metadata about when it was cached
@@ -34,6 +35,11 @@ func NewManager(baseDir string) *Manager {
 	}
 }
 
+// BaseDir returns the data directory the graph is stored in
+func (m *Manager) BaseDir() string {
+	return m.baseDir
+}
+
 // LoadEntity loads an entity by ID
 func (m *Manager) LoadEntity(id string) (*Entity, error) {
 	filePath := m.getEntityPath(id)
@@ -456,6 +462,8 @@ func backReferencesEqual(a, b []BackReference) bool {
 
 // MergeEntities merges entity2 into entity1, updating all references
 func (m *Manager) MergeEntities(ctx context.Context, entity1ID, entity2ID string, llmClient *llm.Client) error {
+	ctx = llm.WithOperation(ctx, llm.OpMerge, entity1ID+" + "+entity2ID)
+
 	// Load both entities
 	entity1, err := m.LoadEntity(entity1ID)
 	if err != nil {
@@ -482,6 +490,7 @@ func (m *Manager) MergeEntities(ctx context.Context, entity1ID, entity2ID string
 		Content:  mergedContent,
 		BackRefs: entity1.BackRefs, // Will be rebuilt
 	}
+	merged.RecordPrompt(prompts.Merge, prompts.Version(prompts.Merge))
 
 	// Merge metadata
 	// Combine aliases
@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// NameMatcher finds the titles and aliases of entities in text. It is an
+// Aho-Corasick automaton over every name, so a page is scanned once however
+// many entities the graph holds.
+//
+// Matching ignores case, except for short names and acronyms ("UN", "NASA"),
+// which must match exactly so that common words do not turn into entities.
+// Names only match whole words.
+type NameMatcher struct {
+	nodes []matcherNode
+	names []matcherName
+}
+
+// NameMatch is an occurrence of an entity's name in text. Start and End
+// count characters (Unicode code points), not bytes.
+type NameMatch struct {
+	EntityID string
+	Name     string // The title or alias that matched
+	Start    int
+	End      int
+}
+
+type matcherNode struct {
+	next map[rune]int
+	fail int
+	out  []int // Names ending here, including those reached by failure links
+}
+
+type matcherName struct {
+	entityID string
+	name     string
+	length   int
+	exact    bool
+}
+
+// minNameLength is the shortest name worth matching
+const minNameLength = 2
+
+// NewNameMatcher builds a matcher for the titles and aliases of entities
+func NewNameMatcher(entities []*Entity) *NameMatcher {
+	m := &NameMatcher{nodes: []matcherNode{{next: make(map[rune]int)}}}
+	for _, entity := range entities {
+		seen := make(map[string]bool)
+		for _, name := range append([]string{entity.Title}, entity.Metadata.Aliases...) {
+			name = strings.Join(strings.Fields(name), " ")
+			key := strings.ToLower(name)
+			if len([]rune(name)) < minNameLength || seen[key] {
+				continue
+			}
+			seen[key] = true
+			m.add(entity.Metadata.ID, name)
+		}
+	}
+	m.build()
+	return m
+}
+
+// Names returns how many names the matcher looks for
+func (m *NameMatcher) Names() int {
+	return len(m.names)
+}
+
+func (m *NameMatcher) add(entityID, name string) {
+	runes := []rune(name)
+	state := 0
+	for _, r := range runes {
+		r = foldRune(r)
+		next, ok := m.nodes[state].next[r]
+		if !ok {
+			next = len(m.nodes)
+			m.nodes = append(m.nodes, matcherNode{next: make(map[rune]int)})
+			m.nodes[state].next[r] = next
+		}
+		state = next
+	}
+	m.nodes[state].out = append(m.nodes[state].out, len(m.names))
+	m.names = append(m.names, matcherName{
+		entityID: entityID,
+		name:     name,
+		length:   len(runes),
+		exact:    len(runes) <= 3 || isAcronym(name),
+	})
+}
+
+// build sets each node's failure link, breadth first, to the node for the
+// longest proper suffix of its path that is also a path in the trie
+func (m *NameMatcher) build() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[state].next {
+			fail := m.nodes[state].fail
+			for fail != 0 && !hasEdge(m.nodes[fail], r) {
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].next[r]; ok && next != child {
+				fail = next
+			} else {
+				fail = 0
+			}
+			m.nodes[child].fail = fail
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[fail].out...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+func hasEdge(node matcherNode, r rune) bool {
+	_, ok := node.next[r]
+	return ok
+}
+
+// Find returns the names in text, in order. Where matches overlap the
+// leftmost, then longest, wins; entities sharing a name all match it.
+func (m *NameMatcher) Find(text string) []NameMatch {
+	runes := []rune(text)
+	var found []NameMatch
+	state := 0
+	for i, r := range runes {
+		r = foldRune(r)
+		for state != 0 && !hasEdge(m.nodes[state], r) {
+			state = m.nodes[state].fail
+		}
+		if next, ok := m.nodes[state].next[r]; ok {
+			state = next
+		}
+		for _, index := range m.nodes[state].out {
+			name := m.names[index]
+			start, end := i+1-name.length, i+1
+			if !isWordBoundary(runes, start, end) {
+				continue
+			}
+			if name.exact && string(runes[start:end]) != name.name {
+				continue
+			}
+			found = append(found, NameMatch{EntityID: name.entityID, Name: name.name, Start: start, End: end})
+		}
+	}
+
+	slices.SortStableFunc(found, func(a, b NameMatch) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+	matches := make([]NameMatch, 0, len(found))
+	for _, match := range found {
+		if n := len(matches); n > 0 {
+			last := matches[n-1]
+			sameSpan := match.Start == last.Start && match.End == last.End
+			if !sameSpan && match.Start < last.End {
+				continue
+			}
+			if sameSpan && match.EntityID == last.EntityID {
+				continue
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// foldRune makes matching ignore case and the kind of space or apostrophe
+func foldRune(r rune) rune {
+	switch {
+	case unicode.IsSpace(r):
+		return ' '
+	case r == '’' || r == '‘':
+		return '\''
+	}
+	return unicode.ToLower(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordBoundary(runes []rune, start, end int) bool {
+	if start > 0 && isWordRune(runes[start-1]) && isWordRune(runes[start]) {
+		return false
+	}
+	if end < len(runes) && isWordRune(runes[end]) && isWordRune(runes[end-1]) {
+		return false
+	}
+	return true
+}
+
+// isAcronym reports whether a name is written in capitals, like "NATO"
+func isAcronym(name string) bool {
+	letters := 0
+	for _, r := range name {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
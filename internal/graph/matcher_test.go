@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func testEntity(id, title string, aliases ...string) *Entity {
+	entity := NewEntity(id, EntityConcept)
+	entity.Title = title
+	entity.Metadata.Aliases = aliases
+	return entity
+}
+
+func TestNameMatcherFind(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities []*Entity
+		text     string
+		want     []NameMatch
+	}{
+		{
+			name:     "ignores case",
+			entities: []*Entity{testEntity("people/barack-obama", "Barack Obama")},
+			text:     "Then barack OBAMA spoke.",
+			want:     []NameMatch{{EntityID: "people/barack-obama", Name: "Barack Obama", Start: 5, End: 17}},
+		},
+		{
+			name:     "offsets count characters",
+			entities: []*Entity{testEntity("organizations/cafe-zurich", "Café Zürich")},
+			text:     "Über Café Zürich",
+			want:     []NameMatch{{EntityID: "organizations/cafe-zurich", Name: "Café Zürich", Start: 5, End: 16}},
+		},
+		{
+			name: "longest match wins at the same start",
+			entities: []*Entity{
+				testEntity("places/new-york", "New York"),
+				testEntity("organizations/new-york-times", "New York Times"),
+			},
+			text: "the New York Times said",
+			want: []NameMatch{{EntityID: "organizations/new-york-times", Name: "New York Times", Start: 4, End: 18}},
+		},
+		{
+			name: "leftmost match wins an overlap",
+			entities: []*Entity{
+				testEntity("organizations/bank-of-america", "Bank of America"),
+				testEntity("organizations/america-movil", "America Movil"),
+			},
+			text: "Bank of America Movil",
+			want: []NameMatch{{EntityID: "organizations/bank-of-america", Name: "Bank of America", Start: 0, End: 15}},
+		},
+		{
+			name: "match found through a failure link",
+			entities: []*Entity{
+				testEntity("organizations/new-jersey-devils", "New Jersey Devils"),
+				testEntity("places/jersey-city", "Jersey City"),
+			},
+			text: "New Jersey City",
+			want: []NameMatch{{EntityID: "places/jersey-city", Name: "Jersey City", Start: 4, End: 15}},
+		},
+		{
+			name:     "acronyms match exactly",
+			entities: []*Entity{testEntity("organizations/nasa", "NASA")},
+			text:     "nasa and NASA",
+			want:     []NameMatch{{EntityID: "organizations/nasa", Name: "NASA", Start: 9, End: 13}},
+		},
+		{
+			name:     "whole words only",
+			entities: []*Entity{testEntity("people/ann", "Ann")},
+			text:     "Annual report by Ann.",
+			want:     []NameMatch{{EntityID: "people/ann", Name: "Ann", Start: 17, End: 20}},
+		},
+		{
+			name:     "aliases match",
+			entities: []*Entity{testEntity("organizations/un", "United Nations", "UN")},
+			text:     "The UN, or United Nations, met.",
+			want: []NameMatch{
+				{EntityID: "organizations/un", Name: "UN", Start: 4, End: 6},
+				{EntityID: "organizations/un", Name: "United Nations", Start: 11, End: 25},
+			},
+		},
+		{
+			name: "entities sharing a name all match",
+			entities: []*Entity{
+				testEntity("organizations/apple", "Apple"),
+				testEntity("concepts/apple", "Apple"),
+			},
+			text: "Apple",
+			want: []NameMatch{
+				{EntityID: "organizations/apple", Name: "Apple", Start: 0, End: 5},
+				{EntityID: "concepts/apple", Name: "Apple", Start: 0, End: 5},
+			},
+		},
+		{
+			name:     "curly apostrophes and runs of spaces",
+			entities: []*Entity{testEntity("people/conan-obrien", "Conan O'Brien")},
+			text:     "Conan\nO’Brien",
+			want:     []NameMatch{{EntityID: "people/conan-obrien", Name: "Conan O'Brien", Start: 0, End: 13}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewNameMatcher(tt.entities).Find(tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Find(%q) =\n  %+v\nwant\n  %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameMatcherNames(t *testing.T) {
+	entities := []*Entity{
+		testEntity("people/a", "Ada Lovelace", "ada lovelace", "Countess of Lovelace"),
+		testEntity("people/b", "X"), // Too short to match
+	}
+	if got := NewNameMatcher(entities).Names(); got != 2 {
+		t.Errorf("Names() = %d, want 2", got)
+	}
+}
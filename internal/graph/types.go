@@ -22,8 +22,17 @@ type Metadata struct {
 	Aliases []string   `yaml:"aliases,omitempty"`
 	Created time.Time  `yaml:"created"`
 	Updated time.Time  `yaml:"updated"`
-	Sources []string   `yaml:"sources,omitempty"`
-	Tags    []string   `yaml:"tags,omitempty"`
+	// API token that made the latest change through the operations layer
+	UpdatedBy string   `yaml:"updated_by,omitempty"`
+	Sources   []string `yaml:"sources,omitempty"`
+	Tags      []string `yaml:"tags,omitempty"`
+	// Source entities only: hash of the captured content and its WARC archive
+	ContentHash string `yaml:"content_hash,omitempty"`
+	Archive     string `yaml:"archive,omitempty"`
+	// Chunks of each split source the entity was extracted from, by URL
+	SourceChunks map[string][]int `yaml:"source_chunks,omitempty"`
+	// Versions of the prompts that generated the content, by prompt name
+	Prompts map[string]string `yaml:"prompts,omitempty"`
 }
 
 // Entity represents a node in the knowledge graph
@@ -60,6 +69,17 @@ func (t EntityType) IsValid() bool {
 	}
 }
 
+// EntityTypeNames lists the valid entity types
+func EntityTypeNames() []string {
+	return []string{
+		string(EntityPerson),
+		string(EntityOrganization),
+		string(EntityConcept),
+		string(EntityWork),
+		string(EntityEvent),
+	}
+}
+
 // RelationshipType defines common relationship types
 type RelationshipType string
 
@@ -72,3 +92,16 @@ const (
 	RelSpokeAt     RelationshipType = "spoke_at"
 	RelConnected   RelationshipType = "connected"
 )
+
+// RelationshipTypeNames lists the common relationship types
+func RelationshipTypeNames() []string {
+	return []string{
+		string(RelFounded),
+		string(RelAuthored),
+		string(RelRecommended),
+		string(RelAttended),
+		string(RelMemberOf),
+		string(RelSpokeAt),
+		string(RelConnected),
+	}
+}
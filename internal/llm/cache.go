@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheMode controls how the response cache is used
+type CacheMode string
+
+const (
+	CacheOff       CacheMode = "off"
+	CacheReadWrite CacheMode = "rw"     // Serve hits, call the provider on misses and store the result
+	CacheReplay    CacheMode = "replay" // Serve hits only; a miss is an error and the provider is never called
+)
+
+// ErrCacheMiss is returned in replay mode when a request was never recorded
+var ErrCacheMiss = errors.New("LLM cache miss in replay mode")
+
+// ParseCacheMode validates a cache mode name
+func ParseCacheMode(value string) (CacheMode, error) {
+	switch CacheMode(strings.ToLower(strings.TrimSpace(value))) {
+	case "", CacheOff:
+		return CacheOff, nil
+	case CacheReadWrite, "read-write":
+		return CacheReadWrite, nil
+	case CacheReplay, "replay-only":
+		return CacheReplay, nil
+	}
+	return "", fmt.Errorf("invalid cache mode %q (use off, rw, or replay)", value)
+}
+
+// Cache is a content-addressed store of chat responses on disk. Each entry is
+// a JSON file named by the hash of everything that determines the response:
+// model, messages, tools, response format and sampling parameters.
+type Cache struct {
+	dir  string
+	mode CacheMode
+}
+
+// NewCache creates a cache rooted at dir
+func NewCache(dir string, mode CacheMode) *Cache {
+	return &Cache{dir: dir, mode: mode}
+}
+
+// Mode returns the cache mode
+func (c *Cache) Mode() CacheMode {
+	return c.mode
+}
+
+// cacheEntry is the on-disk form of a cached response. The request is kept
+// alongside the response so entries can be inspected when debugging.
+type cacheEntry struct {
+	Key      string       `json:"key"`
+	Created  time.Time    `json:"created"`
+	Request  cacheKey     `json:"request"`
+	Response ChatResponse `json:"response"`
+}
+
+// cacheKey is the canonical form of a request that is hashed for the key
+type cacheKey struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     string          `json:"tool_choice,omitempty"`
+	ResponseFormat string          `json:"response_format,omitempty"`
+	SchemaName     string          `json:"schema_name,omitempty"`
+	Schema         json.RawMessage `json:"schema,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+}
+
+func newCacheKey(request ChatRequest) (cacheKey, string, error) {
+	key := cacheKey{
+		Model:       request.Model,
+		Messages:    request.Messages,
+		Tools:       request.Tools,
+		ToolChoice:  request.ToolChoice,
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+	}
+	if format := request.ResponseFormat; format != nil {
+		key.ResponseFormat = format.Type
+		key.SchemaName = format.Name
+		if format.Schema != nil {
+			schema, err := format.Schema.MarshalJSON()
+			if err != nil {
+				return key, "", fmt.Errorf("failed to marshal schema: %w", err)
+			}
+			key.Schema = schema
+		}
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return key, "", fmt.Errorf("failed to marshal cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return key, hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Cache) entryPath(hash string) string {
+	return filepath.Join(c.dir, hash[:2], hash+".json")
+}
+
+// Get returns the cached response for request, or nil on a miss. In replay
+// mode a miss returns ErrCacheMiss.
+func (c *Cache) Get(request ChatRequest) (*ChatResponse, error) {
+	_, hash, err := newCacheKey(request)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(c.entryPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			if c.mode == CacheReplay {
+				return nil, fmt.Errorf("%w: %s (model %s)", ErrCacheMiss, hash[:12], request.Model)
+			}
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cache entry %s: %w", hash[:12], err)
+	}
+	return &entry.Response, nil
+}
+
+// Put stores a response. It is a no-op unless the mode is read-write.
+func (c *Cache) Put(request ChatRequest, response *ChatResponse) error {
+	if c.mode != CacheReadWrite {
+		return nil
+	}
+
+	key, hash, err := newCacheKey(request)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cacheEntry{
+		Key:      hash,
+		Created:  time.Now(),
+		Request:  key,
+		Response: *response,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	path := c.entryPath(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write then rename so a concurrent reader never sees a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+	return os.Rename(tmp.Name(), path)
+}
@@ -4,210 +4,390 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"time"
 
-	"github.com/revrost/go-openrouter"
 	"github.com/revrost/go-openrouter/jsonschema"
+
+	"silvia/internal/prompts"
+	"silvia/internal/resilience"
 )
 
+// Client is the LLM entry point used throughout silvia. It delegates to a
+// Provider, so the backend can be OpenRouter, a local OpenAI-compatible
+// server, or a fake for tests. Models are chosen per task from a shared
+// ModelConfig; use ForTask to get a client for a specific task.
 type Client struct {
-	openRouterClient *openrouter.Client
+	provider Provider
+	models   *modelRegistry
+	usage    *UsageLedger
+	cache    *Cache
+	task     Task
+	retry    resilience.Policy
+	limiter  *resilience.Limiter
+	breaker  *resilience.Breaker
 }
 
+// NewClient creates a client backed by OpenRouter
 func NewClient(apiKey string) *Client {
-	client := openrouter.NewClient(apiKey)
+	return NewClientWithProvider(NewOpenRouterProvider(apiKey))
+}
+
+// NewClientWithProvider creates a client backed by the given provider
+func NewClientWithProvider(provider Provider) *Client {
 	return &Client{
-		openRouterClient: client,
+		provider: provider,
+		models:   newModelRegistry(),
+		task:     TaskDefault,
+		retry:    resilience.DefaultPolicy,
+		breaker:  resilience.NewBreaker(provider.Name(), 5, time.Minute),
 	}
 }
 
-func (c *Client) Complete(ctx context.Context, prompt string, model string) (string, error) {
-	if model == "" {
-		model = "openai/gpt-3.5-turbo"
+// NewClientFromConfig creates a client for the provider described by config
+func NewClientFromConfig(config ProviderConfig) (*Client, error) {
+	provider, err := NewProvider(config)
+	if err != nil {
+		return nil, err
 	}
+	client := NewClientWithProvider(provider)
+	client.models.global = config.Model
+	return client, nil
+}
 
-	request := openrouter.ChatCompletionRequest{
-		Model: model,
-		Messages: []openrouter.ChatCompletionMessage{
-			{
-				Role:    openrouter.ChatMessageRoleUser,
-				Content: openrouter.Content{Text: prompt},
-			},
-		},
+// Provider returns the provider behind the client
+func (c *Client) Provider() Provider {
+	return c.provider
+}
+
+// ForTask returns a client that uses the model configured for task. It shares
+// the provider and model mapping, so /models changes apply to it immediately.
+func (c *Client) ForTask(task Task) *Client {
+	view := *c
+	view.task = task
+	return &view
+}
+
+// Task returns the task this client selects models for
+func (c *Client) Task() Task {
+	return c.task
+}
+
+// SetUsageLedger enables usage recording and budget enforcement
+func (c *Client) SetUsageLedger(ledger *UsageLedger) {
+	c.usage = ledger
+}
+
+// UsageLedger returns the usage ledger, or nil if usage is not recorded
+func (c *Client) UsageLedger() *UsageLedger {
+	return c.usage
+}
+
+// SetCache enables the response cache
+func (c *Client) SetCache(cache *Cache) {
+	c.cache = cache
+}
+
+// SetRetryPolicy sets how failed provider calls are retried
+func (c *Client) SetRetryPolicy(policy resilience.Policy) {
+	c.retry = policy
+}
+
+// SetRateLimit limits provider calls to perMinute per minute with the given
+// burst. perMinute <= 0 removes the limit.
+func (c *Client) SetRateLimit(perMinute float64, burst int) {
+	c.limiter = resilience.NewLimiter(perMinute, burst)
+}
+
+// CircuitOpen reports whether calls to the provider are paused after repeated
+// failures, and until when
+func (c *Client) CircuitOpen() (bool, time.Time) {
+	return c.breaker.Open()
+}
+
+// chat sends a request using the model configured for the client's task,
+// trying fallback models in order if the primary fails. An explicit model
+// argument replaces the configured one and disables fallbacks. Cached
+// responses are served first; other calls are refused once a budget is
+// exceeded and recorded in the usage ledger. Transient provider failures are
+// retried with backoff, and the circuit breaker refuses calls while the
+// provider keeps failing.
+func (c *Client) chat(ctx context.Context, request ChatRequest, model string) (*ChatResponse, error) {
+	return c.chatStream(ctx, request, model, nil)
+}
+
+// chatStream is chat with streaming: if onDelta is not nil it receives the
+// content as it arrives (all at once for cache hits and providers that
+// cannot stream). A call that fails after content was delivered is neither
+// retried nor passed to a fallback model, since that would repeat output.
+func (c *Client) chatStream(ctx context.Context, request ChatRequest, model string, onDelta func(string)) (*ChatResponse, error) {
+	op := OperationFromContext(ctx)
+
+	settings := c.models.resolve(c.task)
+	models := append([]string{settings.Model}, settings.Fallbacks...)
+	if model != "" {
+		models = []string{model}
 	}
+	request.Temperature = settings.Temperature
+	request.MaxTokens = settings.MaxTokens
+
+	var lastErr error
+	for _, m := range models {
+		request.Model = m
+
+		if c.cache != nil && c.cache.Mode() != CacheOff {
+			response, err := c.cache.Get(request)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if response != nil {
+				if onDelta != nil {
+					onDelta(response.Content)
+				}
+				c.recordUsage(op, m, response, true)
+				return response, nil
+			}
+		}
 
-	response, err := c.openRouterClient.CreateChatCompletion(ctx, request)
-	if err != nil {
-		return "", fmt.Errorf("failed to create completion: %w", err)
+		if c.usage != nil {
+			if err := c.usage.Check(op); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := c.breaker.Allow(); err != nil {
+			return nil, err
+		}
+		var response *ChatResponse
+		streamed := false
+		err := resilience.Do(ctx, c.retry, func(ctx context.Context) error {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return err
+			}
+			var err error
+			response, streamed, err = c.send(ctx, request, onDelta)
+			if err != nil && streamed {
+				return resilience.Permanent(err)
+			}
+			return err
+		}, func(attempt int, delay time.Duration, err error) {
+			log.Printf("LLM call to %s failed (%v), retry %d in %s", m, err, attempt, delay.Round(time.Millisecond))
+		})
+		c.breaker.Record(err)
+		if err == nil {
+			if c.cache != nil {
+				if err := c.cache.Put(request, response); err != nil {
+					log.Printf("Warning: failed to cache LLM response: %v", err)
+				}
+			}
+			c.recordUsage(op, m, response, false)
+			return response, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || streamed {
+			break
+		}
 	}
+	return nil, lastErr
+}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
+// send makes a single provider call, streaming if onDelta is set and the
+// provider supports it. streamed reports whether any content was delivered.
+func (c *Client) send(ctx context.Context, request ChatRequest, onDelta func(string)) (response *ChatResponse, streamed bool, err error) {
+	streamer, ok := c.provider.(StreamingProvider)
+	if onDelta == nil || !ok {
+		response, err = c.provider.Chat(ctx, request)
+		if err == nil && onDelta != nil {
+			onDelta(response.Content)
+		}
+		return response, err == nil && onDelta != nil, err
 	}
 
-	return response.Choices[0].Message.Content.Text, nil
+	response, err = streamer.ChatStream(ctx, request, func(delta string) {
+		streamed = true
+		onDelta(delta)
+	})
+	return response, streamed, err
 }
 
-func (c *Client) CompleteWithSystem(ctx context.Context, systemPrompt, userPrompt string, model string) (string, error) {
-	if model == "" {
-		model = "openai/gpt-3.5-turbo"
+// recordUsage appends a successful call to the usage ledger. Cache hits are
+// recorded with their tokens but no cost, since nothing was paid for them.
+func (c *Client) recordUsage(op Operation, model string, response *ChatResponse, cached bool) {
+	if c.usage == nil {
+		return
+	}
+	if response.Model != "" {
+		model = response.Model
+	}
+	cost := response.Usage.Cost
+	if cached {
+		cost = 0
+	}
+	err := c.usage.Record(UsageEntry{
+		Time:             time.Now(),
+		Operation:        op,
+		Task:             c.task,
+		Provider:         c.provider.Name(),
+		Model:            model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		TotalTokens:      response.Usage.TotalTokens,
+		Cost:             cost,
+		Cached:           cached,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to record LLM usage: %v", err)
+	}
+}
+
+func (c *Client) Complete(ctx context.Context, prompt string, model string) (string, error) {
+	response, err := c.chat(ctx, ChatRequest{
+		Messages: []Message{
+			{Role: "user", Content: prompt},
+		},
+	}, model)
+	if err != nil {
+		return "", fmt.Errorf("failed to create completion: %w", err)
 	}
 
-	request := openrouter.ChatCompletionRequest{
-		Model: model,
-		Messages: []openrouter.ChatCompletionMessage{
-			{
-				Role:    openrouter.ChatMessageRoleSystem,
-				Content: openrouter.Content{Text: systemPrompt},
-			},
-			{
-				Role:    openrouter.ChatMessageRoleUser,
-				Content: openrouter.Content{Text: userPrompt},
-			},
+	return response.Content, nil
+}
+
+// CompleteStream is Complete with the response passed to onDelta as it is
+// generated. It returns the full response once the stream ends.
+func (c *Client) CompleteStream(ctx context.Context, prompt string, model string, onDelta func(delta string)) (string, error) {
+	response, err := c.chatStream(ctx, ChatRequest{
+		Messages: []Message{
+			{Role: "user", Content: prompt},
 		},
+	}, model, onDelta)
+	if err != nil {
+		return "", fmt.Errorf("failed to create completion: %w", err)
 	}
 
-	response, err := c.openRouterClient.CreateChatCompletion(ctx, request)
+	return response.Content, nil
+}
+
+func (c *Client) CompleteWithSystem(ctx context.Context, systemPrompt, userPrompt string, model string) (string, error) {
+	response, err := c.chat(ctx, ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
+	}, model)
 	if err != nil {
 		return "", fmt.Errorf("failed to create completion: %w", err)
 	}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
+	return response.Content, nil
+}
+
+// CompleteWithSystemStream is CompleteWithSystem with the response passed to
+// onDelta as it is generated
+func (c *Client) CompleteWithSystemStream(ctx context.Context, systemPrompt, userPrompt string, model string, onDelta func(delta string)) (string, error) {
+	response, err := c.chatStream(ctx, ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
+	}, model, onDelta)
+	if err != nil {
+		return "", fmt.Errorf("failed to create completion: %w", err)
 	}
 
-	return response.Choices[0].Message.Content.Text, nil
+	return response.Content, nil
 }
 
 // CompleteWithStructuredOutput completes with a JSON schema for structured output
 // The result parameter should be a pointer to a struct that will be populated with the response.
 // Use this when you have a well-defined output structure and want schema validation.
 func (c *Client) CompleteWithStructuredOutput(ctx context.Context, systemPrompt, userPrompt string, result any, model string) error {
-	if model == "" {
-		model = "openai/gpt-4o-mini" // Use a model that supports json_schema response format
-	}
-
 	// Generate JSON schema from the output type
 	schema, err := jsonschema.GenerateSchemaForType(result)
 	if err != nil {
 		return fmt.Errorf("failed to generate schema: %w", err)
 	}
 
-	request := openrouter.ChatCompletionRequest{
-		Model: model,
-		Messages: []openrouter.ChatCompletionMessage{
-			{
-				Role:    openrouter.ChatMessageRoleSystem,
-				Content: openrouter.Content{Text: systemPrompt},
-			},
-			{
-				Role:    openrouter.ChatMessageRoleUser,
-				Content: openrouter.Content{Text: userPrompt},
-			},
+	request := ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
 		},
-		ResponseFormat: &openrouter.ChatCompletionResponseFormat{
-			Type: openrouter.ChatCompletionResponseFormatTypeJSONSchema,
-			JSONSchema: &openrouter.ChatCompletionResponseFormatJSONSchema{
-				Name:   "result",
-				Schema: schema,
-				Strict: false, // Some models don't support strict mode
-			},
+		ResponseFormat: &ResponseFormat{
+			Type:   ResponseFormatJSONSchema,
+			Name:   "result",
+			Schema: schema,
 		},
 	}
 
-	response, err := c.openRouterClient.CreateChatCompletion(ctx, request)
-	if err != nil {
-		return fmt.Errorf("failed to create structured completion: %w", err)
-	}
+	for attempt := 0; ; attempt++ {
+		response, err := c.chat(ctx, request, model)
+		if err != nil {
+			return fmt.Errorf("failed to create structured completion: %w", err)
+		}
 
-	if len(response.Choices) == 0 {
-		return fmt.Errorf("no completion choices returned")
-	}
+		// Models without schema support often fence or pad the JSON; try a
+		// local repair before asking the model again
+		err = json.Unmarshal([]byte(response.Content), result)
+		if err != nil {
+			err = json.Unmarshal([]byte(repairJSON(response.Content)), result)
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxRepairAttempts {
+			return fmt.Errorf("failed to unmarshal structured response: %w", err)
+		}
 
-	// Unmarshal directly into the result
-	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content.Text), result); err != nil {
-		return fmt.Errorf("failed to unmarshal structured response: %w", err)
+		log.Printf("Structured response was not valid JSON (%v), asking for a correction", err)
+		request.Messages = append(request.Messages,
+			Message{Role: "assistant", Content: response.Content},
+			Message{Role: "user", Content: fmt.Sprintf("That response was not valid JSON (%v). Reply with only the corrected JSON matching the schema, without code fences or commentary.", err)},
+		)
 	}
-
-	return nil
 }
 
 // CompleteWithJSONMode completes with JSON mode enabled (less strict than schema).
 // Use this when you need JSON output but don't have a predefined schema or need more flexibility.
 // Returns the raw JSON string for manual parsing.
 func (c *Client) CompleteWithJSONMode(ctx context.Context, systemPrompt, userPrompt string, model string) (string, error) {
-	if model == "" {
-		model = "openai/gpt-4-turbo"
-	}
-
-	request := openrouter.ChatCompletionRequest{
-		Model: model,
-		Messages: []openrouter.ChatCompletionMessage{
-			{
-				Role:    openrouter.ChatMessageRoleSystem,
-				Content: openrouter.Content{Text: systemPrompt},
-			},
-			{
-				Role:    openrouter.ChatMessageRoleUser,
-				Content: openrouter.Content{Text: userPrompt},
-			},
+	response, err := c.chat(ctx, ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
 		},
-		ResponseFormat: &openrouter.ChatCompletionResponseFormat{
-			Type: openrouter.ChatCompletionResponseFormatTypeJSONObject,
-		},
-	}
-
-	response, err := c.openRouterClient.CreateChatCompletion(ctx, request)
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject},
+	}, model)
 	if err != nil {
 		return "", fmt.Errorf("failed to create JSON completion: %w", err)
 	}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
-	}
-
 	// Validate that response is valid JSON
 	var test json.RawMessage
-	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content.Text), &test); err != nil {
+	if err := json.Unmarshal([]byte(response.Content), &test); err != nil {
 		return "", fmt.Errorf("response is not valid JSON: %w", err)
 	}
 
-	return response.Choices[0].Message.Content.Text, nil
+	return response.Content, nil
 }
 
 func (c *Client) ListModels(ctx context.Context) ([]string, error) {
-	models, err := c.openRouterClient.ListModels(ctx)
+	models, err := c.provider.ListModels(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list models: %w", err)
 	}
-
-	var modelNames []string
-	for _, model := range models {
-		modelNames = append(modelNames, model.ID)
-	}
-
-	return modelNames, nil
+	return models, nil
 }
 
+// MergeEntities merges two entity descriptions with the merge prompt. It uses
+// the merge task's model unless the client was already scoped to another task.
 func (c *Client) MergeEntities(ctx context.Context, entity1Content, entity2Content string, model string) (string, error) {
-	if model == "" {
-		model = "anthropic/claude-3.5-sonnet"
+	systemPrompt, err := prompts.Render(prompts.Merge, nil)
+	if err != nil {
+		return "", err
 	}
 
-	systemPrompt := `You are a knowledge graph entity merger. Your task is to merge two entity descriptions into a single, coherent entity that preserves ALL information, references, and relationships from both inputs.
-
-CRITICAL REQUIREMENTS:
-1. Preserve ALL wiki-links in [[entity-id]] format from both entities
-2. Preserve ALL factual information from both entities
-3. Preserve ALL sources listed from both entities
-4. Preserve ALL relationships and connections mentioned
-5. Remove only truly redundant information (exact duplicates)
-6. Organize the merged content coherently with appropriate sections
-7. Do NOT add any new information not present in either source
-8. Do NOT remove any unique information from either source
-9. Maintain a neutral, encyclopedic tone
-
-Output the merged content in markdown format without frontmatter (that will be handled separately).`
-
 	userPrompt := fmt.Sprintf(`Please merge these two entity descriptions:
 
 ENTITY 1:
@@ -218,12 +398,16 @@ ENTITY 2:
 
 Provide the merged content:`, entity1Content, entity2Content)
 
-	return c.CompleteWithSystem(ctx, systemPrompt, userPrompt, model)
+	client := c
+	if c.task == TaskDefault {
+		client = c.ForTask(TaskMerge)
+	}
+	return client.CompleteWithSystem(ctx, systemPrompt, userPrompt, model)
 }
 
 // FunctionCallRequest represents a request with function calling capabilities
 type FunctionCallRequest struct {
-	Model      string
+	Model      string // Overrides the task's configured model if set
 	Messages   []Message
 	Tools      []Tool
 	ToolChoice string // "auto", "none", or specific tool name
@@ -271,88 +455,17 @@ type FunctionCallResponse struct {
 
 // CompleteWithFunctions performs a completion with function calling capabilities
 func (c *Client) CompleteWithFunctions(ctx context.Context, request FunctionCallRequest) (*FunctionCallResponse, error) {
-	if request.Model == "" {
-		request.Model = "openai/gpt-4-turbo" // Default to a model that supports function calling
-	}
-
-	// Convert our messages to OpenRouter format
-	orMessages := make([]openrouter.ChatCompletionMessage, len(request.Messages))
-	for i, msg := range request.Messages {
-		orMsg := openrouter.ChatCompletionMessage{
-			Role:       msg.Role,
-			Content:    openrouter.Content{Text: msg.Content},
-			ToolCallID: msg.ToolCallID,
-		}
-
-		// Convert tool calls if present
-		if len(msg.ToolCalls) > 0 {
-			orMsg.ToolCalls = make([]openrouter.ToolCall, len(msg.ToolCalls))
-			for j, tc := range msg.ToolCalls {
-				orMsg.ToolCalls[j] = openrouter.ToolCall{
-					ID:   tc.ID,
-					Type: openrouter.ToolType(tc.Type),
-					Function: openrouter.FunctionCall{
-						Name:      tc.Function.Name,
-						Arguments: tc.Function.Arguments,
-					},
-				}
-			}
-		}
-		orMessages[i] = orMsg
-	}
-
-	// Convert our tools to OpenRouter format
-	orTools := make([]openrouter.Tool, len(request.Tools))
-	for i, tool := range request.Tools {
-		orTools[i] = openrouter.Tool{
-			Type: openrouter.ToolTypeFunction,
-			Function: &openrouter.FunctionDefinition{
-				Name:        tool.Function.Name,
-				Description: tool.Function.Description,
-				Parameters:  tool.Function.Parameters,
-			},
-		}
-	}
-
-	// Create the OpenRouter request
-	orRequest := openrouter.ChatCompletionRequest{
-		Model:      request.Model,
-		Messages:   orMessages,
-		Tools:      orTools,
+	response, err := c.chat(ctx, ChatRequest{
+		Messages:   request.Messages,
+		Tools:      request.Tools,
 		ToolChoice: request.ToolChoice,
-	}
-
-	// Make the API call
-	response, err := c.openRouterClient.CreateChatCompletion(ctx, orRequest)
+	}, request.Model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create completion with functions: %w", err)
 	}
 
-	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no completion choices returned")
-	}
-
-	choice := response.Choices[0]
-
-	// Convert the response
-	result := &FunctionCallResponse{
-		Content: choice.Message.Content.Text,
-	}
-
-	// Convert tool calls if present
-	if len(choice.Message.ToolCalls) > 0 {
-		result.ToolCalls = make([]ToolCall, len(choice.Message.ToolCalls))
-		for i, tc := range choice.Message.ToolCalls {
-			result.ToolCalls[i] = ToolCall{
-				ID:   tc.ID,
-				Type: string(tc.Type),
-				Function: FunctionCall{
-					Name:      tc.Function.Name,
-					Arguments: tc.Function.Arguments,
-				},
-			}
-		}
-	}
-
-	return result, nil
+	return &FunctionCallResponse{
+		Content:   response.Content,
+		ToolCalls: response.ToolCalls,
+	}, nil
 }
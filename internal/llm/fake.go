@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeProvider is a deterministic provider for tests and offline runs. It
+// returns queued responses in order, then falls back to a handler (if set) or
+// a canned reply derived from the request. Every request is recorded.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []*ChatResponse
+	handler   func(ChatRequest) (*ChatResponse, error)
+	requests  []ChatRequest
+}
+
+// NewFakeProvider creates a fake provider with no queued responses
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// QueueResponse appends text responses returned by subsequent Chat calls
+func (p *FakeProvider) QueueResponse(contents ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, content := range contents {
+		p.responses = append(p.responses, &ChatResponse{Content: content})
+	}
+}
+
+// QueueToolCalls appends a response that asks for the given tool calls
+func (p *FakeProvider) QueueToolCalls(calls ...ToolCall) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = append(p.responses, &ChatResponse{ToolCalls: calls})
+}
+
+// SetHandler sets a function that answers requests once the queue is empty
+func (p *FakeProvider) SetHandler(handler func(ChatRequest) (*ChatResponse, error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = handler
+}
+
+// Requests returns every request received so far
+func (p *FakeProvider) Requests() []ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ChatRequest(nil), p.requests...)
+}
+
+func (p *FakeProvider) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, request)
+	var response *ChatResponse
+	if len(p.responses) > 0 {
+		response = p.responses[0]
+		p.responses = p.responses[1:]
+	}
+	handler := p.handler
+	p.mu.Unlock()
+
+	if response == nil && handler != nil {
+		return handler(request)
+	}
+	if response == nil {
+		response = &ChatResponse{Content: cannedResponse(request)}
+	}
+
+	result := *response
+	result.Model = request.Model
+	result.Usage = Usage{
+		PromptTokens:     approxTokens(request.Messages),
+		CompletionTokens: len(strings.Fields(result.Content)),
+	}
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return &result, nil
+}
+
+// ChatStream answers like Chat, delivering the content word by word
+func (p *FakeProvider) ChatStream(ctx context.Context, request ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	response, err := p.Chat(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(response.Content, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if word != "" {
+			onDelta(word)
+		}
+	}
+	return response, nil
+}
+
+func (p *FakeProvider) ListModels(ctx context.Context) ([]string, error) {
+	return []string{"fake/model"}, nil
+}
+
+// cannedResponse returns valid JSON for JSON requests and an echo of the last
+// user message otherwise
+func cannedResponse(request ChatRequest) string {
+	if request.ResponseFormat != nil {
+		return "{}"
+	}
+	for i := len(request.Messages) - 1; i >= 0; i-- {
+		if request.Messages[i].Role == "user" {
+			prompt := []rune(request.Messages[i].Content)
+			if len(prompt) > 80 {
+				prompt = prompt[:80]
+			}
+			return fmt.Sprintf("fake response to: %s", string(prompt))
+		}
+	}
+	return "fake response"
+}
+
+func approxTokens(messages []Message) int {
+	count := 0
+	for _, msg := range messages {
+		count += len(strings.Fields(msg.Content))
+	}
+	return count
+}
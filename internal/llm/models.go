@@ -0,0 +1,262 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Task names a kind of LLM work so each can use its own model
+type Task string
+
+const (
+	TaskDefault    Task = "default"
+	TaskExtraction Task = "extraction"  // Entity extraction from sources
+	TaskSummary    Task = "summary"     // Source summaries
+	TaskMerge      Task = "merge"       // Merging entity descriptions
+	TaskRefine     Task = "refine"      // Refining entity content
+	TaskChatTools  Task = "chat-tools"  // Choosing tools for a chat query
+	TaskChatAnswer Task = "chat-answer" // Writing the chat answer from tool results
+)
+
+// Tasks lists all tasks in display order
+var Tasks = []Task{TaskDefault, TaskExtraction, TaskSummary, TaskMerge, TaskRefine, TaskChatTools, TaskChatAnswer}
+
+// TaskModel configures the model used for a task
+type TaskModel struct {
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Fallbacks   []string `json:"fallbacks,omitempty"` // Tried in order if the model fails
+}
+
+// ModelConfig maps tasks to models. Tasks without an entry use TaskDefault.
+type ModelConfig struct {
+	Tasks map[Task]TaskModel `json:"tasks"`
+}
+
+// DefaultModelConfig returns the built-in task mapping
+func DefaultModelConfig() ModelConfig {
+	return ModelConfig{
+		Tasks: map[Task]TaskModel{
+			TaskDefault:    {Model: "openai/gpt-3.5-turbo"},
+			TaskExtraction: {Model: "openai/gpt-4o-mini"}, // Needs json_schema support
+			TaskSummary:    {Model: "openai/gpt-3.5-turbo"},
+			TaskMerge:      {Model: "anthropic/claude-3.5-sonnet"},
+			TaskRefine:     {Model: "openai/gpt-3.5-turbo"},
+			TaskChatTools:  {Model: "openai/gpt-4-turbo"}, // Needs function calling
+			TaskChatAnswer: {Model: "openai/gpt-3.5-turbo"},
+		},
+	}
+}
+
+// ParseTask validates a task name
+func ParseTask(name string) (Task, error) {
+	task := Task(strings.ToLower(strings.TrimSpace(name)))
+	for _, t := range Tasks {
+		if t == task {
+			return task, nil
+		}
+	}
+	return "", fmt.Errorf("unknown task %q (valid: %s)", name, taskNames())
+}
+
+func taskNames() string {
+	names := make([]string, len(Tasks))
+	for i, t := range Tasks {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseModelOverrides parses "task=model" pairs as given on the command line
+func ParseModelOverrides(values []string) (map[Task]string, error) {
+	overrides := make(map[Task]string)
+	for _, value := range values {
+		name, model, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("invalid model override %q (expected task=model)", value)
+		}
+		task, err := ParseTask(name)
+		if err != nil {
+			return nil, err
+		}
+		overrides[task] = strings.TrimSpace(model)
+	}
+	return overrides, nil
+}
+
+// ModelOverridesFromEnv reads SILVIA_MODEL_<TASK> variables, e.g.
+// SILVIA_MODEL_EXTRACTION or SILVIA_MODEL_CHAT_ANSWER
+func ModelOverridesFromEnv() map[Task]string {
+	overrides := make(map[Task]string)
+	for _, task := range Tasks {
+		key := "SILVIA_MODEL_" + strings.ToUpper(strings.ReplaceAll(string(task), "-", "_"))
+		if model := os.Getenv(key); model != "" {
+			overrides[task] = model
+		}
+	}
+	return overrides
+}
+
+// modelRegistry holds the task mapping shared by a client and its task views.
+// The file config is what /models persists; overrides come from flags and
+// environment variables and are never written back.
+type modelRegistry struct {
+	mu        sync.RWMutex
+	path      string
+	config    ModelConfig
+	overrides map[Task]string
+	global    string // Replaces every task's model (ProviderConfig.Model)
+}
+
+func newModelRegistry() *modelRegistry {
+	return &modelRegistry{
+		config:    DefaultModelConfig(),
+		overrides: make(map[Task]string),
+	}
+}
+
+// resolve returns the effective model settings for a task
+func (r *modelRegistry) resolve(task Task) TaskModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if task == "" {
+		task = TaskDefault
+	}
+	settings, ok := r.config.Tasks[task]
+	if !ok || settings.Model == "" {
+		settings = r.config.Tasks[TaskDefault]
+	}
+	if model, ok := r.overrides[task]; ok {
+		settings.Model = model
+	}
+	if r.global != "" {
+		settings.Model = r.global
+		settings.Fallbacks = nil
+	}
+	return settings
+}
+
+// LoadModelConfig reads the task mapping from path, keeping built-in defaults
+// for tasks the file does not mention. A missing file is not an error; the
+// path is remembered so SetTaskModel can save to it.
+func (c *Client) LoadModelConfig(path string) error {
+	r := c.models
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.path = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read model config: %w", err)
+	}
+
+	var loaded ModelConfig
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("failed to parse model config: %w", err)
+	}
+
+	config := DefaultModelConfig()
+	for task, settings := range loaded.Tasks {
+		if _, err := ParseTask(string(task)); err != nil {
+			return fmt.Errorf("model config: %w", err)
+		}
+		config.Tasks[task] = settings
+	}
+	r.config = config
+	return nil
+}
+
+// SetModelOverrides sets task models that take precedence over the config file
+func (c *Client) SetModelOverrides(overrides map[Task]string) {
+	r := c.models
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for task, model := range overrides {
+		r.overrides[task] = model
+	}
+}
+
+// SetTaskModel switches the model for a task and saves the mapping
+func (c *Client) SetTaskModel(task Task, model string) error {
+	r := c.models
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings := r.config.Tasks[task]
+	settings.Model = model
+	r.config.Tasks[task] = settings
+	delete(r.overrides, task) // An explicit switch wins over startup overrides
+
+	return r.save()
+}
+
+// ResetModelConfig restores the built-in model for a task, or for every task
+// if task is empty, and saves the mapping
+func (c *Client) ResetModelConfig(task Task) error {
+	r := c.models
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	defaults := DefaultModelConfig()
+	if task == "" {
+		r.config = defaults
+		r.overrides = make(map[Task]string)
+	} else {
+		r.config.Tasks[task] = defaults.Tasks[task]
+		delete(r.overrides, task)
+	}
+
+	return r.save()
+}
+
+// TaskModelInfo describes the effective model of a task
+type TaskModelInfo struct {
+	Task       Task
+	TaskModel  TaskModel
+	Overridden bool // Set by a flag or environment variable
+}
+
+// ModelMapping returns the effective model of every task
+func (c *Client) ModelMapping() []TaskModelInfo {
+	infos := make([]TaskModelInfo, 0, len(Tasks))
+	for _, task := range Tasks {
+		c.models.mu.RLock()
+		_, overridden := c.models.overrides[task]
+		overridden = overridden || c.models.global != ""
+		c.models.mu.RUnlock()
+		infos = append(infos, TaskModelInfo{
+			Task:       task,
+			TaskModel:  c.models.resolve(task),
+			Overridden: overridden,
+		})
+	}
+	return infos
+}
+
+// save writes the file config; the caller holds the lock
+func (r *modelRegistry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r.config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal model config: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write model config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/revrost/go-openrouter"
+
+	"silvia/internal/resilience"
+)
+
+// OpenRouterProvider sends requests to OpenRouter
+type OpenRouterProvider struct {
+	client *openrouter.Client
+}
+
+// NewOpenRouterProvider creates an OpenRouter provider
+func NewOpenRouterProvider(apiKey string) *OpenRouterProvider {
+	config := openrouter.DefaultConfig(apiKey)
+	config.HTTPClient = &http.Client{Transport: statusTransport{next: http.DefaultTransport}}
+	return &OpenRouterProvider{
+		client: openrouter.NewClientWithConfig(*config),
+	}
+}
+
+// statusTransport turns retryable HTTP statuses into resilience.HTTPError so
+// the Retry-After header survives; the openrouter client would drop it.
+type statusTransport struct {
+	next http.RoundTripper
+}
+
+func (t statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !resilience.RetryableStatus(resp.StatusCode) {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return nil, resilience.NewHTTPError(resp, strings.TrimSpace(string(body)))
+}
+
+func (p *OpenRouterProvider) Name() string {
+	return ProviderOpenRouter
+}
+
+// newOpenRouterRequest converts a request to the OpenRouter wire format
+func newOpenRouterRequest(request ChatRequest) openrouter.ChatCompletionRequest {
+	// Convert our messages to OpenRouter format
+	orMessages := make([]openrouter.ChatCompletionMessage, len(request.Messages))
+	for i, msg := range request.Messages {
+		orMsg := openrouter.ChatCompletionMessage{
+			Role:       msg.Role,
+			Content:    openrouter.Content{Text: msg.Content},
+			ToolCallID: msg.ToolCallID,
+		}
+
+		// Convert tool calls if present
+		if len(msg.ToolCalls) > 0 {
+			orMsg.ToolCalls = make([]openrouter.ToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				orMsg.ToolCalls[j] = openrouter.ToolCall{
+					ID:   tc.ID,
+					Type: openrouter.ToolType(tc.Type),
+					Function: openrouter.FunctionCall{
+						Name:      tc.Function.Name,
+						Arguments: tc.Function.Arguments,
+					},
+				}
+			}
+		}
+		orMessages[i] = orMsg
+	}
+
+	orRequest := openrouter.ChatCompletionRequest{
+		Model:     request.Model,
+		Messages:  orMessages,
+		MaxTokens: request.MaxTokens,
+		Usage:     &openrouter.IncludeUsage{Include: true}, // Report cost for the usage ledger
+	}
+	if request.Temperature != nil {
+		orRequest.Temperature = float32(*request.Temperature)
+	}
+
+	// Convert our tools to OpenRouter format
+	if len(request.Tools) > 0 {
+		orRequest.Tools = make([]openrouter.Tool, len(request.Tools))
+		for i, tool := range request.Tools {
+			orRequest.Tools[i] = openrouter.Tool{
+				Type: openrouter.ToolTypeFunction,
+				Function: &openrouter.FunctionDefinition{
+					Name:        tool.Function.Name,
+					Description: tool.Function.Description,
+					Parameters:  tool.Function.Parameters,
+				},
+			}
+		}
+		orRequest.ToolChoice = request.ToolChoice
+	}
+
+	if format := request.ResponseFormat; format != nil {
+		switch format.Type {
+		case ResponseFormatJSONSchema:
+			orRequest.ResponseFormat = &openrouter.ChatCompletionResponseFormat{
+				Type: openrouter.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openrouter.ChatCompletionResponseFormatJSONSchema{
+					Name:   format.Name,
+					Schema: format.Schema,
+					Strict: false, // Some models don't support strict mode
+				},
+			}
+		case ResponseFormatJSONObject:
+			orRequest.ResponseFormat = &openrouter.ChatCompletionResponseFormat{
+				Type: openrouter.ChatCompletionResponseFormatTypeJSONObject,
+			}
+		}
+	}
+
+	return orRequest
+}
+
+func (p *OpenRouterProvider) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+	response, err := p.client.CreateChatCompletion(ctx, newOpenRouterRequest(request))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no completion choices returned")
+	}
+
+	choice := response.Choices[0]
+	result := &ChatResponse{
+		Model:   response.Model,
+		Content: choice.Message.Content.Text,
+	}
+
+	if len(choice.Message.ToolCalls) > 0 {
+		result.ToolCalls = make([]ToolCall, len(choice.Message.ToolCalls))
+		for i, tc := range choice.Message.ToolCalls {
+			result.ToolCalls[i] = ToolCall{
+				ID:   tc.ID,
+				Type: string(tc.Type),
+				Function: FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			}
+		}
+	}
+
+	if response.Usage != nil {
+		result.Usage = Usage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+			Cost:             response.Usage.Cost,
+		}
+	}
+
+	return result, nil
+}
+
+// ChatStream performs a streaming chat completion, passing content to
+// onDelta as it arrives. Tool calls are not streamed.
+func (p *OpenRouterProvider) ChatStream(ctx context.Context, request ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	stream, err := p.client.CreateChatCompletionStream(ctx, newOpenRouterRequest(request))
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	result := &ChatResponse{Model: request.Model}
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+		if chunk.Usage != nil {
+			result.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+				Cost:             chunk.Usage.Cost,
+			}
+		}
+	}
+
+	// The stream ends quietly on cancellation, so report it here
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+func (p *OpenRouterProvider) ListModels(ctx context.Context) ([]string, error) {
+	models, err := p.client.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var modelNames []string
+	for _, model := range models {
+		modelNames = append(modelNames, model.ID)
+	}
+
+	return modelNames, nil
+}
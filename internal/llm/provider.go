@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Provider is a chat completion backend. Client builds every kind of request
+// (plain, system+user, structured output, JSON mode, function calling) on top
+// of a single Chat call, so a provider only has to speak the chat API.
+type Provider interface {
+	// Name identifies the provider, e.g. "openrouter" or "openai"
+	Name() string
+
+	// Chat performs a single chat completion
+	Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error)
+
+	// ListModels returns the model IDs the backend serves
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// StreamingProvider is implemented by providers that can stream content as
+// it is generated. ChatStream calls onDelta with each piece of content and
+// returns the complete response, including usage, when the stream ends.
+type StreamingProvider interface {
+	Provider
+	ChatStream(ctx context.Context, request ChatRequest, onDelta func(delta string)) (*ChatResponse, error)
+}
+
+// ChatRequest is a provider-independent chat completion request
+type ChatRequest struct {
+	Model          string
+	Messages       []Message
+	Tools          []Tool
+	ToolChoice     string
+	ResponseFormat *ResponseFormat
+	Temperature    *float64 // Provider default if nil
+	MaxTokens      int      // Provider default if 0
+}
+
+// Response format types
+const (
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat requests JSON output, optionally constrained by a schema
+type ResponseFormat struct {
+	Type   string         // ResponseFormatJSONObject or ResponseFormatJSONSchema
+	Name   string         // Schema name, for json_schema
+	Schema json.Marshaler // JSON Schema, for json_schema
+}
+
+// ChatResponse is a provider-independent chat completion response
+type ChatResponse struct {
+	Model     string     `json:"model"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Usage     Usage      `json:"usage"`
+}
+
+// Usage reports the tokens (and cost, where the provider reports it) of a request
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Provider names accepted in ProviderConfig
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderOpenAI     = "openai" // Any OpenAI-compatible endpoint (llama.cpp, Ollama, vLLM, ...)
+	ProviderFake       = "fake"
+)
+
+// ProviderConfig selects and configures a provider
+type ProviderConfig struct {
+	Provider string // openrouter (default), openai, or fake
+	APIKey   string
+	BaseURL  string // Required for openai, e.g. http://localhost:11434/v1
+	Model    string // If set, used for every request instead of the caller's model
+}
+
+// ConfigFromEnv reads provider configuration from the environment:
+// SILVIA_LLM_PROVIDER, SILVIA_LLM_BASE_URL, SILVIA_LLM_API_KEY, SILVIA_LLM_MODEL,
+// falling back to OPENROUTER_API_KEY for the OpenRouter key.
+func ConfigFromEnv() ProviderConfig {
+	config := ProviderConfig{
+		Provider: os.Getenv("SILVIA_LLM_PROVIDER"),
+		APIKey:   os.Getenv("SILVIA_LLM_API_KEY"),
+		BaseURL:  os.Getenv("SILVIA_LLM_BASE_URL"),
+		Model:    os.Getenv("SILVIA_LLM_MODEL"),
+	}
+	if config.Provider == "" {
+		config.Provider = ProviderOpenRouter
+	}
+	if config.APIKey == "" && config.Provider == ProviderOpenRouter {
+		config.APIKey = os.Getenv("OPENROUTER_API_KEY")
+	}
+	return config
+}
+
+// NewProvider creates the provider described by config
+func NewProvider(config ProviderConfig) (Provider, error) {
+	switch config.Provider {
+	case "", ProviderOpenRouter:
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("OpenRouter requires an API key (set OPENROUTER_API_KEY)")
+		}
+		return NewOpenRouterProvider(config.APIKey), nil
+	case ProviderOpenAI:
+		if config.BaseURL == "" {
+			return nil, fmt.Errorf("OpenAI-compatible provider requires a base URL")
+		}
+		return NewOpenAIProvider(config.BaseURL, config.APIKey), nil
+	case ProviderFake:
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", config.Provider)
+	}
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxRepairAttempts bounds how often a malformed structured response is sent
+// back to the model for correction
+const maxRepairAttempts = 1
+
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// repairJSON fixes the common ways models wrap or break JSON: markdown code
+// fences, prose around the object, and trailing commas. It does not validate
+// the result.
+func repairJSON(content string) string {
+	s := strings.TrimSpace(content)
+
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[i+1:] // Drop the language tag line
+		}
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	}
+
+	start := strings.IndexAny(s, "{[")
+	if start < 0 {
+		return s
+	}
+	closer := "}"
+	if s[start] == '[' {
+		closer = "]"
+	}
+	if end := strings.LastIndex(s, closer); end > start {
+		s = s[start : end+1]
+	}
+
+	return trailingComma.ReplaceAllString(s, "$1")
+}
@@ -0,0 +1,419 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operation identifies what an LLM call was made for, e.g. ingesting a URL
+// or refining an entity. It travels in the context so calls deep inside the
+// extractor are still attributed to the ingest that started them.
+type Operation struct {
+	Kind   string `json:"kind"`             // ingest, refine, merge, chat, recheck, ...
+	Target string `json:"target,omitempty"` // URL, entity ID, or chat message
+	Run    string `json:"run,omitempty"`    // Identifies one run of the operation
+
+	spend *runSpend // What the run has spent, for per-operation limits
+}
+
+// runSpend is what one run of an operation has spent. It travels in the
+// run's context, so it is dropped with the run.
+type runSpend struct {
+	mu   sync.Mutex
+	cost float64
+}
+
+func (r *runSpend) add(cost float64) {
+	r.mu.Lock()
+	r.cost += cost
+	r.mu.Unlock()
+}
+
+func (r *runSpend) total() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cost
+}
+
+// Operation kinds used across silvia
+const (
+	OpIngest  = "ingest"
+	OpRefine  = "refine"
+	OpMerge   = "merge"
+	OpChat    = "chat"
+	OpRecheck = "recheck"
+	OpOther   = "other"
+)
+
+type operationKey struct{}
+
+// WithOperation tags ctx so LLM calls made with it are attributed to the
+// operation. An existing tag is kept, so nested operations count toward the
+// outermost one. Each tag starts a new run, which per-operation limits apply
+// to: ingesting the same URL again starts from nothing.
+func WithOperation(ctx context.Context, kind, target string) context.Context {
+	if _, ok := ctx.Value(operationKey{}).(Operation); ok {
+		return ctx
+	}
+	if runes := []rune(target); len(runes) > 120 {
+		target = string(runes[:120]) + "..."
+	}
+	return context.WithValue(ctx, operationKey{}, Operation{Kind: kind, Target: target, Run: newRunID(), spend: &runSpend{}})
+}
+
+// newRunID returns a random ID for one run of an operation
+func newRunID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// OperationFromContext returns the operation ctx is tagged with
+func OperationFromContext(ctx context.Context) Operation {
+	if op, ok := ctx.Value(operationKey{}).(Operation); ok {
+		return op
+	}
+	return Operation{Kind: OpOther}
+}
+
+// String returns "kind target", or just the kind without a target
+func (o Operation) String() string {
+	if o.Target == "" {
+		return o.Kind
+	}
+	return o.Kind + " " + o.Target
+}
+
+// UsageEntry is one LLM call in the ledger
+type UsageEntry struct {
+	Time             time.Time `json:"time"`
+	Operation        Operation `json:"operation"`
+	Task             Task      `json:"task"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost"` // USD as reported by the provider; 0 if unknown
+	Cached           bool      `json:"cached,omitempty"`
+}
+
+// Budget limits spending in USD. Zero means no limit.
+type Budget struct {
+	DailyLimit      float64            `json:"daily_limit,omitempty"`      // All calls in a calendar day
+	OperationLimits map[string]float64 `json:"operation_limits,omitempty"` // Per run of an operation, by kind
+}
+
+// ErrBudgetExceeded is returned instead of calling the provider once a budget is used up
+var ErrBudgetExceeded = errors.New("LLM budget exceeded")
+
+// UsageLedger records every LLM call to an append-only JSONL file and
+// enforces budgets. The file is shared by every silvia process using the
+// data directory, so today's spend is read from it rather than counted in
+// memory.
+type UsageLedger struct {
+	mu         sync.Mutex
+	path       string
+	budgetPath string
+	budget     Budget
+	day        string  // Date of dailyCost, YYYY-MM-DD local time
+	dailyCost  float64 // Spend so far today, by every process
+	offset     int64   // How much of the file dailyCost has read
+}
+
+// NewUsageLedger opens the ledger (usage.jsonl) and budget (budget.json) in dir
+func NewUsageLedger(dir string) (*UsageLedger, error) {
+	l := &UsageLedger{
+		path:       filepath.Join(dir, "usage.jsonl"),
+		budgetPath: filepath.Join(dir, "budget.json"),
+		day:        time.Now().Format("2006-01-02"),
+	}
+
+	if data, err := os.ReadFile(l.budgetPath); err == nil {
+		if err := json.Unmarshal(data, &l.budget); err != nil {
+			return nil, fmt.Errorf("failed to parse budget: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read budget: %w", err)
+	}
+
+	if err := l.refresh(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Check returns ErrBudgetExceeded if op may not make another call
+func (l *UsageLedger) Check(op Operation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit := l.budget.DailyLimit; limit > 0 {
+		if err := l.refresh(); err != nil {
+			return err
+		}
+		if l.dailyCost >= limit {
+			return fmt.Errorf("%w: spent $%.4f of $%.2f daily limit", ErrBudgetExceeded, l.dailyCost, limit)
+		}
+	}
+	if limit := l.budget.OperationLimits[op.Kind]; limit > 0 && op.spend != nil {
+		if spent := op.spend.total(); spent >= limit {
+			return fmt.Errorf("%w: %s spent $%.4f of $%.2f %s limit", ErrBudgetExceeded, op, spent, limit, op.Kind)
+		}
+	}
+	return nil
+}
+
+// Record appends an entry to the ledger
+func (l *UsageLedger) Record(entry UsageEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry.Operation.spend != nil {
+		entry.Operation.spend.add(entry.Cost)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return nil
+}
+
+// refresh adds today's entries written since the last refresh, by this
+// process or another, to dailyCost, starting over at midnight or if the
+// file was replaced. The caller holds the lock.
+func (l *UsageLedger) refresh() error {
+	if today := time.Now().Format("2006-01-02"); today != l.day {
+		l.day = today
+		l.dailyCost, l.offset = 0, 0
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			l.dailyCost, l.offset = 0, 0
+			return nil
+		}
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	if info.Size() < l.offset {
+		l.dailyCost, l.offset = 0, 0
+	}
+	if info.Size() == l.offset {
+		return nil
+	}
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	// Only whole lines are read; one being written is read next time
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		l.offset += int64(len(line))
+		var entry UsageEntry
+		if json.Unmarshal(line, &entry) == nil && entry.Time.Local().Format("2006-01-02") == l.day {
+			l.dailyCost += entry.Cost
+		}
+	}
+	return nil
+}
+
+// Entries returns ledger entries at or after since (all entries if zero)
+func (l *UsageLedger) Entries(since time.Time) ([]UsageEntry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []UsageEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	entries := []UsageEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry UsageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Skip a line torn by a crash mid-write
+		}
+		if !since.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// Budget returns the current budget and today's spend
+func (l *UsageLedger) Budget() (Budget, float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.refresh(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return l.budget, l.dailyCost
+}
+
+// SetBudget replaces the budget and saves it
+func (l *UsageLedger) SetBudget(budget Budget) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := json.MarshalIndent(budget, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal budget: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.budgetPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(l.budgetPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write budget: %w", err)
+	}
+	l.budget = budget
+	return nil
+}
+
+// Usage grouping keys accepted by SummarizeUsage
+const (
+	UsageByOperation = "operation" // Each operation instance, e.g. "ingest https://..."
+	UsageByKind      = "kind"      // Operation kind, e.g. "ingest"
+	UsageByModel     = "model"
+	UsageBySource    = "source" // Source URL of ingest and recheck operations
+)
+
+// UsageGroup aggregates ledger entries sharing a key
+type UsageGroup struct {
+	Key              string  `json:"key"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// UsageSummary is the aggregated usage of a set of entries
+type UsageSummary struct {
+	Since  time.Time    `json:"since,omitempty"`
+	By     string       `json:"by"`
+	Total  UsageGroup   `json:"total"`
+	Groups []UsageGroup `json:"groups"`
+}
+
+// SummarizeUsage groups entries by operation, kind, model, or source,
+// most expensive first
+func SummarizeUsage(entries []UsageEntry, by string) (*UsageSummary, error) {
+	var keyOf func(UsageEntry) string
+	switch by {
+	case "", UsageByKind:
+		by = UsageByKind
+		keyOf = func(e UsageEntry) string { return e.Operation.Kind }
+	case UsageByOperation:
+		keyOf = func(e UsageEntry) string { return e.Operation.String() }
+	case UsageByModel:
+		keyOf = func(e UsageEntry) string { return e.Model }
+	case UsageBySource:
+		keyOf = func(e UsageEntry) string {
+			if e.Operation.Kind == OpIngest || e.Operation.Kind == OpRecheck {
+				return e.Operation.Target
+			}
+			return "(not a source)"
+		}
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (use operation, kind, model, or source)", by)
+	}
+
+	summary := &UsageSummary{By: by, Total: UsageGroup{Key: "total"}, Groups: []UsageGroup{}}
+	index := make(map[string]int)
+	for _, entry := range entries {
+		key := keyOf(entry)
+		i, ok := index[key]
+		if !ok {
+			i = len(summary.Groups)
+			index[key] = i
+			summary.Groups = append(summary.Groups, UsageGroup{Key: key})
+		}
+		summary.Groups[i].add(entry)
+		summary.Total.add(entry)
+	}
+
+	sort.SliceStable(summary.Groups, func(i, j int) bool {
+		if summary.Groups[i].Cost != summary.Groups[j].Cost {
+			return summary.Groups[i].Cost > summary.Groups[j].Cost
+		}
+		return summary.Groups[i].TotalTokens > summary.Groups[j].TotalTokens
+	})
+	return summary, nil
+}
+
+func (g *UsageGroup) add(entry UsageEntry) {
+	g.Calls++
+	g.PromptTokens += entry.PromptTokens
+	g.CompletionTokens += entry.CompletionTokens
+	g.TotalTokens += entry.TotalTokens
+	g.Cost += entry.Cost
+}
+
+// ParseSince parses a --since value: a duration such as 24h or 7d, a date
+// (YYYY-MM-DD), or "today"
+func ParseSince(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	now := time.Now()
+	switch {
+	case value == "":
+		return time.Time{}, nil
+	case value == "today":
+		year, month, day := now.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location()), nil
+	case strings.HasSuffix(value, "d"):
+		var days int
+		if _, err := fmt.Sscanf(value, "%dd", &days); err == nil && days > 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (use e.g. 24h, 7d, today, or 2006-01-02)", value)
+}
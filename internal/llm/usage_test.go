@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDailyLimitCountsEveryProcess(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewUsageLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.SetBudget(Budget{DailyLimit: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second process opened before any spend sees what the first records
+	second, err := NewUsageLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := OperationFromContext(WithOperation(context.Background(), OpIngest, "https://example.com"))
+	if err := second.Check(op); err != nil {
+		t.Fatalf("Check before any spend: %v", err)
+	}
+
+	yesterday := UsageEntry{Time: time.Now().AddDate(0, 0, -1), Operation: op, Cost: 5}
+	today := UsageEntry{Time: time.Now(), Operation: op, Cost: 0.6}
+	for _, entry := range []UsageEntry{yesterday, today, today} {
+		if err := first.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := second.Check(op); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Check after $1.20 spent elsewhere today = %v, want the budget exceeded", err)
+	}
+	if _, spent := second.Budget(); spent < 1.19 || spent > 1.21 {
+		t.Errorf("today's spend = %.2f, want 1.20", spent)
+	}
+}
+
+func TestOperationLimitCoversOneRun(t *testing.T) {
+	ledger, err := NewUsageLedger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.SetBudget(Budget{OperationLimits: map[string]float64{OpIngest: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	run := OperationFromContext(WithOperation(context.Background(), OpIngest, "https://example.com"))
+	if err := ledger.Record(UsageEntry{Time: time.Now(), Operation: run, Cost: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Check(run); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Check for a run at its limit = %v, want the budget exceeded", err)
+	}
+
+	// Ingesting the same URL again is a new run, starting from nothing
+	again := OperationFromContext(WithOperation(context.Background(), OpIngest, "https://example.com"))
+	if err := ledger.Check(again); err != nil {
+		t.Errorf("Check for a new run = %v", err)
+	}
+
+	// Calls outside any operation have no per-run limit
+	if err := ledger.Check(OperationFromContext(context.Background())); err != nil {
+		t.Errorf("Check outside an operation = %v", err)
+	}
+}
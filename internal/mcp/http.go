@@ -0,0 +1,323 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// maxMessageSize limits the body of a POST to the MCP endpoint
+const maxMessageSize = 4 << 20
+
+// streamKeepAlive is how often an idle notification stream gets a ping
+const streamKeepAlive = 30 * time.Second
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcInternalError  = -32603
+)
+
+// HTTPTransport serves MCP over streamable HTTP so that it can be mounted
+// on the API server. Each POST carries one JSON-RPC message, and a request
+// is answered in the response body. A GET opens a server-sent event stream
+// of the server's notifications, such as changes to the resource list.
+//
+// Every client shares one MCP server, so requests get IDs unique across
+// clients while they are handled, and the client's own ID, which may also
+// be a string, is restored in the response.
+type HTTPTransport struct {
+	mu        sync.Mutex
+	onMessage func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	onClose   func()
+	onError   func(error)
+	lastID    transport.RequestId
+	pending   map[transport.RequestId]chan *transport.BaseJsonRpcMessage // Requests awaiting a response
+	streams   map[chan []byte]struct{}                                   // Open notification streams
+	closed    bool
+}
+
+// NewHTTPTransport creates a transport to serve as an http.Handler
+func NewHTTPTransport() *HTTPTransport {
+	return &HTTPTransport{
+		pending: make(map[transport.RequestId]chan *transport.BaseJsonRpcMessage),
+		streams: make(map[chan []byte]struct{}),
+	}
+}
+
+// Start implements transport.Transport. Requests arrive through ServeHTTP.
+func (t *HTTPTransport) Start(ctx context.Context) error {
+	return nil
+}
+
+// Send delivers a response to the request waiting for it, and anything
+// else to every open notification stream
+func (t *HTTPTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	var id transport.RequestId
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		id = message.JsonRpcResponse.Id
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		id = message.JsonRpcError.Id
+	default:
+		return t.broadcast(message)
+	}
+
+	t.mu.Lock()
+	reply, ok := t.pending[id]
+	delete(t.pending, id)
+	t.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no request waiting for response %d", id)
+	}
+	reply <- message
+	return nil
+}
+
+// broadcast sends a message to every open notification stream, skipping
+// streams too far behind to take it
+func (t *HTTPTransport) broadcast(message *transport.BaseJsonRpcMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for stream := range t.streams {
+		select {
+		case stream <- data:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close ends every notification stream and refuses further messages
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	for stream := range t.streams {
+		close(stream)
+		delete(t.streams, stream)
+	}
+	onClose := t.onClose
+	t.mu.Unlock()
+
+	if onClose != nil {
+		onClose()
+	}
+	return nil
+}
+
+// SetCloseHandler implements transport.Transport
+func (t *HTTPTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onClose = handler
+}
+
+// SetErrorHandler implements transport.Transport
+func (t *HTTPTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = handler
+}
+
+// SetMessageHandler implements transport.Transport
+func (t *HTTPTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMessage = handler
+}
+
+// ServeHTTP handles POSTed messages and GET notification streams
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleStream(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handler returns the message handler, or nil once the transport is closed
+func (t *HTTPTransport) handler() func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	return t.onMessage
+}
+
+// handlePost passes one message to the server. Requests wait for their
+// response; notifications and responses are accepted straight away.
+func (t *HTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		writeRPCError(w, http.StatusBadRequest, nil, rpcParseError, "Failed to read request body")
+		return
+	}
+
+	var message struct {
+		Jsonrpc string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		writeRPCError(w, http.StatusBadRequest, nil, rpcParseError, "Invalid JSON-RPC message (batches are not supported)")
+		return
+	}
+	if message.Jsonrpc != "2.0" {
+		writeRPCError(w, http.StatusBadRequest, message.ID, rpcInvalidRequest, "jsonrpc must be 2.0")
+		return
+	}
+
+	handle := t.handler()
+	if handle == nil {
+		http.Error(w, "MCP server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// A response to a server request; the server sends none, so nothing
+	// waits for it
+	if message.Method == "" {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if len(message.ID) == 0 || string(message.ID) == "null" {
+		handle(r.Context(), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: message.Jsonrpc,
+			Method:  message.Method,
+			Params:  message.Params,
+		}))
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	id, reply := t.await()
+	handle(r.Context(), transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Id:      id,
+		Jsonrpc: message.Jsonrpc,
+		Method:  message.Method,
+		Params:  message.Params,
+	}))
+
+	select {
+	case response := <-reply:
+		writeRPCResponse(w, response, message.ID)
+	case <-r.Context().Done():
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}
+}
+
+// await reserves a request ID and the channel its response arrives on
+func (t *HTTPTransport) await() (transport.RequestId, chan *transport.BaseJsonRpcMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastID++
+	reply := make(chan *transport.BaseJsonRpcMessage, 1)
+	t.pending[t.lastID] = reply
+	return t.lastID, reply
+}
+
+// handleStream sends the server's notifications as server-sent events
+// until the client goes away or the transport closes
+func (t *HTTPTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	stream := make(chan []byte, 16)
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		http.Error(w, "MCP server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	t.streams[stream] = struct{}{}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.streams, stream)
+		t.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case data, ok := <-stream:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeRPCResponse writes a response with the ID the client sent
+func writeRPCResponse(w http.ResponseWriter, message *transport.BaseJsonRpcMessage, id json.RawMessage) {
+	data, err := json.Marshal(message)
+	if err == nil {
+		var fields map[string]json.RawMessage
+		if err = json.Unmarshal(data, &fields); err == nil {
+			fields["id"] = id
+			data, err = json.Marshal(fields)
+		}
+	}
+	if err != nil {
+		writeRPCError(w, http.StatusInternalServerError, id, rpcInternalError, "Failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// writeRPCError writes a JSON-RPC error for a message the server could not
+// take
+func writeRPCError(w http.ResponseWriter, status int, id json.RawMessage, code int, message string) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   map[string]any{"code": code, "message": message},
+	})
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	mcp "github.com/metoro-io/mcp-golang"
+	"silvia/internal/graph"
+	"silvia/internal/operations"
+)
+
+// Prompt argument types. The MCP library names prompt arguments after the
+// Go fields and only allows strings.
+
+// profilePersonArgs are the arguments of the profile_person prompt
+type profilePersonArgs struct {
+	Person string `jsonschema:"required,description=Entity ID or name of the person"`
+}
+
+// organizationNetworkArgs are the arguments of the organization_network prompt
+type organizationNetworkArgs struct {
+	Organization string `jsonschema:"required,description=Entity ID or name of the organization"`
+}
+
+// findConnectionsArgs are the arguments of the find_connections prompt
+type findConnectionsArgs struct {
+	From string `jsonschema:"required,description=Entity ID or name to start from"`
+	To   string `jsonschema:"required,description=Entity ID or name to reach"`
+}
+
+// registerPrompts registers prompts for common investigative workflows.
+// Each prompt embeds what the graph already knows, so the assistant starts
+// from the stored entities and uses the tools to fill gaps.
+func registerPrompts(server *mcp.Server, ops *operations.Operations) error {
+	err := server.RegisterPrompt(
+		"profile_person",
+		"Build a sourced profile of a person from the knowledge graph",
+		func(args profilePersonArgs) (*mcp.PromptResponse, error) {
+			entity, known := resolveEntity(ops, args.Person, graph.EntityPerson)
+
+			var prompt strings.Builder
+			fmt.Fprintf(&prompt, "Write a profile of %s using the Silvia knowledge graph.\n\n", args.Person)
+			prompt.WriteString("Cover who they are, their roles and affiliations over time, the people and organizations they are connected to, ")
+			prompt.WriteString("their notable works and events, and any open questions. Cite the source of every claim and say where the graph has no source.\n\n")
+			if known {
+				writeEntityContext(&prompt, ops, entity)
+				prompt.WriteString("Read the related entities with read_entity or their silvia://entity/ resources before writing. ")
+			} else {
+				prompt.WriteString("No entity matches yet. Search with search_entities; if nothing is found, say so rather than guessing. ")
+			}
+			prompt.WriteString("Use suggest_related to find entities that may be connected but are not yet linked, and mention them separately.")
+
+			return userPrompt("Profile of "+args.Person, prompt.String()), nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = server.RegisterPrompt(
+		"organization_network",
+		"Summarize an organization's network of people, organizations and events",
+		func(args organizationNetworkArgs) (*mcp.PromptResponse, error) {
+			entity, known := resolveEntity(ops, args.Organization, graph.EntityOrganization)
+
+			var prompt strings.Builder
+			fmt.Fprintf(&prompt, "Summarize the network around %s using the Silvia knowledge graph.\n\n", args.Organization)
+			prompt.WriteString("Group its connections by relationship type (leadership, members, funders, affiliates, works, events), ")
+			prompt.WriteString("note who links it to other organizations, and point out clusters and gaps. Cite sources for every claim.\n\n")
+			if known {
+				writeEntityContext(&prompt, ops, entity)
+				prompt.WriteString("Follow the strongest connections one step further with get_related_entities to find second-degree ties. ")
+			} else {
+				prompt.WriteString("No entity matches yet. Search with search_entities and get_entities_by_type with type organization. ")
+			}
+			prompt.WriteString("End with a short list of the most central people and organizations.")
+
+			return userPrompt("Network of "+args.Organization, prompt.String()), nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = server.RegisterPrompt(
+		"find_connections",
+		"Explain how two entities are connected",
+		func(args findConnectionsArgs) (*mcp.PromptResponse, error) {
+			from, fromKnown := resolveEntity(ops, args.From, "")
+			to, toKnown := resolveEntity(ops, args.To, "")
+
+			var prompt strings.Builder
+			fmt.Fprintf(&prompt, "Explain how %s and %s are connected, using the Silvia knowledge graph.\n\n", args.From, args.To)
+			switch {
+			case !fromKnown || !toKnown:
+				prompt.WriteString("At least one of them is not in the graph yet. Search with search_entities before drawing any conclusion.\n")
+			default:
+				path, err := ops.Search.FindPath(from.Metadata.ID, to.Metadata.ID, 0)
+				if err != nil {
+					fmt.Fprintf(&prompt, "The graph has no chain of relationships between %s and %s within six steps. ", from.Metadata.ID, to.Metadata.ID)
+					prompt.WriteString("Look for indirect ties through shared sources, tags or similar entities (suggest_related), and say clearly if there is no evidence of a connection.\n")
+				} else {
+					prompt.WriteString("The shortest chain of relationships in the graph is:\n")
+					for _, step := range path.Steps {
+						if step.Reverse {
+							fmt.Fprintf(&prompt, "- %s ← %s ← %s\n", step.From, step.Type, step.To)
+						} else {
+							fmt.Fprintf(&prompt, "- %s → %s → %s\n", step.From, step.Type, step.To)
+						}
+					}
+					prompt.WriteString("\nRead each entity on the chain to confirm and cite every link, and check with find_path whether other routes exist.\n")
+				}
+			}
+			prompt.WriteString("Distinguish documented relationships from inference.")
+
+			return userPrompt(fmt.Sprintf("Connections between %s and %s", args.From, args.To), prompt.String()), nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// resolveEntity finds the entity a prompt argument names: an exact ID, or
+// the best search match, preferring entityType when given
+func resolveEntity(ops *operations.Operations, name string, entityType graph.EntityType) (*graph.Entity, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, false
+	}
+	if entity, err := ops.Entity.ReadEntity(name); err == nil {
+		return entity, true
+	}
+
+	result, err := ops.Search.SearchEntities(name)
+	if err != nil || len(result.Results) == 0 {
+		return nil, false
+	}
+	for _, match := range result.Results {
+		if entityType == "" || match.Entity.Metadata.Type == entityType {
+			return match.Entity, true
+		}
+	}
+	return result.Results[0].Entity, true
+}
+
+// writeEntityContext adds an entity's stored markdown and its relationships
+// to a prompt
+func writeEntityContext(prompt *strings.Builder, ops *operations.Operations, entity *graph.Entity) {
+	fmt.Fprintf(prompt, "The graph's entry for it (%s):\n\n", entityURI(entity.Metadata.ID))
+	prompt.WriteString(graph.FormatEntityMarkdown(entity))
+	prompt.WriteString("\n\n")
+
+	related, err := ops.Search.GetRelatedEntities(entity.Metadata.ID)
+	if err != nil {
+		return
+	}
+	writeRelated := func(heading string, byType map[string][]*graph.Entity) {
+		if len(byType) == 0 {
+			return
+		}
+		prompt.WriteString(heading + ":\n")
+		for _, relType := range slices.Sorted(maps.Keys(byType)) {
+			entities := byType[relType]
+			ids := make([]string, len(entities))
+			for i, e := range entities {
+				ids[i] = e.Metadata.ID
+			}
+			fmt.Fprintf(prompt, "- %s: %s\n", relType, strings.Join(ids, ", "))
+		}
+		prompt.WriteString("\n")
+	}
+	writeRelated("Outgoing relationships", related.OutgoingByType)
+	writeRelated("Incoming relationships", related.IncomingByType)
+}
+
+// userPrompt wraps text as a single user message
+func userPrompt(description, text string) *mcp.PromptResponse {
+	return mcp.NewPromptResponse(description, mcp.NewPromptMessage(mcp.NewTextContent(text), mcp.RoleUser))
+}
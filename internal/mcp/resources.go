@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	mcp "github.com/metoro-io/mcp-golang"
+	"silvia/internal/graph"
+	"silvia/internal/operations"
+)
+
+// Resource URIs. Entity URIs end in the entity ID; source URIs in the
+// escaped source URL.
+const (
+	entityURIPrefix = "silvia://entity/"
+	sourceURIPrefix = "silvia://source/"
+)
+
+// resourceWatchInterval is how often the graph and source archives are
+// checked for changes
+const resourceWatchInterval = 2 * time.Second
+
+// entityURI returns the resource URI of an entity
+func entityURI(id string) string {
+	return entityURIPrefix + id
+}
+
+// sourceURI returns the resource URI of an archived source
+func sourceURI(sourceURL string) string {
+	return sourceURIPrefix + url.PathEscape(sourceURL)
+}
+
+// resourceSet exposes every entity and archived source as an MCP resource
+// and keeps the list in step with the files on disk. The MCP library can
+// only serve resources registered one URI at a time, so each file change
+// registers or deregisters its resource, which tells the client that the
+// resource list changed.
+type resourceSet struct {
+	server  *mcp.Server
+	ops     *operations.Operations
+	dataDir string
+
+	mu       sync.Mutex
+	files    map[string]time.Time // Watched file path to modification time
+	versions map[string]string    // Registered resource URI to the version registered
+}
+
+// resource is a resource that can be registered, with a version that
+// changes whenever its content does
+type resource struct {
+	version  string
+	register func() error
+}
+
+// registerResources registers the current entities and sources as
+// resources. Call Watch to keep them current.
+func registerResources(server *mcp.Server, ops *operations.Operations, dataDir string) (*resourceSet, error) {
+	set := &resourceSet{
+		server:   server,
+		ops:      ops,
+		dataDir:  dataDir,
+		files:    make(map[string]time.Time),
+		versions: make(map[string]string),
+	}
+	if err := set.sync(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Watch rescans the data directory until ctx is done, updating resources
+// for entities and sources that were added, edited or removed, whether by
+// silvia or by hand
+func (r *resourceSet) Watch(ctx context.Context) {
+	ticker := time.NewTicker(resourceWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.sync(); err != nil {
+				log.Printf("Failed to refresh resources: %v", err)
+			}
+		}
+	}
+}
+
+// sync compares the markdown files under graph/ and sources/ with the last
+// scan and re-registers the resources whose files changed
+func (r *resourceSet) sync() error {
+	files := make(map[string]time.Time)
+	for _, dir := range []string{"graph", "sources"} {
+		root := filepath.Join(r.dataDir, dir)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".md") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil // Removed while walking
+			}
+			files[path] = info.ModTime()
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := len(files) != len(r.files)
+	for path, modTime := range files {
+		if previous, ok := r.files[path]; !ok || !previous.Equal(modTime) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+	r.files = files
+
+	current, err := r.resources()
+	if err != nil {
+		return err
+	}
+	for uri := range r.versions {
+		if _, ok := current[uri]; !ok {
+			r.server.DeregisterResource(uri)
+			delete(r.versions, uri)
+		}
+	}
+	for uri, res := range current {
+		if version, ok := r.versions[uri]; ok && version == res.version {
+			continue
+		}
+		if err := res.register(); err != nil {
+			return fmt.Errorf("failed to register %s: %w", uri, err)
+		}
+		r.versions[uri] = res.version
+	}
+	return nil
+}
+
+// resources returns the current resources by URI. Callers hold r.mu.
+func (r *resourceSet) resources() (map[string]resource, error) {
+	current := make(map[string]resource)
+
+	entities, err := r.ops.Search.ListEntities("")
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range entities {
+		id := entity.Metadata.ID
+		uri := entityURI(id)
+		description := fmt.Sprintf("%s entity %s", entity.Metadata.Type, id)
+		path := filepath.Join(r.dataDir, "graph", id+".md")
+		current[uri] = resource{
+			version: r.files[path].String(),
+			register: func() error {
+				return r.server.RegisterResource(uri, entity.Title, description, "text/markdown", func() (*mcp.ResourceResponse, error) {
+					return r.readEntity(uri, id)
+				})
+			},
+		}
+	}
+
+	archived, err := r.ops.Source.ListArchivedSources()
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range archived {
+		sourceURL := source.URL
+		uri := sourceURI(sourceURL)
+		name := source.Title
+		if name == "" {
+			name = sourceURL
+		}
+		description := fmt.Sprintf("Capture of %s from %s", sourceURL, source.FetchedAt.Format(time.DateOnly))
+		current[uri] = resource{
+			version: source.ArchivePath + " " + r.files[source.ArchivePath].String(),
+			register: func() error {
+				return r.server.RegisterResource(uri, name, description, "text/markdown", func() (*mcp.ResourceResponse, error) {
+					return r.readSource(uri, sourceURL)
+				})
+			},
+		}
+	}
+
+	return current, nil
+}
+
+// readEntity returns an entity as it is stored: frontmatter and markdown
+func (r *resourceSet) readEntity(uri, id string) (*mcp.ResourceResponse, error) {
+	entity, err := r.ops.Entity.ReadEntity(id)
+	if err != nil {
+		return nil, err
+	}
+	text := graph.FormatEntityMarkdown(entity)
+	return mcp.NewResourceResponse(mcp.NewTextEmbeddedResource(uri, text, "text/markdown")), nil
+}
+
+// readSource returns the latest archived capture of a source
+func (r *resourceSet) readSource(uri, sourceURL string) (*mcp.ResourceResponse, error) {
+	_, text, err := r.ops.Source.ReadArchivedSource(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewResourceResponse(mcp.NewTextEmbeddedResource(uri, text, "text/markdown")), nil
+}
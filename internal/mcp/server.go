@@ -1,82 +1,127 @@
 package mcp
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"sync"
 
 	mcp "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
-	"silvia/internal/graph"
-	"silvia/internal/llm"
 	"silvia/internal/operations"
-	"silvia/internal/sources"
 )
 
-// Server exposes Silvia's functionality using the operations layer
+// Server exposes Silvia's functionality to AI assistants over one
+// transport: every registry tool, entities and sources as resources, and
+// prompts for investigative workflows
 type Server struct {
-	ops *operations.Operations
+	server    *mcp.Server
+	transport transport.Transport
+	resources *resourceSet
 }
 
-// RunMCPServer runs the MCP server using the operations layer
-func RunMCPServer() error {
-	// Set up logging to stderr so it doesn't interfere with stdio protocol
-	log.SetOutput(os.Stderr)
-	log.SetPrefix("[SILVIA-MCP] ")
-	log.SetFlags(log.Ltime | log.Lmicroseconds)
-
-	// Check if we're in MCP mode (stdio connected)
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		return fmt.Errorf("MCP server mode requires stdin/stdout to be connected (not a terminal)")
-	}
+// NewServer creates an MCP server for ops that serves over t
+func NewServer(ops *operations.Operations, dataDir string, t transport.Transport) (*Server, error) {
+	server := mcp.NewServer(t, mcp.WithName("silvia"))
 
-	log.Println("Starting Silvia MCP server v2 with operations layer...")
+	if err := RegisterOperationsTools(server, ops); err != nil {
+		return nil, fmt.Errorf("failed to register operations tools: %w", err)
+	}
 
-	// Initialize data directory
-	dataDir := os.Getenv("SILVIA_DATA_DIR")
-	if dataDir == "" {
-		dataDir = "./data"
+	// Expose entities and sources as resources, and investigative prompts
+	resources, err := registerResources(server, ops, dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register resources: %w", err)
 	}
+	if err := registerPrompts(server, ops); err != nil {
+		return nil, fmt.Errorf("failed to register prompts: %w", err)
+	}
+
+	return &Server{server: server, transport: t, resources: resources}, nil
+}
 
-	// Initialize graph manager
-	graphManager := graph.NewManager(dataDir)
-	if err := graphManager.InitializeDirectories(); err != nil {
-		return fmt.Errorf("failed to initialize directories: %w", err)
+// Serve handles requests until ctx is done, telling clients when entities
+// or sources change, then closes the transport
+func (s *Server) Serve(ctx context.Context) error {
+	if err := s.server.Serve(); err != nil {
+		return fmt.Errorf("server error: %w", err)
 	}
+	s.resources.Watch(ctx)
+	return s.transport.Close()
+}
 
-	// Initialize LLM client if API key is available
-	var llmClient *llm.Client
-	openrouterKey := os.Getenv("OPENROUTER_API_KEY")
-	if openrouterKey != "" {
-		llmClient = llm.NewClient(openrouterKey)
-		log.Println("LLM client initialized")
-	} else {
-		log.Println("Warning: No OPENROUTER_API_KEY found, LLM features disabled")
+// RunStdio serves MCP over in and out, the way desktop assistants launch
+// it, until in is closed or ctx is done. Requests already received are
+// answered before it returns.
+func RunStdio(ctx context.Context, ops *operations.Operations, dataDir string, in io.Reader, out io.Writer) error {
+	t := newStdioTransport(in, out)
+	server, err := NewServer(ops, dataDir, t)
+	if err != nil {
+		return err
 	}
 
-	// Initialize sources manager
-	sourcesManager := sources.NewManager()
+	serveCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		select {
+		case <-t.eof:
+		case <-ctx.Done():
+		}
+		t.inFlight.Wait()
+		stop()
+	}()
+
+	log.Println("MCP server ready on stdio")
+	return server.Serve(serveCtx)
+}
+
+// stdioTransport is the library's stdio transport, noting when input ends
+// and which requests are still being answered
+type stdioTransport struct {
+	*stdio.StdioServerTransport
+	eof      chan struct{}
+	inFlight sync.WaitGroup
+}
 
-	// Create operations layer
-	ops := operations.New(graphManager, llmClient, sourcesManager, dataDir)
-	log.Println("Operations layer initialized")
+func newStdioTransport(in io.Reader, out io.Writer) *stdioTransport {
+	t := &stdioTransport{eof: make(chan struct{})}
+	t.StdioServerTransport = stdio.NewStdioServerTransportWithIO(&eofReader{r: in, eof: t.eof}, out)
+	return t
+}
 
-	// Create the MCP server with stdio transport
-	server := mcp.NewServer(stdio.NewStdioServerTransport())
+// SetMessageHandler counts each request as in flight before handling it
+func (t *stdioTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.StdioServerTransport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			t.inFlight.Add(1)
+		}
+		handler(ctx, message)
+	})
+}
 
-	// Register all operations-based tools with the MCP server
-	log.Println("Registering operations-based tools with MCP server...")
-	if err := RegisterOperationsTools(server, ops); err != nil {
-		return fmt.Errorf("failed to register operations tools: %w", err)
+// Send writes a message, marking a request answered once its response is out
+func (t *stdioTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	err := t.StdioServerTransport.Send(ctx, message)
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType || message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		t.inFlight.Done()
 	}
+	return err
+}
 
-	// Serve MCP requests
-	log.Println("MCP server v2 ready, serving requests...")
-	if err := server.Serve(); err != nil {
-		return fmt.Errorf("server error: %w", err)
-	}
+// eofReader closes eof once reading from r fails, normally because the
+// client closed its end
+type eofReader struct {
+	r    io.Reader
+	eof  chan struct{}
+	once sync.Once
+}
 
-	// Block forever - the server runs in background goroutines
-	select {}
+func (e *eofReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err != nil {
+		e.once.Do(func() { close(e.eof) })
+	}
+	return n, err
 }
@@ -4,285 +4,222 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/invopop/jsonschema"
 	mcp "github.com/metoro-io/mcp-golang"
 	"silvia/internal/operations"
+	"silvia/internal/tools"
 )
 
-// RegisterOperationsTools registers all operations-based tools with the MCP server
-func RegisterOperationsTools(server *mcp.Server, ops *operations.Operations) error {
-	// Register entity operations
-	if err := registerEntityOperations(server, ops.Entity); err != nil {
-		return fmt.Errorf("failed to register entity operations: %w", err)
-	}
+// legacyToolNames maps names earlier versions of the MCP server used to the
+// registry tools that replaced them, so existing assistant setups keep
+// working
+var legacyToolNames = map[string]string{
+	"create_entity": "create_entity_ops",
+	"ingest_url":    "ingest_source",
+}
 
-	// Register queue operations
-	if err := registerQueueOperations(server, ops.Queue); err != nil {
-		return fmt.Errorf("failed to register queue operations: %w", err)
+// runChainArgs are the arguments of the run_chain tool
+type runChainArgs struct {
+	Steps []chainStep `json:"steps" jsonschema:"required,description=Steps to run in order"`
+}
+
+// RegisterOperationsTools registers every tool in the tool registry with the
+// MCP server, plus run_chain to run several of them in sequence. The MCP
+// tool set therefore always matches what the chat agent and chains can use.
+func RegisterOperationsTools(server *mcp.Server, ops *operations.Operations) error {
+	manager := tools.NewManager(ops)
+
+	registered := manager.Registry().List()
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name() < registered[j].Name() })
+	for _, tool := range registered {
+		if err := registerTool(server, ops, tool.Name(), tool); err != nil {
+			return fmt.Errorf("failed to register %s: %w", tool.Name(), err)
+		}
 	}
 
-	// Register search operations
-	if err := registerSearchOperations(server, ops.Search); err != nil {
-		return fmt.Errorf("failed to register search operations: %w", err)
+	for legacy, name := range legacyToolNames {
+		tool, err := manager.Registry().Get(name)
+		if err != nil {
+			return fmt.Errorf("legacy tool %s: %w", legacy, err)
+		}
+		if err := registerTool(server, ops, legacy, tool); err != nil {
+			return fmt.Errorf("failed to register %s: %w", legacy, err)
+		}
 	}
 
-	// Register source operations
-	if err := registerSourceOperations(server, ops.Source); err != nil {
-		return fmt.Errorf("failed to register source operations: %w", err)
+	if err := registerChainOperations(server, ops); err != nil {
+		return fmt.Errorf("failed to register chain operations: %w", err)
 	}
 
 	return nil
 }
 
-func registerEntityOperations(server *mcp.Server, entityOps *operations.EntityOps) error {
-	// Merge entities
-	err := server.RegisterTool(
-		"merge_entities",
-		"Merge entity2 into entity1, updating all references",
-		func(args struct {
-			Entity1ID string `json:"entity1_id" jsonschema:"required,description=ID of entity to merge into"`
-			Entity2ID string `json:"entity2_id" jsonschema:"required,description=ID of entity to merge from"`
-		}) (*mcp.ToolResponse, error) {
-			ctx := context.Background()
-			result, err := entityOps.MergeEntities(ctx, args.Entity1ID, args.Entity2ID)
-			if err != nil {
-				return nil, err
-			}
+// toolArgs holds the arguments of a registry tool. The MCP library builds a
+// tool's input schema from the Go type of its handler's argument, which is
+// the same for every registry tool, so JSONSchema returns the schema of the
+// tool being registered instead.
+type toolArgs map[string]any
 
-			response := fmt.Sprintf("Successfully merged %s into %s. Updated %d references.",
-				result.DeletedEntityID, args.Entity1ID, len(result.UpdatedFiles))
-
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
-	if err != nil {
-		return err
-	}
+var (
+	schemaMu      sync.Mutex
+	pendingSchema *jsonschema.Schema // Schema of the tool being registered; guarded by schemaMu
+)
 
-	// Rename entity
-	err = server.RegisterTool(
-		"rename_entity",
-		"Rename an entity and update all references",
-		func(args struct {
-			OldID string `json:"old_id" jsonschema:"required,description=Current entity ID"`
-			NewID string `json:"new_id" jsonschema:"required,description=New entity ID"`
-		}) (*mcp.ToolResponse, error) {
-			result, err := entityOps.RenameEntity(args.OldID, args.NewID)
-			if err != nil {
-				return nil, err
-			}
+// JSONSchema returns the input schema of the tool being registered
+func (toolArgs) JSONSchema() *jsonschema.Schema {
+	return pendingSchema
+}
 
-			response := fmt.Sprintf("Successfully renamed %s to %s. Updated %d references.",
-				result.OldID, result.NewID, len(result.UpdatedFiles))
+// Caller identifies a client calling tools over HTTP
+type Caller struct {
+	Actor string                 // Name changes are attributed to
+	Allow func(tools.Tool) error // Refuses tools the client may not use
+}
 
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
-	if err != nil {
-		return err
-	}
+type callerKey struct{}
 
-	// Create entity
-	err = server.RegisterTool(
-		"create_entity",
-		"Create a new entity",
-		func(args struct {
-			Type    string `json:"type" jsonschema:"required,description=Entity type (person/organization/concept/work/event)"`
-			ID      string `json:"id" jsonschema:"required,description=Entity ID"`
-			Title   string `json:"title" jsonschema:"required,description=Entity title"`
-			Content string `json:"content" jsonschema:"description=Entity content"`
-		}) (*mcp.ToolResponse, error) {
-			entity, err := entityOps.CreateEntity(args.Type, args.ID, args.Title, args.Content)
-			if err != nil {
-				return nil, err
-			}
+// WithCaller returns a context whose tool calls are made as caller. Calls
+// without one, such as over stdio, may use every tool.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
 
-			response := fmt.Sprintf("Created entity %s (%s)", entity.Metadata.ID, entity.Title)
+// managerFor returns a tool manager acting for the context's caller, or an
+// error if the caller may not use every tool in names
+func managerFor(ctx context.Context, ops *operations.Operations, names ...string) (*tools.Manager, error) {
+	caller, _ := ctx.Value(callerKey{}).(Caller)
+	manager := tools.NewManager(ops.As(caller.Actor))
+	if caller.Allow == nil {
+		return manager, nil
+	}
+	for _, name := range names {
+		tool, err := manager.Registry().Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := caller.Allow(tool); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
 
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
-	if err != nil {
-		return err
+// registerTool registers a registry tool under name
+func registerTool(server *mcp.Server, ops *operations.Operations, name string, tool tools.Tool) error {
+	description := tool.Description()
+	if tools.IsMutating(tool) {
+		description += " (changes the knowledge graph or queue)"
 	}
 
-	return nil
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	pendingSchema = inputSchema(tool.Parameters())
+	return server.RegisterTool(name, description, func(ctx context.Context, args toolArgs) (*mcp.ToolResponse, error) {
+		return callTool(ctx, ops, tool.Name(), args)
+	})
 }
 
-func registerQueueOperations(server *mcp.Server, queueOps *operations.QueueOps) error {
-	// Get queue
-	err := server.RegisterTool(
-		"get_queue",
-		"Get the current source queue status",
-		func(args struct{}) (*mcp.ToolResponse, error) {
-			status, err := queueOps.GetQueue()
-			if err != nil {
-				return nil, err
-			}
+// inputSchema describes a tool's parameters as a JSON schema
+func inputSchema(params []tools.Parameter) *jsonschema.Schema {
+	schema := &jsonschema.Schema{Type: "object", Properties: jsonschema.NewProperties()}
+	for _, param := range params {
+		description := param.Description
+		if param.Default != nil {
+			description += fmt.Sprintf(" (default %v)", param.Default)
+		}
+		property := &jsonschema.Schema{Type: schemaType(param.Type), Description: description}
+		if property.Type == "array" {
+			property.Items = &jsonschema.Schema{Type: "string"}
+		}
+		schema.Properties.Set(param.Name, property)
+		if param.Required {
+			schema.Required = append(schema.Required, param.Name)
+		}
+	}
+	return schema
+}
 
-			// Format as JSON for better structure
-			data, _ := json.MarshalIndent(status, "", "  ")
+// schemaType maps a tool parameter type to a JSON schema type
+func schemaType(name string) string {
+	switch strings.ToLower(name) {
+	case "int", "integer":
+		return "integer"
+	case "float", "number":
+		return "number"
+	case "bool", "boolean":
+		return "boolean"
+	case "map", "object":
+		return "object"
+	case "[]string", "array":
+		return "array"
+	default:
+		return "string"
+	}
+}
 
-			return mcp.NewToolResponse(mcp.NewTextContent(string(data))), nil
-		},
-	)
+// callTool runs a registry tool with the arguments the client sent and
+// returns its data as JSON
+func callTool(ctx context.Context, ops *operations.Operations, name string, args toolArgs) (*mcp.ToolResponse, error) {
+	manager, err := managerFor(ctx, ops, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Add to queue
-	err = server.RegisterTool(
-		"add_to_queue",
-		"Add a URL to the processing queue",
-		func(args struct {
-			URL         string `json:"url" jsonschema:"required,description=URL to add"`
-			Priority    int    `json:"priority" jsonschema:"description=Priority (0=low 1=medium 2=high)"`
-			FromSource  string `json:"from_source" jsonschema:"description=Source this came from"`
-			Description string `json:"description" jsonschema:"description=Why this URL is being added"`
-		}) (*mcp.ToolResponse, error) {
-			err := queueOps.AddToQueue(args.URL, args.Priority, args.FromSource, args.Description)
-			if err != nil {
-				return nil, err
-			}
-
-			response := fmt.Sprintf("Added %s to queue with priority %d", args.URL, args.Priority)
-
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
+	if args == nil {
+		args = toolArgs{}
+	}
+	result, err := manager.Execute(ctx, name, args)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%s", result.Error)
 	}
 
-	// Remove from queue
-	err = server.RegisterTool(
-		"remove_from_queue",
-		"Remove a URL from the processing queue",
-		func(args struct {
-			URL string `json:"url" jsonschema:"required,description=URL to remove"`
-		}) (*mcp.ToolResponse, error) {
-			err := queueOps.RemoveFromQueue(args.URL)
-			if err != nil {
-				return nil, err
-			}
-
-			response := fmt.Sprintf("Removed %s from queue", args.URL)
-
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
+	data, err := json.MarshalIndent(result.Data, "", "  ")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to encode result: %w", err)
 	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(data))), nil
+}
 
-	return nil
+// chainStep is one step of a run_chain call
+type chainStep struct {
+	Tool    string         `json:"tool" jsonschema:"required,description=Name of the tool to run"`
+	Args    map[string]any `json:"args,omitempty" jsonschema:"description=Tool arguments; strings like $1.data.id or $1.data[*].id refer to earlier results"`
+	ForEach string         `json:"for_each,omitempty" jsonschema:"description=Reference to a list (e.g. $1.data.results[*].entity.metadata.id) to run the tool once per element; use $item in args"`
 }
 
-func registerSearchOperations(server *mcp.Server, searchOps *operations.SearchOps) error {
-	// Search entities
+func registerChainOperations(server *mcp.Server, ops *operations.Operations) error {
+	// Run a chain of tools
 	err := server.RegisterTool(
-		"search_entities",
-		"Search for entities in the knowledge graph",
-		func(args struct {
-			Query string `json:"query" jsonschema:"required,description=Search query"`
-		}) (*mcp.ToolResponse, error) {
-			result, err := searchOps.SearchEntities(args.Query)
-			if err != nil {
-				return nil, err
-			}
-
-			// Format results
-			var response string
-			if result.Total == 0 {
-				response = "No entities found"
-			} else {
-				response = fmt.Sprintf("Found %d entities:\n", result.Total)
-				for i, match := range result.Results {
-					if i >= 10 { // Limit to 10 results
-						response += fmt.Sprintf("\n... and %d more", result.Total-10)
-						break
-					}
-					response += fmt.Sprintf("\n%d. %s (%s) - %s",
-						i+1, match.Entity.Title, match.Entity.Metadata.ID, match.Snippet)
-				}
+		"run_chain",
+		"Run several tools in sequence, where later steps use earlier results via $<step>.<path> references",
+		func(ctx context.Context, args runChainArgs) (*mcp.ToolResponse, error) {
+			chain := make([]tools.ToolCall, len(args.Steps))
+			names := make([]string, len(args.Steps))
+			for i, step := range args.Steps {
+				chain[i] = tools.ToolCall{Tool: step.Tool, Args: step.Args, ForEach: step.ForEach}
+				names[i] = step.Tool
 			}
 
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	// Get related entities
-	err = server.RegisterTool(
-		"get_related_entities",
-		"Get all entities related to a specific entity",
-		func(args struct {
-			EntityID string `json:"entity_id" jsonschema:"required,description=Entity ID"`
-		}) (*mcp.ToolResponse, error) {
-			result, err := searchOps.GetRelatedEntities(args.EntityID)
+			manager, err := managerFor(ctx, ops, names...)
 			if err != nil {
 				return nil, err
 			}
-
-			// Format results
-			var response string
-			response = fmt.Sprintf("Related entities for %s:\n", result.Entity.Title)
-
-			// Outgoing relationships
-			if len(result.OutgoingByType) > 0 {
-				response += "\nOutgoing relationships:\n"
-				for relType, entities := range result.OutgoingByType {
-					response += fmt.Sprintf("  %s: %d entities\n", relType, len(entities))
-				}
-			}
-
-			// Incoming relationships
-			if len(result.IncomingByType) > 0 {
-				response += "\nIncoming relationships:\n"
-				for relType, entities := range result.IncomingByType {
-					response += fmt.Sprintf("  %s: %d entities\n", relType, len(entities))
-				}
-			}
-
-			if len(result.BrokenLinks) > 0 {
-				response += fmt.Sprintf("\nBroken links: %d\n", len(result.BrokenLinks))
-			}
-
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func registerSourceOperations(server *mcp.Server, sourceOps *operations.SourceOps) error {
-	// Ingest source
-	err := server.RegisterTool(
-		"ingest_url",
-		"Ingest a URL and extract entities",
-		func(args struct {
-			URL   string `json:"url" jsonschema:"required,description=URL to ingest"`
-			Force bool   `json:"force" jsonschema:"description=Force re-ingestion"`
-		}) (*mcp.ToolResponse, error) {
-			ctx := context.Background()
-			result, err := sourceOps.IngestSource(ctx, args.URL, args.Force)
+			results, err := manager.ExecuteChain(ctx, chain)
 			if err != nil {
 				return nil, err
 			}
 
-			response := fmt.Sprintf("Ingested %s\nExtracted %d entities and %d links\nArchived to: %s\nProcessing time: %v",
-				result.SourceURL,
-				len(result.ExtractedEntities),
-				len(result.ExtractedLinks),
-				result.ArchivedPath,
-				result.ProcessingTime)
-
-			return mcp.NewToolResponse(mcp.NewTextContent(response)), nil
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode results: %w", err)
+			}
+			return mcp.NewToolResponse(mcp.NewTextContent(string(data))), nil
 		},
 	)
 	if err != nil {
@@ -0,0 +1,355 @@
+package operations
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"silvia/internal/graph"
+	"silvia/internal/sources"
+)
+
+// Annotating pages: the browser extension asks which known entities a page
+// mentions before deciding whether to ingest it.
+
+// maxAnnotateText limits the characters of text annotated at once
+const maxAnnotateText = 2 << 20
+
+// maxUnknownNouns limits the unknown names reported for a page
+const maxUnknownNouns = 30
+
+// snippetContext is how many characters around a mention its snippet shows
+const snippetContext = 60
+
+// nameIndex holds the name matcher for the graph, rebuilt when an entity
+// file changes
+type nameIndex struct {
+	mu       sync.Mutex
+	version  string
+	matcher  *graph.NameMatcher
+	entities map[string]*graph.Entity
+}
+
+// nameMatcher returns a matcher for the current graph and the entities it
+// matches by ID. The graph directory is checked on each call, so entities
+// changed by another process are picked up too.
+func (s *SearchOps) nameMatcher() (*graph.NameMatcher, map[string]*graph.Entity, error) {
+	version, err := s.graphVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.names.mu.Lock()
+	defer s.names.mu.Unlock()
+	if s.names.matcher != nil && s.names.version == version {
+		return s.names.matcher, s.names.entities, nil
+	}
+
+	entities, err := s.graph.ListAllEntities()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	byID := make(map[string]*graph.Entity, len(entities))
+	for _, entity := range entities {
+		byID[entity.Metadata.ID] = entity
+	}
+	s.names.matcher, s.names.entities, s.names.version = graph.NewNameMatcher(entities), byID, version
+	return s.names.matcher, s.names.entities, nil
+}
+
+// graphVersion summarizes the entity files' names, sizes and modification
+// times, so that any change to the graph changes it
+func (s *SearchOps) graphVersion() (string, error) {
+	var count int
+	var size, latest int64
+	graphDir := filepath.Join(s.dataDir, "graph")
+	err := filepath.Walk(graphDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		count++
+		size += info.Size()
+		latest = max(latest, info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%d", count, size, latest), nil
+}
+
+// Annotate finds the known entities mentioned in a page, given as plain
+// text or as HTML, by their titles and aliases. It also reports proper
+// nouns that match no entity; with HTML, those shown as links get the
+// link's URL, resolved against pageURL, so they can be queued.
+func (s *SearchOps) Annotate(text, rawHTML, pageURL string) (*AnnotateResult, error) {
+	if text == "" && rawHTML == "" {
+		return nil, NewOperationError("annotate", pageURL, invalidf("text or html is required"))
+	}
+
+	result := &AnnotateResult{Matches: []Annotation{}, Entities: []AnnotatedEntity{}, Unknown: []UnknownProperNoun{}}
+	var links []sources.PageLink
+	if rawHTML != "" {
+		text, links = sources.PageText(rawHTML)
+		result.Text = text
+	}
+	runes := []rune(text)
+	if len(runes) > maxAnnotateText {
+		return nil, NewOperationError("annotate", pageURL, invalidf("text is too long (%d characters, at most %d)", len(runes), maxAnnotateText))
+	}
+
+	matcher, entities, err := s.nameMatcher()
+	if err != nil {
+		return nil, NewOperationError("annotate", pageURL, err)
+	}
+
+	mentions := make(map[string]int)
+	known := make([]bool, len(runes))
+	for _, match := range matcher.Find(text) {
+		entity := entities[match.EntityID]
+		if entity == nil {
+			continue
+		}
+		result.Matches = append(result.Matches, Annotation{
+			EntityID: match.EntityID,
+			Type:     string(entity.Metadata.Type),
+			Title:    entity.Title,
+			Name:     match.Name,
+			Start:    match.Start,
+			End:      match.End,
+			Snippet:  snippetAround(runes, match.Start, match.End),
+		})
+		if mentions[match.EntityID] == 0 {
+			result.Entities = append(result.Entities, AnnotatedEntity{
+				ID:    match.EntityID,
+				Type:  string(entity.Metadata.Type),
+				Title: entity.Title,
+			})
+		}
+		mentions[match.EntityID]++
+		for i := match.Start; i < match.End; i++ {
+			known[i] = true
+		}
+	}
+	for i := range result.Entities {
+		result.Entities[i].Mentions = mentions[result.Entities[i].ID]
+	}
+	slices.SortStableFunc(result.Entities, func(a, b AnnotatedEntity) int {
+		return b.Mentions - a.Mentions
+	})
+
+	for _, noun := range unknownProperNouns(runes, known) {
+		noun.URL = linkFor(noun.Text, links, pageURL)
+		result.Unknown = append(result.Unknown, noun)
+	}
+	return result, nil
+}
+
+// snippetAround returns the text around a mention on one line
+func snippetAround(runes []rune, start, end int) string {
+	from, to := max(0, start-snippetContext), min(len(runes), end+snippetContext)
+	snippet := strings.Join(strings.Fields(string(runes[from:to])), " ")
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// nounConnectors may join the capitalized words of a name, as in "Bank of
+// England" or "Ludwig van Beethoven"
+var nounConnectors = map[string]bool{
+	"of": true, "de": true, "del": true, "der": true, "van": true, "von": true,
+	"la": true, "le": true, "da": true, "du": true, "al": true, "bin": true,
+}
+
+// commonCapitalized are words often capitalized without being names
+var commonCapitalized = map[string]bool{
+	"i": true, "a": true, "an": true, "the": true, "this": true, "that": true, "these": true,
+	"those": true, "it": true, "its": true, "he": true, "she": true, "we": true, "they": true,
+	"you": true, "his": true, "her": true, "our": true, "their": true, "my": true, "your": true,
+	"in": true, "on": true, "at": true, "by": true, "for": true, "from": true, "with": true,
+	"as": true, "to": true, "of": true, "and": true, "but": true, "or": true, "if": true,
+	"when": true, "while": true, "after": true, "before": true, "however": true, "there": true,
+	"what": true, "why": true, "how": true, "who": true, "where": true, "which": true,
+	"mr": true, "mrs": true, "ms": true, "dr": true, "read": true, "more": true, "share": true,
+	"also": true, "then": true, "now": true, "so": true, "yet": true, "still": true, "here": true,
+	"meanwhile": true, "today": true, "yesterday": true, "some": true, "many": true, "most": true,
+	"all": true, "one": true, "every": true, "each": true, "no": true, "not": true, "only": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true,
+	"saturday": true, "sunday": true, "january": true, "february": true, "march": true,
+	"april": true, "may": true, "june": true, "july": true, "august": true, "september": true,
+	"october": true, "november": true, "december": true,
+}
+
+// pageWord is a word of a page's text and where it is
+type pageWord struct {
+	text          string
+	start, end    int
+	capitalized   bool
+	sentenceStart bool
+	joined        bool // Only a space separates it from the word before
+}
+
+// pageWords splits text into words, noting which begin a sentence
+func pageWords(runes []rune) []pageWord {
+	var words []pageWord
+	sentenceStart, joined := true, false
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if !isWordRune(r) {
+			switch {
+			case strings.ContainsRune(".!?:;\"“”\n", r):
+				sentenceStart, joined = true, false
+			case r == ' ' || r == '\t':
+			default:
+				joined = false
+			}
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(runes) && (isWordRune(runes[i]) ||
+			(strings.ContainsRune("-'’.", runes[i]) && i+1 < len(runes) && isWordRune(runes[i+1]))) {
+			i++
+		}
+		words = append(words, pageWord{
+			text:          string(runes[start:i]),
+			start:         start,
+			end:           i,
+			capitalized:   unicode.IsUpper(r),
+			sentenceStart: sentenceStart,
+			joined:        joined,
+		})
+		sentenceStart, joined = false, true
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// unknownProperNouns finds runs of capitalized words not covered by known
+// matches, most mentioned first. Names of several words count from one
+// mention; single words only from two mentions that do not begin a sentence.
+// A name at the start of a sentence may have a capitalized opener stuck to
+// it ("Meanwhile Alex Karp"), so it counts as the rest of the run when that
+// is mentioned elsewhere.
+func unknownProperNouns(runes []rune, known []bool) []UnknownProperNoun {
+	words := pageWords(runes)
+	nouns := make(map[string]*UnknownProperNoun)
+	var order []string
+	add := func(first, last pageWord) {
+		for i := first.start; i < last.end; i++ {
+			if known[i] {
+				return
+			}
+		}
+		text := string(runes[first.start:last.end])
+		if noun, ok := nouns[text]; ok {
+			noun.Mentions++
+			return
+		}
+		nouns[text] = &UnknownProperNoun{Text: text, Mentions: 1, Start: first.start, End: last.end}
+		order = append(order, text)
+	}
+
+	type run struct{ first, last int }
+	var openers []run
+	for i := 0; i < len(words); i++ {
+		if !words[i].capitalized {
+			continue
+		}
+		last := i
+		for j := i + 1; j < len(words) && words[j].joined; j++ {
+			if words[j].capitalized {
+				last = j
+				continue
+			}
+			if !nounConnectors[words[j].text] || j+1 >= len(words) || !words[j+1].joined || !words[j+1].capitalized {
+				break
+			}
+		}
+
+		first := i
+		if words[i].sentenceStart && commonCapitalized[strings.ToLower(words[i].text)] {
+			first = i + 1
+		}
+		switch {
+		case first > last:
+		case first < last && words[first].sentenceStart && words[first+1].capitalized:
+			openers = append(openers, run{first, last})
+		case first < last:
+			add(words[first], words[last])
+		case !words[first].sentenceStart && !commonCapitalized[strings.ToLower(words[first].text)] && len([]rune(words[first].text)) > 2:
+			add(words[first], words[first])
+		}
+		i = last
+	}
+	for _, r := range openers {
+		if first := r.first + 1; first < r.last {
+			if _, ok := nouns[string(runes[words[first].start:words[r.last].end])]; ok {
+				add(words[first], words[r.last])
+				continue
+			}
+		}
+		add(words[r.first], words[r.last])
+	}
+
+	unknown := make([]UnknownProperNoun, 0, len(order))
+	for _, text := range order {
+		noun := nouns[text]
+		if noun.Mentions < 2 && !strings.Contains(text, " ") {
+			continue
+		}
+		unknown = append(unknown, *noun)
+	}
+	slices.SortFunc(unknown, func(a, b UnknownProperNoun) int {
+		if a.Mentions != b.Mentions {
+			return b.Mentions - a.Mentions
+		}
+		return a.Start - b.Start
+	})
+	if len(unknown) > maxUnknownNouns {
+		unknown = unknown[:maxUnknownNouns]
+	}
+	return unknown
+}
+
+// linkFor returns the absolute URL of the first web link on the page whose
+// text names noun
+func linkFor(noun string, links []sources.PageLink, pageURL string) string {
+	base, _ := url.Parse(pageURL)
+	lower := strings.ToLower(noun)
+	for _, link := range links {
+		if !strings.Contains(strings.ToLower(link.Text), lower) {
+			continue
+		}
+		target, err := url.Parse(link.URL)
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			target = base.ResolveReference(target)
+		}
+		if target.Scheme == "http" || target.Scheme == "https" {
+			return target.String()
+		}
+	}
+	return ""
+}
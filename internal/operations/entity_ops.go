@@ -11,6 +11,7 @@ import (
 
 	"silvia/internal/graph"
 	"silvia/internal/llm"
+	"silvia/internal/prompts"
 )
 
 // EntityOps handles all entity-related operations
@@ -18,6 +19,8 @@ type EntityOps struct {
 	graph   *graph.Manager
 	llm     *llm.Client
 	dataDir string
+	events  *EventLog
+	actor   string // API token changes are attributed to; empty for local changes
 }
 
 // NewEntityOps creates a new entity operations handler
@@ -45,13 +48,13 @@ func (e *EntityOps) CreateEntity(entityType, id, title, content string) (*graph.
 	isValid := slices.Contains(validTypes, entityType)
 	if !isValid {
 		return nil, NewOperationError("create entity", id,
-			fmt.Errorf("invalid entity type: %s (must be one of: %s)",
+			invalidf("invalid entity type: %s (must be one of: %s)",
 				entityType, strings.Join(validTypes, ", ")))
 	}
 
 	// Check if entity already exists
 	if e.graph.EntityExists(id) {
-		return nil, NewOperationError("create entity", id, fmt.Errorf("entity already exists"))
+		return nil, NewOperationError("create entity", id, conflictf("entity already exists"))
 	}
 
 	// Create the entity
@@ -59,10 +62,11 @@ func (e *EntityOps) CreateEntity(entityType, id, title, content string) (*graph.
 		Title:   title,
 		Content: content,
 		Metadata: graph.Metadata{
-			ID:      id,
-			Type:    graph.EntityType(entityType),
-			Created: time.Now(),
-			Updated: time.Now(),
+			ID:        id,
+			Type:      graph.EntityType(entityType),
+			Created:   time.Now(),
+			Updated:   time.Now(),
+			UpdatedBy: e.actor,
 		},
 	}
 
@@ -70,6 +74,7 @@ func (e *EntityOps) CreateEntity(entityType, id, title, content string) (*graph.
 	if err := e.graph.SaveEntity(entity); err != nil {
 		return nil, NewOperationError("create entity", id, err)
 	}
+	e.events.Publish(EventEntityCreated, id, entityEventData(entity))
 
 	return entity, nil
 }
@@ -92,26 +97,30 @@ func (e *EntityOps) UpdateEntity(id string, title, content string) (*graph.Entit
 
 	// Update timestamp
 	entity.Metadata.Updated = time.Now()
+	entity.Metadata.UpdatedBy = e.actor
 
 	// Save the updated entity
 	if err := e.graph.SaveEntity(entity); err != nil {
 		return nil, NewOperationError("update entity", id, err)
 	}
+	e.events.Publish(EventEntityUpdated, id, entityEventData(entity))
 
 	return entity, nil
 }
 
 // MergeEntities merges entity2 into entity1, updating all references
 func (e *EntityOps) MergeEntities(ctx context.Context, entity1ID, entity2ID string) (*MergeResult, error) {
+	ctx = llm.WithOperation(ctx, llm.OpMerge, entity1ID+" + "+entity2ID)
+
 	// Validate both entities exist
 	entity1, err := e.graph.LoadEntity(entity1ID)
 	if err != nil {
-		return nil, NewOperationError("merge entities", entity1ID, fmt.Errorf("first entity not found: %w", err))
+		return nil, NewOperationError("merge entities", entity1ID, notFoundf("first entity not found: %w", err))
 	}
 
 	entity2, err := e.graph.LoadEntity(entity2ID)
 	if err != nil {
-		return nil, NewOperationError("merge entities", entity2ID, fmt.Errorf("second entity not found: %w", err))
+		return nil, NewOperationError("merge entities", entity2ID, notFoundf("second entity not found: %w", err))
 	}
 
 	// Track which files get updated
@@ -143,6 +152,7 @@ func (e *EntityOps) MergeEntities(ctx context.Context, entity1ID, entity2ID stri
 		mergedContent, err := e.llm.MergeEntities(ctx, entity1.Content, entity2.Content, "")
 		if err == nil {
 			entity1.Content = mergedContent
+			entity1.RecordPrompt(prompts.Merge, prompts.Version(prompts.Merge))
 		}
 	} else {
 		// Simple concatenation if no LLM
@@ -167,6 +177,7 @@ func (e *EntityOps) MergeEntities(ctx context.Context, entity1ID, entity2ID stri
 
 	// Update timestamp
 	entity1.Metadata.Updated = time.Now()
+	entity1.Metadata.UpdatedBy = e.actor
 
 	// Save the merged entity
 	if err := e.graph.SaveEntity(entity1); err != nil {
@@ -184,6 +195,11 @@ func (e *EntityOps) MergeEntities(ctx context.Context, entity1ID, entity2ID stri
 		fmt.Printf("Warning: failed to rebuild back-references: %v\n", err)
 	}
 
+	e.events.Publish(EventEntityMerged, entity1ID, map[string]any{
+		"merged_id":     entity2ID,
+		"updated_files": updatedFiles,
+	})
+
 	return &MergeResult{
 		MergedEntity:    entity1,
 		UpdatedFiles:    updatedFiles,
@@ -195,12 +211,12 @@ func (e *EntityOps) MergeEntities(ctx context.Context, entity1ID, entity2ID stri
 func (e *EntityOps) RenameEntity(oldID, newID string) (*RenameResult, error) {
 	// Validate old entity exists
 	if _, err := e.graph.LoadEntity(oldID); err != nil {
-		return nil, NewOperationError("rename entity", oldID, fmt.Errorf("entity not found"))
+		return nil, NewOperationError("rename entity", oldID, notFoundf("entity not found"))
 	}
 
 	// Check if new ID already exists
 	if e.graph.EntityExists(newID) {
-		return nil, NewOperationError("rename entity", newID, fmt.Errorf("target entity already exists"))
+		return nil, NewOperationError("rename entity", newID, conflictf("target entity already exists"))
 	}
 
 	// Use graph's rename function which handles all the complexity
@@ -210,6 +226,10 @@ func (e *EntityOps) RenameEntity(oldID, newID string) (*RenameResult, error) {
 
 	// Get list of updated files (all entities that referenced the old ID)
 	updatedFiles := e.getEntitiesReferencingTarget(newID)
+	e.events.Publish(EventEntityRenamed, newID, map[string]any{
+		"old_id":        oldID,
+		"updated_files": updatedFiles,
+	})
 
 	return &RenameResult{
 		OldID:        oldID,
@@ -218,30 +238,103 @@ func (e *EntityOps) RenameEntity(oldID, newID string) (*RenameResult, error) {
 	}, nil
 }
 
+// MoveEntity moves an entity to a new ID, which may change its type, and
+// updates all references
+func (e *EntityOps) MoveEntity(oldID, newID string) (*RenameResult, error) {
+	if !e.graph.EntityExists(oldID) {
+		return nil, NewOperationError("move entity", oldID, notFoundf("entity not found"))
+	}
+	if e.graph.EntityExists(newID) {
+		return nil, NewOperationError("move entity", newID, conflictf("target entity already exists"))
+	}
+	if _, name, ok := strings.Cut(newID, "/"); !ok || name == "" {
+		return nil, NewOperationError("move entity", newID, invalidf("invalid entity ID format: must be 'type/name'"))
+	}
+
+	if err := e.graph.MoveEntity(oldID, newID); err != nil {
+		return nil, NewOperationError("move entity", oldID, err)
+	}
+
+	updatedFiles := e.getEntitiesReferencingTarget(newID)
+	e.events.Publish(EventEntityRenamed, newID, map[string]any{
+		"old_id":        oldID,
+		"updated_files": updatedFiles,
+	})
+
+	return &RenameResult{
+		OldID:        oldID,
+		NewID:        newID,
+		UpdatedFiles: updatedFiles,
+	}, nil
+}
+
+// LinkEntities adds a relationship from one entity to another
+func (e *EntityOps) LinkEntities(sourceID, relType, targetID string) (*graph.Entity, error) {
+	if relType == "" {
+		return nil, NewOperationError("link entities", sourceID, invalidf("relationship type cannot be empty"))
+	}
+
+	source, err := e.graph.LoadEntity(sourceID)
+	if err != nil {
+		return nil, NewOperationError("link entities", sourceID, notFoundf("source entity not found: %w", err))
+	}
+	if !e.graph.EntityExists(targetID) {
+		return nil, NewOperationError("link entities", targetID, notFoundf("target entity not found"))
+	}
+
+	source.AddRelationship(relType, targetID, nil, "")
+	source.Metadata.UpdatedBy = e.actor
+
+	// Saving also updates the target's back-references
+	if err := e.graph.SaveEntity(source); err != nil {
+		return nil, NewOperationError("link entities", sourceID, err)
+	}
+	e.events.Publish(EventEntityUpdated, sourceID, entityEventData(source))
+
+	return source, nil
+}
+
+// RebuildReferences recomputes every entity's back-references
+func (e *EntityOps) RebuildReferences() error {
+	if err := e.graph.RebuildAllBackReferences(); err != nil {
+		return NewOperationError("rebuild references", "", err)
+	}
+	return nil
+}
+
 // DeleteEntity deletes an entity
 func (e *EntityOps) DeleteEntity(id string) error {
 	// Check if entity exists
 	if !e.graph.EntityExists(id) {
-		return NewOperationError("delete entity", id, fmt.Errorf("entity not found"))
+		return NewOperationError("delete entity", id, notFoundf("entity not found"))
 	}
 
 	// Check for references
 	referencingEntities := e.getEntitiesReferencingTarget(id)
 	if len(referencingEntities) > 0 {
 		return NewOperationError("delete entity", id,
-			fmt.Errorf("cannot delete: entity is referenced by %d other entities", len(referencingEntities)))
+			conflictf("cannot delete: entity is referenced by %d other entities", len(referencingEntities)))
 	}
 
 	// Delete the entity
 	if err := e.deleteEntity(id); err != nil {
 		return NewOperationError("delete entity", id, err)
 	}
+	e.events.Publish(EventEntityDeleted, id, nil)
 
 	return nil
 }
 
 // RefineEntity uses LLM to refine an entity's content based on its sources
 func (e *EntityOps) RefineEntity(ctx context.Context, id string, guidance string) (*graph.Entity, error) {
+	return e.RefineEntityStream(ctx, id, guidance, nil)
+}
+
+// RefineEntityStream is RefineEntity with the refined content passed to
+// onDelta as it is generated
+func (e *EntityOps) RefineEntityStream(ctx context.Context, id string, guidance string, onDelta func(delta string)) (*graph.Entity, error) {
+	ctx = llm.WithOperation(ctx, llm.OpRefine, id)
+
 	if e.llm == nil {
 		return nil, NewOperationError("refine entity", id, fmt.Errorf("LLM client not available"))
 	}
@@ -254,45 +347,61 @@ func (e *EntityOps) RefineEntity(ctx context.Context, id string, guidance string
 
 	// Build context from sources
 	var sourceContext strings.Builder
-	sourceContext.WriteString("Current entity content:\n")
-	sourceContext.WriteString(entity.Content)
-	sourceContext.WriteString("\n\nSources:\n")
-
 	for _, sourceURL := range entity.Metadata.Sources {
 		// In a full implementation, we would fetch and include source content
 		sourceContext.WriteString(fmt.Sprintf("- %s\n", sourceURL))
 	}
 
-	// Create refinement prompt
-	systemPrompt := "You are a knowledge graph entity refiner. Improve the entity description based on the sources and guidance provided. Preserve all wiki-links in [[entity-id]] format."
-
-	userPrompt := sourceContext.String()
-	if guidance != "" {
-		userPrompt += "\n\nGuidance: " + guidance
+	prompt, err := prompts.Render(prompts.Refine, prompts.RefineData{
+		Title:         entity.Title,
+		Type:          string(entity.Metadata.Type),
+		Content:       entity.Content,
+		SourceContent: sourceContext.String(),
+		Guidance:      guidance,
+	})
+	if err != nil {
+		return nil, NewOperationError("refine entity", id, err)
 	}
 
 	// Get refined content from LLM
-	refinedContent, err := e.llm.CompleteWithSystem(ctx, systemPrompt, userPrompt, "")
+	refinedContent, err := e.llm.ForTask(llm.TaskRefine).CompleteStream(ctx, prompt, "", onDelta)
 	if err != nil {
 		return nil, NewOperationError("refine entity", id, fmt.Errorf("LLM refinement failed: %w", err))
 	}
 
 	// Update entity content
 	entity.Content = refinedContent
+	entity.RecordPrompt(prompts.Refine, prompts.Version(prompts.Refine))
 
 	// Update timestamp
 	entity.Metadata.Updated = time.Now()
+	entity.Metadata.UpdatedBy = e.actor
 
 	// Save the refined entity
 	if err := e.graph.SaveEntity(entity); err != nil {
 		return nil, NewOperationError("refine entity", id, err)
 	}
+	e.events.Publish(EventEntityUpdated, id, entityEventData(entity))
 
 	return entity, nil
 }
 
 // Helper methods
 
+// entityEventData describes an entity in created and updated events
+func entityEventData(entity *graph.Entity) map[string]any {
+	return map[string]any{
+		"type":  entity.Metadata.Type,
+		"title": entity.Title,
+	}
+}
+
+// PublishEntity publishes an event about an entity saved without EntityOps,
+// as the CLI's ingest does
+func (l *EventLog) PublishEntity(eventType string, entity *graph.Entity) {
+	l.Publish(eventType, entity.Metadata.ID, entityEventData(entity))
+}
+
 // getEntitiesReferencingTarget finds all entities that reference a target entity
 func (e *EntityOps) getEntitiesReferencingTarget(targetID string) []string {
 	referencingEntities := []string{}
@@ -0,0 +1,329 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventEntityCreated  = "entity.created"
+	EventEntityUpdated  = "entity.updated"
+	EventEntityDeleted  = "entity.deleted"
+	EventEntityRenamed  = "entity.renamed"
+	EventEntityMerged   = "entity.merged"
+	EventQueueAdded     = "queue.added"
+	EventQueueRemoved   = "queue.removed"
+	EventQueueUpdated   = "queue.updated"
+	EventQueueCleared   = "queue.cleared"
+	EventSourceIngested = "source.ingested"
+	EventQuoteCreated   = "quote.created"
+	EventJobProgress    = "job.progress"
+	EventJobFinished    = "job.finished"
+)
+
+// maxEvents is how many events the log keeps; older ones are dropped once
+// it holds twice as many
+const maxEvents = 1000
+
+// eventPollInterval is how often followers check the log for events
+// written by other processes
+const eventPollInterval = time.Second
+
+// eventLockWait is how long publishing waits for another process to finish
+// appending to the log; a lock older than staleEventLock was left behind
+const (
+	eventLockWait  = 2 * time.Second
+	staleEventLock = 10 * time.Second
+)
+
+// Event is a change to the graph, the queue or a job
+type Event struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"`
+	Time    time.Time       `json:"time"`
+	Subject string          `json:"subject,omitempty"` // Entity ID, source URL or job ID
+	Actor   string          `json:"actor,omitempty"`   // API token that made the change; empty for local changes
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// EventLog records events as JSON lines in a short file that every silvia
+// process appends to, so a follower sees changes made by the CLI, the API
+// server and the MCP server alike. Event IDs are timestamps assigned under
+// a lock on the file, each above the last one logged by any process, so a
+// client can resume after the last ID it saw.
+type EventLog struct {
+	*eventFile
+	actor string // Recorded on every event published through this log
+}
+
+// eventFile is the state shared by an event log and its attributed views
+type eventFile struct {
+	path string
+
+	mu      sync.Mutex
+	written int           // Events appended since the log was last trimmed
+	notify  chan struct{} // Closed and replaced on each publish
+}
+
+// NewEventLog creates an event log in the data directory
+func NewEventLog(dataDir string) *EventLog {
+	return &EventLog{eventFile: &eventFile{
+		path:   filepath.Join(dataDir, ".silvia", "events.jsonl"),
+		notify: make(chan struct{}),
+	}}
+}
+
+// As returns a view of the log that records actor on the events it
+// publishes. A nil log stays nil.
+func (l *EventLog) As(actor string) *EventLog {
+	if l == nil {
+		return nil
+	}
+	return &EventLog{eventFile: l.eventFile, actor: actor}
+}
+
+// Publish appends an event. Failing to record an event never fails the
+// operation that caused it, so errors are only logged. A nil log ignores
+// events.
+func (l *EventLog) Publish(eventType, subject string, data any) {
+	if l == nil {
+		return
+	}
+
+	event := Event{Type: eventType, Time: time.Now(), Subject: subject, Actor: l.actor}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			fmt.Printf("Warning: failed to encode %s event: %v\n", eventType, err)
+			return
+		}
+		event.Data = encoded
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unlock, err := lockFile(l.path+".lock", "event log", eventLockWait, staleEventLock)
+	if err != nil {
+		fmt.Printf("Warning: failed to record %s event: %v\n", eventType, err)
+		return
+	}
+	defer unlock()
+
+	// Another process's clock may be ahead of ours
+	lastID, err := l.lastLoggedID()
+	if err != nil {
+		fmt.Printf("Warning: failed to record %s event: %v\n", eventType, err)
+		return
+	}
+	event.ID = max(event.Time.UnixNano(), lastID+1)
+
+	if err := l.append(event); err != nil {
+		fmt.Printf("Warning: failed to record %s event: %v\n", eventType, err)
+		return
+	}
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// Since returns the logged events with IDs after afterID, oldest first. An
+// afterID of 0 returns every event still in the log.
+func (l *EventLog) Since(afterID int64) ([]Event, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // Skip a line cut short by a crash
+		}
+		if event.ID > afterID {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return events, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return events, nil
+}
+
+// LastID returns the ID of the newest logged event, or 0 if there is none,
+// so a follower can start with whatever happens next
+func (l *EventLog) LastID() int64 {
+	id, _ := l.lastLoggedID()
+	return id
+}
+
+// Matches reports whether the event's type starts with one of the given
+// prefixes, such as "entity" or "job.finished". Every event matches an
+// empty list.
+func (e Event) Matches(types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, prefix := range types {
+		if prefix = strings.TrimSpace(prefix); prefix != "" && strings.HasPrefix(e.Type, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe sends events after afterID to the returned channel, starting
+// with any already in the log, until ctx is done. Events published in this
+// process arrive at once; those from other processes within a second.
+func (l *EventLog) Subscribe(ctx context.Context, afterID int64) <-chan Event {
+	events := make(chan Event, 16)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(eventPollInterval)
+		defer ticker.Stop()
+
+		var lastSize int64 = -1
+		var lastMod time.Time
+		for {
+			l.mu.Lock()
+			notify := l.notify
+			l.mu.Unlock()
+
+			// Only reread the log when it has changed
+			if info, err := os.Stat(l.path); err == nil && (info.Size() != lastSize || !info.ModTime().Equal(lastMod)) {
+				lastSize, lastMod = info.Size(), info.ModTime()
+				pending, _ := l.Since(afterID)
+				for _, event := range pending {
+					select {
+					case events <- event:
+						afterID = event.ID
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-notify:
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// lastLoggedID returns the ID of the last event in the log, reading back
+// from its end, or 0 if there is none
+func (l *eventFile) lastLoggedID() (int64, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read event log: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event log: %w", err)
+	}
+	end := info.Size()
+	for size := int64(4096); ; size *= 2 {
+		start := max(end-size, 0)
+		block := make([]byte, end-start)
+		if _, err := file.ReadAt(block, start); err != nil {
+			return 0, fmt.Errorf("failed to read event log: %w", err)
+		}
+
+		// The first line of a block that does not start the file may be
+		// cut off, so it is only read from a larger block
+		lines := strings.Split(string(block), "\n")
+		for i := len(lines) - 1; i > 0 || (i == 0 && start == 0); i-- {
+			var event Event
+			if json.Unmarshal([]byte(lines[i]), &event) == nil && event.ID > 0 {
+				return event.ID, nil
+			}
+		}
+		if start == 0 {
+			return 0, nil
+		}
+	}
+}
+
+// append writes one event, trimming the log when it has grown. Callers
+// hold l.mu and the file lock.
+func (l *eventFile) append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	l.written++
+	if l.written >= maxEvents {
+		l.written = 0
+		return l.trim()
+	}
+	return nil
+}
+
+// trim drops all but the newest maxEvents events once the log holds twice
+// that many. Callers hold l.mu and the file lock.
+func (l *eventFile) trim() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) < 2*maxEvents {
+		return nil
+	}
+
+	kept := strings.Join(lines[len(lines)-maxEvents:], "")
+	if !strings.HasSuffix(kept, "\n") {
+		kept += "\n"
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(kept), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventIDsIncreaseAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+	local := NewEventLog(dir)
+	local.Publish(EventQueueAdded, "https://example.com/a", nil)
+	first := local.LastID()
+
+	// Another process, whose clock runs an hour ahead, appends an event
+	ahead := time.Now().Add(time.Hour).UnixNano()
+	line := fmt.Sprintf(`{"id":%d,"type":%q,"time":%q}`+"\n", ahead, EventQueueUpdated, time.Now().Format(time.RFC3339Nano))
+	file, err := os.OpenFile(filepath.Join(dir, ".silvia", "events.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(line); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	// A follower that has seen the other process's event still gets the
+	// ones published here afterwards
+	local.Publish(EventQueueRemoved, "https://example.com/a", nil)
+	events, err := NewEventLog(dir).Since(ahead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != EventQueueRemoved {
+		t.Fatalf("events after %d = %+v, want the %s event", ahead, events, EventQueueRemoved)
+	}
+
+	all, err := local.Since(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].ID <= all[i-1].ID {
+			t.Errorf("event %d has ID %d, not above %d", i, all[i].ID, all[i-1].ID)
+		}
+	}
+	if all[0].ID != first {
+		t.Errorf("first ID = %d, want %d", all[0].ID, first)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".silvia", "events.jsonl.lock")); !os.IsNotExist(err) {
+		t.Errorf("lock left behind: %v", err)
+	}
+}
+
+func TestLastLoggedIDReadsBackFromTheEnd(t *testing.T) {
+	log := NewEventLog(t.TempDir())
+	if id := log.LastID(); id != 0 {
+		t.Errorf("LastID() of an empty log = %d, want 0", id)
+	}
+
+	// Events larger than the first block read from the end
+	big := map[string]string{"text": strings.Repeat("x", 10000)}
+	for range 3 {
+		log.Publish(EventSourceIngested, "https://example.com/a", big)
+	}
+	events, err := log.Since(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if id := log.LastID(); id != events[2].ID {
+		t.Errorf("LastID() = %d, want %d", id, events[2].ID)
+	}
+}
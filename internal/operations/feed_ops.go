@@ -0,0 +1,426 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"silvia/internal/graph"
+	"silvia/internal/sources"
+)
+
+// feedsLockWait is how long a change waits for another process to finish
+// changing the feeds file; a lock older than staleFeedsLock was left behind
+const (
+	feedsLockWait  = 5 * time.Second
+	staleFeedsLock = 30 * time.Second
+)
+
+// FeedOps handles RSS/Atom feed subscriptions. Like the queue, the feeds file
+// is shared between processes, so every change to it goes through update.
+type FeedOps struct {
+	mu        sync.Mutex
+	graph     *graph.Manager
+	queue     *QueueOps
+	source    *SourceOps
+	fetcher   *sources.FeedFetcher
+	dataDir   string
+	feedsPath string
+}
+
+// NewFeedOps creates a new feed operations handler
+func NewFeedOps(graphManager *graph.Manager, queueOps *QueueOps, sourceOps *SourceOps, dataDir string) *FeedOps {
+	return &FeedOps{
+		graph:     graphManager,
+		queue:     queueOps,
+		source:    sourceOps,
+		fetcher:   sources.NewFeedFetcher(),
+		dataDir:   dataDir,
+		feedsPath: filepath.Join(dataDir, ".silvia", "feeds.json"),
+	}
+}
+
+// AddFeed subscribes to a feed. The feed is fetched once to validate it.
+func (f *FeedOps) AddFeed(ctx context.Context, feedURL string, priority int, keywords []string, matchEntities bool) (*FeedSubscription, error) {
+	if feedURL == "" {
+		return nil, NewOperationError("add feed", feedURL, invalidf("URL cannot be empty"))
+	}
+
+	if priority < 0 || priority > 2 {
+		return nil, NewOperationError("add feed", feedURL, invalidf("invalid priority: %d (must be 0-2)", priority))
+	}
+
+	feeds, err := f.loadFeeds()
+	if err != nil {
+		return nil, NewOperationError("add feed", feedURL, err)
+	}
+
+	for _, feed := range feeds {
+		if feed.URL == feedURL {
+			return nil, NewOperationError("add feed", feedURL, conflictf("already subscribed"))
+		}
+	}
+
+	parsed, err := f.fetcher.FetchFeed(ctx, feedURL)
+	if err != nil {
+		return nil, NewOperationError("add feed", feedURL, err)
+	}
+
+	name := parsed.Title
+	if name == "" {
+		name = feedURL
+	}
+
+	sub := &FeedSubscription{
+		Name:          name,
+		URL:           feedURL,
+		Priority:      priority,
+		Keywords:      normalizeKeywords(keywords),
+		MatchEntities: matchEntities,
+		AddedAt:       time.Now(),
+	}
+
+	// The feed may have been added elsewhere while it was being fetched
+	err = f.update(func(feeds []*FeedSubscription) ([]*FeedSubscription, error) {
+		for _, feed := range feeds {
+			if feed.URL == feedURL {
+				return nil, conflictf("already subscribed")
+			}
+		}
+		return append(feeds, sub), nil
+	})
+	if err != nil {
+		return nil, NewOperationError("add feed", feedURL, err)
+	}
+
+	return sub, nil
+}
+
+// ListFeeds returns all feed subscriptions
+func (f *FeedOps) ListFeeds() ([]*FeedSubscription, error) {
+	feeds, err := f.loadFeeds()
+	if err != nil {
+		return nil, NewOperationError("list feeds", "", err)
+	}
+	return feeds, nil
+}
+
+// RemoveFeed unsubscribes from a feed, identified by URL or name
+func (f *FeedOps) RemoveFeed(identifier string) error {
+	if identifier == "" {
+		return NewOperationError("remove feed", identifier, invalidf("feed cannot be empty"))
+	}
+
+	err := f.update(func(feeds []*FeedSubscription) ([]*FeedSubscription, error) {
+		remaining := make([]*FeedSubscription, 0, len(feeds))
+		for _, feed := range feeds {
+			if feed.URL != identifier && !strings.EqualFold(feed.Name, identifier) {
+				remaining = append(remaining, feed)
+			}
+		}
+		if len(remaining) == len(feeds) {
+			return nil, notFoundf("not subscribed")
+		}
+		return remaining, nil
+	})
+	if err != nil {
+		return NewOperationError("remove feed", identifier, err)
+	}
+
+	return nil
+}
+
+// PollFeeds fetches all subscribed feeds (or only the one matching identifier)
+// and queues entries that are new and pass the feed's filters. Feeds are
+// polled without holding the feeds file's lock, so only their poll state is
+// written back, onto whatever feeds are subscribed by then.
+func (f *FeedOps) PollFeeds(ctx context.Context, identifier string) ([]FeedPollResult, error) {
+	feeds, err := f.loadFeeds()
+	if err != nil {
+		return nil, NewOperationError("poll feeds", identifier, err)
+	}
+
+	var selected []*FeedSubscription
+	for _, feed := range feeds {
+		if identifier == "" || feed.URL == identifier || strings.EqualFold(feed.Name, identifier) {
+			selected = append(selected, feed)
+		}
+	}
+
+	if identifier != "" && len(selected) == 0 {
+		return nil, NewOperationError("poll feeds", identifier, notFoundf("not subscribed"))
+	}
+
+	// Build lookup of queued URLs once for all feeds, including those
+	// skipped or finished with
+	queued := make(map[string]bool)
+	if status, err := f.queue.GetQueue(QueueStatuses...); err == nil {
+		for _, item := range status.Items {
+			queued[item.URL] = true
+		}
+	}
+
+	// Entity names are only loaded when some feed filters on them
+	var entityTerms []string
+	for _, feed := range selected {
+		if feed.MatchEntities {
+			entityTerms = f.loadEntityTerms()
+			break
+		}
+	}
+
+	results := make([]FeedPollResult, 0, len(selected))
+	polled := make(map[string]*FeedSubscription)
+	for _, feed := range selected {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, f.pollFeed(ctx, feed, queued, entityTerms))
+		polled[feed.URL] = feed
+	}
+
+	// Feeds added or removed while polling are kept as they are now
+	err = f.update(func(current []*FeedSubscription) ([]*FeedSubscription, error) {
+		for _, feed := range current {
+			if p, ok := polled[feed.URL]; ok {
+				feed.LastPolled, feed.LastError = p.LastPolled, p.LastError
+			}
+		}
+		return current, nil
+	})
+	if err != nil {
+		return results, NewOperationError("poll feeds", identifier, err)
+	}
+
+	return results, nil
+}
+
+// pollFeed fetches a single feed and queues its new, matching entries
+func (f *FeedOps) pollFeed(ctx context.Context, feed *FeedSubscription, queued map[string]bool, entityTerms []string) FeedPollResult {
+	result := FeedPollResult{
+		Feed: feed.Name,
+		URL:  feed.URL,
+	}
+
+	parsed, err := f.fetcher.FetchFeed(ctx, feed.URL)
+	feed.LastPolled = time.Now()
+	if err != nil {
+		feed.LastError = err.Error()
+		result.Error = err.Error()
+		return result
+	}
+	feed.LastError = ""
+
+	result.Entries = len(parsed.Entries)
+	for _, entry := range parsed.Entries {
+		if entry.URL == "" {
+			continue
+		}
+
+		if queued[entry.URL] || f.source.IsSourceProcessed(entry.URL) {
+			result.Duplicates++
+			continue
+		}
+
+		matched, reason := matchFeedEntry(entry, feed, entityTerms)
+		if !matched {
+			result.Filtered++
+			continue
+		}
+
+		description := entry.Title
+		if reason != "" {
+			description = fmt.Sprintf("%s (matched %s)", entry.Title, reason)
+		}
+
+		if err := f.queue.AddToQueue(entry.URL, feed.Priority, feed.URL, description); err != nil {
+			result.Duplicates++
+			continue
+		}
+
+		queued[entry.URL] = true
+		result.Queued = append(result.Queued, QueueItem{
+			URL:         entry.URL,
+			Priority:    feed.Priority,
+			AddedAt:     time.Now(),
+			FromSource:  feed.URL,
+			Description: description,
+			Status:      QueuePending,
+		})
+	}
+
+	return result
+}
+
+// matchFeedEntry applies a feed's keyword and entity filters to an entry.
+// With no filters configured every entry matches. Otherwise an entry matches
+// if it mentions any keyword or any known entity title/alias.
+func matchFeedEntry(entry sources.FeedEntry, feed *FeedSubscription, entityTerms []string) (bool, string) {
+	if len(feed.Keywords) == 0 && !feed.MatchEntities {
+		return true, ""
+	}
+
+	text := strings.ToLower(entry.Title + " " + entry.Summary)
+
+	for _, keyword := range feed.Keywords {
+		if containsTerm(text, keyword) {
+			return true, fmt.Sprintf("keyword %q", keyword)
+		}
+	}
+
+	if feed.MatchEntities {
+		for _, term := range entityTerms {
+			if containsTerm(text, term) {
+				return true, fmt.Sprintf("entity %q", term)
+			}
+		}
+	}
+
+	return false, ""
+}
+
+// containsTerm checks for a lowercase term in text on word boundaries
+func containsTerm(text, term string) bool {
+	if term == "" {
+		return false
+	}
+
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], term)
+		if idx < 0 {
+			return false
+		}
+		start := offset + idx
+		end := start + len(term)
+
+		beforeOK := start == 0 || !isWordChar(text[start-1])
+		afterOK := end == len(text) || !isWordChar(text[end])
+		if beforeOK && afterOK {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// loadEntityTerms collects lowercase titles and aliases of all non-source entities
+func (f *FeedOps) loadEntityTerms() []string {
+	entities, err := f.graph.ListAllEntities()
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		term = strings.ToLower(strings.TrimSpace(term))
+		// Very short names produce too many false positives
+		if len(term) < 3 || seen[term] {
+			return
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+
+	for _, entity := range entities {
+		if strings.HasPrefix(entity.Metadata.ID, "sources/") {
+			continue
+		}
+		add(entity.Title)
+		for _, alias := range entity.Metadata.Aliases {
+			add(alias)
+		}
+	}
+
+	// Prefer longer, more specific names when reporting a match
+	sort.Slice(terms, func(i, j int) bool {
+		return len(terms[i]) > len(terms[j])
+	})
+
+	return terms
+}
+
+func normalizeKeywords(keywords []string) []string {
+	var normalized []string
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" {
+			normalized = append(normalized, keyword)
+		}
+	}
+	return normalized
+}
+
+// update applies change to the feeds file while holding its lock, then
+// writes back the feeds it returns
+func (f *FeedOps) update(change func(feeds []*FeedSubscription) ([]*FeedSubscription, error)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unlock, err := lockFile(f.feedsPath+".lock", "feeds", feedsLockWait, staleFeedsLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	feeds, err := f.loadFeeds()
+	if err != nil {
+		return err
+	}
+	feeds, err = change(feeds)
+	if err != nil {
+		return err
+	}
+	return f.saveFeeds(feeds)
+}
+
+// loadFeeds loads feed subscriptions from disk
+func (f *FeedOps) loadFeeds() ([]*FeedSubscription, error) {
+	data, err := os.ReadFile(f.feedsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*FeedSubscription{}, nil
+		}
+		return nil, fmt.Errorf("failed to read feeds file: %w", err)
+	}
+
+	var feeds []*FeedSubscription
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("failed to parse feeds file: %w", err)
+	}
+
+	return feeds, nil
+}
+
+// saveFeeds saves feed subscriptions to disk
+func (f *FeedOps) saveFeeds(feeds []*FeedSubscription) error {
+	dir := filepath.Dir(f.feedsPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(feeds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal feeds: %w", err)
+	}
+
+	tmp := f.feedsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write feeds file: %w", err)
+	}
+	if err := os.Rename(tmp, f.feedsPath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write feeds file: %w", err)
+	}
+
+	return nil
+}
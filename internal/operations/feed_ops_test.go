@@ -0,0 +1,71 @@
+package operations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"silvia/internal/llm"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>City Desk</title>
+<item><title>Council Approves Transit Plan</title><link>https://example.com/2024/transit-plan</link></item>
+</channel></rss>`
+
+func TestPollFeedsKeepsFeedsChangedWhilePolling(t *testing.T) {
+	ops, _ := newTestOps(t, llm.NewFakeProvider(), t.TempDir(), llm.CacheOff)
+	ctx := context.Background()
+
+	// Polling the city feed subscribes to the county feed and drops the
+	// state feed before the poll finishes
+	var polling atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/city" && polling.Swap(false) {
+			if _, err := ops.Feed.AddFeed(ctx, "http://"+r.Host+"/county", 1, nil, false); err != nil {
+				t.Errorf("AddFeed during poll: %v", err)
+			}
+			if err := ops.Feed.RemoveFeed("http://" + r.Host + "/state"); err != nil {
+				t.Errorf("RemoveFeed during poll: %v", err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/city", "/state"} {
+		if _, err := ops.Feed.AddFeed(ctx, server.URL+path, 1, nil, false); err != nil {
+			t.Fatalf("AddFeed %s: %v", path, err)
+		}
+	}
+
+	polling.Store(true)
+	results, err := ops.Feed.PollFeeds(ctx, server.URL+"/city")
+	if err != nil {
+		t.Fatalf("PollFeeds: %v", err)
+	}
+	if len(results) != 1 || results[0].Error != "" {
+		t.Fatalf("PollFeeds = %+v, want one successful poll", results)
+	}
+
+	feeds, err := ops.Feed.ListFeeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	polled := make(map[string]bool)
+	for _, feed := range feeds {
+		polled[feed.URL] = !feed.LastPolled.IsZero()
+	}
+	want := map[string]bool{server.URL + "/city": true, server.URL + "/county": false}
+	if len(polled) != len(want) {
+		t.Fatalf("feeds after poll = %v, want %v", polled, want)
+	}
+	for url, wasPolled := range want {
+		if got, ok := polled[url]; !ok || got != wasPolled {
+			t.Errorf("feed %s: subscribed %v, polled %v; want subscribed, polled %v", url, ok, got, wasPolled)
+		}
+	}
+}
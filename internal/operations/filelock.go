@@ -0,0 +1,40 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockFile takes the lock file at path, which every silvia process sharing
+// the data directory checks before changing what it guards, waiting up to
+// wait for a process that holds it. A lock older than stale was left behind
+// by a process that stopped and is broken. It returns how to release the
+// lock; what names the guarded file in errors.
+func lockFile(path, what string, wait, stale time.Duration) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %s: %w", what, err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > stale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another process (remove %s if none is running)", what, path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
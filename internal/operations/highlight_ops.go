@@ -0,0 +1,206 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"silvia/internal/sources"
+)
+
+// Highlights: passages the user selects in the browser are archived with
+// the capture, and each is recorded as a quote or claim in data/quotes that
+// points back to the exact passage.
+
+// quotesDir returns where quote records are kept
+func (s *SourceOps) quotesDir() string {
+	return filepath.Join(s.dataDir, "quotes")
+}
+
+// quoteID names the quote recorded from a highlight. Highlight IDs are
+// stable, so capturing a passage again updates the same quote.
+func quoteID(highlightID string) string {
+	return "q-" + strings.TrimPrefix(highlightID, "h-")
+}
+
+// saveQuotes records a quote or claim for each of the source's highlights,
+// linked to the extracted entities the passage names
+func (s *SourceOps) saveQuotes(source *sources.Source, archivePath string, entities []ExtractedEntity) ([]Quote, error) {
+	if len(source.Highlights) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(s.quotesDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create quotes directory: %w", err)
+	}
+
+	now := time.Now()
+	quotes := make([]Quote, 0, len(source.Highlights))
+	for _, h := range source.Highlights {
+		quote := Quote{
+			ID:          quoteID(h.ID),
+			Kind:        h.Kind,
+			Text:        h.Text,
+			Note:        h.Note,
+			SourceURL:   source.URL,
+			ArchivePath: archivePath,
+			HighlightID: h.ID,
+			Anchor:      h.Anchor,
+			Entities:    mentionedEntities(h.Text, entities),
+			Created:     now,
+			CreatedBy:   s.actor,
+		}
+
+		path := filepath.Join(s.quotesDir(), quote.ID+".json")
+		created := true
+		if existing, err := readQuote(path); err == nil {
+			quote.Created, quote.CreatedBy = existing.Created, existing.CreatedBy
+			created = false
+		}
+
+		data, err := json.MarshalIndent(quote, "", "  ")
+		if err != nil {
+			return quotes, err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return quotes, fmt.Errorf("failed to write quote %s: %w", quote.ID, err)
+		}
+		quotes = append(quotes, quote)
+
+		if created {
+			s.events.Publish(EventQuoteCreated, quote.ID, map[string]any{
+				"kind":      quote.Kind,
+				"url":       quote.SourceURL,
+				"highlight": quote.HighlightID,
+			})
+		}
+	}
+	return quotes, nil
+}
+
+// mentionedEntities returns the IDs of the entities whose names appear in
+// text
+func mentionedEntities(text string, entities []ExtractedEntity) []string {
+	lower := strings.ToLower(text)
+	var ids []string
+	for _, entity := range entities {
+		if entity.Name != "" && strings.Contains(lower, strings.ToLower(entity.Name)) && !slices.Contains(ids, entity.ID) {
+			ids = append(ids, entity.ID)
+		}
+	}
+	return ids
+}
+
+func readQuote(path string) (*Quote, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var quote Quote
+	if err := json.Unmarshal(data, &quote); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// ListHighlights returns the highlights made in every capture of a URL,
+// oldest capture first, so the browser extension can show them again. A
+// passage highlighted in several captures is listed once, with its latest.
+func (s *SourceOps) ListHighlights(url string) ([]SourceHighlight, error) {
+	if url == "" {
+		return nil, NewOperationError("list highlights", "", invalidf("url is required"))
+	}
+
+	latest := make(map[string]SourceHighlight)
+	var order []string
+	root := filepath.Join(s.dataDir, "sources")
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		if _, err := os.Stat(sources.HighlightsPath(path)); err != nil {
+			return nil
+		}
+		archived, ok := readArchiveHeader(path, info)
+		if !ok || archived.URL != url {
+			return nil
+		}
+		highlights, err := sources.LoadHighlights(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, h := range highlights {
+			existing, seen := latest[h.ID]
+			if seen && !archived.FetchedAt.After(existing.CapturedAt) {
+				continue
+			}
+			if !seen {
+				order = append(order, h.ID)
+			}
+			latest[h.ID] = SourceHighlight{
+				Highlight:   h,
+				ArchivePath: path,
+				CapturedAt:  archived.FetchedAt,
+				QuoteID:     quoteID(h.ID),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, NewOperationError("list highlights", url, err)
+	}
+
+	// Within a capture, highlights stay in the order they were made
+	highlights := make([]SourceHighlight, 0, len(order))
+	for _, id := range order {
+		highlights = append(highlights, latest[id])
+	}
+	slices.SortStableFunc(highlights, func(a, b SourceHighlight) int {
+		return a.CapturedAt.Compare(b.CapturedAt)
+	})
+	return highlights, nil
+}
+
+// ListQuotes returns the quotes and claims recorded from highlights, newest
+// first. A non-empty entityID keeps only those naming that entity, and a
+// non-empty url only those from that source.
+func (s *SourceOps) ListQuotes(entityID, url string) ([]Quote, error) {
+	entries, err := os.ReadDir(s.quotesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, NewOperationError("list quotes", "", err)
+	}
+
+	quotes := []Quote{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		quote, err := readQuote(filepath.Join(s.quotesDir(), entry.Name()))
+		if err != nil {
+			continue
+		}
+		if entityID != "" && !slices.Contains(quote.Entities, entityID) {
+			continue
+		}
+		if url != "" && quote.SourceURL != url {
+			continue
+		}
+		quotes = append(quotes, *quote)
+	}
+	slices.SortFunc(quotes, func(a, b Quote) int {
+		if c := b.Created.Compare(a.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return quotes, nil
+}
@@ -0,0 +1,643 @@
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"silvia/internal/resilience"
+	"silvia/internal/sources"
+)
+
+// DefaultJobWorkers is how many jobs run at once unless SetWorkers is called
+const DefaultJobWorkers = 2
+
+// A job belongs to the process that submitted it, which renews its
+// heartbeat every jobHeartbeatInterval until it finishes. An unfinished job
+// whose heartbeat is older than staleJob lost its process.
+const (
+	jobHeartbeatInterval = 15 * time.Second
+	staleJob             = time.Minute
+)
+
+// JobKind is the kind of work a job does
+type JobKind string
+
+// Job kinds
+const (
+	JobIngest JobKind = "ingest" // Ingest one source, from its URL or captured HTML
+	JobRefine JobKind = "refine" // Refine an entity from its sources
+	JobMerge  JobKind = "merge"  // Merge one entity into another
+	JobQueue  JobKind = "queue"  // Ingest sources from the queue
+)
+
+// JobStatus is where a job is in its life
+type JobStatus string
+
+// Job statuses
+const (
+	JobPending   JobStatus = "pending"   // Waiting for a worker
+	JobRunning   JobStatus = "running"   // Being worked on
+	JobDone      JobStatus = "done"      // Finished successfully
+	JobFailed    JobStatus = "failed"    // Finished with an error
+	JobCancelled JobStatus = "cancelled" // Cancelled before it finished
+)
+
+// Stages reported by refine and merge jobs
+const (
+	StageRefine = "refine"
+	StageMerge  = "merge"
+)
+
+// Finished reports whether a job with this status will not change again
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// JobParams holds the arguments for a job; which ones apply depends on the
+// kind
+type JobParams struct {
+	URL        string              `json:"url,omitempty"`        // ingest
+	Force      bool                `json:"force,omitempty"`      // ingest, queue: reprocess sources already ingested
+	HTML       string              `json:"-"`                    // ingest: captured page, used instead of fetching
+	Title      string              `json:"title,omitempty"`      // ingest: title of the captured page
+	Metadata   map[string]string   `json:"metadata,omitempty"`   // ingest: metadata of the captured page
+	Highlights []sources.Highlight `json:"highlights,omitempty"` // ingest: passages highlighted in the captured page
+	EntityID   string              `json:"entity_id,omitempty"`  // refine
+	Guidance   string              `json:"guidance,omitempty"`   // refine
+	Entity1ID  string              `json:"entity1_id,omitempty"` // merge: the entity kept
+	Entity2ID  string              `json:"entity2_id,omitempty"` // merge: the entity merged into it
+	Limit      int                 `json:"limit,omitempty"`      // queue: sources to process, 0 for all
+	Actor      string              `json:"actor,omitempty"`      // API token that submitted the job; its changes are attributed to it
+}
+
+// JobProgress reports the stage a job is at. For queue jobs Item and Items
+// count sources; Current and Total count chunks within the stage.
+type JobProgress struct {
+	IngestProgress
+	Item  int `json:"item,omitempty"`
+	Items int `json:"items,omitempty"`
+}
+
+// JobResult summarizes what a finished job did
+type JobResult struct {
+	Summary  string   `json:"summary"`
+	Entities []string `json:"entities,omitempty"` // IDs of entities created or changed
+	Failures []string `json:"failures,omitempty"` // Sources that could not be ingested, or only in part
+}
+
+// Job is a long-running operation run in the background
+type Job struct {
+	ID       string      `json:"id"`
+	Kind     JobKind     `json:"kind"`
+	Status   JobStatus   `json:"status"`
+	Params   JobParams   `json:"params"`
+	Progress JobProgress `json:"progress"`
+	Result   *JobResult  `json:"result,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Created  time.Time   `json:"created"`
+	Started  *time.Time  `json:"started,omitempty"`
+	Finished *time.Time  `json:"finished,omitempty"`
+
+	Owner     int        `json:"owner,omitempty"`     // PID of the process running the job
+	Heartbeat *time.Time `json:"heartbeat,omitempty"` // When the owner last said it was still running
+}
+
+// JobOps runs jobs on a bounded pool of workers and keeps their state as
+// one JSON file per job, so progress survives the request that started it
+type JobOps struct {
+	ops     *Operations
+	dir     string
+	workers int
+	events  *EventLog
+
+	mu      sync.Mutex
+	beating bool // Whether heartbeats are being sent
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+	pending []string // IDs of jobs waiting for a worker, oldest first
+	running int
+}
+
+// NewJobOps creates a job manager that runs work through ops
+func NewJobOps(ops *Operations, dataDir string, workers int) *JobOps {
+	if workers < 1 {
+		workers = DefaultJobWorkers
+	}
+	return &JobOps{
+		ops:     ops,
+		dir:     filepath.Join(dataDir, ".silvia", "jobs"),
+		workers: workers,
+		jobs:    make(map[string]*Job),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// SetWorkers changes how many jobs may run at once
+func (j *JobOps) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	j.mu.Lock()
+	j.workers = workers
+	j.schedule()
+	j.mu.Unlock()
+}
+
+// Submit validates and queues a job, returning its state when queued
+func (j *JobOps) Submit(kind JobKind, params JobParams) (*Job, error) {
+	if err := validateJob(kind, params); err != nil {
+		return nil, NewOperationError("submit job", string(kind), err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.load(); err != nil {
+		return nil, NewOperationError("submit job", string(kind), err)
+	}
+
+	job := &Job{
+		ID:      newJobID(),
+		Kind:    kind,
+		Status:  JobPending,
+		Params:  params,
+		Created: time.Now(),
+		Owner:   os.Getpid(),
+	}
+	j.jobs[job.ID] = job
+	j.pending = append(j.pending, job.ID)
+	if err := j.save(job); err != nil {
+		delete(j.jobs, job.ID)
+		j.pending = j.pending[:len(j.pending)-1]
+		return nil, NewOperationError("submit job", string(kind), err)
+	}
+	j.schedule()
+	if !j.beating {
+		j.beating = true
+		go j.beat()
+	}
+
+	return j.snapshot(job), nil
+}
+
+// GetJob returns a copy of a job's current state
+func (j *JobOps) GetJob(id string) (*Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.load(); err != nil {
+		return nil, NewOperationError("get job", id, err)
+	}
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return nil, NewOperationError("get job", id, notFoundf("job not found"))
+	}
+	return j.snapshot(job), nil
+}
+
+// ListJobs returns jobs newest first, optionally only those with status
+func (j *JobOps) ListJobs(status JobStatus) ([]*Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.load(); err != nil {
+		return nil, NewOperationError("list jobs", "", err)
+	}
+
+	jobs := make([]*Job, 0, len(j.jobs))
+	for _, job := range j.jobs {
+		if status == "" || job.Status == status {
+			jobs = append(jobs, j.snapshot(job))
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].Created.After(jobs[b].Created)
+	})
+	return jobs, nil
+}
+
+// ActiveCount returns how many jobs are pending or running
+func (j *JobOps) ActiveCount() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pending) + j.running
+}
+
+// CancelJob stops a pending or running job. A running job stops at its next
+// cancellation point; its status changes once it has.
+func (j *JobOps) CancelJob(id string) (*Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.load(); err != nil {
+		return nil, NewOperationError("cancel job", id, err)
+	}
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return nil, NewOperationError("cancel job", id, notFoundf("job not found"))
+	}
+	if job.Status.Finished() {
+		return nil, NewOperationError("cancel job", id, conflictf("job already %s", job.Status))
+	}
+	if !j.owns(id) {
+		return nil, NewOperationError("cancel job", id, conflictf("job is run by another process (pid %d)", job.Owner))
+	}
+
+	if job.Status == JobPending {
+		j.pending = slices.DeleteFunc(j.pending, func(pendingID string) bool { return pendingID == id })
+		j.finish(job, nil, context.Canceled)
+	} else if cancel := j.cancels[id]; cancel != nil {
+		cancel()
+	}
+	return j.snapshot(job), nil
+}
+
+// CancelAll cancels every pending and running job, for use at shutdown
+func (j *JobOps) CancelAll() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, id := range j.pending {
+		if job, ok := j.jobs[id]; ok {
+			j.finish(job, nil, context.Canceled)
+		}
+	}
+	j.pending = nil
+	for _, cancel := range j.cancels {
+		cancel()
+	}
+}
+
+// schedule starts pending jobs while workers are free. Callers hold j.mu.
+func (j *JobOps) schedule() {
+	for j.running < j.workers && len(j.pending) > 0 {
+		id := j.pending[0]
+		j.pending = j.pending[1:]
+		job, ok := j.jobs[id]
+		if !ok {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		j.cancels[id] = cancel
+		j.running++
+
+		now := time.Now()
+		job.Status = JobRunning
+		job.Started = &now
+		j.saveOrLog(job)
+
+		go j.run(ctx, job)
+	}
+}
+
+// run does a job's work on a worker and records how it ended
+func (j *JobOps) run(ctx context.Context, job *Job) {
+	result, err := j.execute(ctx, job)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cancel := j.cancels[job.ID]; cancel != nil {
+		cancel()
+	}
+	delete(j.cancels, job.ID)
+	j.running--
+	if ctx.Err() != nil && err != nil {
+		err = context.Canceled
+	}
+	j.finish(job, result, err)
+	j.schedule()
+}
+
+// finish moves a job to its final status. Callers hold j.mu.
+func (j *JobOps) finish(job *Job, result *JobResult, err error) {
+	now := time.Now()
+	job.Finished = &now
+	job.Result = result
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = JobCancelled
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = JobDone
+	}
+	j.saveOrLog(job)
+	j.events.As(job.Params.Actor).Publish(EventJobFinished, job.ID, map[string]any{
+		"kind":   job.Kind,
+		"status": job.Status,
+		"error":  job.Error,
+		"result": job.Result,
+	})
+}
+
+// execute does the work for a job's kind
+func (j *JobOps) execute(ctx context.Context, job *Job) (*JobResult, error) {
+	params := job.Params
+	ops := j.ops.As(params.Actor)
+
+	switch job.Kind {
+	case JobIngest:
+		result, err := j.ingest(ctx, ops, params, func(p IngestProgress) {
+			j.setProgress(job, JobProgress{IngestProgress: p})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ingestJobResult(result), nil
+
+	case JobRefine:
+		j.setProgress(job, JobProgress{IngestProgress: IngestProgress{Stage: StageRefine, Detail: params.EntityID}})
+		entity, err := ops.Entity.RefineEntity(ctx, params.EntityID, params.Guidance)
+		if err != nil {
+			return nil, err
+		}
+		return &JobResult{
+			Summary:  "Refined " + entity.Metadata.ID,
+			Entities: []string{entity.Metadata.ID},
+		}, nil
+
+	case JobMerge:
+		j.setProgress(job, JobProgress{IngestProgress: IngestProgress{Stage: StageMerge, Detail: params.Entity2ID + " into " + params.Entity1ID}})
+		result, err := ops.Entity.MergeEntities(ctx, params.Entity1ID, params.Entity2ID)
+		if err != nil {
+			return nil, err
+		}
+		return &JobResult{
+			Summary:  fmt.Sprintf("Merged %s into %s, updating %d files", result.DeletedEntityID, result.MergedEntity.Metadata.ID, len(result.UpdatedFiles)),
+			Entities: []string{result.MergedEntity.Metadata.ID},
+		}, nil
+
+	case JobQueue:
+		return j.processQueue(ctx, job)
+
+	default:
+		return nil, fmt.Errorf("unknown job kind: %s", job.Kind)
+	}
+}
+
+// ingest ingests captured HTML if the job has it, fetching the URL otherwise
+func (j *JobOps) ingest(ctx context.Context, ops *Operations, params JobParams, onProgress IngestProgressFunc) (*IngestResult, error) {
+	if params.HTML != "" {
+		return ops.Source.ExtractFromHTMLWithProgress(ctx, params.URL, params.HTML, params.Title, params.Metadata, params.Highlights, onProgress)
+	}
+	return ops.Source.IngestSourceWithProgress(ctx, params.URL, params.Force, onProgress)
+}
+
+// processQueue ingests pending sources from the queue, highest priority
+// first. Each is claimed before it is ingested, and the ingest marks it done
+// or failed; a failure is recorded and the job moves on to the next one.
+// While the LLM provider is failing the job pauses instead, leaving the
+// rest of the queue pending rather than failing every source in turn.
+func (j *JobOps) processQueue(ctx context.Context, job *Job) (*JobResult, error) {
+	ops := j.ops.As(job.Params.Actor)
+	status, err := ops.Queue.GetQueue(QueuePending)
+	if err != nil {
+		return nil, err
+	}
+	items := status.TotalCount
+	if job.Params.Limit > 0 && job.Params.Limit < items {
+		items = job.Params.Limit
+	}
+
+	result := &JobResult{}
+	processed := 0
+	for item := 1; item <= items; item++ {
+		if err := ctx.Err(); err != nil {
+			result.Summary = fmt.Sprintf("Stopped after %d of %d sources", processed, items)
+			return result, err
+		}
+
+		next, err := ops.Queue.ProcessNextItem()
+		if err != nil {
+			return result, err
+		}
+		if next == nil {
+			break // Claimed by someone else
+		}
+
+		ingested, err := ops.Source.IngestSourceWithProgress(ctx, next.URL, job.Params.Force, func(p IngestProgress) {
+			j.setProgress(job, JobProgress{IngestProgress: p, Item: item, Items: items})
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				// The interrupted source went back to pending
+				result.Summary = fmt.Sprintf("Stopped after %d of %d sources", processed, items)
+				return result, ctx.Err()
+			}
+			if errors.Is(err, resilience.ErrCircuitOpen) {
+				// So did this one; the rest were never claimed
+				result.Summary = fmt.Sprintf("Paused after %d of %d sources: %v", processed, items, err)
+				return result, nil
+			}
+			result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", next.URL, err))
+			continue
+		}
+		processed++
+		ingestedResult := ingestJobResult(ingested)
+		result.Entities = append(result.Entities, ingestedResult.Entities...)
+		for _, failure := range ingestedResult.Failures {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s: %s", next.URL, failure))
+		}
+	}
+
+	result.Summary = fmt.Sprintf("Ingested %d of %d sources", processed, items)
+	if len(result.Failures) > 0 {
+		result.Summary += fmt.Sprintf(", %d failed", len(result.Failures))
+	}
+	return result, nil
+}
+
+// setProgress records a job's progress and saves it
+func (j *JobOps) setProgress(job *Job, progress JobProgress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job.Progress = progress
+	j.saveOrLog(job)
+	j.events.As(job.Params.Actor).Publish(EventJobProgress, job.ID, map[string]any{
+		"kind":     job.Kind,
+		"progress": progress,
+	})
+}
+
+// ingestJobResult summarizes an ingest for a job result
+func ingestJobResult(result *IngestResult) *JobResult {
+	jobResult := &JobResult{
+		Summary: fmt.Sprintf("Extracted %d entities and %d links from %s", len(result.ExtractedEntities), len(result.ExtractedLinks), result.SourceURL),
+	}
+	if len(result.Quotes) > 0 {
+		jobResult.Summary += fmt.Sprintf(", recording %d highlights", len(result.Quotes))
+	}
+	if err := ingestOutcome(result, nil); err != nil {
+		jobResult.Failures = append(jobResult.Failures, err.Error())
+	}
+	for _, entity := range result.ExtractedEntities {
+		if entity.IsNew || entity.WasUpdated {
+			jobResult.Entities = append(jobResult.Entities, entity.ID)
+		}
+	}
+	return jobResult
+}
+
+// validateJob checks that a job has the parameters its kind needs
+func validateJob(kind JobKind, params JobParams) error {
+	switch kind {
+	case JobIngest:
+		if params.URL == "" {
+			return invalidf("URL is required")
+		}
+		if len(params.Highlights) > 0 && params.HTML == "" {
+			return invalidf("highlights need the captured page's HTML")
+		}
+		if _, err := sources.NormalizeHighlights(params.URL, params.Highlights); err != nil {
+			return invalidf("%v", err)
+		}
+	case JobRefine:
+		if params.EntityID == "" {
+			return invalidf("entity_id is required")
+		}
+	case JobMerge:
+		if params.Entity1ID == "" || params.Entity2ID == "" {
+			return invalidf("entity1_id and entity2_id are required")
+		}
+		if params.Entity1ID == params.Entity2ID {
+			return invalidf("cannot merge an entity with itself")
+		}
+	case JobQueue:
+		if params.Limit < 0 {
+			return invalidf("limit cannot be negative")
+		}
+	default:
+		return invalidf("unknown job kind: %q (must be ingest, refine, merge or queue)", kind)
+	}
+	return nil
+}
+
+// newJobID names a job by when it was submitted, with a random suffix so
+// jobs submitted in the same second differ
+func newJobID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// snapshot copies a job so callers can read it without holding j.mu
+func (j *JobOps) snapshot(job *Job) *Job {
+	copied := *job
+	if job.Result != nil {
+		result := *job.Result
+		copied.Result = &result
+	}
+	return &copied
+}
+
+// load reads saved jobs this process does not have yet and rereads the
+// unfinished ones other processes own, so their progress shows. A job
+// whose owner stopped renewing its heartbeat is marked failed, since its
+// work was lost with the process. Callers hold j.mu.
+func (j *JobOps) load() error {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read jobs directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if known, ok := j.jobs[strings.TrimSuffix(name, ".json")]; ok && (known.Status.Finished() || j.owns(known.ID)) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, name))
+		if err != nil {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil || job.ID == "" {
+			continue // Skip unreadable files rather than failing every request
+		}
+		if !job.Status.Finished() && (job.Heartbeat == nil || time.Since(*job.Heartbeat) > staleJob) {
+			now := time.Now()
+			job.Status = JobFailed
+			job.Error = "interrupted: silvia stopped before the job finished"
+			job.Finished = &now
+			j.saveOrLog(&job)
+		}
+		j.jobs[job.ID] = &job
+	}
+	return nil
+}
+
+// owns reports whether this process is running or about to run a job.
+// Callers hold j.mu.
+func (j *JobOps) owns(id string) bool {
+	_, running := j.cancels[id]
+	return running || slices.Contains(j.pending, id)
+}
+
+// beat renews the heartbeat of this process's unfinished jobs until none
+// are left
+func (j *JobOps) beat() {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.mu.Lock()
+		if len(j.pending) == 0 && len(j.cancels) == 0 {
+			j.beating = false
+			j.mu.Unlock()
+			return
+		}
+		for _, job := range j.jobs {
+			if !job.Status.Finished() && j.owns(job.ID) {
+				j.saveOrLog(job)
+			}
+		}
+		j.mu.Unlock()
+	}
+}
+
+// save writes a job's state, renewing the heartbeat of an unfinished one.
+// Callers hold j.mu.
+func (j *JobOps) save(job *Job) error {
+	if !job.Status.Finished() {
+		now := time.Now()
+		job.Heartbeat = &now
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	// Write then rename so a crash never leaves half a job
+	path := filepath.Join(j.dir, job.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
+
+// saveOrLog saves a job whose state has already changed in memory, where
+// a failed write should not stop the job
+func (j *JobOps) saveOrLog(job *Job) {
+	if err := j.save(job); err != nil {
+		fmt.Printf("Warning: failed to save job %s: %v\n", job.ID, err)
+	}
+}
@@ -0,0 +1,70 @@
+package operations
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJobsOfOtherProcesses(t *testing.T) {
+	dataDir := t.TempDir()
+	dir := filepath.Join(dataDir, ".silvia", "jobs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := time.Now()
+	stale := time.Now().Add(-2 * staleJob)
+	saved := []Job{
+		{ID: "live", Kind: JobIngest, Status: JobRunning, Owner: 1, Heartbeat: &fresh},
+		{ID: "lost", Kind: JobIngest, Status: JobRunning, Owner: 1, Heartbeat: &stale},
+		{ID: "old", Kind: JobIngest, Status: JobPending}, // Saved before jobs had heartbeats
+		{ID: "done", Kind: JobIngest, Status: JobDone, Owner: 1},
+	}
+	write := func(job Job) {
+		data, err := json.Marshal(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, job.ID+".json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, job := range saved {
+		write(job)
+	}
+
+	jobs := NewJobOps(nil, dataDir, 1)
+	want := map[string]JobStatus{"live": JobRunning, "lost": JobFailed, "old": JobFailed, "done": JobDone}
+	for id, status := range want {
+		job, err := jobs.GetJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != status {
+			t.Errorf("%s is %s, want %s", id, job.Status, status)
+		}
+	}
+
+	// Another process's job cannot be cancelled here, and its progress
+	// shows as its owner saves it
+	if _, err := jobs.CancelJob("live"); ErrorCode(err) != CodeConflict {
+		t.Errorf("cancelling another process's job: %v, want a conflict", err)
+	}
+	live := saved[0]
+	live.Progress.Stage = StageExtract
+	live.Heartbeat = &fresh
+	write(live)
+	if job, err := jobs.GetJob("live"); err != nil || job.Progress.Stage != StageExtract {
+		t.Errorf("GetJob(live) = %+v, %v; want its new progress", job, err)
+	}
+
+	// Once its owner stops, the job is marked failed
+	live.Heartbeat = &stale
+	write(live)
+	if job, err := jobs.GetJob("live"); err != nil || job.Status != JobFailed {
+		t.Errorf("GetJob(live) = %+v, %v; want failed after its heartbeat stopped", job, err)
+	}
+}
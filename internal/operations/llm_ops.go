@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"silvia/internal/llm"
 )
@@ -20,6 +21,11 @@ func NewLLMOps(llmClient *llm.Client) *LLMOps {
 	}
 }
 
+// Client returns the underlying LLM client, or nil if none is configured
+func (l *LLMOps) Client() *llm.Client {
+	return l.llm
+}
+
 // FunctionCallRequest represents a request for LLM function calling
 type FunctionCallRequest struct {
 	Messages   []LLMMessage
@@ -74,7 +80,6 @@ func (l *LLMOps) ExecuteFunctionCall(ctx context.Context, userInput string, tool
 
 	// Create the LLM request
 	llmRequest := llm.FunctionCallRequest{
-		Model: "openai/gpt-4-turbo", // Use a model that supports function calling
 		Messages: []llm.Message{
 			{
 				Role:    "user",
@@ -86,7 +91,7 @@ func (l *LLMOps) ExecuteFunctionCall(ctx context.Context, userInput string, tool
 	}
 
 	// Call the LLM with function calling
-	llmResponse, err := l.llm.CompleteWithFunctions(ctx, llmRequest)
+	llmResponse, err := l.llm.ForTask(llm.TaskChatTools).CompleteWithFunctions(ctx, llmRequest)
 	if err != nil {
 		return nil, fmt.Errorf("LLM function call failed: %w", err)
 	}
@@ -113,74 +118,29 @@ func (l *LLMOps) ExecuteFunctionCall(ctx context.Context, userInput string, tool
 	return response, nil
 }
 
-// RefineEntityWithLLM uses the LLM to refine an entity's content
-func (l *LLMOps) RefineEntityWithLLM(ctx context.Context, entityID string, currentContent string, instructions string) (string, error) {
-	if l.llm == nil {
-		return "", fmt.Errorf("LLM client not initialized")
+// GetUsage summarizes recorded LLM usage since a time (zero for all time),
+// grouped by operation, kind, model, or source
+func (l *LLMOps) GetUsage(since time.Time, by string) (*UsageReport, error) {
+	if l.llm == nil || l.llm.UsageLedger() == nil {
+		return nil, NewOperationError("get usage", "", fmt.Errorf("usage tracking not enabled"))
 	}
 
-	prompt := fmt.Sprintf(`You are refining the content for entity: %s
-
-Current content:
-%s
-
-Instructions: %s
-
-Please provide the refined content, maintaining the same markdown format with wiki-links to other entities where appropriate.`, entityID, currentContent, instructions)
-
-	response, err := l.llm.Complete(ctx, prompt, "")
+	ledger := l.llm.UsageLedger()
+	entries, err := ledger.Entries(since)
 	if err != nil {
-		return "", fmt.Errorf("LLM refinement failed: %w", err)
-	}
-
-	return response, nil
-}
-
-// ExtractEntitiesFromText uses the LLM to extract entities from text
-func (l *LLMOps) ExtractEntitiesFromText(ctx context.Context, text string, sourceURL string) ([]LLMExtractedEntity, error) {
-	if l.llm == nil {
-		return nil, fmt.Errorf("LLM client not initialized")
+		return nil, NewOperationError("get usage", "", err)
 	}
 
-	prompt := fmt.Sprintf(`Extract entities from the following text. Return as JSON array with objects containing:
-- type: "person", "organization", "concept", "work", or "event"
-- id: a kebab-case identifier
-- name: the display name
-- description: a brief description
-- relationships: array of {target: "entity-id", type: "relationship-type"}
-
-Source URL: %s
-
-Text:
-%s`, sourceURL, text)
-
-	response, err := l.llm.Complete(ctx, prompt, "")
+	summary, err := llm.SummarizeUsage(entries, by)
 	if err != nil {
-		return nil, fmt.Errorf("entity extraction failed: %w", err)
-	}
-
-	// Parse the JSON response
-	var entities []LLMExtractedEntity
-	if err := json.Unmarshal([]byte(response), &entities); err != nil {
-		// If parsing fails, return empty list rather than error
-		// The LLM might not always return valid JSON
-		return []LLMExtractedEntity{}, nil
+		return nil, NewOperationError("get usage", "", err)
 	}
-
-	return entities, nil
-}
-
-// LLMExtractedEntity represents an entity extracted by the LLM
-type LLMExtractedEntity struct {
-	Type          string                     `json:"type"`
-	ID            string                     `json:"id"`
-	Name          string                     `json:"name"`
-	Description   string                     `json:"description"`
-	Relationships []LLMExtractedRelationship `json:"relationships"`
-}
-
-// LLMExtractedRelationship represents a relationship extracted by the LLM
-type LLMExtractedRelationship struct {
-	Target string `json:"target"`
-	Type   string `json:"type"`
+	summary.Since = since
+
+	budget, spentToday := ledger.Budget()
+	return &UsageReport{
+		Summary:    summary,
+		Budget:     budget,
+		SpentToday: spentToday,
+	}, nil
 }
@@ -14,11 +14,44 @@ func New(graphManager *graph.Manager, llmClient *llm.Client, sourcesManager *sou
 		Source: NewSourceOps(graphManager, llmClient, sourcesManager, dataDir),
 		Search: NewSearchOps(graphManager, dataDir),
 		LLM:    NewLLMOps(llmClient),
+		Events: NewEventLog(dataDir),
 	}
+	ops.Feed = NewFeedOps(graphManager, ops.Queue, ops.Source, dataDir)
+	ops.Source.queue = ops.Queue
+	ops.Jobs = NewJobOps(ops, dataDir, DefaultJobWorkers)
+
+	// Changes made through any of these are published to the event log
+	ops.Entity.events = ops.Events
+	ops.Queue.events = ops.Events
+	ops.Source.events = ops.Events
+	ops.Jobs.events = ops.Events
 
 	return ops
 }
 
+// As returns operations that attribute the changes they make to actor, the
+// name of an API token: published events record it, and entities they save
+// note it as updated_by. Search, feeds and jobs are shared with o; a job
+// carries its own actor in its params.
+func (o *Operations) As(actor string) *Operations {
+	if actor == "" {
+		return o
+	}
+	events := o.Events.As(actor)
+
+	entity := *o.Entity
+	entity.actor, entity.events = actor, events
+	queue := *o.Queue
+	queue.events = events
+	source := *o.Source
+	source.actor, source.events, source.queue = actor, events, &queue
+
+	attributed := *o
+	attributed.Entity, attributed.Queue, attributed.Source = &entity, &queue, &source
+	attributed.Events = events
+	return &attributed
+}
+
 // NewWithDefaults creates Operations with default configurations
 func NewWithDefaults(dataDir string) *Operations {
 	// Initialize graph manager
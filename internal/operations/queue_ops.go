@@ -1,18 +1,60 @@
 package operations
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
+	"sync"
 	"time"
+
+	"silvia/internal/resilience"
+)
+
+// States of a queued source. Pending sources wait to be ingested, highest
+// priority first. Claiming one marks it in progress until its ingest is done
+// or has failed. Skipped sources are left alone, and deferred ones until the
+// time they were deferred to. Finished sources stay in the queue, so a URL
+// that was ingested, skipped or failed is not queued again by accident.
+const (
+	QueuePending    = "pending"
+	QueueInProgress = "in_progress"
+	QueueDone       = "done"
+	QueueFailed     = "failed"
+	QueueSkipped    = "skipped"
+	QueueDeferred   = "deferred"
 )
 
-// QueueOps handles source queue operations
+// QueueStatuses lists every state a queued source can be in
+var QueueStatuses = []string{QueuePending, QueueInProgress, QueueDone, QueueFailed, QueueSkipped, QueueDeferred}
+
+// waitingStatuses are the states of sources not finished with, which
+// GetQueue lists by default
+var waitingStatuses = []string{QueuePending, QueueInProgress, QueueDeferred}
+
+// staleClaim is how long a source may stay in progress before it is
+// assumed that whoever claimed it stopped, and it can be claimed again
+const staleClaim = time.Hour
+
+// queueLockWait is how long a change waits for another process to finish
+// changing the queue file; a lock older than staleQueueLock was left behind
+const (
+	queueLockWait  = 5 * time.Second
+	staleQueueLock = 30 * time.Second
+)
+
+// QueueOps handles source queue operations. The queue lives in one file,
+// shared by the CLI, the HTTP server and the MCP server. Every change
+// re-reads it under a lock, so processes using it at once do not undo each
+// other's changes.
 type QueueOps struct {
 	dataDir   string
 	queuePath string
+	mu        *sync.Mutex // Shared with the copies made by Operations.As
+	events    *EventLog
 }
 
 // NewQueueOps creates a new queue operations handler
@@ -20,237 +62,450 @@ func NewQueueOps(dataDir string) *QueueOps {
 	return &QueueOps{
 		dataDir:   dataDir,
 		queuePath: filepath.Join(dataDir, ".silvia", "queue.json"),
+		mu:        &sync.Mutex{},
 	}
 }
 
-// GetQueue returns the current queue status
-func (q *QueueOps) GetQueue() (*QueueStatus, error) {
+// GetQueue returns the queued sources in the given states, in the order
+// they would be ingested. With no states it returns those still waiting:
+// pending, in progress and deferred. ByStatus counts the whole queue.
+func (q *QueueOps) GetQueue(statuses ...string) (*QueueStatus, error) {
+	if len(statuses) == 0 {
+		statuses = waitingStatuses
+	}
+	for _, status := range statuses {
+		if !slices.Contains(QueueStatuses, status) {
+			return nil, NewOperationError("get queue", "", invalidf("unknown status %q", status))
+		}
+	}
+
 	items, err := q.loadQueue()
 	if err != nil {
 		return nil, NewOperationError("get queue", "", err)
 	}
 
-	if len(items) == 0 {
-		return &QueueStatus{
-			Items:      []QueueItem{},
-			TotalCount: 0,
-			ByPriority: map[int]int{0: 0, 1: 0, 2: 0},
-		}, nil
+	result := &QueueStatus{
+		Items:      []QueueItem{},
+		ByPriority: map[int]int{0: 0, 1: 0, 2: 0},
+		ByStatus:   make(map[string]int),
 	}
-
-	// Convert internal items to QueueItem
-	queueItems := make([]QueueItem, len(items))
-	byPriority := map[int]int{0: 0, 1: 0, 2: 0}
-
-	var oldest, newest *QueueItem
-
-	for i, item := range items {
-		qi := QueueItem{
-			URL:         item.URL,
-			Priority:    item.Priority,
-			AddedAt:     item.AddedAt,
-			FromSource:  item.FromSource,
-			Description: item.Description,
-			Status:      "pending",
+	for _, item := range items {
+		result.ByStatus[item.Status]++
+		if !slices.Contains(statuses, item.Status) {
+			continue
 		}
-		queueItems[i] = qi
-		byPriority[item.Priority]++
 
-		if oldest == nil || qi.AddedAt.Before(oldest.AddedAt) {
-			oldest = &qi
+		qi := item.toItem()
+		result.Items = append(result.Items, qi)
+		result.ByPriority[item.Priority]++
+		if result.OldestItem == nil || qi.AddedAt.Before(result.OldestItem.AddedAt) {
+			result.OldestItem = &qi
 		}
-		if newest == nil || qi.AddedAt.After(newest.AddedAt) {
-			newest = &qi
+		if result.NewestItem == nil || qi.AddedAt.After(result.NewestItem.AddedAt) {
+			result.NewestItem = &qi
 		}
 	}
-
-	return &QueueStatus{
-		Items:      queueItems,
-		TotalCount: len(queueItems),
-		ByPriority: byPriority,
-		OldestItem: oldest,
-		NewestItem: newest,
-	}, nil
+	result.TotalCount = len(result.Items)
+	return result, nil
 }
 
-// AddToQueue adds a source to the queue
-func (q *QueueOps) AddToQueue(url string, priority int, fromSource, description string) error {
-	if url == "" {
-		return NewOperationError("add to queue", url, fmt.Errorf("URL cannot be empty"))
-	}
-
-	if priority < 0 || priority > 2 {
-		return NewOperationError("add to queue", url, fmt.Errorf("invalid priority: %d (must be 0-2)", priority))
-	}
-
+// GetItem returns the queued source with a URL, in whatever state, or nil
+// if the URL was never queued
+func (q *QueueOps) GetItem(url string) (*QueueItem, error) {
 	items, err := q.loadQueue()
 	if err != nil {
-		return NewOperationError("add to queue", url, err)
+		return nil, NewOperationError("get queue item", url, err)
 	}
-
-	// Check if already in queue
-	for _, item := range items {
-		if item.URL == url {
-			return NewOperationError("add to queue", url, fmt.Errorf("already in queue"))
-		}
+	if item := findQueued(items, url); item != nil {
+		qi := item.toItem()
+		return &qi, nil
 	}
+	return nil, nil
+}
 
-	// Add new item
-	newItem := &queuedSource{
-		URL:         url,
-		Priority:    priority,
-		AddedAt:     time.Now(),
-		FromSource:  fromSource,
-		Description: description,
+// AddToQueue adds a source to the queue. A URL already queued, even one
+// that is finished with, is a conflict; use Requeue to try it again.
+func (q *QueueOps) AddToQueue(url string, priority int, fromSource, description string) error {
+	if url == "" {
+		return NewOperationError("add to queue", url, invalidf("URL cannot be empty"))
 	}
 
-	items = append(items, newItem)
+	if priority < 0 || priority > 2 {
+		return NewOperationError("add to queue", url, invalidf("invalid priority: %d (must be 0-2)", priority))
+	}
 
-	// Sort by priority (high to low) then by time (old to new)
-	sort.Slice(items, func(i, j int) bool {
-		if items[i].Priority != items[j].Priority {
-			return items[i].Priority > items[j].Priority
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		if existing := findQueued(items, url); existing != nil {
+			if existing.Status == QueuePending {
+				return nil, conflictf("already in queue")
+			}
+			return nil, conflictf("already in queue (%s)", existing.Status)
 		}
-		return items[i].AddedAt.Before(items[j].AddedAt)
+		now := time.Now()
+		return append(items, &queuedSource{
+			URL:         url,
+			Priority:    priority,
+			AddedAt:     now,
+			FromSource:  fromSource,
+			Description: description,
+			Status:      QueuePending,
+			UpdatedAt:   now,
+		}), nil
 	})
-
-	if err := q.saveQueue(items); err != nil {
+	if err != nil {
 		return NewOperationError("add to queue", url, err)
 	}
+	q.events.Publish(EventQueueAdded, url, map[string]any{
+		"priority":    priority,
+		"description": description,
+	})
 
 	return nil
 }
 
-// RemoveFromQueue removes a source from the queue
+// RemoveFromQueue removes a source from the queue, forgetting it was ever
+// queued
 func (q *QueueOps) RemoveFromQueue(url string) error {
 	if url == "" {
-		return NewOperationError("remove from queue", url, fmt.Errorf("URL cannot be empty"))
+		return NewOperationError("remove from queue", url, invalidf("URL cannot be empty"))
 	}
 
-	items, err := q.loadQueue()
-	if err != nil {
-		return NewOperationError("remove from queue", url, err)
-	}
-
-	// Find and remove the item
-	found := false
-	newItems := make([]*queuedSource, 0, len(items))
-	for _, item := range items {
-		if item.URL == url {
-			found = true
-		} else {
-			newItems = append(newItems, item)
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		if findQueued(items, url) == nil {
+			return nil, notFoundf("not found in queue")
 		}
-	}
-
-	if !found {
-		return NewOperationError("remove from queue", url, fmt.Errorf("not found in queue"))
-	}
-
-	if err := q.saveQueue(newItems); err != nil {
+		return slices.DeleteFunc(items, func(item *queuedSource) bool { return item.URL == url }), nil
+	})
+	if err != nil {
 		return NewOperationError("remove from queue", url, err)
 	}
+	q.events.Publish(EventQueueRemoved, url, nil)
 
 	return nil
 }
 
-// GetNextItem returns the highest priority item from the queue
+// GetNextItem returns the source that would be ingested next, without
+// claiming it, or nil when none is ready
 func (q *QueueOps) GetNextItem() (*QueueItem, error) {
 	items, err := q.loadQueue()
 	if err != nil {
 		return nil, NewOperationError("get next item", "", err)
 	}
 
-	if len(items) == 0 {
-		return nil, nil
+	now := time.Now()
+	for _, item := range items {
+		if item.ready(now) {
+			qi := item.toItem()
+			return &qi, nil
+		}
 	}
-
-	// Items are already sorted by priority
-	item := items[0]
-
-	return &QueueItem{
-		URL:         item.URL,
-		Priority:    item.Priority,
-		AddedAt:     item.AddedAt,
-		FromSource:  item.FromSource,
-		Description: item.Description,
-		Status:      "pending",
-	}, nil
+	return nil, nil
 }
 
-// ProcessNextItem gets and removes the next item from the queue
+// ProcessNextItem claims the source to ingest next, marking it in progress,
+// or returns nil when none is ready. Ingesting it through SourceOps records
+// the outcome; otherwise the caller marks it done or failed.
 func (q *QueueOps) ProcessNextItem() (*QueueItem, error) {
-	item, err := q.GetNextItem()
+	var claimed *queuedSource
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		now := time.Now()
+		for _, item := range items {
+			if item.ready(now) {
+				item.claim(now)
+				claimed = item
+				return items, nil
+			}
+		}
+		return nil, errUnchanged
+	})
 	if err != nil {
-		return nil, err
+		return nil, NewOperationError("process next item", "", err)
 	}
-
-	if item == nil {
+	if claimed == nil {
 		return nil, nil
 	}
+	q.publishStatus(claimed)
+
+	item := claimed.toItem()
+	return &item, nil
+}
+
+// StartItem claims a particular queued source, marking it in progress. It
+// is a conflict if someone else is ingesting it.
+func (q *QueueOps) StartItem(url string) error {
+	return q.setStatus("start queue item", url, func(item *queuedSource, now time.Time) error {
+		if item.Status == QueueInProgress && now.Sub(item.UpdatedAt) < staleClaim {
+			return conflictf("already in progress")
+		}
+		item.claim(now)
+		return nil
+	})
+}
+
+// MarkDone records that a queued source was ingested
+func (q *QueueOps) MarkDone(url string) error {
+	return q.setStatus("mark queue item done", url, func(item *queuedSource, now time.Time) error {
+		item.Status, item.Error = QueueDone, ""
+		return nil
+	})
+}
+
+// MarkFailed records that ingesting a queued source failed, and why
+func (q *QueueOps) MarkFailed(url string, cause error) error {
+	return q.setStatus("mark queue item failed", url, func(item *queuedSource, now time.Time) error {
+		item.Status = QueueFailed
+		if cause != nil {
+			item.Error = cause.Error()
+		}
+		return nil
+	})
+}
+
+// Skip marks a queued source as not worth ingesting. It stays in the queue
+// so it is not queued again.
+func (q *QueueOps) Skip(url string) error {
+	return q.setStatus("skip queue item", url, func(item *queuedSource, now time.Time) error {
+		item.Status = QueueSkipped
+		return nil
+	})
+}
+
+// Defer puts a queued source aside until a time, after which it is pending
+// again. A zero time defers it until it is requeued.
+func (q *QueueOps) Defer(url string, until time.Time) error {
+	return q.setStatus("defer queue item", url, func(item *queuedSource, now time.Time) error {
+		item.Status, item.DeferredUntil = QueueDeferred, until
+		return nil
+	})
+}
 
-	// Remove from queue
-	if err := q.RemoveFromQueue(item.URL); err != nil {
-		return nil, err
+// Requeue makes a queued source pending again, whatever happened to it
+func (q *QueueOps) Requeue(url string) error {
+	return q.setStatus("requeue item", url, func(item *queuedSource, now time.Time) error {
+		item.Status, item.Error, item.DeferredUntil = QueuePending, "", time.Time{}
+		return nil
+	})
+}
+
+// SetStatus moves a queued source to a state by name, as the HTTP API and
+// tools do. Until applies to deferring and cause to failing.
+func (q *QueueOps) SetStatus(url, status string, until time.Time, cause error) error {
+	if url == "" {
+		return NewOperationError("set queue status", url, invalidf("URL cannot be empty"))
 	}
+	switch status {
+	case QueuePending:
+		return q.Requeue(url)
+	case QueueInProgress:
+		return q.StartItem(url)
+	case QueueDone:
+		return q.MarkDone(url)
+	case QueueFailed:
+		return q.MarkFailed(url, cause)
+	case QueueSkipped:
+		return q.Skip(url)
+	case QueueDeferred:
+		return q.Defer(url, until)
+	default:
+		return NewOperationError("set queue status", url, invalidf("unknown status %q", status))
+	}
+}
 
-	return item, nil
+// FinishIngest records the outcome of ingesting a source on its queue
+// item, if it has one; SourceOps does so for every ingest. A source that
+// was already ingested counts as done, and one whose ingest was cancelled,
+// or refused while the LLM circuit breaker is open, is pending again. A
+// failed re-ingest does not undo a source that is done or was skipped.
+func (q *QueueOps) FinishIngest(url string, ingestErr error) {
+	if q == nil {
+		return
+	}
+	var changed *queuedSource
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		item := findQueued(items, url)
+		if item == nil {
+			return nil, errUnchanged
+		}
+		switch {
+		case ingestErr == nil, errors.Is(ingestErr, ErrConflict):
+			item.Status, item.Error = QueueDone, ""
+		case errors.Is(ingestErr, context.Canceled), errors.Is(ingestErr, context.DeadlineExceeded),
+			errors.Is(ingestErr, resilience.ErrCircuitOpen):
+			item.Status = QueuePending
+		case item.Status == QueueDone || item.Status == QueueSkipped:
+			return nil, errUnchanged
+		default:
+			item.Status, item.Error = QueueFailed, ingestErr.Error()
+		}
+		item.UpdatedAt = time.Now()
+		changed = item
+		return items, nil
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to update queue for %s: %v\n", url, err)
+		return
+	}
+	if changed != nil {
+		q.publishStatus(changed)
+	}
 }
 
 // ClearQueue removes all items from the queue
 func (q *QueueOps) ClearQueue() error {
-	if err := q.saveQueue([]*queuedSource{}); err != nil {
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		return []*queuedSource{}, nil
+	})
+	if err != nil {
 		return NewOperationError("clear queue", "", err)
 	}
+	q.events.Publish(EventQueueCleared, "", nil)
 	return nil
 }
 
 // UpdatePriority changes the priority of an item in the queue
 func (q *QueueOps) UpdatePriority(url string, newPriority int) error {
 	if newPriority < 0 || newPriority > 2 {
-		return NewOperationError("update priority", url, fmt.Errorf("invalid priority: %d (must be 0-2)", newPriority))
+		return NewOperationError("update priority", url, invalidf("invalid priority: %d (must be 0-2)", newPriority))
 	}
 
-	items, err := q.loadQueue()
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		item := findQueued(items, url)
+		if item == nil {
+			return nil, notFoundf("not found in queue")
+		}
+		item.Priority = newPriority
+		return items, nil
+	})
 	if err != nil {
 		return NewOperationError("update priority", url, err)
 	}
 
-	found := false
-	for _, item := range items {
-		if item.URL == url {
-			item.Priority = newPriority
-			found = true
-			break
+	return nil
+}
+
+// setStatus applies change to a queued source and publishes its new state
+func (q *QueueOps) setStatus(op, url string, change func(item *queuedSource, now time.Time) error) error {
+	var changed *queuedSource
+	err := q.update(func(items []*queuedSource) ([]*queuedSource, error) {
+		item := findQueued(items, url)
+		if item == nil {
+			return nil, notFoundf("not found in queue")
+		}
+		now := time.Now()
+		if err := change(item, now); err != nil {
+			return nil, err
+		}
+		if item.Status != QueueDeferred {
+			item.DeferredUntil = time.Time{}
 		}
+		item.UpdatedAt = now
+		changed = item
+		return items, nil
+	})
+	if err != nil {
+		return NewOperationError(op, url, err)
 	}
+	q.publishStatus(changed)
+	return nil
+}
 
-	if !found {
-		return NewOperationError("update priority", url, fmt.Errorf("not found in queue"))
+func (q *QueueOps) publishStatus(item *queuedSource) {
+	data := map[string]any{"status": item.Status}
+	if item.Error != "" {
+		data["error"] = item.Error
 	}
+	if !item.DeferredUntil.IsZero() {
+		data["until"] = item.DeferredUntil
+	}
+	q.events.Publish(EventQueueUpdated, item.URL, data)
+}
 
-	// Re-sort
-	sort.Slice(items, func(i, j int) bool {
-		if items[i].Priority != items[j].Priority {
-			return items[i].Priority > items[j].Priority
-		}
-		return items[i].AddedAt.Before(items[j].AddedAt)
-	})
+// queuedSource is how a queued source is stored. Queue files written before
+// sources had states hold no status; those sources are pending.
+type queuedSource struct {
+	URL           string    `json:"url"`
+	Priority      int       `json:"priority"`
+	AddedAt       time.Time `json:"added_at"`
+	FromSource    string    `json:"from_source,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+	Attempts      int       `json:"attempts,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	DeferredUntil time.Time `json:"deferred_until,omitzero"`
+}
 
-	if err := q.saveQueue(items); err != nil {
-		return NewOperationError("update priority", url, err)
+func (s *queuedSource) toItem() QueueItem {
+	return QueueItem{
+		URL:           s.URL,
+		Priority:      s.Priority,
+		AddedAt:       s.AddedAt,
+		FromSource:    s.FromSource,
+		Description:   s.Description,
+		Status:        s.Status,
+		UpdatedAt:     s.UpdatedAt,
+		Attempts:      s.Attempts,
+		Error:         s.Error,
+		DeferredUntil: s.DeferredUntil,
 	}
+}
+
+// ready reports whether the source can be claimed: it is pending, deferred
+// to a time that has passed, or claimed so long ago its claim is stale
+func (s *queuedSource) ready(now time.Time) bool {
+	switch s.Status {
+	case QueuePending:
+		return true
+	case QueueDeferred:
+		return !s.DeferredUntil.IsZero() && !now.Before(s.DeferredUntil)
+	case QueueInProgress:
+		return now.Sub(s.UpdatedAt) >= staleClaim
+	}
+	return false
+}
 
+func (s *queuedSource) claim(now time.Time) {
+	s.Status, s.Error, s.DeferredUntil = QueueInProgress, "", time.Time{}
+	s.UpdatedAt = now
+	s.Attempts++
+}
+
+func findQueued(items []*queuedSource, url string) *queuedSource {
+	for _, item := range items {
+		if item.URL == url {
+			return item
+		}
+	}
 	return nil
 }
 
-// Internal queue item structure (matches CLI's structure for compatibility)
-type queuedSource struct {
-	URL         string    `json:"url"`
-	Priority    int       `json:"priority"`
-	AddedAt     time.Time `json:"added_at"`
-	FromSource  string    `json:"from_source,omitempty"`
-	Description string    `json:"description,omitempty"`
+// errUnchanged tells update that a change had nothing to do
+var errUnchanged = errors.New("queue unchanged")
+
+// update applies change to the queue file while holding its lock, then
+// writes back the items it returns
+func (q *QueueOps) update(change func(items []*queuedSource) ([]*queuedSource, error)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	unlock, err := lockFile(q.queuePath+".lock", "queue", queueLockWait, staleQueueLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	items, err := q.loadQueue()
+	if err != nil {
+		return err
+	}
+	items, err = change(items)
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return q.saveQueue(items)
 }
 
 // loadQueue loads the queue from disk
@@ -268,11 +523,29 @@ func (q *QueueOps) loadQueue() ([]*queuedSource, error) {
 	if err := json.Unmarshal(data, &items); err != nil {
 		return nil, fmt.Errorf("failed to parse queue file: %w", err)
 	}
+	for _, item := range items {
+		if item.Status == "" {
+			item.Status = QueuePending
+		}
+		if item.UpdatedAt.IsZero() {
+			item.UpdatedAt = item.AddedAt
+		}
+	}
+
+	// Highest priority first, oldest first within a priority. Files the
+	// CLI wrote used to be in heap order, so this is not left to saveQueue.
+	slices.SortStableFunc(items, func(a, b *queuedSource) int {
+		if a.Priority != b.Priority {
+			return b.Priority - a.Priority
+		}
+		return a.AddedAt.Compare(b.AddedAt)
+	})
 
 	return items, nil
 }
 
-// saveQueue saves the queue to disk
+// saveQueue saves the queue to disk, replacing the file at once so readers
+// never see it half written
 func (q *QueueOps) saveQueue(items []*queuedSource) error {
 	// Ensure directory exists
 	dir := filepath.Dir(q.queuePath)
@@ -285,7 +558,12 @@ func (q *QueueOps) saveQueue(items []*queuedSource) error {
 		return fmt.Errorf("failed to marshal queue: %w", err)
 	}
 
-	if err := os.WriteFile(q.queuePath, data, 0644); err != nil {
+	tmp := q.queuePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write queue file: %w", err)
+	}
+	if err := os.Rename(tmp, q.queuePath); err != nil {
+		os.Remove(tmp)
 		return fmt.Errorf("failed to write queue file: %w", err)
 	}
 
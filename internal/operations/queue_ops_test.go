@@ -0,0 +1,302 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"silvia/internal/resilience"
+)
+
+// newTestQueue returns a queue in a temporary data directory holding the
+// given URLs, all pending, with the first at the highest priority
+func newTestQueue(t *testing.T, urls ...string) *QueueOps {
+	t.Helper()
+	q := NewQueueOps(t.TempDir())
+	for i, url := range urls {
+		priority := max(2-i, 0)
+		if err := q.AddToQueue(url, priority, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return q
+}
+
+func queueItem(t *testing.T, q *QueueOps, url string) *QueueItem {
+	t.Helper()
+	item, err := q.GetItem(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item == nil {
+		t.Fatalf("%s is not queued", url)
+	}
+	return item
+}
+
+func TestQueueTransitions(t *testing.T) {
+	const url = "https://example.com/a"
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		setup    func(q *QueueOps) error // Puts the item in its starting state
+		change   func(q *QueueOps) error
+		status   string
+		wantCode string // ErrorCode of the change's error, if it fails
+		ready    bool   // Whether the item is next to be claimed afterwards
+	}{
+		{
+			name:   "claim pending",
+			change: func(q *QueueOps) error { return q.StartItem(url) },
+			status: QueueInProgress,
+		},
+		{
+			name:     "claim in progress",
+			setup:    func(q *QueueOps) error { return q.StartItem(url) },
+			change:   func(q *QueueOps) error { return q.StartItem(url) },
+			status:   QueueInProgress,
+			wantCode: CodeConflict,
+		},
+		{
+			name:   "done",
+			setup:  func(q *QueueOps) error { return q.StartItem(url) },
+			change: func(q *QueueOps) error { return q.MarkDone(url) },
+			status: QueueDone,
+		},
+		{
+			name:   "failed",
+			setup:  func(q *QueueOps) error { return q.StartItem(url) },
+			change: func(q *QueueOps) error { return q.MarkFailed(url, errors.New("boom")) },
+			status: QueueFailed,
+		},
+		{
+			name:   "skipped",
+			change: func(q *QueueOps) error { return q.Skip(url) },
+			status: QueueSkipped,
+		},
+		{
+			name:   "deferred",
+			change: func(q *QueueOps) error { return q.Defer(url, future) },
+			status: QueueDeferred,
+		},
+		{
+			name:   "deferred until a time that has passed",
+			change: func(q *QueueOps) error { return q.Defer(url, past) },
+			status: QueueDeferred,
+			ready:  true,
+		},
+		{
+			name:   "deferred indefinitely",
+			change: func(q *QueueOps) error { return q.Defer(url, time.Time{}) },
+			status: QueueDeferred,
+		},
+		{
+			name:   "requeue failed",
+			setup:  func(q *QueueOps) error { return q.MarkFailed(url, errors.New("boom")) },
+			change: func(q *QueueOps) error { return q.Requeue(url) },
+			status: QueuePending,
+			ready:  true,
+		},
+		{
+			name:   "requeue skipped by name",
+			setup:  func(q *QueueOps) error { return q.Skip(url) },
+			change: func(q *QueueOps) error { return q.SetStatus(url, QueuePending, time.Time{}, nil) },
+			status: QueuePending,
+			ready:  true,
+		},
+		{
+			name:     "unknown status",
+			change:   func(q *QueueOps) error { return q.SetStatus(url, "archived", time.Time{}, nil) },
+			status:   QueuePending,
+			wantCode: CodeInvalid,
+			ready:    true,
+		},
+		{
+			name:     "unknown URL",
+			change:   func(q *QueueOps) error { return q.MarkDone("https://example.com/missing") },
+			status:   QueuePending,
+			wantCode: CodeNotFound,
+			ready:    true,
+		},
+		{
+			name:     "queue a URL again",
+			setup:    func(q *QueueOps) error { return q.MarkDone(url) },
+			change:   func(q *QueueOps) error { return q.AddToQueue(url, 1, "", "") },
+			status:   QueueDone,
+			wantCode: CodeConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(t, url)
+			if tt.setup != nil {
+				if err := tt.setup(q); err != nil {
+					t.Fatalf("setup: %v", err)
+				}
+			}
+
+			err := tt.change(q)
+			if tt.wantCode == "" && err != nil {
+				t.Fatalf("change: %v", err)
+			}
+			if tt.wantCode != "" && (err == nil || ErrorCode(err) != tt.wantCode) {
+				t.Fatalf("change error = %v, want code %s", err, tt.wantCode)
+			}
+
+			if got := queueItem(t, q, url).Status; got != tt.status {
+				t.Errorf("status = %s, want %s", got, tt.status)
+			}
+			next, err := q.GetNextItem()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ready := next != nil; ready != tt.ready {
+				t.Errorf("ready = %v, want %v", ready, tt.ready)
+			}
+		})
+	}
+}
+
+func TestProcessNextItemClaimsByPriority(t *testing.T) {
+	q := newTestQueue(t, "https://example.com/high", "https://example.com/medium", "https://example.com/low")
+	if err := q.Skip("https://example.com/medium"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"https://example.com/high", "https://example.com/low"} {
+		item, err := q.ProcessNextItem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item == nil || item.URL != want {
+			t.Fatalf("claimed %+v, want %s", item, want)
+		}
+		if item.Status != QueueInProgress || item.Attempts != 1 {
+			t.Errorf("claimed item is %s after %d attempts, want in progress after 1", item.Status, item.Attempts)
+		}
+	}
+
+	item, err := q.ProcessNextItem()
+	if err != nil || item != nil {
+		t.Fatalf("ProcessNextItem() = %+v, %v on an exhausted queue, want nil", item, err)
+	}
+
+	status, err := q.GetQueue(QueueStatuses...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{QueueInProgress: 2, QueueSkipped: 1}
+	if fmt.Sprint(status.ByStatus) != fmt.Sprint(want) {
+		t.Errorf("ByStatus = %v, want %v", status.ByStatus, want)
+	}
+}
+
+func TestFinishIngest(t *testing.T) {
+	const url = "https://example.com/a"
+
+	tests := []struct {
+		name      string
+		start     func(q *QueueOps) error
+		ingestErr error
+		status    string
+		errText   string
+	}{
+		{name: "success", ingestErr: nil, status: QueueDone},
+		{
+			name:      "already ingested",
+			ingestErr: NewOperationError("ingest source", url, conflictf("source already processed")),
+			status:    QueueDone,
+		},
+		{
+			name:      "failure",
+			ingestErr: errors.New("fetch failed: 404"),
+			status:    QueueFailed,
+			errText:   "fetch failed: 404",
+		},
+		{name: "cancelled", ingestErr: context.Canceled, status: QueuePending},
+		{name: "timed out", ingestErr: fmt.Errorf("extraction failed: %w", context.DeadlineExceeded), status: QueuePending},
+		{
+			name:      "circuit open",
+			ingestErr: fmt.Errorf("LLM extraction failed: %w", resilience.ErrCircuitOpen),
+			status:    QueuePending,
+		},
+		{
+			name:      "failed re-ingest of a done source",
+			start:     func(q *QueueOps) error { return q.MarkDone(url) },
+			ingestErr: errors.New("fetch failed: 500"),
+			status:    QueueDone,
+		},
+		{
+			name:      "failed re-ingest of a skipped source",
+			start:     func(q *QueueOps) error { return q.Skip(url) },
+			ingestErr: errors.New("fetch failed: 500"),
+			status:    QueueSkipped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(t, url)
+			start := tt.start
+			if start == nil {
+				start = func(q *QueueOps) error { return q.StartItem(url) }
+			}
+			if err := start(q); err != nil {
+				t.Fatal(err)
+			}
+
+			q.FinishIngest(url, tt.ingestErr)
+
+			item := queueItem(t, q, url)
+			if item.Status != tt.status {
+				t.Errorf("status = %s, want %s", item.Status, tt.status)
+			}
+			if item.Error != tt.errText {
+				t.Errorf("error = %q, want %q", item.Error, tt.errText)
+			}
+		})
+	}
+}
+
+func TestFinishIngestUnqueuedURL(t *testing.T) {
+	q := newTestQueue(t, "https://example.com/a")
+	q.FinishIngest("https://example.com/b", nil)
+
+	if item, err := q.GetItem("https://example.com/b"); err != nil || item != nil {
+		t.Errorf("GetItem() = %+v, %v; finishing an unqueued URL should not queue it", item, err)
+	}
+	var nilQueue *QueueOps
+	nilQueue.FinishIngest("https://example.com/a", nil) // Must not panic
+}
+
+func TestQueueEvents(t *testing.T) {
+	const url = "https://example.com/a"
+	q := newTestQueue(t)
+	q.events = NewEventLog(q.dataDir)
+
+	if err := q.AddToQueue(url, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.StartItem(url); err != nil {
+		t.Fatal(err)
+	}
+	q.FinishIngest(url, errors.New("boom"))
+
+	events, err := q.events.Since(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Type)
+	}
+	want := []string{EventQueueAdded, EventQueueUpdated, EventQueueUpdated}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
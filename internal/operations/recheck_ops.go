@@ -0,0 +1,456 @@
+package operations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"silvia/internal/llm"
+	"silvia/internal/sources"
+)
+
+// Source rechecking: processed sources are refetched, compared against the
+// last archived snapshot, and only changed passages are re-extracted.
+
+// sourceSnapshots is the on-disk record of every capture of each source
+type sourceSnapshots struct {
+	Sources map[string]*sourceHistory `json:"sources"`
+}
+
+type sourceHistory struct {
+	LastChecked time.Time        `json:"last_checked"`
+	Snapshots   []SourceSnapshot `json:"snapshots"`
+}
+
+// RecheckSources refetches processed sources and reports which ones changed.
+// With all set every processed source is checked; otherwise only sources not
+// checked within maxAge. limit caps the number of sources checked (0 = no cap).
+func (s *SourceOps) RecheckSources(ctx context.Context, all bool, maxAge time.Duration, limit int) (*RecheckReport, error) {
+	tracker, err := s.loadTracker()
+	if err != nil {
+		return nil, NewOperationError("recheck sources", "", err)
+	}
+
+	snapshots, err := s.loadSnapshots()
+	if err != nil {
+		return nil, NewOperationError("recheck sources", "", err)
+	}
+
+	// Oldest checks first so a limited run makes progress through the list
+	type candidate struct {
+		url     string
+		checked time.Time
+	}
+	var candidates []candidate
+	for url, processedAt := range tracker.ProcessedURLs {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			continue
+		}
+		checked := processedAt
+		if history, ok := snapshots.Sources[url]; ok && history.LastChecked.After(checked) {
+			checked = history.LastChecked
+		}
+		if !all && time.Since(checked) < maxAge {
+			continue
+		}
+		candidates = append(candidates, candidate{url: url, checked: checked})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].checked.Before(candidates[j].checked)
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	report := &RecheckReport{StartedAt: time.Now()}
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := s.RecheckSource(ctx, c.url)
+		if err != nil {
+			result = &RecheckResult{URL: c.url, Status: RecheckError, Error: err.Error()}
+		}
+		report.Results = append(report.Results, *result)
+	}
+	report.FinishedAt = time.Now()
+
+	if len(report.Results) > 0 {
+		if path, err := s.writeRecheckReport(report); err == nil {
+			report.ReportPath = path
+		} else {
+			fmt.Printf("Warning: failed to write recheck report: %v\n", err)
+		}
+	}
+
+	return report, nil
+}
+
+// RecheckSource refetches a single source, stores a new snapshot, and
+// re-runs extraction on passages that were added or changed
+func (s *SourceOps) RecheckSource(ctx context.Context, url string) (*RecheckResult, error) {
+	ctx = llm.WithOperation(ctx, llm.OpRecheck, url)
+
+	snapshots, err := s.loadSnapshots()
+	if err != nil {
+		return nil, NewOperationError("recheck source", url, err)
+	}
+
+	history := snapshots.Sources[url]
+	if history == nil {
+		history = &sourceHistory{}
+		snapshots.Sources[url] = history
+	}
+
+	// Find the previous version: last snapshot, or the archive from the original ingest
+	var previous *SourceSnapshot
+	if len(history.Snapshots) > 0 {
+		previous = &history.Snapshots[len(history.Snapshots)-1]
+	} else if archived := s.findArchivedSnapshot(url); archived != nil {
+		previous = archived
+	}
+
+	source, err := s.sources.Fetch(ctx, url)
+	if err != nil {
+		history.LastChecked = time.Now()
+		s.saveSnapshots(snapshots)
+		return nil, NewOperationError("recheck source", url, fmt.Errorf("failed to fetch: %w", err))
+	}
+
+	result := &RecheckResult{
+		URL:      url,
+		Title:    source.Title,
+		TextHash: hashText(source.Content),
+	}
+	if source.Capture != nil {
+		result.ContentHash = source.Capture.ContentHash()
+	}
+
+	var oldText string
+	if previous != nil {
+		result.PreviousFetchedAt = previous.FetchedAt
+		result.PreviousHash = previous.TextHash
+		oldText = previousText(previous.ArchivePath, url)
+	}
+
+	switch {
+	case previous == nil || oldText == "":
+		result.Status = RecheckBaseline
+	case hashText(oldText) == result.TextHash:
+		result.Status = RecheckUnchanged
+	default:
+		result.Status = RecheckChanged
+		result.Added, result.Removed = diffParagraphs(oldText, source.Content)
+		if len(result.Added) == 0 && len(result.Removed) == 0 {
+			// Only whitespace or ordering noise
+			result.Status = RecheckUnchanged
+		}
+	}
+
+	// Re-extract only the passages that are new in this version
+	extracted := true
+	if result.Status == RecheckChanged && len(result.Added) > 0 {
+		changed := *source
+		changed.Content = "The following passages were added or changed since this source was last captured:\n\n" +
+			strings.Join(result.Added, "\n\n")
+
+		extraction, err := s.extractor.Extract(ctx, &changed)
+		if err != nil {
+			result.Error = fmt.Sprintf("re-extraction failed: %v", err)
+			extracted = false
+		} else {
+			result.ExtractedEntities, _ = s.processExtractionResult(extraction, url)
+			if extraction.FailedChunks > 0 {
+				result.Error = fmt.Sprintf("re-extraction failed for %d of %d chunks", extraction.FailedChunks, extraction.ChunkCount)
+				extracted = false
+			}
+		}
+	}
+
+	// Archive a new dated snapshot unless nothing changed. One whose new
+	// passages were not all extracted is not archived, so the next recheck
+	// diffs against the same version and tries them again.
+	if result.Status != RecheckUnchanged && extracted {
+		archivedPath, err := s.archiveSource(source)
+		if err != nil {
+			return nil, NewOperationError("recheck source", url, err)
+		}
+		result.ArchivedPath = archivedPath
+		history.Snapshots = append(history.Snapshots, SourceSnapshot{
+			FetchedAt:   time.Now(),
+			ContentHash: result.ContentHash,
+			TextHash:    result.TextHash,
+			ArchivePath: archivedPath,
+			WARC:        source.Metadata["warc"],
+		})
+		if result.Status == RecheckChanged && len(result.Added) > 0 {
+			s.markSourceProcessed(url)
+		}
+	}
+
+	history.LastChecked = time.Now()
+	if err := s.saveSnapshots(snapshots); err != nil {
+		return result, NewOperationError("recheck source", url, err)
+	}
+
+	return result, nil
+}
+
+// GetSourceSnapshots returns the recorded snapshots of a source, oldest first
+func (s *SourceOps) GetSourceSnapshots(url string) ([]SourceSnapshot, error) {
+	snapshots, err := s.loadSnapshots()
+	if err != nil {
+		return nil, NewOperationError("get source snapshots", url, err)
+	}
+	if history, ok := snapshots.Sources[url]; ok {
+		return history.Snapshots, nil
+	}
+	return []SourceSnapshot{}, nil
+}
+
+// findArchivedSnapshot locates the markdown archive written when a source was
+// first ingested, for sources that predate snapshot tracking
+func (s *SourceOps) findArchivedSnapshot(url string) *SourceSnapshot {
+	var best *SourceSnapshot
+	root := filepath.Join(s.dataDir, "sources")
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		frontmatter, _ := splitFrontmatter(string(data))
+		if frontmatterValue(frontmatter, "url") != url {
+			return nil
+		}
+		if best == nil || info.ModTime().After(best.FetchedAt) {
+			best = &SourceSnapshot{
+				FetchedAt:   info.ModTime(),
+				ContentHash: frontmatterValue(frontmatter, "content_hash"),
+				ArchivePath: path,
+				WARC:        frontmatterValue(frontmatter, "warc"),
+			}
+		}
+		return nil
+	})
+
+	if best != nil {
+		if text := previousText(best.ArchivePath, url); text != "" {
+			best.TextHash = hashText(text)
+		}
+	}
+
+	return best
+}
+
+// writeRecheckReport writes a markdown report of a recheck run to data/reports
+func (s *SourceOps) writeRecheckReport(report *RecheckReport) (string, error) {
+	dir := filepath.Join(s.dataDir, "reports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# Source Recheck %s\n\n", report.StartedAt.Format("2006-01-02 15:04")))
+
+	counts := make(map[RecheckStatus]int)
+	for _, r := range report.Results {
+		counts[r.Status]++
+	}
+	b.WriteString(fmt.Sprintf("Checked %d sources: %d changed, %d unchanged, %d new baselines, %d errors.\n\n",
+		len(report.Results), counts[RecheckChanged], counts[RecheckUnchanged], counts[RecheckBaseline], counts[RecheckError]))
+
+	for _, r := range report.Results {
+		if r.Status != RecheckChanged {
+			continue
+		}
+		title := r.Title
+		if title == "" {
+			title = r.URL
+		}
+		b.WriteString(fmt.Sprintf("## %s\n\n", title))
+		b.WriteString(fmt.Sprintf("- URL: %s\n", r.URL))
+		if !r.PreviousFetchedAt.IsZero() {
+			b.WriteString(fmt.Sprintf("- Previous capture: %s\n", r.PreviousFetchedAt.Format("2006-01-02 15:04")))
+		}
+		b.WriteString(fmt.Sprintf("- New snapshot: %s\n", r.ArchivedPath))
+		b.WriteString(fmt.Sprintf("- %d passages added or changed, %d removed\n\n", len(r.Added), len(r.Removed)))
+
+		for _, p := range r.Removed {
+			b.WriteString(quoteDiff("-", p))
+		}
+		for _, p := range r.Added {
+			b.WriteString(quoteDiff("+", p))
+		}
+		b.WriteString("\n")
+	}
+
+	var failed []RecheckResult
+	for _, r := range report.Results {
+		if r.Status == RecheckError {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		b.WriteString("## Errors\n\n")
+		for _, r := range failed {
+			b.WriteString(fmt.Sprintf("- %s: %s\n", r.URL, r.Error))
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("recheck-%s.md", report.StartedAt.Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+func quoteDiff(marker, paragraph string) string {
+	return "```diff\n" + marker + " " + strings.ReplaceAll(paragraph, "\n", "\n"+marker+" ") + "\n```\n"
+}
+
+// diffParagraphs compares two texts paragraph by paragraph using a longest
+// common subsequence and returns paragraphs only in newText and only in oldText
+func diffParagraphs(oldText, newText string) (added, removed []string) {
+	oldParas := splitParagraphs(oldText)
+	newParas := splitParagraphs(newText)
+
+	n, m := len(oldParas), len(newParas)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if normalizeWhitespace(oldParas[i]) == normalizeWhitespace(newParas[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case normalizeWhitespace(oldParas[i]) == normalizeWhitespace(newParas[j]):
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, oldParas[i])
+			i++
+		default:
+			added = append(added, newParas[j])
+			j++
+		}
+	}
+	removed = append(removed, oldParas[i:]...)
+	added = append(added, newParas[j:]...)
+
+	return added, removed
+}
+
+func splitParagraphs(text string) []string {
+	var paras []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paras = append(paras, p)
+		}
+	}
+	return paras
+}
+
+func normalizeWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// hashText hashes the whitespace-normalized text of a source
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(normalizeWhitespace(text)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// previousText returns the text of an archived capture for comparison. The
+// text is re-derived from the archived raw HTML with the current converter so
+// that converter changes do not show up as edits; archives without raw HTML
+// (written by older versions) cannot be compared and yield "".
+func previousText(archivePath, url string) string {
+	if archivePath == "" {
+		return ""
+	}
+	rawHTML, err := os.ReadFile(strings.TrimSuffix(archivePath, ".md") + ".html")
+	if err != nil {
+		return ""
+	}
+	return sources.MainContentMarkdown(string(rawHTML), url)
+}
+
+// splitFrontmatter separates a "---" delimited frontmatter block from the body
+func splitFrontmatter(content string) (string, string) {
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content
+	}
+	rest := content[4:]
+	end := strings.Index(rest, "\n---\n")
+	if end < 0 {
+		return "", content
+	}
+	return rest[:end], rest[end+5:]
+}
+
+// frontmatterValue reads a simple "key: value" line from frontmatter
+func frontmatterValue(frontmatter, key string) string {
+	for _, line := range strings.Split(frontmatter, "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func (s *SourceOps) getSnapshotsPath() string {
+	return filepath.Join(s.dataDir, ".silvia", "source_snapshots.json")
+}
+
+func (s *SourceOps) loadSnapshots() (*sourceSnapshots, error) {
+	data, err := os.ReadFile(s.getSnapshotsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &sourceSnapshots{Sources: make(map[string]*sourceHistory)}, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshots file: %w", err)
+	}
+
+	var snapshots sourceSnapshots
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshots file: %w", err)
+	}
+	if snapshots.Sources == nil {
+		snapshots.Sources = make(map[string]*sourceHistory)
+	}
+	return &snapshots, nil
+}
+
+func (s *SourceOps) saveSnapshots(snapshots *sourceSnapshots) error {
+	path := s.getSnapshotsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshots: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,65 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"silvia/internal/llm"
+)
+
+func TestRecheckSourceRetriesFailedExtraction(t *testing.T) {
+	page := testPage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+	url := server.URL + "/2024/transit-plan"
+
+	failing := false
+	provider := llm.NewFakeProvider()
+	provider.SetHandler(func(request llm.ChatRequest) (*llm.ChatResponse, error) {
+		if failing {
+			return nil, errors.New("model overloaded")
+		}
+		return fakeExtraction(request)
+	})
+	ops, _ := newTestOps(t, provider, t.TempDir(), llm.CacheOff)
+	ctx := context.Background()
+
+	if _, err := ops.Source.ExtractFromHTML(ctx, url, page, "Council Approves Transit Plan", nil, nil); err != nil {
+		t.Fatalf("ExtractFromHTML: %v", err)
+	}
+
+	// The page gains a paragraph, but extracting it fails
+	page = strings.Replace(testPage, "</article>", "<p>The airport authority agreed to contribute forty million dollars toward the new light rail station and its parking garage.</p></article>", 1)
+	failing = true
+	result, err := ops.Source.RecheckSource(ctx, url)
+	if err != nil {
+		t.Fatalf("RecheckSource: %v", err)
+	}
+	if result.Status != RecheckChanged || result.Error == "" || result.ArchivedPath != "" {
+		t.Fatalf("failed recheck = %s, error %q, archived %q; want changed, an error and no snapshot", result.Status, result.Error, result.ArchivedPath)
+	}
+
+	// The next recheck still sees the change, and records it once extracted
+	failing = false
+	result, err = ops.Source.RecheckSource(ctx, url)
+	if err != nil {
+		t.Fatalf("RecheckSource: %v", err)
+	}
+	if result.Status != RecheckChanged || result.Error != "" || result.ArchivedPath == "" {
+		t.Fatalf("retried recheck = %s, error %q, archived %q; want changed and a snapshot", result.Status, result.Error, result.ArchivedPath)
+	}
+	snapshots, err := ops.Source.GetSourceSnapshots(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Errorf("got %d snapshots, want 1", len(snapshots))
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"silvia/internal/graph"
@@ -13,6 +15,7 @@ import (
 type SearchOps struct {
 	graph   *graph.Manager
 	dataDir string
+	names   *nameIndex // Entity names for annotating pages
 }
 
 // NewSearchOps creates a new search operations handler
@@ -20,13 +23,14 @@ func NewSearchOps(graphManager *graph.Manager, dataDir string) *SearchOps {
 	return &SearchOps{
 		graph:   graphManager,
 		dataDir: dataDir,
+		names:   &nameIndex{},
 	}
 }
 
 // SearchEntities searches for entities matching a query
 func (s *SearchOps) SearchEntities(query string) (*SearchResult, error) {
 	if query == "" {
-		return nil, NewOperationError("search entities", "", fmt.Errorf("query cannot be empty"))
+		return nil, NewOperationError("search entities", "", invalidf("query cannot be empty"))
 	}
 
 	// Use graph's search function
@@ -103,7 +107,7 @@ func (s *SearchOps) GetEntitiesByType(entityType string) ([]*graph.Entity, error
 	}
 	if !isValid {
 		return nil, NewOperationError("get entities by type", entityType,
-			fmt.Errorf("invalid entity type: %s", entityType))
+			invalidf("invalid entity type: %s", entityType))
 	}
 
 	// Get all entities from the graph
@@ -123,6 +127,28 @@ func (s *SearchOps) GetEntitiesByType(entityType string) ([]*graph.Entity, error
 	return filtered, nil
 }
 
+// ListEntities returns all entities, or those of one type, sorted by ID
+func (s *SearchOps) ListEntities(entityType string) ([]*graph.Entity, error) {
+	var entities []*graph.Entity
+	var err error
+	if entityType == "" {
+		entities, err = s.getAllEntities()
+		if err != nil {
+			return nil, NewOperationError("list entities", "", err)
+		}
+	} else {
+		entities, err = s.GetEntitiesByType(entityType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].Metadata.ID < entities[j].Metadata.ID
+	})
+	return entities, nil
+}
+
 // SuggestRelated suggests entities that might be related based on content similarity
 func (s *SearchOps) SuggestRelated(entityID string, limit int) ([]*graph.Entity, error) {
 	// Load the source entity
@@ -342,3 +368,61 @@ func (s *SearchOps) getAllEntities() ([]*graph.Entity, error) {
 
 	return entities, nil
 }
+
+// FindPath finds the shortest chain of relationships, followed in either
+// direction, from one entity to another. maxDepth caps the number of steps
+// (0 means 6).
+func (s *SearchOps) FindPath(fromID, toID string, maxDepth int) (*ConnectionPath, error) {
+	if maxDepth <= 0 {
+		maxDepth = 6
+	}
+	entities, err := s.getAllEntities()
+	if err != nil {
+		return nil, NewOperationError("find path", fromID, err)
+	}
+
+	// Index relationships both ways, so a path may follow a link backwards
+	neighbors := make(map[string][]PathStep)
+	for _, entity := range entities {
+		id := entity.Metadata.ID
+		for _, rel := range entity.Relationships {
+			neighbors[id] = append(neighbors[id], PathStep{From: id, Type: rel.Type, To: rel.Target})
+			neighbors[rel.Target] = append(neighbors[rel.Target], PathStep{From: rel.Target, Type: rel.Type, To: id, Reverse: true})
+		}
+	}
+	for _, id := range []string{fromID, toID} {
+		if !slices.ContainsFunc(entities, func(e *graph.Entity) bool { return e.Metadata.ID == id }) {
+			return nil, NewOperationError("find path", id, notFoundf("entity not found"))
+		}
+	}
+
+	// Breadth-first search, remembering how each entity was reached
+	reachedBy := map[string]*PathStep{fromID: nil}
+	frontier := []string{fromID}
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		if _, ok := reachedBy[toID]; ok {
+			break
+		}
+		var next []string
+		for _, id := range frontier {
+			for _, step := range neighbors[id] {
+				if _, seen := reachedBy[step.To]; seen {
+					continue
+				}
+				reachedBy[step.To] = &step
+				next = append(next, step.To)
+			}
+		}
+		frontier = next
+	}
+
+	if _, ok := reachedBy[toID]; !ok {
+		return nil, NewOperationError("find path", fromID, notFoundf("no connection to %s within %d steps", toID, maxDepth))
+	}
+	path := &ConnectionPath{From: fromID, To: toID}
+	for id := toID; reachedBy[id] != nil; id = reachedBy[id].From {
+		path.Steps = append(path.Steps, *reachedBy[id])
+	}
+	slices.Reverse(path.Steps)
+	return path, nil
+}
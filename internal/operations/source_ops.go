@@ -12,6 +12,7 @@ import (
 
 	"silvia/internal/graph"
 	"silvia/internal/llm"
+	"silvia/internal/prompts"
 	"silvia/internal/sources"
 )
 
@@ -22,6 +23,9 @@ type SourceOps struct {
 	sources   *sources.Manager
 	extractor *sources.Extractor
 	dataDir   string
+	events    *EventLog
+	queue     *QueueOps // Records the outcome of ingesting a queued source
+	actor     string    // API token changes are attributed to; empty for local changes
 }
 
 // NewSourceOps creates a new source operations handler
@@ -37,14 +41,27 @@ func NewSourceOps(graphManager *graph.Manager, llmClient *llm.Client, sourcesMan
 
 // IngestSource ingests a source URL, extracting entities and relationships
 func (s *SourceOps) IngestSource(ctx context.Context, url string, force bool) (*IngestResult, error) {
+	return s.IngestSourceWithProgress(ctx, url, force, nil)
+}
+
+// IngestSourceWithProgress is IngestSource with each stage reported to
+// onProgress, which may be nil. If the URL is queued, its queue item is
+// marked done or failed. A source some of whose chunks failed to extract
+// keeps what was extracted but is not marked processed, and its queue item
+// is marked failed, so it can be ingested again.
+func (s *SourceOps) IngestSourceWithProgress(ctx context.Context, url string, force bool, onProgress IngestProgressFunc) (result *IngestResult, err error) {
+	defer func() { s.queue.FinishIngest(url, ingestOutcome(result, err)) }()
+	ctx = llm.WithOperation(ctx, llm.OpIngest, url)
+
 	startTime := time.Now()
 
 	// Check if already processed
 	if !force && s.isSourceProcessed(url) {
-		return nil, NewOperationError("ingest source", url, fmt.Errorf("source already processed"))
+		return nil, NewOperationError("ingest source", url, conflictf("source already processed"))
 	}
 
 	// Fetch the source
+	reportIngestProgress(onProgress, IngestProgress{Stage: StageFetch, Detail: url})
 	source, err := s.sources.Fetch(ctx, url)
 	if err != nil {
 		return nil, NewOperationError("ingest source", url, fmt.Errorf("failed to fetch: %w", err))
@@ -58,28 +75,62 @@ func (s *SourceOps) IngestSource(ctx context.Context, url string, force bool) (*
 	}
 
 	// Extract entities and relationships
-	extractResult, err := s.extractor.Extract(ctx, source)
+	reportIngestProgress(onProgress, IngestProgress{Stage: StageExtract, Detail: source.Title})
+	extractResult, err := s.extractorFor(onProgress).Extract(ctx, source)
 	if err != nil {
 		return nil, NewOperationError("ingest source", url, fmt.Errorf("extraction failed: %w", err))
 	}
 
 	// Process extraction results using shared logic
+	reportIngestProgress(onProgress, IngestProgress{Stage: StageWrite, Total: len(extractResult.Entities)})
 	extractedEntities, extractedLinks := s.processExtractionResult(extractResult, url)
+	sourceEntity := s.saveSourceEntity(source, extractResult, archivedPath)
 
-	// Mark source as processed
-	s.markSourceProcessed(url)
+	// Mark source as processed, unless chunks are missing from it
+	if extractResult.FailedChunks == 0 {
+		s.markSourceProcessed(url)
+	}
+	s.events.Publish(EventSourceIngested, url, map[string]any{
+		"entities":      len(extractedEntities),
+		"links":         len(extractedLinks),
+		"failed_chunks": extractResult.FailedChunks,
+	})
 
 	return &IngestResult{
 		SourceURL:         url,
 		ArchivedPath:      archivedPath,
+		SourceEntity:      sourceEntity,
 		ExtractedEntities: extractedEntities,
 		ExtractedLinks:    extractedLinks,
+		ChunkCount:        extractResult.ChunkCount,
+		FailedChunks:      extractResult.FailedChunks,
 		ProcessingTime:    time.Since(startTime),
 	}, nil
 }
 
-// ExtractFromHTML extracts entities from HTML content (used by browser extension)
-func (s *SourceOps) ExtractFromHTML(ctx context.Context, url, html, title string, metadata map[string]string) (*IngestResult, error) {
+// ExtractFromHTML extracts entities from HTML content (used by browser
+// extension). Passages the user highlighted are archived with the page,
+// steer extraction, and are recorded as quotes or claims.
+func (s *SourceOps) ExtractFromHTML(ctx context.Context, url, html, title string, metadata map[string]string, highlights []sources.Highlight) (*IngestResult, error) {
+	return s.ExtractFromHTMLWithProgress(ctx, url, html, title, metadata, highlights, nil)
+}
+
+// ExtractFromHTMLWithProgress is ExtractFromHTML with each stage reported to
+// onProgress, which may be nil. If the URL is queued, its queue item is
+// marked done or failed. A source some of whose chunks failed to extract
+// keeps what was extracted but is not marked processed, and its queue item
+// is marked failed, so it can be ingested again.
+func (s *SourceOps) ExtractFromHTMLWithProgress(ctx context.Context, url, html, title string, metadata map[string]string, highlights []sources.Highlight, onProgress IngestProgressFunc) (result *IngestResult, err error) {
+	defer func() { s.queue.FinishIngest(url, ingestOutcome(result, err)) }()
+	ctx = llm.WithOperation(ctx, llm.OpIngest, url)
+
+	startTime := time.Now()
+
+	highlights, err = sources.NormalizeHighlights(url, highlights)
+	if err != nil {
+		return nil, NewOperationError("extract from HTML", url, invalidf("%v", err))
+	}
+
 	// Convert HTML to markdown
 	webFetcher := sources.NewWebFetcher()
 	markdown := webFetcher.ConvertHTMLToMarkdown(html)
@@ -91,6 +142,7 @@ func (s *SourceOps) ExtractFromHTML(ctx context.Context, url, html, title string
 		Content:    markdown,
 		RawContent: html,
 		Metadata:   metadata,
+		Highlights: highlights,
 	}
 
 	// Add metadata
@@ -107,26 +159,122 @@ func (s *SourceOps) ExtractFromHTML(ctx context.Context, url, html, title string
 	}
 
 	// Extract entities
-	extractResult, err := s.extractor.Extract(ctx, source)
+	reportIngestProgress(onProgress, IngestProgress{Stage: StageExtract, Detail: title})
+	extractResult, err := s.extractorFor(onProgress).Extract(ctx, source)
 	if err != nil {
 		return nil, NewOperationError("extract from HTML", url, err)
 	}
 
 	// Process extraction results using shared logic
+	reportIngestProgress(onProgress, IngestProgress{Stage: StageWrite, Total: len(extractResult.Entities)})
 	extractedEntities, extractedLinks := s.processExtractionResult(extractResult, url)
+	sourceEntity := s.saveSourceEntity(source, extractResult, archivedPath)
 
-	// Mark source as processed
-	s.markSourceProcessed(url)
+	// Record the highlights as quotes and claims pointing back to them
+	quotes, err := s.saveQuotes(source, archivedPath, extractedEntities)
+	if err != nil {
+		fmt.Printf("Warning: failed to save quotes: %v\n", err)
+	}
+
+	// Mark source as processed, unless chunks are missing from it
+	if extractResult.FailedChunks == 0 {
+		s.markSourceProcessed(url)
+	}
+	s.events.Publish(EventSourceIngested, url, map[string]any{
+		"entities":      len(extractedEntities),
+		"links":         len(extractedLinks),
+		"highlights":    len(highlights),
+		"failed_chunks": extractResult.FailedChunks,
+	})
 
 	return &IngestResult{
 		SourceURL:         url,
 		ArchivedPath:      archivedPath,
+		SourceEntity:      sourceEntity,
 		ExtractedEntities: extractedEntities,
 		ExtractedLinks:    extractedLinks,
-		ProcessingTime:    time.Since(time.Now()),
+		ChunkCount:        extractResult.ChunkCount,
+		FailedChunks:      extractResult.FailedChunks,
+		ProcessingTime:    time.Since(startTime),
+		Quotes:            quotes,
 	}, nil
 }
 
+// ingestOutcome is the error an ingest is recorded with on the queue. A
+// partial extraction counts as failed so the source is tried again.
+func ingestOutcome(result *IngestResult, err error) error {
+	if err == nil && result != nil {
+		return partialExtraction(result.FailedChunks, result.ChunkCount)
+	}
+	return err
+}
+
+// ExtractionOutcome is the error an ingest that ran the extractor itself,
+// as the CLI does, is recorded with on the queue
+func ExtractionOutcome(extraction *sources.ExtractionResult, err error) error {
+	if err == nil && extraction != nil {
+		return partialExtraction(extraction.FailedChunks, extraction.ChunkCount)
+	}
+	return err
+}
+
+func partialExtraction(failed, total int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("extraction failed for %d of %d chunks", failed, total)
+}
+
+// saveSourceEntity saves the entity summarizing a source, with the hash and
+// WARC archive of its capture, and returns its ID. Extractions without a
+// summary have no source entity.
+func (s *SourceOps) saveSourceEntity(source *sources.Source, extractResult *sources.ExtractionResult, archivedPath string) string {
+	if extractResult.SourceSummary == nil {
+		return ""
+	}
+	entity := sources.NewSourceEntity(source, extractResult.SourceSummary, archivedPath)
+	entity.Metadata.UpdatedBy = s.actor
+	existed := s.graph.EntityExists(entity.Metadata.ID)
+	if err := s.graph.SaveEntity(entity); err != nil {
+		fmt.Printf("Warning: failed to save source entity %s: %v\n", entity.Metadata.ID, err)
+		return ""
+	}
+	if existed {
+		s.events.Publish(EventEntityUpdated, entity.Metadata.ID, entityEventData(entity))
+	} else {
+		s.events.Publish(EventEntityCreated, entity.Metadata.ID, entityEventData(entity))
+	}
+	return entity.Metadata.ID
+}
+
+// extractorFor returns an extractor that reports chunk progress to
+// onProgress. Each caller with a callback gets its own extractor so
+// concurrent ingests do not see each other's progress.
+func (s *SourceOps) extractorFor(onProgress IngestProgressFunc) *sources.Extractor {
+	if onProgress == nil {
+		return s.extractor
+	}
+	extractor := sources.NewExtractor(s.llm)
+	extractor.SetProgressFunc(func(p sources.ExtractionProgress) {
+		detail := p.Heading
+		if p.Err != nil {
+			detail = p.Err.Error()
+		}
+		current := p.Chunk - 1
+		if p.Done {
+			current = p.Chunk
+		}
+		onProgress(IngestProgress{Stage: p.Stage, Current: current, Total: p.Total, Detail: detail})
+	})
+	return extractor
+}
+
+func reportIngestProgress(onProgress IngestProgressFunc, p IngestProgress) {
+	if onProgress != nil {
+		onProgress(p)
+	}
+}
+
 // processExtractionResult is the shared logic for processing extraction results
 // Used by both IngestSource and ExtractFromHTML to ensure consistent behavior
 func (s *SourceOps) processExtractionResult(extractResult *sources.ExtractionResult, sourceURL string) ([]ExtractedEntity, []ExtractedLink) {
@@ -146,18 +294,22 @@ func (s *SourceOps) processExtractionResult(extractResult *sources.ExtractionRes
 				Title:   extracted.Name,
 				Content: extracted.Description,
 				Metadata: graph.Metadata{
-					ID:      entityID,
-					Type:    graph.EntityType(extracted.Type),
-					Sources: []string{sourceURL},
-					Created: time.Now(),
-					Updated: time.Now(),
+					ID:        entityID,
+					Type:      graph.EntityType(extracted.Type),
+					Sources:   []string{sourceURL},
+					Created:   time.Now(),
+					Updated:   time.Now(),
+					UpdatedBy: s.actor,
 				},
 			}
+			entity.RecordPrompt(prompts.Extraction, extractResult.PromptVersion)
+			entity.RecordChunks(sourceURL, extracted.Chunks, extractResult.ChunkCount)
 
 			if err := s.graph.SaveEntity(entity); err != nil {
 				fmt.Printf("Warning: failed to save entity %s: %v\n", entityID, err)
 				continue
 			}
+			s.events.Publish(EventEntityCreated, entityID, entityEventData(entity))
 		} else {
 			// Update existing entity with new source
 			entity, err := s.graph.LoadEntity(entityID)
@@ -166,15 +318,20 @@ func (s *SourceOps) processExtractionResult(extractResult *sources.ExtractionRes
 				continue
 			}
 
-			// Add source if not already present
+			// Add source if not already present, and the chunks it was found in
 			hasSource := slices.Contains(entity.Metadata.Sources, sourceURL)
-			if !hasSource {
-				entity.Metadata.Sources = append(entity.Metadata.Sources, sourceURL)
+			chunksChanged := entity.RecordChunks(sourceURL, extracted.Chunks, extractResult.ChunkCount)
+			if !hasSource || chunksChanged {
+				if !hasSource {
+					entity.Metadata.Sources = append(entity.Metadata.Sources, sourceURL)
+				}
 				entity.Metadata.Updated = time.Now()
+				entity.Metadata.UpdatedBy = s.actor
 				if err := s.graph.SaveEntity(entity); err != nil {
 					fmt.Printf("Warning: failed to update entity %s: %v\n", entityID, err)
 					continue
 				}
+				s.events.Publish(EventEntityUpdated, entityID, entityEventData(entity))
 				wasUpdated = true
 			}
 		}
@@ -187,6 +344,7 @@ func (s *SourceOps) processExtractionResult(extractResult *sources.ExtractionRes
 			Content:     extracted.Description,
 			IsNew:       isNew,
 			WasUpdated:  wasUpdated,
+			Chunks:      extracted.Chunks,
 		})
 	}
 
@@ -219,12 +377,36 @@ func (s *SourceOps) archiveSource(source *sources.Source) (string, error) {
 		return "", fmt.Errorf("failed to create archive directory: %w", err)
 	}
 
+	// Keep the full original HTML; the markdown only holds the main content
+	rawPath, err := sources.SaveRawHTML(archivePath, source)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep the user's highlights with their DOM anchors
+	highlightsPath, err := sources.SaveHighlights(archivePath, source)
+	if err != nil {
+		return "", err
+	}
+
+	// Preserve the exact capture as WARC; this also records the content hash
+	// and WARC path in the source metadata written below
+	if _, err := sources.ArchiveCapture(s.dataDir, source); err != nil {
+		fmt.Printf("Warning: failed to write WARC archive: %v\n", err)
+	}
+
 	// Prepare content with metadata
 	var content strings.Builder
 	content.WriteString("---\n")
 	content.WriteString(fmt.Sprintf("url: %s\n", source.URL))
 	content.WriteString(fmt.Sprintf("title: %s\n", source.Title))
 	content.WriteString(fmt.Sprintf("fetched_at: %s\n", time.Now().Format(time.RFC3339)))
+	if rawPath != "" {
+		content.WriteString(fmt.Sprintf("raw_html: %s\n", filepath.Base(rawPath)))
+	}
+	if highlightsPath != "" {
+		content.WriteString(fmt.Sprintf("highlights: %s\n", filepath.Base(highlightsPath)))
+	}
 	if source.Metadata != nil {
 		for key, value := range source.Metadata {
 			content.WriteString(fmt.Sprintf("%s: %s\n", key, value))
@@ -232,6 +414,15 @@ func (s *SourceOps) archiveSource(source *sources.Source) (string, error) {
 	}
 	content.WriteString("---\n\n")
 	content.WriteString(source.Content)
+	if len(source.Highlights) > 0 {
+		content.WriteString("\n\n## Highlights\n")
+		for _, h := range source.Highlights {
+			content.WriteString("\n> " + strings.ReplaceAll(h.Text, "\n", "\n> ") + "\n")
+			if h.Note != "" {
+				content.WriteString(fmt.Sprintf("\n%s\n", h.Note))
+			}
+		}
+	}
 
 	// Write to file
 	if err := os.WriteFile(archivePath, []byte(content.String()), 0644); err != nil {
@@ -241,6 +432,84 @@ func (s *SourceOps) archiveSource(source *sources.Source) (string, error) {
 	return archivePath, nil
 }
 
+// ListArchivedSources returns the latest archive of every ingested source,
+// sorted by URL
+func (s *SourceOps) ListArchivedSources() ([]ArchivedSource, error) {
+	latest := make(map[string]ArchivedSource)
+	root := filepath.Join(s.dataDir, "sources")
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		source, ok := readArchiveHeader(path, info)
+		if !ok {
+			return nil
+		}
+		if existing, seen := latest[source.URL]; !seen || source.FetchedAt.After(existing.FetchedAt) {
+			latest[source.URL] = source
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, NewOperationError("list sources", "", err)
+	}
+
+	archived := make([]ArchivedSource, 0, len(latest))
+	for _, source := range latest {
+		archived = append(archived, source)
+	}
+	slices.SortFunc(archived, func(a, b ArchivedSource) int { return strings.Compare(a.URL, b.URL) })
+	return archived, nil
+}
+
+// ReadArchivedSource returns the latest archive of a source: its metadata
+// and the markdown captured from it
+func (s *SourceOps) ReadArchivedSource(url string) (*ArchivedSource, string, error) {
+	archived, err := s.ListArchivedSources()
+	if err != nil {
+		return nil, "", err
+	}
+	index := slices.IndexFunc(archived, func(a ArchivedSource) bool { return a.URL == url })
+	if index < 0 {
+		return nil, "", NewOperationError("read source", url, notFoundf("no archived capture"))
+	}
+	data, err := os.ReadFile(archived[index].ArchivePath)
+	if err != nil {
+		return nil, "", NewOperationError("read source", url, err)
+	}
+	return &archived[index], string(data), nil
+}
+
+// readArchiveHeader reads the URL, title and fetch time from an archive's
+// frontmatter
+func readArchiveHeader(path string, info os.FileInfo) (ArchivedSource, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ArchivedSource{}, false
+	}
+	frontmatter, _ := splitFrontmatter(string(data))
+	url := frontmatterValue(frontmatter, "url")
+	if url == "" {
+		return ArchivedSource{}, false
+	}
+	fetchedAt, err := time.Parse(time.RFC3339, frontmatterValue(frontmatter, "fetched_at"))
+	if err != nil {
+		fetchedAt = info.ModTime()
+	}
+	return ArchivedSource{
+		URL:         url,
+		Title:       frontmatterValue(frontmatter, "title"),
+		FetchedAt:   fetchedAt,
+		ArchivePath: path,
+	}, true
+}
+
 // generateEntityID generates a consistent ID for an entity
 func (s *SourceOps) generateEntityID(entityType, name string) string {
 	// Convert type to singular form
@@ -290,7 +559,18 @@ func (s *SourceOps) loadTracker() (*sourceTracker, error) {
 
 	var tracker sourceTracker
 	if err := json.Unmarshal(data, &tracker); err != nil {
-		return nil, err
+		// The CLI tracker stores a list of processed sources instead
+		var list []struct {
+			URL         string    `json:"url"`
+			ProcessedAt time.Time `json:"processed_at"`
+		}
+		if listErr := json.Unmarshal(data, &list); listErr != nil {
+			return nil, err
+		}
+		tracker.ProcessedURLs = make(map[string]time.Time, len(list))
+		for _, item := range list {
+			tracker.ProcessedURLs[item.URL] = item.ProcessedAt
+		}
 	}
 	if tracker.ProcessedURLs == nil {
 		tracker.ProcessedURLs = make(map[string]time.Time)
@@ -313,6 +593,11 @@ func (s *SourceOps) saveTracker(tracker *sourceTracker) error {
 	return os.WriteFile(trackerPath, data, 0644)
 }
 
+// IsSourceProcessed reports whether a URL has already been ingested
+func (s *SourceOps) IsSourceProcessed(url string) bool {
+	return s.isSourceProcessed(url)
+}
+
 func (s *SourceOps) isSourceProcessed(url string) bool {
 	tracker, err := s.loadTracker()
 	if err != nil {
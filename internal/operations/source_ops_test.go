@@ -0,0 +1,235 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"silvia/internal/graph"
+	"silvia/internal/llm"
+	"silvia/internal/sources"
+)
+
+const testPageURL = "https://example.com/2024/transit-plan"
+
+const testPage = `<!DOCTYPE html>
+<html><head><title>Council Approves Transit Plan</title></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<article>
+<h1>Council Approves Transit Plan</h1>
+<p>The city council voted on Tuesday to approve a transit plan sponsored by council member James Okafor. The plan adds three bus rapid transit lines and extends light rail service to the airport by 2030.</p>
+<p>The Metro Transit Authority will run the new lines, funded by a half-cent sales tax approved by voters last November along with federal matching grants.</p>
+</article>
+</body></html>`
+
+const testExtraction = `{
+  "entities": [
+    {"name": "James Okafor", "type": "person", "description": "City council member", "content": "Sponsored the transit plan."},
+    {"name": "Metro Transit Authority", "type": "organization", "description": "Runs the city's transit", "content": "Will run the new lines."}
+  ],
+  "relationships": [{"source": "James Okafor", "target": "Metro Transit Authority", "type": "oversees"}],
+  "links": [{"url": "https://example.com/2023/sales-tax", "title": "Sales tax vote", "relevance": "high", "category": "reference"}]
+}`
+
+const testSummary = `{"title": "Council Approves Transit Plan", "publication": "Example News", "key_themes": ["transit"], "analysis": "A large expansion."}`
+
+// fakeExtraction answers extraction requests, which ask for a JSON schema,
+// with testExtraction and everything else with testSummary
+func fakeExtraction(request llm.ChatRequest) (*llm.ChatResponse, error) {
+	if request.ResponseFormat != nil && request.ResponseFormat.Type == llm.ResponseFormatJSONSchema {
+		return &llm.ChatResponse{Content: testExtraction}, nil
+	}
+	return &llm.ChatResponse{Content: testSummary}, nil
+}
+
+// newTestOps returns operations on a fresh data directory whose LLM calls
+// go to provider through a cache in cacheDir
+func newTestOps(t *testing.T, provider llm.Provider, cacheDir string, mode llm.CacheMode) (*Operations, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	client := llm.NewClientWithProvider(provider)
+	client.SetCache(llm.NewCache(cacheDir, mode))
+	return New(graph.NewManager(dataDir), client, sources.NewManager(), dataDir), dataDir
+}
+
+func TestExtractFromHTMLWithFakeProvider(t *testing.T) {
+	cacheDir := t.TempDir()
+	ctx := context.Background()
+
+	// Record: the fake provider answers and the cache keeps its responses
+	provider := llm.NewFakeProvider()
+	provider.SetHandler(fakeExtraction)
+	ops, dataDir := newTestOps(t, provider, cacheDir, llm.CacheReadWrite)
+	if err := ops.Queue.AddToQueue(testPageURL, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ops.Source.ExtractFromHTML(ctx, testPageURL, testPage, "Council Approves Transit Plan", nil, nil)
+	if err != nil {
+		t.Fatalf("ExtractFromHTML: %v", err)
+	}
+	recorded := len(provider.Requests())
+	if recorded == 0 {
+		t.Fatal("the provider was never called")
+	}
+	checkIngest(t, ops, dataDir, result)
+
+	// Replay: a provider that fails every request, so the same ingest into
+	// a new graph must be answered from the cache alone
+	offline := llm.NewFakeProvider()
+	offline.SetHandler(func(llm.ChatRequest) (*llm.ChatResponse, error) {
+		return nil, errors.New("provider called during replay")
+	})
+	replayOps, replayDir := newTestOps(t, offline, cacheDir, llm.CacheReplay)
+	if err := replayOps.Queue.AddToQueue(testPageURL, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := replayOps.Source.ExtractFromHTML(ctx, testPageURL, testPage, "Council Approves Transit Plan", nil, nil)
+	if err != nil {
+		t.Fatalf("replayed ExtractFromHTML: %v", err)
+	}
+	if n := len(offline.Requests()); n != 0 {
+		t.Errorf("replay made %d provider requests, want none", n)
+	}
+	checkIngest(t, replayOps, replayDir, replayed)
+}
+
+// checkIngest checks what ingesting testPage left in the graph and queue
+func checkIngest(t *testing.T, ops *Operations, dataDir string, result *IngestResult) {
+	t.Helper()
+
+	var ids []string
+	for _, entity := range result.ExtractedEntities {
+		ids = append(ids, entity.ID)
+		if !entity.IsNew {
+			t.Errorf("%s was not new", entity.ID)
+		}
+	}
+	if got := strings.Join(ids, ","); got != "people/james-okafor,organizations/metro-transit-authority" {
+		t.Errorf("extracted %s", got)
+	}
+	if len(result.ExtractedLinks) != 1 || result.FailedChunks != 0 {
+		t.Errorf("got %d links and %d failed chunks, want 1 and 0", len(result.ExtractedLinks), result.FailedChunks)
+	}
+
+	person, err := ops.Entity.ReadEntity("people/james-okafor")
+	if err != nil {
+		t.Fatalf("extracted person was not saved: %v", err)
+	}
+	if len(person.Metadata.Sources) != 1 || person.Metadata.Sources[0] != testPageURL {
+		t.Errorf("person sources = %v, want [%s]", person.Metadata.Sources, testPageURL)
+	}
+
+	// The source entity records the capture it was made from
+	source, err := ops.Entity.ReadEntity(result.SourceEntity)
+	if err != nil {
+		t.Fatalf("source entity %q was not saved: %v", result.SourceEntity, err)
+	}
+	if !strings.HasPrefix(source.Metadata.ContentHash, "sha256:") {
+		t.Errorf("source entity content hash = %q", source.Metadata.ContentHash)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, source.Metadata.Archive)); err != nil {
+		t.Errorf("source entity archive: %v", err)
+	}
+
+	item := queueItem(t, ops.Queue, testPageURL)
+	if item.Status != QueueDone {
+		t.Errorf("queue item is %s, want done", item.Status)
+	}
+	if !ops.Source.isSourceProcessed(testPageURL) {
+		t.Error("source was not marked processed")
+	}
+}
+
+func TestExtractFromHTMLReplayMiss(t *testing.T) {
+	ops, _ := newTestOps(t, llm.NewFakeProvider(), t.TempDir(), llm.CacheReplay)
+	if err := ops.Queue.AddToQueue(testPageURL, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ops.Source.ExtractFromHTML(context.Background(), testPageURL, testPage, "Council Approves Transit Plan", nil, nil)
+	if !errors.Is(err, llm.ErrCacheMiss) {
+		t.Fatalf("error = %v, want a cache miss", err)
+	}
+	if item := queueItem(t, ops.Queue, testPageURL); item.Status != QueueFailed {
+		t.Errorf("queue item is %s, want failed", item.Status)
+	}
+	if ops.Source.isSourceProcessed(testPageURL) {
+		t.Error("a failed source was marked processed")
+	}
+}
+
+func TestExtractFromHTMLPartialExtraction(t *testing.T) {
+	provider := llm.NewFakeProvider()
+	provider.SetHandler(func(request llm.ChatRequest) (*llm.ChatResponse, error) {
+		last := request.Messages[len(request.Messages)-1].Content
+		if strings.Contains(last, "This is part 2 of") {
+			return nil, errors.New("model overloaded")
+		}
+		return fakeExtraction(request)
+	})
+	ops, _ := newTestOps(t, provider, t.TempDir(), llm.CacheOff)
+	if err := ops.Queue.AddToQueue(testPageURL, 1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Enough paragraphs to be split into several chunks
+	var page strings.Builder
+	page.WriteString("<html><body><article><h1>Council Approves Transit Plan</h1>")
+	for range 3 * sources.DefaultChunkSize / 200 {
+		page.WriteString("<p>The city council voted on Tuesday to approve a transit plan sponsored by council member James Okafor, adding bus rapid transit lines to the airport.</p>\n")
+	}
+	page.WriteString("</article></body></html>")
+
+	result, err := ops.Source.ExtractFromHTML(context.Background(), testPageURL, page.String(), "Council Approves Transit Plan", nil, nil)
+	if err != nil {
+		t.Fatalf("ExtractFromHTML: %v", err)
+	}
+	if result.ChunkCount < 3 || result.FailedChunks != 1 {
+		t.Fatalf("%d of %d chunks failed, want 1 of at least 3", result.FailedChunks, result.ChunkCount)
+	}
+	if len(result.ExtractedEntities) == 0 {
+		t.Error("entities from the chunks that worked were dropped")
+	}
+	if ops.Source.isSourceProcessed(testPageURL) {
+		t.Error("a partially extracted source was marked processed")
+	}
+	if item := queueItem(t, ops.Queue, testPageURL); item.Status != QueueFailed || !strings.Contains(item.Error, "1 of") {
+		t.Errorf("queue item is %s (%q), want failed for the missing chunk", item.Status, item.Error)
+	}
+}
+
+func TestExtractFromHTMLHighlights(t *testing.T) {
+	provider := llm.NewFakeProvider()
+	provider.SetHandler(fakeExtraction)
+	ops, _ := newTestOps(t, provider, t.TempDir(), llm.CacheOff)
+
+	highlights := []sources.Highlight{
+		{Text: "funded by a half-cent sales tax", Kind: sources.HighlightClaim, Note: "Check the vote count"},
+		{Text: "extends light rail service to the airport"},
+	}
+	if _, err := ops.Source.ExtractFromHTML(context.Background(), testPageURL, testPage, "Council Approves Transit Plan", nil, highlights); err != nil {
+		t.Fatalf("ExtractFromHTML: %v", err)
+	}
+
+	var prompt string
+	for _, request := range provider.Requests() {
+		if request.ResponseFormat != nil && request.ResponseFormat.Type == llm.ResponseFormatJSONSchema {
+			prompt = request.Messages[len(request.Messages)-1].Content
+		}
+	}
+	want := `=== USER HIGHLIGHTS ===
+The user highlighted these passages. Give priority to the entities, claims and relationships they contain, even if they are only mentioned in passing elsewhere:
+1. [claim] "funded by a half-cent sales tax"
+   User's note: Check the vote count
+2. [quote] "extends light rail service to the airport"
+=== END OF HIGHLIGHTS ===`
+	if !strings.Contains(prompt, want) {
+		t.Errorf("extraction prompt lacks the highlights:\n%s", prompt)
+	}
+}
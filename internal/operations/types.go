@@ -1,9 +1,14 @@
 package operations
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"time"
 
 	"silvia/internal/graph"
+	"silvia/internal/llm"
+	"silvia/internal/sources"
 )
 
 // Operations provides a unified interface for all business operations
@@ -13,6 +18,9 @@ type Operations struct {
 	Source *SourceOps
 	Search *SearchOps
 	LLM    *LLMOps
+	Feed   *FeedOps
+	Jobs   *JobOps
+	Events *EventLog
 }
 
 // MergeResult contains the result of merging two entities
@@ -33,11 +41,35 @@ type RenameResult struct {
 type IngestResult struct {
 	SourceURL         string
 	ArchivedPath      string
+	SourceEntity      string // ID of the entity summarizing the source, if any
 	ExtractedEntities []ExtractedEntity
 	ExtractedLinks    []ExtractedLink
+	ChunkCount        int
+	FailedChunks      int // Chunks whose extraction failed; the source is not marked processed
 	ProcessingTime    time.Duration
+	Quotes            []Quote // Quotes and claims recorded from the user's highlights
 }
 
+// Stages reported while a source is ingested
+const (
+	StageFetch     = "fetch"     // Downloading the source
+	StageSummarize = "summarize" // Condensing the chunks of a long source
+	StageExtract   = "extract"   // Extracting entities and links
+	StageWrite     = "write"     // Saving entities to the graph
+)
+
+// IngestProgress reports how far an ingest has got. Current and Total count
+// chunks while extracting or summarizing and are zero otherwise.
+type IngestProgress struct {
+	Stage   string `json:"stage,omitempty"`
+	Current int    `json:"current,omitempty"`
+	Total   int    `json:"total,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// IngestProgressFunc receives ingest progress updates
+type IngestProgressFunc func(IngestProgress)
+
 // ExtractedEntity represents an entity extracted from a source
 type ExtractedEntity struct {
 	ID          string
@@ -47,6 +79,7 @@ type ExtractedEntity struct {
 	Content     string
 	IsNew       bool
 	WasUpdated  bool
+	Chunks      []int // Chunks of a long source the entity was found in
 }
 
 // ExtractedLink represents a link extracted from a source
@@ -73,6 +106,44 @@ type SearchMatch struct {
 	Matches []string
 }
 
+// AnnotateResult lists the known entities a page mentions, and names on it
+// that are not in the graph yet. Offsets count characters of Text.
+type AnnotateResult struct {
+	Text     string              `json:"text,omitempty"` // The page's text, when it was given as HTML
+	Matches  []Annotation        `json:"matches"`
+	Entities []AnnotatedEntity   `json:"entities"` // Each entity matched, most mentioned first
+	Unknown  []UnknownProperNoun `json:"unknown"`
+}
+
+// Annotation is one mention of a known entity
+type Annotation struct {
+	EntityID string `json:"entity_id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Name     string `json:"name"` // The title or alias that matched
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Snippet  string `json:"snippet"`
+}
+
+// AnnotatedEntity is an entity mentioned on a page and how often
+type AnnotatedEntity struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Mentions int    `json:"mentions"`
+}
+
+// UnknownProperNoun is a name on a page that matches no entity, a candidate
+// for the queue. URL is a link on the page with that text, if there is one.
+type UnknownProperNoun struct {
+	Text     string `json:"text"`
+	Mentions int    `json:"mentions"`
+	Start    int    `json:"start"` // First mention
+	End      int    `json:"end"`
+	URL      string `json:"url,omitempty"`
+}
+
 // RelatedEntitiesResult contains related entities organized by relationship type
 type RelatedEntitiesResult struct {
 	Entity         *graph.Entity
@@ -82,25 +153,152 @@ type RelatedEntitiesResult struct {
 	All            []*graph.Entity
 }
 
+// PathStep is one relationship on a path between two entities. Reverse is
+// set when the relationship points from To back to From.
+type PathStep struct {
+	From    string
+	Type    string
+	To      string
+	Reverse bool
+}
+
+// ConnectionPath is the shortest chain of relationships linking two entities
+type ConnectionPath struct {
+	From  string
+	To    string
+	Steps []PathStep // Empty when From and To are the same entity
+}
+
 // QueueItem represents an item in the source queue
 type QueueItem struct {
-	URL         string
-	Priority    int
-	AddedAt     time.Time
-	FromSource  string
-	Description string
-	Status      string
+	URL           string
+	Priority      int
+	AddedAt       time.Time
+	FromSource    string
+	Description   string
+	Status        string    // pending, in_progress, done, failed, skipped or deferred
+	UpdatedAt     time.Time // When the status last changed
+	Attempts      int       // Times the source was claimed for ingesting
+	Error         string    // Why the last attempt failed
+	DeferredUntil time.Time // When a deferred source is pending again; zero until requeued
 }
 
-// QueueStatus represents the current state of the queue
+// QueueStatus represents the current state of the queue. Items, TotalCount
+// and ByPriority cover the states asked for; ByStatus counts every source.
 type QueueStatus struct {
 	Items      []QueueItem
 	TotalCount int
 	ByPriority map[int]int
+	ByStatus   map[string]int
 	OldestItem *QueueItem
 	NewestItem *QueueItem
 }
 
+// FeedSubscription represents a subscribed RSS or Atom feed
+type FeedSubscription struct {
+	Name          string    `json:"name"`
+	URL           string    `json:"url"`
+	Priority      int       `json:"priority"`
+	Keywords      []string  `json:"keywords,omitempty"`
+	MatchEntities bool      `json:"match_entities,omitempty"`
+	AddedAt       time.Time `json:"added_at"`
+	LastPolled    time.Time `json:"last_polled,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// FeedPollResult contains the result of polling a single feed
+type FeedPollResult struct {
+	Feed       string
+	URL        string
+	Entries    int
+	Queued     []QueueItem
+	Duplicates int
+	Filtered   int
+	Error      string
+}
+
+// RecheckStatus describes the outcome of rechecking a source
+type RecheckStatus string
+
+const (
+	RecheckUnchanged RecheckStatus = "unchanged"
+	RecheckChanged   RecheckStatus = "changed"
+	RecheckBaseline  RecheckStatus = "baseline" // No comparable earlier capture; recorded for next time
+	RecheckError     RecheckStatus = "error"
+)
+
+// SourceSnapshot is one dated capture of a source
+type SourceSnapshot struct {
+	FetchedAt   time.Time `json:"fetched_at"`
+	ContentHash string    `json:"content_hash,omitempty"` // Hash of the raw response body
+	TextHash    string    `json:"text_hash,omitempty"`    // Hash of the extracted article text
+	ArchivePath string    `json:"archive_path"`
+	WARC        string    `json:"warc,omitempty"`
+}
+
+// ArchivedSource is the latest markdown capture of an ingested source
+type ArchivedSource struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	ArchivePath string    `json:"archive_path"`
+}
+
+// SourceHighlight is a highlight saved with a capture of a source
+type SourceHighlight struct {
+	sources.Highlight
+	ArchivePath string    `json:"archive_path"` // Capture the highlight was made in
+	CapturedAt  time.Time `json:"captured_at"`
+	QuoteID     string    `json:"quote_id"` // Quote or claim recorded from it
+}
+
+// Quote is a quotation or claim taken from a highlight. It points back to
+// the exact passage: the source, the capture and the highlight's DOM anchor.
+type Quote struct {
+	ID          string                  `json:"id"`
+	Kind        string                  `json:"kind"` // quote or claim
+	Text        string                  `json:"text"`
+	Note        string                  `json:"note,omitempty"`
+	SourceURL   string                  `json:"source_url"`
+	ArchivePath string                  `json:"archive_path"`
+	HighlightID string                  `json:"highlight_id"`
+	Anchor      sources.HighlightAnchor `json:"anchor"`
+	Entities    []string                `json:"entities,omitempty"` // Extracted entities the passage names
+	Created     time.Time               `json:"created"`
+	CreatedBy   string                  `json:"created_by,omitempty"`
+}
+
+// RecheckResult contains the result of rechecking a single source
+type RecheckResult struct {
+	URL               string
+	Title             string
+	Status            RecheckStatus
+	ContentHash       string
+	TextHash          string
+	PreviousHash      string
+	PreviousFetchedAt time.Time
+	ArchivedPath      string
+	Added             []string // Paragraphs added or changed since the previous capture
+	Removed           []string // Paragraphs no longer present
+	ExtractedEntities []ExtractedEntity
+	Error             string
+}
+
+// RecheckReport summarizes a recheck run
+type RecheckReport struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Results    []RecheckResult
+	ReportPath string
+}
+
+// UsageReport contains summarized LLM usage and the current budget
+type UsageReport struct {
+	Summary    *llm.UsageSummary `json:"summary"`
+	Budget     llm.Budget        `json:"budget"`
+	SpentToday float64           `json:"spent_today"`
+}
+
 // OperationError represents an error from an operation
 type OperationError struct {
 	Operation string
@@ -115,6 +313,10 @@ func (e *OperationError) Error() string {
 	return e.Operation + " failed: " + e.Cause.Error()
 }
 
+func (e *OperationError) Unwrap() error {
+	return e.Cause
+}
+
 // NewOperationError creates a new operation error
 func NewOperationError(operation, entity string, cause error) error {
 	return &OperationError{
@@ -123,3 +325,63 @@ func NewOperationError(operation, entity string, cause error) error {
 		Cause:     cause,
 	}
 }
+
+// Sentinel errors classifying why an operation failed. Causes wrap them, so
+// callers can check with errors.Is or ask ErrorCode for a short code.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid input")
+)
+
+// Error codes returned by ErrorCode
+const (
+	CodeNotFound = "not_found"
+	CodeConflict = "conflict"
+	CodeInvalid  = "invalid"
+	CodeInternal = "internal"
+)
+
+// ErrorCode classifies an error from an operation as not_found, conflict,
+// invalid or internal
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrInvalid):
+		return CodeInvalid
+	default:
+		return CodeInternal
+	}
+}
+
+// classifiedError is an error message tagged with one of the sentinel errors
+type classifiedError struct {
+	err  error
+	kind error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// notFoundf formats an error that wraps ErrNotFound
+func notFoundf(format string, args ...any) error {
+	return &classifiedError{err: fmt.Errorf(format, args...), kind: ErrNotFound}
+}
+
+// conflictf formats an error that wraps ErrConflict
+func conflictf(format string, args ...any) error {
+	return &classifiedError{err: fmt.Errorf(format, args...), kind: ErrConflict}
+}
+
+// invalidf formats an error that wraps ErrInvalid
+func invalidf(format string, args ...any) error {
+	return &classifiedError{err: fmt.Errorf(format, args...), kind: ErrInvalid}
+}
@@ -0,0 +1,320 @@
+// Package prompts holds the LLM prompts as text/template files. Defaults are
+// embedded in the binary; a file with the same name in the data directory's
+// prompts folder replaces one, so the house style can be tuned without a
+// rebuild. Files starting with an underscore hold shared {{define}} blocks,
+// such as the citation rules, that the other prompts include.
+package prompts
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Prompt names
+const (
+	Extraction = "extraction" // Entity, relationship and link extraction
+	Summary    = "summary"    // Structured source summaries
+	Condense   = "condense"   // Notes on one chunk of a long source
+	Merge      = "merge"      // Merging two entities
+	Refine     = "refine"     // Refining an entity from its sources
+)
+
+// Prompt parts, {{define}} blocks named <prompt>.<part> in a prompt's file.
+// They render on their own but share the prompt's version.
+const (
+	ExtractionRequest    = "extraction.request"    // The message for one chunk of a source
+	ExtractionHighlights = "extraction.highlights" // Passages the user highlighted, given []Highlight
+)
+
+// ExtractionData fills in the extraction prompt
+type ExtractionData struct {
+	EntityTypes       []string // Valid entity types
+	RelationshipTypes []string // Preferred relationship types
+}
+
+// ExtractionRequestData fills in the extraction request for one chunk
+type ExtractionRequestData struct {
+	Title          string
+	URL            string
+	SourceEntityID string // Entity that citations should link to
+	Author         string
+	Date           string
+	Publication    string
+	Part           int // Which chunk this is, from 1
+	Parts          int // How many chunks the source was split into
+	Heading        string
+	Highlights     []Highlight
+	Content        string
+	Links          []string // Links found in the source, on the first part only
+	MoreLinks      int      // Links left out of Links
+}
+
+// Highlight is a passage the user highlighted in a source
+type Highlight struct {
+	Kind string // quote or claim
+	Text string
+	Note string // The user's note on it, if any
+}
+
+// RefineData fills in the refine prompt
+type RefineData struct {
+	Title         string
+	Type          string
+	Content       string // Current content of the entity
+	SourceContent string // Source material to refine from
+	Guidance      string // Optional guidance from the user
+}
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+// versionPattern reads the declared version from a {{/* version: N */}} header
+var versionPattern = regexp.MustCompile(`^\{\{/\*\s*version:\s*([^\s*]+)\s*\*/`)
+
+// Library is a parsed set of prompt templates
+type Library struct {
+	root       *template.Template
+	versions   map[string]string
+	overridden map[string]bool
+	dir        string
+}
+
+// PromptInfo describes one prompt in a library
+type PromptInfo struct {
+	Name       string
+	Version    string
+	Overridden bool // Edited in the data directory, differing from the default
+}
+
+// NewLibrary parses the embedded default prompts
+func NewLibrary() (*Library, error) {
+	return LoadLibrary("")
+}
+
+// LoadLibrary parses the embedded prompts, replacing any that have a file of
+// the same name in dir. A missing dir is not an error.
+func LoadLibrary(dir string) (*Library, error) {
+	sources := make(map[string]string)
+	overridden := make(map[string]bool)
+
+	files, err := fs.Glob(embedded, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := embedded.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sources[templateName(file)] = string(data)
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read prompt: %w", err)
+			}
+			name := templateName(file)
+			overridden[name] = sources[name] != string(data)
+			sources[name] = string(data)
+		}
+	}
+
+	library := &Library{
+		root:       template.New("prompts").Funcs(template.FuncMap{"join": join, "inc": inc}).Option("missingkey=error"),
+		versions:   make(map[string]string),
+		overridden: overridden,
+		dir:        dir,
+	}
+
+	// Partials first, so any prompt can include them, then the prompts
+	names := sortedNames(sources)
+	for _, name := range names {
+		if _, err := library.root.New(name).Parse(sources[name]); err != nil {
+			return nil, fmt.Errorf("failed to parse prompt %s: %w", name, err)
+		}
+	}
+
+	// A prompt's version is its declared version plus a hash of its text and
+	// the shared partials, so edits show up even when nobody bumps the number
+	var partials strings.Builder
+	for _, name := range names {
+		if isPartial(name) {
+			partials.WriteString(sources[name])
+		}
+	}
+	for _, name := range names {
+		if isPartial(name) {
+			continue
+		}
+		declared := "0"
+		if match := versionPattern.FindStringSubmatch(sources[name]); match != nil {
+			declared = match[1]
+		}
+		sum := sha256.Sum256([]byte(sources[name] + partials.String()))
+		library.versions[name] = fmt.Sprintf("v%s-%s", declared, hex.EncodeToString(sum[:4]))
+	}
+
+	return library, nil
+}
+
+// Render executes the named prompt, or a part of one, with data
+func (l *Library) Render(name string, data any) (string, error) {
+	prompt, _, isPart := strings.Cut(name, ".")
+	if _, ok := l.versions[prompt]; !ok {
+		return "", fmt.Errorf("unknown prompt: %s", name)
+	}
+	if isPart && l.root.Lookup(name) == nil {
+		// An override written before the part was added
+		return "", fmt.Errorf("prompt %s does not define %s; compare it with the default", prompt, name)
+	}
+	var out strings.Builder
+	if err := l.root.ExecuteTemplate(&out, name, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", name, err)
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// Version returns the version of the named prompt, or "" if there is none
+func (l *Library) Version(name string) string {
+	return l.versions[name]
+}
+
+// List describes the prompts in the library, by name
+func (l *Library) List() []PromptInfo {
+	infos := make([]PromptInfo, 0, len(l.versions))
+	for _, name := range sortedNames(l.versions) {
+		infos = append(infos, PromptInfo{
+			Name:       name,
+			Version:    l.versions[name],
+			Overridden: l.overridden[name],
+		})
+	}
+	return infos
+}
+
+// Dir returns the directory overrides were loaded from
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// WriteDefaults copies the embedded prompts into dir for editing, leaving
+// files that already exist alone, and returns the files written
+func WriteDefaults(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create prompts directory: %w", err)
+	}
+	files, err := fs.Glob(embedded, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.Base(file))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		data, err := embedded.ReadFile(file)
+		if err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return written, fmt.Errorf("failed to write prompt: %w", err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func templateName(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), ".tmpl")
+}
+
+func isPartial(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// sortedNames returns the keys of m with partials first
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if isPartial(names[i]) != isPartial(names[j]) {
+			return isPartial(names[i])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// inc adds one, to number list items from 1 in templates
+func inc(i int) int {
+	return i + 1
+}
+
+// join joins a list of strings for use in templates
+func join(items []string, sep string) string {
+	return strings.Join(items, sep)
+}
+
+var (
+	mu     sync.RWMutex
+	active *Library
+)
+
+// Default returns the library used by Render and Version, which holds the
+// embedded prompts until Use is called
+func Default() *Library {
+	mu.RLock()
+	library := active
+	mu.RUnlock()
+	if library != nil {
+		return library
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		library, err := NewLibrary()
+		if err != nil {
+			panic(fmt.Sprintf("embedded prompts are invalid: %v", err)) // A build problem, not a runtime one
+		}
+		active = library
+	}
+	return active
+}
+
+// Use makes library the one used by Render and Version
+func Use(library *Library) {
+	mu.Lock()
+	active = library
+	mu.Unlock()
+}
+
+// Render executes a prompt from the default library
+func Render(name string, data any) (string, error) {
+	return Default().Render(name, data)
+}
+
+// Version returns a prompt's version in the default library
+func Version(name string) string {
+	return Default().Version(name)
+}
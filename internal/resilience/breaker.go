@@ -0,0 +1,92 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while a breaker is refusing calls
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Breaker stops calls to a failing service. After threshold consecutive
+// failures it opens for cooldown, then lets a single trial call through
+// (half-open); success closes it again, failure reopens it.
+type Breaker struct {
+	mu        sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial call is in flight
+	lastErr   error
+}
+
+// NewBreaker creates a breaker for the named service
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown}
+}
+
+// Allow returns an error wrapping ErrCircuitOpen if calls are refused
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return fmt.Errorf("%w: %s is failing (%v), retry after %s",
+			ErrCircuitOpen, b.name, b.lastErr, b.openUntil.Format("15:04:05"))
+	}
+	b.trial = true
+	return nil
+}
+
+// Record updates the breaker with the outcome of an allowed call. Only
+// transient failures count toward opening it; any other error shows the
+// service is reachable and closes it. A cancelled call changes nothing.
+func (b *Breaker) Record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+
+	switch {
+	case IsRetryable(err):
+		b.failures++
+		b.lastErr = err
+		if b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.cooldown)
+		}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// Says nothing about the service
+	default:
+		b.failures = 0
+		b.lastErr = nil
+	}
+}
+
+// Open reports whether the breaker is refusing calls, and until when
+func (b *Breaker) Open() (bool, time.Time) {
+	if b == nil {
+		return false, time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= b.threshold && time.Now().Before(b.openUntil) {
+		return true, b.openUntil
+	}
+	return false, time.Time{}
+}
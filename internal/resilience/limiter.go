@@ -0,0 +1,99 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket: up to burst calls at once, refilled at rate
+// calls per second. A nil Limiter never waits.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter allowing perMinute calls per minute with the
+// given burst. perMinute <= 0 returns nil, which does not limit.
+func NewLimiter(perMinute float64, burst int) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   perMinute / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a call is allowed or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// LimiterSet keeps one limiter per key, e.g. per host
+type LimiterSet struct {
+	mu        sync.Mutex
+	perMinute float64
+	burst     int
+	limiters  map[string]*Limiter
+}
+
+// NewLimiterSet creates limiters on demand with the same rate and burst
+func NewLimiterSet(perMinute float64, burst int) *LimiterSet {
+	return &LimiterSet{
+		perMinute: perMinute,
+		burst:     burst,
+		limiters:  make(map[string]*Limiter),
+	}
+}
+
+// Wait blocks until a call for key is allowed or ctx is done
+func (s *LimiterSet) Wait(ctx context.Context, key string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	limiter, ok := s.limiters[key]
+	if !ok {
+		limiter = NewLimiter(s.perMinute, s.burst)
+		s.limiters[key] = limiter
+	}
+	s.mu.Unlock()
+	return limiter.Wait(ctx)
+}
@@ -0,0 +1,196 @@
+// Package resilience provides retries with backoff, rate limiting and circuit
+// breaking for calls to remote services (LLM providers and fetched websites).
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Policy configures retries
+type Policy struct {
+	MaxRetries int           // Retries after the first attempt; 0 disables retrying
+	BaseDelay  time.Duration // Delay before the first retry, doubled for each further retry
+	MaxDelay   time.Duration // Upper bound for a single delay, including Retry-After
+	Jitter     float64       // Fraction of the delay randomized, 0..1
+}
+
+// DefaultPolicy retries three times, waiting about 1s, 2s and 4s
+var DefaultPolicy = Policy{
+	MaxRetries: 3,
+	BaseDelay:  time.Second,
+	MaxDelay:   time.Minute,
+	Jitter:     0.2,
+}
+
+// HTTPError is a failed HTTP response worth classifying for retries
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration // From the Retry-After header; 0 if absent
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// NewHTTPError builds an HTTPError from a response, reading Retry-After
+func NewHTTPError(resp *http.Response, message string) *HTTPError {
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After")),
+		Message:    message,
+	}
+}
+
+// RetryableStatus reports whether an HTTP status is worth retrying
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// StatusCoder is implemented by errors that carry an HTTP status code
+type StatusCoder interface {
+	HTTPStatus() int
+}
+
+// HTTPStatus returns the status code of the error
+func (e *HTTPError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Do returns it without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsRetryable reports whether err is transient: a retryable HTTP status, a
+// timeout, or a dropped connection. Context cancellation is never retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	var coder StatusCoder
+	if errors.As(err, &coder) {
+		return RetryableStatus(coder.HTTPStatus())
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the server-requested delay carried by err, if any
+func retryAfter(err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Delay returns the wait before retry number attempt (1-based)
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		spread := float64(delay) * p.Jitter
+		delay += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the
+// retries are used up. Between attempts it waits the policy's backoff, or the
+// server's Retry-After if longer (capped at MaxDelay). onRetry, if not nil,
+// is told about each retry before the wait.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt >= policy.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		delay := policy.Delay(attempt + 1)
+		if after := retryAfter(err); after > delay {
+			delay = after
+			if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+				delay = policy.MaxDelay
+			}
+		}
+		if onRetry != nil {
+			onRetry(attempt+1, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
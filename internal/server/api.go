@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"silvia/internal/operations"
+)
+
+// Error codes for failures that do not come from an operation
+const (
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeUnavailable      = "unavailable"
+	codeBusy             = "busy"
+)
+
+// Pagination defaults for list endpoints
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// errorResponse is the body of every error response. Error stays a plain
+// string so older clients that show it keep working.
+type errorResponse struct {
+	Success   bool   `json:"success"`
+	Error     string `json:"error"`
+	Code      string `json:"code"`                // not_found, conflict, invalid, internal, bad_request, ...
+	Operation string `json:"operation,omitempty"` // Operation that failed, for operation errors
+	Entity    string `json:"entity,omitempty"`    // Entity, URL or job the operation was working on
+}
+
+// Page is one page of a list endpoint's results
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"` // Matching items across all pages
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// writeJSON writes v as the JSON response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError reports an operation's failure, choosing the status from its
+// error code
+func writeError(w http.ResponseWriter, err error) {
+	code := operations.ErrorCode(err)
+	status := http.StatusInternalServerError
+	switch code {
+	case operations.CodeNotFound:
+		status = http.StatusNotFound
+	case operations.CodeConflict:
+		status = http.StatusConflict
+	case operations.CodeInvalid:
+		status = http.StatusBadRequest
+	}
+
+	response := errorResponse{Error: err.Error(), Code: code}
+	var opErr *operations.OperationError
+	if errors.As(err, &opErr) {
+		response.Operation = opErr.Operation
+		response.Entity = opErr.Entity
+	}
+	writeJSON(w, status, response)
+}
+
+// writeErrorStatus reports a failure that is not an operation's, such as a
+// malformed request
+func writeErrorStatus(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: message, Code: code})
+}
+
+// allowMethod reports whether the request uses method, writing a 405 if not
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return false
+	}
+	return true
+}
+
+// decodeJSON reads the request body into v, writing a 400 if it is not
+// valid JSON
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return false
+	}
+	return true
+}
+
+// pageParams reads ?offset= and ?limit=, writing a 400 if either is invalid
+func pageParams(w http.ResponseWriter, r *http.Request) (offset, limit int, ok bool) {
+	offset, limit = 0, defaultPageLimit
+	query := r.URL.Query()
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "offset must be a non-negative integer")
+			return 0, 0, false
+		}
+		offset = n
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageLimit {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	return offset, limit, true
+}
+
+// paginate returns one page of items
+func paginate[T any](items []T, offset, limit int) Page[T] {
+	page := Page[T]{Items: []T{}, Total: len(items), Offset: offset, Limit: limit}
+	if offset < len(items) {
+		end := min(offset+limit, len(items))
+		page.Items = items[offset:end]
+	}
+	return page
+}
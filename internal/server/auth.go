@@ -0,0 +1,224 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"silvia/internal/operations"
+)
+
+// codeForbidden is returned when a token lacks the scope an endpoint needs
+const codeForbidden = "forbidden"
+
+// publicPaths answer without a token, so the extension can check that the
+// server is up and clients can fetch the API description
+var publicPaths = []string{"/api/status", "/api/openapi.json"}
+
+// sharedTokenName attributes changes made with the -token shared token
+const sharedTokenName = "shared"
+
+type tokenKey struct{}
+
+// UseTokens makes the server accept the named tokens in store alongside the
+// shared token. Once either exists, every API request needs one.
+func (s *Server) UseTokens(store *TokenStore) {
+	s.tokens = store
+}
+
+// AllowOrigins sets the browser origins, such as
+// chrome-extension://<extension-id>, that may call the API. A trailing *
+// matches any origin with that prefix. The web UI's own origin is always
+// allowed.
+func (s *Server) AllowOrigins(origins []string) {
+	s.origins = origins
+}
+
+// AllowHosts sets the host names, besides localhost and loopback
+// addresses, that requests may be addressed to, such as the name of a
+// reverse proxy in front of the server
+func (s *Server) AllowHosts(hosts []string) {
+	s.hosts = hosts
+}
+
+// authRequired reports whether any token is configured. A tokens file that
+// cannot be read counts as configured, so auth never silently turns off.
+func (s *Server) authRequired() (bool, error) {
+	if s.token != "" {
+		return true, nil
+	}
+	if s.tokens == nil {
+		return false, nil
+	}
+	return s.tokens.Configured()
+}
+
+// authenticate returns the token a request carries. When no tokens are
+// configured every request is trusted as a local, unattributed one.
+func (s *Server) authenticate(r *http.Request) (*Token, error) {
+	required, err := s.authRequired()
+	if !required {
+		return &Token{Scopes: []Scope{ScopeAdmin}}, nil
+	}
+
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		return nil, err
+	}
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.token)) == 1 {
+		return &Token{Name: sharedTokenName, Scopes: []Scope{ScopeAdmin}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.tokens != nil {
+		return s.tokens.Authenticate(secret), nil
+	}
+	return nil, nil
+}
+
+// authMiddleware rejects API and MCP requests without a valid token, except
+// to the public paths, and records the token for the handlers' scope checks
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protected := strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == mcpPath
+		if !protected || slices.Contains(publicPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := s.authenticate(r)
+		if err != nil {
+			log.Printf("Refused request: %v", err)
+			writeErrorStatus(w, http.StatusInternalServerError, operations.CodeInternal, "Cannot read API tokens")
+			return
+		}
+		if token == nil {
+			writeErrorStatus(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+	})
+}
+
+// authorize reports whether the request's token grants scope, writing a
+// 401 or 403 if not
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, scope Scope) bool {
+	token, _ := r.Context().Value(tokenKey{}).(*Token)
+	if token == nil {
+		writeErrorStatus(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return false
+	}
+	if !token.Allows(scope) {
+		writeErrorStatus(w, http.StatusForbidden, codeForbidden, "Token "+token.Name+" lacks the "+string(scope)+" scope")
+		return false
+	}
+	return true
+}
+
+// actor names the token that made a request, for attributing its changes
+func actor(r *http.Request) string {
+	if token, _ := r.Context().Value(tokenKey{}).(*Token); token != nil {
+		return token.Name
+	}
+	return ""
+}
+
+// opsFor returns operations that attribute their changes to the request's
+// token
+func (s *Server) opsFor(r *http.Request) *operations.Operations {
+	return s.ops.As(actor(r))
+}
+
+// corsMiddleware lets allowed browser origins call the API and refuses
+// requests from any other origin outright, so a web page cannot drive the
+// API through the user's browser. Requests without an Origin header, such
+// as those from scripts, are not browser requests and pass through.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.originAllowed(r, origin) {
+			log.Printf("Refused request from origin %s; allow it with -allow-origin", origin)
+			writeErrorStatus(w, http.StatusForbidden, codeForbidden, "Origin not allowed: "+origin)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID, Mcp-Protocol-Version")
+
+		// Handle preflight
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostMiddleware refuses requests addressed to any host but a loopback
+// name or an allowlisted one. A page whose DNS name was rebound to
+// 127.0.0.1 sends its own name as Host, even from a script without an
+// Origin header, so it cannot reach a server that trusts local requests.
+func (s *Server) hostMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.hostAllowed(r.Host) {
+			log.Printf("Refused request for host %s; allow it with -allow-host", r.Host)
+			writeErrorStatus(w, http.StatusForbidden, codeForbidden, "Host not allowed: "+r.Host)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostAllowed reports whether hostport names a loopback or allowlisted host
+func (s *Server) hostAllowed(hostport string) bool {
+	if isLoopbackHost(hostport) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	return slices.ContainsFunc(s.hosts, func(allowed string) bool {
+		return strings.EqualFold(host, allowed)
+	})
+}
+
+// originAllowed reports whether origin is the server's own or on the
+// allowlist. The server's own origin only counts under a loopback host
+// name, so a page that rebinds its DNS name to 127.0.0.1 is still refused.
+func (s *Server) originAllowed(r *http.Request, origin string) bool {
+	if origin == "http://"+r.Host && isLoopbackHost(r.Host) {
+		return true // The web UI
+	}
+	for _, allowed := range s.origins {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(origin, prefix) {
+				return true
+			}
+		} else if origin == strings.TrimSuffix(allowed, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+	}
+	return strings.EqualFold(host, "localhost") || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"silvia/internal/graph"
+)
+
+// createEntityRequest is the body of POST /api/entities
+type createEntityRequest struct {
+	Type    string `json:"type"` // person, organization, concept, work or event
+	ID      string `json:"id"`   // Full ID, e.g. people/ada-lovelace
+	Title   string `json:"title"`
+	Content string `json:"content,omitempty"`
+}
+
+// linkRequest is the body of POST /api/entities/link
+type linkRequest struct {
+	SourceID string `json:"source_id"`
+	Type     string `json:"type"` // Relationship type, e.g. authored
+	TargetID string `json:"target_id"`
+}
+
+// annotateRequest is the body of POST /api/annotate. Either the page's
+// text or its HTML is needed; with HTML, offsets refer to the text returned.
+type annotateRequest struct {
+	URL  string `json:"url,omitempty"` // The page, to resolve its links
+	Text string `json:"text,omitempty"`
+	HTML string `json:"html,omitempty"`
+}
+
+// typesResponse is the body of GET /api/types
+type typesResponse struct {
+	EntityTypes       []string `json:"entity_types"`
+	RelationshipTypes []string `json:"relationship_types"`
+}
+
+// successResponse acknowledges an operation that returns nothing else
+type successResponse struct {
+	Success bool `json:"success"`
+}
+
+// handleEntities lists entities (GET) or creates one (POST). The list is
+// sorted by ID, filtered with ?type=, ?tag= and ?q= (a case-insensitive
+// match on ID, title or alias) and paged with ?offset= and ?limit=.
+func (s *Server) handleEntities(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if !s.authorize(w, r, ScopeRead) {
+			return
+		}
+		offset, limit, ok := pageParams(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		entities, err := s.ops.Search.ListEntities(query.Get("type"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		tag := query.Get("tag")
+		text := strings.ToLower(query.Get("q"))
+		if tag != "" || text != "" {
+			var matching []*graph.Entity
+			for _, entity := range entities {
+				if tag != "" && !slices.Contains(entity.Metadata.Tags, tag) {
+					continue
+				}
+				if text != "" && !entityMatches(entity, text) {
+					continue
+				}
+				matching = append(matching, entity)
+			}
+			entities = matching
+		}
+		writeJSON(w, http.StatusOK, paginate(entities, offset, limit))
+
+	case "POST":
+		if !s.authorize(w, r, ScopeWrite) {
+			return
+		}
+		var req createEntityRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if req.ID == "" || req.Type == "" {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "type and id are required")
+			return
+		}
+
+		entity, err := s.opsFor(r).Entity.CreateEntity(req.Type, req.ID, req.Title, req.Content)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/entities/"+entity.Metadata.ID)
+		writeJSON(w, http.StatusCreated, entity)
+
+	default:
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// entityMatches reports whether an entity's ID, title or an alias contains
+// the lowercased text
+func entityMatches(entity *graph.Entity, text string) bool {
+	if strings.Contains(strings.ToLower(entity.Metadata.ID), text) ||
+		strings.Contains(strings.ToLower(entity.Title), text) {
+		return true
+	}
+	for _, alias := range entity.Metadata.Aliases {
+		if strings.Contains(strings.ToLower(alias), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleRelated returns an entity's outgoing and incoming relationships
+// (GET /api/entities/related?id=)
+func (s *Server) handleRelated(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Query parameter 'id' is required")
+		return
+	}
+
+	result, err := s.ops.Search.GetRelatedEntities(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleSuggest returns unlinked entities whose content is similar to an
+// entity's (GET /api/entities/suggest?id=&limit=)
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	query := r.URL.Query()
+	id := query.Get("id")
+	if id == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Query parameter 'id' is required")
+		return
+	}
+	limit := 10
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageLimit {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+			return
+		}
+		limit = n
+	}
+
+	suggestions, err := s.ops.Search.SuggestRelated(id, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if suggestions == nil {
+		suggestions = []*graph.Entity{}
+	}
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+// handleLink adds a relationship between two entities and returns the
+// updated source entity
+func (s *Server) handleLink(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+	var req linkRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	entity, err := s.opsFor(r).Entity.LinkEntities(req.SourceID, req.Type, req.TargetID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entity)
+}
+
+// handleMove moves an entity to a new ID, which may change its type
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+	var req renameRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := s.opsFor(r).Entity.MoveEntity(req.OldID, req.NewID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleRebuildRefs recomputes every entity's back-references
+func (s *Server) handleRebuildRefs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeAdmin) {
+		return
+	}
+	if err := s.ops.Entity.RebuildReferences(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, successResponse{Success: true})
+}
+
+// handleAnnotate reports which known entities a page mentions, where, and
+// the names on it that are not in the graph yet
+func (s *Server) handleAnnotate(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	var req annotateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := s.ops.Search.Annotate(req.Text, req.HTML, req.URL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleTypes lists the known entity and relationship types
+func (s *Server) handleTypes(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	writeJSON(w, http.StatusOK, typesResponse{
+		EntityTypes:       graph.EntityTypeNames(),
+		RelationshipTypes: graph.RelationshipTypeNames(),
+	})
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventKeepAlive is how often an idle event stream gets a ping
+const eventKeepAlive = 30 * time.Second
+
+// handleEvents streams changes to the graph, the queue and jobs as
+// server-sent events, named by type (entity.created, queue.added,
+// job.progress, ...). A client that reconnects with Last-Event-ID, or asks
+// for ?last_event_id=, first gets the events it missed that are still in
+// the log; last_event_id=0 replays the whole log. ?types=entity,job limits
+// the stream to types with those prefixes.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+
+	// Without a resume point, start from now
+	afterID := s.ops.Events.LastID()
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID != "" {
+		id, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || id < 0 {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		afterID = id
+	}
+
+	var types []string
+	if value := r.URL.Query().Get("types"); value != "" {
+		types = strings.Split(value, ",")
+	}
+
+	stream, err := newSSEWriter(w)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ctx, cancel := s.streamContext(r.Context())
+	defer cancel()
+	events := s.ops.Events.Subscribe(ctx, afterID)
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			stream.ping()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Matches(types) {
+				stream.sendID(strconv.FormatInt(event.ID, 10), event.Type, event)
+			}
+		}
+	}
+}
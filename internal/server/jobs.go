@@ -0,0 +1,145 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"silvia/internal/operations"
+	"silvia/internal/sources"
+)
+
+// jobRequest is the body of POST /api/jobs. Which fields apply depends on
+// the kind; see operations.JobParams.
+type jobRequest struct {
+	Kind       operations.JobKind  `json:"kind"`
+	URL        string              `json:"url"`
+	Force      bool                `json:"force"`
+	HTML       string              `json:"html"`
+	Title      string              `json:"title"`
+	Metadata   map[string]string   `json:"metadata"`
+	Highlights []sources.Highlight `json:"highlights"`
+	EntityID   string              `json:"entity_id"`
+	Guidance   string              `json:"guidance"`
+	Entity1ID  string              `json:"entity1_id"`
+	Entity2ID  string              `json:"entity2_id"`
+	Limit      int                 `json:"limit"`
+}
+
+func (req jobRequest) params() operations.JobParams {
+	return operations.JobParams{
+		URL:        req.URL,
+		Force:      req.Force,
+		HTML:       req.HTML,
+		Title:      req.Title,
+		Metadata:   req.Metadata,
+		Highlights: req.Highlights,
+		EntityID:   req.EntityID,
+		Guidance:   req.Guidance,
+		Entity1ID:  req.Entity1ID,
+		Entity2ID:  req.Entity2ID,
+		Limit:      req.Limit,
+	}
+}
+
+// handleJobs submits a job (POST) or lists jobs newest first (GET),
+// optionally filtered with ?status= and ?kind= and paged with ?offset= and
+// ?limit=. A submitted job runs in the background; poll /api/jobs/{id} for
+// its progress.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if !s.authorize(w, r, ScopeRead) {
+			return
+		}
+		offset, limit, ok := pageParams(w, r)
+		if !ok {
+			return
+		}
+		jobs, err := s.ops.Jobs.ListJobs(operations.JobStatus(r.URL.Query().Get("status")))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if kind := operations.JobKind(r.URL.Query().Get("kind")); kind != "" {
+			var matching []*operations.Job
+			for _, job := range jobs {
+				if job.Kind == kind {
+					matching = append(matching, job)
+				}
+			}
+			jobs = matching
+		}
+		writeJSON(w, http.StatusOK, paginate(jobs, offset, limit))
+
+	case "POST":
+		var req jobRequest
+		if !decodeJSON(w, r, &req) || !s.authorize(w, r, jobScope(req.Kind)) {
+			return
+		}
+		params := req.params()
+		params.Actor = actor(r)
+		job, err := s.ops.Jobs.Submit(req.Kind, params)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+
+	default:
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleJob returns a job's state (GET /api/jobs/{id}) or cancels it
+// (DELETE /api/jobs/{id} or POST /api/jobs/{id}/cancel)
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Job ID required")
+		return
+	}
+
+	var (
+		job *operations.Job
+		err error
+	)
+	switch {
+	case action == "" && r.Method == "GET":
+		if !s.authorize(w, r, ScopeRead) {
+			return
+		}
+		job, err = s.ops.Jobs.GetJob(id)
+	case action == "" && r.Method == "DELETE", action == "cancel" && r.Method == "POST":
+		// Cancelling needs the scope that submitting the job did
+		job, err = s.ops.Jobs.GetJob(id)
+		if err == nil {
+			if !s.authorize(w, r, jobScope(job.Kind)) {
+				return
+			}
+			job, err = s.ops.Jobs.CancelJob(id)
+		}
+	case action == "" || action == "cancel":
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	default:
+		writeErrorStatus(w, http.StatusNotFound, operations.CodeNotFound, "Unknown job action: "+action)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// jobScope is the scope needed to submit or cancel a job of kind. Unknown
+// kinds need write; Submit rejects them.
+func jobScope(kind operations.JobKind) Scope {
+	if kind == operations.JobIngest || kind == operations.JobQueue {
+		return ScopeIngest
+	}
+	return ScopeWrite
+}
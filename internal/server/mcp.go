@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"silvia/internal/mcp"
+	"silvia/internal/tools"
+)
+
+// mcpPath is where the MCP endpoint is mounted
+const mcpPath = "/mcp"
+
+// UseMCP serves handler, normally an mcp.HTTPTransport, at /mcp so that
+// remote AI assistants share the server's graph. It takes the same tokens
+// as the API: connecting needs the read scope, and each tool call the scope
+// of the equivalent API endpoint.
+func (s *Server) UseMCP(handler http.Handler) {
+	s.mcp = handler
+}
+
+// handleMCP checks the request's token and passes it on to the MCP handler
+// with the tools the token may use
+func (s *Server) handleMCP(w http.ResponseWriter, r *http.Request) {
+	if s.mcp == nil {
+		writeErrorStatus(w, http.StatusServiceUnavailable, codeUnavailable, "MCP is not enabled")
+		return
+	}
+	if !s.authorize(w, r, ScopeRead) {
+		return
+	}
+
+	token, _ := r.Context().Value(tokenKey{}).(*Token)
+	ctx := mcp.WithCaller(r.Context(), mcp.Caller{
+		Actor: actor(r),
+		Allow: func(tool tools.Tool) error {
+			if scope := toolScope(tool); !token.Allows(scope) {
+				return fmt.Errorf("token %s lacks the %s scope needed for %s", token.Name, scope, tool.Name())
+			}
+			return nil
+		},
+	})
+
+	// Notification streams end when the server stops; requests finish
+	if r.Method == http.MethodGet {
+		var cancel context.CancelFunc
+		ctx, cancel = s.streamContext(ctx)
+		defer cancel()
+	}
+	s.mcp.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// toolScope returns the scope a token needs to call a tool over MCP or in
+// a chain: the access the tool declares, which matches the API endpoints
+// that do the same
+func toolScope(tool tools.Tool) Scope {
+	return Scope(tools.RequiredAccess(tool))
+}
@@ -0,0 +1,425 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"silvia/internal/graph"
+	"silvia/internal/operations"
+	"silvia/internal/prompts"
+)
+
+// apiRoute documents one endpoint for the OpenAPI document. Request and
+// Response are zero values of the body types; their schemas are generated
+// from the Go types so the document cannot drift from the handlers.
+type apiRoute struct {
+	Method   string
+	Path     string
+	Summary  string
+	Tag      string
+	Query    []apiParam
+	Scope    Scope // Token scope required; public when empty
+	Request  any
+	Response any
+	Status   int    // Success status, 200 when zero
+	Stream   bool   // Also answers with text/event-stream
+	Errors   []int  // Error statuses besides 400 and 500
+	NoBody   string // Description of a success response without a body
+}
+
+// apiParam is a query parameter
+type apiParam struct {
+	Name        string
+	Description string
+	Type        string // string, integer or boolean
+	Required    bool
+}
+
+var pageQuery = []apiParam{
+	{Name: "offset", Description: "Items to skip", Type: "integer"},
+	{Name: "limit", Description: "Items to return, 1 to 500 (default 50)", Type: "integer"},
+}
+
+// apiRoutes lists every endpoint the server exposes
+var apiRoutes = []apiRoute{
+	{Method: "GET", Path: "/api/status", Summary: "Server status and whether jobs are running", Tag: "server", Response: statusResponse{}},
+	{Method: "GET", Path: "/", Summary: "Web UI", Tag: "server", NoBody: "The web UI page"},
+	{Method: "GET", Path: "/api/openapi.json", Summary: "This document", Tag: "server", Response: map[string]any{}},
+	{Method: "POST", Path: "/api/ingest", Summary: "Queue a source for ingestion as a background job", Tag: "sources", Scope: ScopeIngest,
+		Request: ingestRequest{}, Response: ingestResponse{}, Status: http.StatusAccepted},
+
+	{Method: "GET", Path: "/api/entities", Summary: "List entities sorted by ID", Tag: "entities", Scope: ScopeRead,
+		Query: append([]apiParam{
+			{Name: "type", Description: "Entity type", Type: "string"},
+			{Name: "tag", Description: "Only entities with this tag", Type: "string"},
+			{Name: "q", Description: "Case-insensitive match on ID, title or alias", Type: "string"},
+		}, pageQuery...),
+		Response: Page[*graph.Entity]{}},
+	{Method: "POST", Path: "/api/entities", Summary: "Create an entity", Tag: "entities", Scope: ScopeWrite,
+		Request: createEntityRequest{}, Response: &graph.Entity{}, Status: http.StatusCreated, Errors: []int{409}},
+	{Method: "GET", Path: "/api/entities/{id}", Summary: "Read an entity", Tag: "entities", Scope: ScopeRead,
+		Response: &graph.Entity{}, Errors: []int{404}},
+	{Method: "PUT", Path: "/api/entities/{id}", Summary: "Update an entity's title or content", Tag: "entities", Scope: ScopeWrite,
+		Request: updateEntityRequest{}, Response: &graph.Entity{}, Errors: []int{404}},
+	{Method: "DELETE", Path: "/api/entities/{id}", Summary: "Delete an entity", Tag: "entities", Scope: ScopeWrite,
+		Status: http.StatusNoContent, Errors: []int{404}, NoBody: "Entity deleted"},
+	{Method: "GET", Path: "/api/entities/search", Summary: "Search entities", Tag: "entities", Scope: ScopeRead,
+		Query: append([]apiParam{
+			{Name: "q", Description: "Search text", Type: "string", Required: true},
+			{Name: "type", Description: "Entity type", Type: "string"},
+		}, pageQuery...),
+		Response: operations.SearchResult{}},
+	{Method: "GET", Path: "/api/entities/related", Summary: "An entity's relationships and back-references", Tag: "entities", Scope: ScopeRead,
+		Query:    []apiParam{{Name: "id", Description: "Entity ID", Type: "string", Required: true}},
+		Response: operations.RelatedEntitiesResult{}, Errors: []int{404}},
+	{Method: "GET", Path: "/api/entities/suggest", Summary: "Suggest unlinked entities with similar content", Tag: "entities", Scope: ScopeRead,
+		Query: []apiParam{
+			{Name: "id", Description: "Entity ID", Type: "string", Required: true},
+			{Name: "limit", Description: "Suggestions to return (default 10)", Type: "integer"},
+		},
+		Response: []*graph.Entity{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/entities/link", Summary: "Add a relationship between two entities", Tag: "entities", Scope: ScopeWrite,
+		Request: linkRequest{}, Response: &graph.Entity{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/entities/merge", Summary: "Merge one entity into another", Tag: "entities", Scope: ScopeWrite,
+		Request: mergeRequest{}, Response: operations.MergeResult{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/entities/rename", Summary: "Rename an entity and update references", Tag: "entities", Scope: ScopeWrite,
+		Request: renameRequest{}, Response: operations.RenameResult{}, Errors: []int{404, 409}},
+	{Method: "POST", Path: "/api/entities/move", Summary: "Move an entity to a new ID, possibly changing its type", Tag: "entities", Scope: ScopeWrite,
+		Request: renameRequest{}, Response: operations.RenameResult{}, Errors: []int{404, 409}},
+	{Method: "POST", Path: "/api/entities/refine", Summary: "Rewrite an entity with the LLM", Tag: "entities", Scope: ScopeWrite,
+		Request: refineRequest{}, Response: &graph.Entity{}, Stream: true, Errors: []int{404}},
+	{Method: "POST", Path: "/api/entities/rebuild-refs", Summary: "Recompute every entity's back-references", Tag: "entities", Scope: ScopeAdmin,
+		Response: successResponse{}},
+	{Method: "GET", Path: "/api/types", Summary: "Known entity and relationship types", Tag: "entities", Scope: ScopeRead, Response: typesResponse{}},
+	{Method: "POST", Path: "/api/annotate", Summary: "Find known entities and unknown names in a page's text or HTML", Tag: "entities", Scope: ScopeRead,
+		Request: annotateRequest{}, Response: operations.AnnotateResult{}},
+
+	{Method: "POST", Path: "/api/chat", Summary: "Answer a question from the knowledge graph", Tag: "chat", Scope: ScopeWrite,
+		Request: chatRequest{}, Response: chatResponse{}, Stream: true, Errors: []int{503}},
+	{Method: "POST", Path: "/api/chain", Summary: "Run a chain of tool calls", Tag: "chat", Scope: ScopeWrite,
+		Request: chainRequest{}, Response: chainResult{}, Errors: []int{422}},
+	{Method: "GET", Path: "/api/events", Summary: "Stream graph, queue and job changes as server-sent events", Tag: "events", Scope: ScopeRead,
+		Query: []apiParam{
+			{Name: "last_event_id", Description: "Resume after this event; 0 replays the log", Type: "integer"},
+			{Name: "types", Description: "Comma-separated event type prefixes", Type: "string"},
+		},
+		Response: operations.Event{}, Stream: true},
+
+	{Method: "GET", Path: "/api/jobs", Summary: "List jobs newest first", Tag: "jobs", Scope: ScopeRead,
+		Query: append([]apiParam{
+			{Name: "status", Description: "pending, running, done, failed or cancelled", Type: "string"},
+			{Name: "kind", Description: "ingest, refine, merge or queue", Type: "string"},
+		}, pageQuery...),
+		Response: Page[*operations.Job]{}},
+	{Method: "POST", Path: "/api/jobs", Summary: "Start a background job; refine and merge need the write scope", Tag: "jobs", Scope: ScopeIngest,
+		Request: jobRequest{}, Response: &operations.Job{}, Status: http.StatusAccepted},
+	{Method: "GET", Path: "/api/jobs/{id}", Summary: "A job's state, progress and result", Tag: "jobs", Scope: ScopeRead,
+		Response: &operations.Job{}, Errors: []int{404}},
+	{Method: "DELETE", Path: "/api/jobs/{id}", Summary: "Cancel a job", Tag: "jobs", Scope: ScopeIngest,
+		Response: &operations.Job{}, Errors: []int{404, 409}},
+	{Method: "POST", Path: "/api/jobs/{id}/cancel", Summary: "Cancel a job", Tag: "jobs", Scope: ScopeIngest,
+		Response: &operations.Job{}, Errors: []int{404, 409}},
+
+	{Method: "GET", Path: "/api/queue", Summary: "Queued sources by priority", Tag: "queue", Scope: ScopeRead,
+		Query: append([]apiParam{
+			{Name: "status", Description: "Comma-separated states (pending, in_progress, done, failed, skipped, deferred) or all; default pending, in_progress and deferred", Type: "string"},
+			{Name: "priority", Description: "Only items of this priority (0-2)", Type: "integer"},
+		}, pageQuery...),
+		Response: operations.QueueStatus{}},
+	{Method: "POST", Path: "/api/queue/add", Summary: "Queue a URL", Tag: "queue", Scope: ScopeWrite,
+		Request: queueAddRequest{}, Response: queueChangeResponse{}, Errors: []int{409}},
+	{Method: "DELETE", Path: "/api/queue/remove", Summary: "Remove a URL from the queue", Tag: "queue", Scope: ScopeWrite,
+		Query:  []apiParam{{Name: "url", Description: "Queued URL", Type: "string", Required: true}},
+		Status: http.StatusNoContent, Errors: []int{404}, NoBody: "Removed"},
+	{Method: "POST", Path: "/api/queue/clear", Summary: "Remove every queued URL", Tag: "queue", Scope: ScopeAdmin,
+		Response: successResponse{}},
+	{Method: "POST", Path: "/api/queue/priority", Summary: "Change a queued URL's priority", Tag: "queue", Scope: ScopeWrite,
+		Request: queuePriorityRequest{}, Response: queueChangeResponse{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/queue/status", Summary: "Skip, defer, requeue or finish a queued URL", Tag: "queue", Scope: ScopeWrite,
+		Request: queueStatusRequest{}, Response: &operations.QueueItem{}, Errors: []int{404, 409}},
+
+	{Method: "GET", Path: "/api/feeds", Summary: "List feed subscriptions", Tag: "feeds", Scope: ScopeRead,
+		Response: []*operations.FeedSubscription{}},
+	{Method: "POST", Path: "/api/feeds", Summary: "Subscribe to an RSS or Atom feed", Tag: "feeds", Scope: ScopeAdmin,
+		Request: feedRequest{}, Response: &operations.FeedSubscription{}, Status: http.StatusCreated, Errors: []int{409}},
+	{Method: "DELETE", Path: "/api/feeds", Summary: "Unsubscribe from a feed", Tag: "feeds", Scope: ScopeAdmin,
+		Query:  []apiParam{{Name: "feed", Description: "Feed URL or name", Type: "string", Required: true}},
+		Status: http.StatusNoContent, Errors: []int{404}, NoBody: "Unsubscribed"},
+	{Method: "POST", Path: "/api/feeds/poll", Summary: "Poll feeds and queue new entries", Tag: "feeds", Scope: ScopeIngest,
+		Request: feedPollRequest{}, Response: []operations.FeedPollResult{}, Errors: []int{404}},
+	{Method: "POST", Path: "/api/sources/recheck", Summary: "Refetch sources and report what changed", Tag: "sources", Scope: ScopeIngest,
+		Request: recheckRequest{}, Response: operations.RecheckReport{}, Errors: []int{404}},
+	{Method: "GET", Path: "/api/sources/snapshots", Summary: "Captures recorded for a source", Tag: "sources", Scope: ScopeRead,
+		Query:    []apiParam{{Name: "url", Description: "Source URL", Type: "string", Required: true}},
+		Response: []operations.SourceSnapshot{}},
+	{Method: "GET", Path: "/api/sources/highlights", Summary: "Highlights made in captures of a source, for the extension to show again", Tag: "sources", Scope: ScopeRead,
+		Query:    []apiParam{{Name: "url", Description: "Source URL", Type: "string", Required: true}},
+		Response: []operations.SourceHighlight{}},
+	{Method: "GET", Path: "/api/quotes", Summary: "Quotes and claims recorded from highlights, newest first", Tag: "sources", Scope: ScopeRead,
+		Query: append([]apiParam{
+			{Name: "entity", Description: "Only quotes naming this entity", Type: "string"},
+			{Name: "url", Description: "Only quotes from this source", Type: "string"},
+		}, pageQuery...),
+		Response: Page[operations.Quote]{}},
+
+	{Method: "GET", Path: "/api/prompts", Summary: "Prompt templates in use and their versions", Tag: "server", Scope: ScopeRead,
+		Response: []prompts.PromptInfo{}},
+	{Method: "GET", Path: "/api/usage", Summary: "LLM token usage and cost", Tag: "server", Scope: ScopeAdmin,
+		Query: []apiParam{
+			{Name: "since", Description: "today, 7d, 2006-01-02, ...", Type: "string"},
+			{Name: "by", Description: "operation, kind, model or source", Type: "string"},
+		},
+		Response: operations.UsageReport{}},
+}
+
+// handleOpenAPI serves the OpenAPI 3 document describing apiRoutes
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+	writeJSON(w, http.StatusOK, openAPIDocument(apiRoutes))
+}
+
+// openAPIDocument builds the document for routes
+func openAPIDocument(routes []apiRoute) map[string]any {
+	schemas := newSchemaSet()
+	errorRef := schemas.schemaFor(reflect.TypeOf(errorResponse{}))
+
+	paths := make(map[string]map[string]any)
+	for _, route := range routes {
+		operation := map[string]any{
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"operationId": operationID(route),
+		}
+
+		var parameters []map[string]any
+		for _, name := range pathParams(route.Path) {
+			parameters = append(parameters, map[string]any{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, param := range route.Query {
+			parameters = append(parameters, map[string]any{
+				"name": param.Name, "in": "query", "required": param.Required,
+				"description": param.Description,
+				"schema":      map[string]any{"type": param.Type},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		if route.NoBody != "" {
+			success["description"] = route.NoBody
+		}
+		if route.Response != nil {
+			content := map[string]any{
+				"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(route.Response))},
+			}
+			if route.Stream {
+				content["text/event-stream"] = map[string]any{"schema": map[string]any{"type": "string"}}
+			}
+			success["content"] = content
+		}
+
+		errorContent := map[string]any{"application/json": map[string]any{"schema": errorRef}}
+		responses := map[string]any{
+			strconv.Itoa(status): success,
+			"400":                map[string]any{"description": "Invalid request", "content": errorContent},
+			"500":                map[string]any{"description": "Operation failed", "content": errorContent},
+		}
+		if route.Scope != "" {
+			operation["description"] = "Requires a token with the " + string(route.Scope) + " or admin scope when tokens are configured."
+			responses["401"] = map[string]any{"description": "Missing or wrong token", "content": errorContent}
+			responses["403"] = map[string]any{"description": "Token lacks the scope", "content": errorContent}
+			operation["security"] = []map[string]any{{"bearerAuth": []string{}}}
+		}
+		for _, code := range route.Errors {
+			responses[strconv.Itoa(code)] = map[string]any{"description": http.StatusText(code), "content": errorContent}
+		}
+		operation["responses"] = responses
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = make(map[string]any)
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Silvia API",
+			"version":     apiVersion,
+			"description": "Knowledge graph operations. Errors share one JSON body; list endpoints page with offset and limit.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+func pathParams(path string) []string {
+	var names []string
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// operationID derives an ID such as postApiEntitiesMerge from a route
+func operationID(route apiRoute) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '-' || r == '{' || r == '}' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// schemaSet generates JSON schemas from Go types, collecting named structs
+// as reusable components
+type schemaSet struct {
+	components map[string]any
+}
+
+func newSchemaSet() *schemaSet {
+	return &schemaSet{components: make(map[string]any)}
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaFor returns the schema for t, or a reference to its component
+func (s *schemaSet) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawJSONType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		// Generic instantiations such as Page[...] are inlined rather than
+		// given an unwieldy component name
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return s.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := s.components[name]; !ok {
+			s.components[name] = map[string]any{} // Placeholder for recursive types
+			s.components[name] = s.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema describes a struct's JSON fields, following encoding/json's
+// rules for tags and embedded structs
+func (s *schemaSet) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	s.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *schemaSet) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				s.addFields(fieldType, properties, required)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.schemaFor(fieldType)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// componentName names a struct's component after its package and type,
+// e.g. operations.Job becomes OperationsJob
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "server" || pkg == "" {
+		return strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	}
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + t.Name()
+}
@@ -2,70 +2,149 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"silvia/internal/llm"
 	"silvia/internal/operations"
+	"silvia/internal/sources"
+	"silvia/internal/tools"
 )
 
+// apiVersion is reported by /api/status and the OpenAPI document
+const apiVersion = "2.0.0"
+
 // Server provides HTTP API using the operations layer
 type Server struct {
 	port     int
-	token    string
+	token    string      // Shared token from -token, with every scope
+	tokens   *TokenStore // Named, scoped tokens
+	origins  []string    // Browser origins allowed to call the API
+	hosts    []string    // Host names besides loopback the server answers to
 	ops      *operations.Operations
+	mcp      http.Handler // MCP endpoint, if mounted
 	server   *http.Server
 	mu       sync.RWMutex
 	lastPing time.Time
-	busy     bool
+
+	// Cancelled when the server stops, to end event streams
+	streams     context.Context
+	stopStreams context.CancelFunc
 }
 
 // NewServer creates a new HTTP server using operations
 func NewServer(port int, token string, ops *operations.Operations) *Server {
+	streams, stopStreams := context.WithCancel(context.Background())
 	return &Server{
-		port:     port,
-		token:    token,
-		ops:      ops,
-		lastPing: time.Now(),
+		port:        port,
+		token:       token,
+		ops:         ops,
+		lastPing:    time.Now(),
+		streams:     streams,
+		stopStreams: stopStreams,
 	}
 }
 
 // Start begins listening for HTTP requests
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
+	s.server = &http.Server{
+		Addr:    fmt.Sprintf("localhost:%d", s.port),
+		Handler: s.Handler(),
+	}
+	// Event streams never finish on their own, so end them when shutting
+	// down rather than waiting for them
+	s.server.RegisterOnShutdown(s.stopStreams)
+
+	log.Printf("API server v2 starting on http://localhost:%d", s.port)
+	return s.server.ListenAndServe()
+}
 
-	// Add CORS middleware wrapper
-	handler := s.corsMiddleware(mux)
+// Handler returns the API's routes wrapped in the CORS middleware
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
 
 	// Register routes
 	mux.HandleFunc("/api/status", s.handleStatus)
 	mux.HandleFunc("/api/ingest", s.handleIngest)
+	mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
 
 	// Entity operations
+	mux.HandleFunc("/api/entities", s.handleEntities)
 	mux.HandleFunc("/api/entities/search", s.handleSearch)
 	mux.HandleFunc("/api/entities/", s.handleEntity)
+	mux.HandleFunc("/api/entities/related", s.handleRelated)
+	mux.HandleFunc("/api/entities/suggest", s.handleSuggest)
+	mux.HandleFunc("/api/entities/link", s.handleLink)
 	mux.HandleFunc("/api/entities/merge", s.handleMerge)
 	mux.HandleFunc("/api/entities/rename", s.handleRename)
+	mux.HandleFunc("/api/entities/move", s.handleMove)
+	mux.HandleFunc("/api/entities/refine", s.handleRefine)
+	mux.HandleFunc("/api/entities/rebuild-refs", s.handleRebuildRefs)
+	mux.HandleFunc("/api/types", s.handleTypes)
+	mux.HandleFunc("/api/annotate", s.handleAnnotate)
+
+	// Natural language queries
+	mux.HandleFunc("/api/chat", s.handleChat)
+
+	// Scripted tool chains
+	mux.HandleFunc("/api/chain", s.handleChain)
+
+	// Change notifications
+	mux.HandleFunc("/api/events", s.handleEvents)
+
+	// Background jobs
+	mux.HandleFunc("/api/jobs", s.handleJobs)
+	mux.HandleFunc("/api/jobs/", s.handleJob)
 
 	// Queue operations
 	mux.HandleFunc("/api/queue", s.handleQueue)
 	mux.HandleFunc("/api/queue/add", s.handleQueueAdd)
 	mux.HandleFunc("/api/queue/remove", s.handleQueueRemove)
+	mux.HandleFunc("/api/queue/clear", s.handleQueueClear)
+	mux.HandleFunc("/api/queue/priority", s.handleQueuePriority)
+	mux.HandleFunc("/api/queue/status", s.handleQueueStatus)
+
+	// Feeds and sources
+	mux.HandleFunc("/api/feeds", s.handleFeeds)
+	mux.HandleFunc("/api/feeds/poll", s.handleFeedPoll)
+	mux.HandleFunc("/api/sources/recheck", s.handleRecheck)
+	mux.HandleFunc("/api/sources/snapshots", s.handleSnapshots)
+	mux.HandleFunc("/api/sources/highlights", s.handleHighlights)
+	mux.HandleFunc("/api/quotes", s.handleQuotes)
+
+	// Prompts and LLM usage
+	mux.HandleFunc("/api/prompts", s.handlePrompts)
+	mux.HandleFunc("/api/usage", s.handleUsage)
+
+	// MCP for AI assistants
+	mux.HandleFunc(mcpPath, s.handleMCP)
+
+	// Web UI
+	mux.HandleFunc("/", s.handleUI)
+
+	// Check the host and origin first so preflight requests need no token
+	return s.hostMiddleware(s.corsMiddleware(s.authMiddleware(mux)))
+}
 
-	s.server = &http.Server{
-		Addr:    fmt.Sprintf("localhost:%d", s.port),
-		Handler: handler,
+// streamContext returns a context for a long-lived response such as an
+// event stream, cancelled when the client goes away or the server stops
+func (s *Server) streamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.streams, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
 	}
-
-	log.Printf("API server v2 starting on http://localhost:%d", s.port)
-	return s.server.ListenAndServe()
 }
 
-// Stop gracefully shuts down the server
+// Stop gracefully shuts down the server, letting requests in progress
+// finish and ending event streams
 func (s *Server) Stop(ctx context.Context) error {
 	if s.server != nil {
 		return s.server.Shutdown(ctx)
@@ -73,167 +152,141 @@ func (s *Server) Stop(ctx context.Context) error {
 	return nil
 }
 
-// corsMiddleware adds CORS headers for browser extension access
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from browser extensions
-		origin := r.Header.Get("Origin")
-
-		// Chrome/Edge extensions have origins like chrome-extension://[id]
-		// Firefox extensions have origins like moz-extension://[id]
-		if strings.HasPrefix(origin, "chrome-extension://") ||
-			strings.HasPrefix(origin, "moz-extension://") ||
-			strings.HasPrefix(origin, "edge-extension://") ||
-			origin == "null" || origin == "" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			if origin == "" || origin == "null" {
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-			}
-		}
-
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		// Handle preflight
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// validateToken checks the authorization token
-func (s *Server) validateToken(r *http.Request) bool {
-	if s.token == "" {
-		return true // No token required if not set
-	}
-
-	auth := r.Header.Get("Authorization")
-	expectedAuth := "Bearer " + s.token
-	return auth == expectedAuth
-}
-
 // handleStatus returns server status
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "GET") {
 		return
 	}
 
 	s.mu.Lock()
 	s.lastPing = time.Now()
-	busy := s.busy
 	s.mu.Unlock()
 
-	response := map[string]any{
-		"status":    "ok",
-		"version":   "2.0.0",
-		"busy":      busy,
-		"timestamp": time.Now().Unix(),
-	}
+	activeJobs := s.ops.Jobs.ActiveCount()
+	writeJSON(w, http.StatusOK, statusResponse{
+		Status:     "ok",
+		Version:    apiVersion,
+		Busy:       activeJobs > 0,
+		ActiveJobs: activeJobs,
+		Timestamp:  time.Now().Unix(),
+	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// statusResponse is the body of GET /api/status
+type statusResponse struct {
+	Status     string `json:"status"`
+	Version    string `json:"version"`
+	Busy       bool   `json:"busy"` // Whether any background job is pending or running
+	ActiveJobs int    `json:"active_jobs"`
+	Timestamp  int64  `json:"timestamp"`
 }
 
-// handleIngest processes content ingestion via operations
-func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// ingestRequest is the body of POST /api/ingest
+type ingestRequest struct {
+	URL      string            `json:"url"`
+	Force    bool              `json:"force,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	HTML     string            `json:"html,omitempty"` // Captured page, used instead of fetching the URL
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Passages highlighted in the captured page, which need HTML
+	Highlights []sources.Highlight `json:"highlights,omitempty"`
+}
 
-	// Validate token
-	if !s.validateToken(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
+// ingestResponse is returned when an ingest job is queued
+type ingestResponse struct {
+	Success bool                 `json:"success"`
+	URL     string               `json:"url"`
+	JobID   string               `json:"job_id"`
+	Status  operations.JobStatus `json:"status"`
+	Message string               `json:"message"`
+}
 
-	// Check if busy
-	s.mu.Lock()
-	if s.busy {
-		s.mu.Unlock()
-		http.Error(w, "Server busy", http.StatusTooManyRequests)
+// handleIngest queues a source for ingestion and returns straight away with
+// the job that will process it. When the extension sends the captured HTML
+// it is used instead of fetching the page again.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeIngest) {
 		return
 	}
-	s.busy = true
-	s.mu.Unlock()
-
-	defer func() {
-		s.mu.Lock()
-		s.busy = false
-		s.mu.Unlock()
-	}()
 
-	// Parse request
-	var req struct {
-		URL   string `json:"url"`
-		Force bool   `json:"force"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+	var req ingestRequest
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
 	// Validate required fields
 	if req.URL == "" {
-		http.Error(w, "URL is required", http.StatusBadRequest)
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "URL is required")
 		return
 	}
 
-	// Process using operations
-	ctx := context.Background()
-	result, err := s.ops.Source.IngestSource(ctx, req.URL, req.Force)
+	job, err := s.ops.Jobs.Submit(operations.JobIngest, operations.JobParams{
+		URL:        req.URL,
+		Force:      req.Force,
+		Title:      req.Title,
+		HTML:       req.HTML,
+		Metadata:   req.Metadata,
+		Highlights: req.Highlights,
+		Actor:      actor(r),
+	})
 	if err != nil {
 		log.Printf("Ingestion error: %v", err)
-		response := map[string]any{
-			"success": false,
-			"error":   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		writeError(w, err)
 		return
 	}
 
-	// Return success response
-	response := map[string]any{
-		"success":            true,
-		"url":                result.SourceURL,
-		"archived_path":      result.ArchivedPath,
-		"extracted_entities": len(result.ExtractedEntities),
-		"extracted_links":    len(result.ExtractedLinks),
-		"processing_time":    result.ProcessingTime.String(),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, ingestResponse{
+		Success: true,
+		URL:     req.URL,
+		JobID:   job.ID,
+		Status:  job.Status,
+		Message: "Queued for ingestion as job " + job.ID,
+	})
 }
 
-// handleSearch handles entity search
+// handleSearch handles entity search. Results are paged with ?offset= and
+// ?limit=; Total counts every match.
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
 		return
 	}
 
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Query parameter 'q' is required")
+		return
+	}
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
 		return
 	}
 
 	result, err := s.ops.Search.SearchEntities(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	if entityType := r.URL.Query().Get("type"); entityType != "" {
+		var matches []operations.SearchMatch
+		for _, match := range result.Results {
+			if string(match.Entity.Metadata.Type) == entityType {
+				matches = append(matches, match)
+			}
+		}
+		result.Results = matches
+		result.Total = len(matches)
+	}
+	result.Results = paginate(result.Results, offset, limit).Items
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// updateEntityRequest is the body of PUT /api/entities/{id}
+type updateEntityRequest struct {
+	Title   string `json:"title,omitempty"` // Kept as is when empty
+	Content string `json:"content"`
 }
 
 // handleEntity handles single entity operations
@@ -241,7 +294,15 @@ func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request) {
 	// Extract entity ID from path
 	path := strings.TrimPrefix(r.URL.Path, "/api/entities/")
 	if path == "" {
-		http.Error(w, "Entity ID required", http.StatusBadRequest)
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Entity ID required")
+		return
+	}
+
+	scope := ScopeWrite
+	if r.Method == "GET" {
+		scope = ScopeRead
+	}
+	if !s.authorize(w, r, scope) {
 		return
 	}
 
@@ -250,172 +311,277 @@ func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request) {
 		// Read entity
 		entity, err := s.ops.Entity.ReadEntity(path)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeError(w, err)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(entity)
+		writeJSON(w, http.StatusOK, entity)
 
 	case "PUT":
-		// Update entity
-		var req struct {
-			Title   string `json:"title,omitempty"`
-			Content string `json:"content"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
+		// Update entity; an empty title keeps the existing one
+		var req updateEntityRequest
+		if !decodeJSON(w, r, &req) {
 			return
 		}
 
-		// If title not provided, use existing title
-		if req.Title == "" {
-			existing, err := s.ops.Entity.ReadEntity(path)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			}
-			req.Title = existing.Title
-		}
-
-		entity, err := s.ops.Entity.UpdateEntity(path, req.Title, req.Content)
+		entity, err := s.opsFor(r).Entity.UpdateEntity(path, req.Title, req.Content)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(entity)
+		writeJSON(w, http.StatusOK, entity)
 
 	case "DELETE":
 		// Delete entity
-		if err := s.ops.Entity.DeleteEntity(path); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := s.opsFor(r).Entity.DeleteEntity(path); err != nil {
+			writeError(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
 	}
 }
 
+// mergeRequest is the body of POST /api/entities/merge
+type mergeRequest struct {
+	Entity1ID string `json:"entity1_id"` // The entity kept
+	Entity2ID string `json:"entity2_id"` // The entity merged into it and deleted
+}
+
 // handleMerge handles entity merge operations
 func (s *Server) handleMerge(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
 		return
 	}
 
-	var req struct {
-		Entity1ID string `json:"entity1_id"`
-		Entity2ID string `json:"entity2_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	var req mergeRequest
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	ctx := context.Background()
-	result, err := s.ops.Entity.MergeEntities(ctx, req.Entity1ID, req.Entity2ID)
+	result, err := s.opsFor(r).Entity.MergeEntities(r.Context(), req.Entity1ID, req.Entity2ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+	writeJSON(w, http.StatusOK, result)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+// renameRequest is the body of POST /api/entities/rename and /move
+type renameRequest struct {
+	OldID string `json:"old_id"`
+	NewID string `json:"new_id"`
 }
 
 // handleRename handles entity rename operations
 func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
 		return
 	}
 
-	var req struct {
-		OldID string `json:"old_id"`
-		NewID string `json:"new_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	var req renameRequest
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	result, err := s.ops.Entity.RenameEntity(req.OldID, req.NewID)
+	result, err := s.opsFor(r).Entity.RenameEntity(req.OldID, req.NewID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	writeJSON(w, http.StatusOK, result)
 }
 
-// handleQueue handles queue status
+// handleQueue handles queue status. ?status= lists sources in the given
+// comma-separated states, or "all", rather than those still waiting;
+// ?priority= keeps items of one priority, and items are paged with ?offset=
+// and ?limit=. TotalCount and ByPriority describe the items in those
+// states, ByStatus the whole queue.
 func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
 		return
 	}
 
-	status, err := s.ops.Queue.GetQueue()
+	var statuses []string
+	switch value := r.URL.Query().Get("status"); value {
+	case "":
+	case "all":
+		statuses = operations.QueueStatuses
+	default:
+		statuses = strings.Split(value, ",")
+	}
+	status, err := s.ops.Queue.GetQueue(statuses...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
+	if value := r.URL.Query().Get("priority"); value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "priority must be 0, 1 or 2")
+			return
+		}
+		var items []operations.QueueItem
+		for _, item := range status.Items {
+			if item.Priority == priority {
+				items = append(items, item)
+			}
+		}
+		status.Items = items
+	}
+	status.Items = paginate(status.Items, offset, limit).Items
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// queueAddRequest is the body of POST /api/queue/add
+type queueAddRequest struct {
+	URL         string `json:"url"`
+	Priority    int    `json:"priority"` // 0 (low) to 2 (high)
+	FromSource  string `json:"from_source,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// queueChangeResponse reports a change to one queued URL
+type queueChangeResponse struct {
+	Status string `json:"status"`
+	URL    string `json:"url"`
 }
 
 // handleQueueAdd adds items to queue
 func (s *Server) handleQueueAdd(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
 		return
 	}
 
-	var req struct {
-		URL         string `json:"url"`
-		Priority    int    `json:"priority"`
-		FromSource  string `json:"from_source"`
-		Description string `json:"description"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	var req queueAddRequest
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if err := s.ops.Queue.AddToQueue(req.URL, req.Priority, req.FromSource, req.Description); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := s.opsFor(r).Queue.AddToQueue(req.URL, req.Priority, req.FromSource, req.Description); err != nil {
+		writeError(w, err)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "added",
-		"url":    req.URL,
-	})
+	writeJSON(w, http.StatusOK, queueChangeResponse{Status: "added", URL: req.URL})
 }
 
 // handleQueueRemove removes items from queue
 func (s *Server) handleQueueRemove(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "DELETE" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethod(w, r, "DELETE") || !s.authorize(w, r, ScopeWrite) {
 		return
 	}
 
 	url := r.URL.Query().Get("url")
 	if url == "" {
-		http.Error(w, "URL parameter required", http.StatusBadRequest)
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "URL parameter required")
 		return
 	}
 
-	if err := s.ops.Queue.RemoveFromQueue(url); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := s.opsFor(r).Queue.RemoveFromQueue(url); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// handleUsage reports LLM token usage and cost, optionally since a time and grouped
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeAdmin) {
+		return
+	}
+
+	since, err := llm.ParseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	report, err := s.ops.LLM.GetUsage(since, r.URL.Query().Get("by"))
+	if err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleChain runs a chain of tool calls in which later steps can refer to
+// earlier results, without involving the LLM. The chain is validated, and
+// the token checked against every step's tool, before anything runs.
+func (s *Server) handleChain(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+
+	var req chainRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	manager := tools.NewManager(s.opsFor(r))
+	if err := manager.ValidateChain(req.Steps); err != nil {
+		writeJSON(w, http.StatusBadRequest, chainResponse(nil, err))
+		return
+	}
+
+	// Each step needs the scope its endpoint would, as over MCP
+	token, _ := r.Context().Value(tokenKey{}).(*Token)
+	for i, call := range req.Steps {
+		tool, err := manager.Registry().Get(call.Tool)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if scope := toolScope(tool); !token.Allows(scope) {
+			writeErrorStatus(w, http.StatusForbidden, codeForbidden,
+				fmt.Sprintf("Step %d: token %s lacks the %s scope needed for %s", i+1, token.Name, scope, tool.Name()))
+			return
+		}
+	}
+
+	results, err := manager.ExecuteChain(r.Context(), req.Steps)
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, chainResponse(results, err))
+}
+
+// chainRequest is the body of POST /api/chain
+type chainRequest struct {
+	Steps []tools.ToolCall `json:"steps"`
+}
+
+// chainResult is the body of a POST /api/chain response
+type chainResult struct {
+	Success    bool               `json:"success"`
+	Results    []tools.ToolResult `json:"results"`
+	Error      string             `json:"error,omitempty"`
+	FailedStep int                `json:"failed_step,omitempty"` // 1-based step that failed
+}
+
+// chainResponse reports a chain's results and, if it stopped early, where
+func chainResponse(results []tools.ToolResult, err error) chainResult {
+	response := chainResult{
+		Success: err == nil,
+		Results: results,
+	}
+	if results == nil {
+		response.Results = []tools.ToolResult{}
+	}
+	if err != nil {
+		response.Error = err.Error()
+		var chainErr *tools.ChainError
+		if errors.As(err, &chainErr) {
+			response.FailedStep = chainErr.Step
+		}
+	}
+	return response
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"silvia/internal/operations"
+	"silvia/internal/prompts"
+)
+
+// queuePriorityRequest is the body of POST /api/queue/priority
+type queuePriorityRequest struct {
+	URL      string `json:"url"`
+	Priority int    `json:"priority"` // 0 (low) to 2 (high)
+}
+
+// queueStatusRequest is the body of POST /api/queue/status
+type queueStatusRequest struct {
+	URL    string    `json:"url"`
+	Status string    `json:"status"`          // pending, in_progress, done, failed, skipped or deferred
+	Until  time.Time `json:"until,omitzero"`  // When a deferred source is pending again; omit to defer until requeued
+	Error  string    `json:"error,omitempty"` // Why a failed source failed
+}
+
+// feedRequest is the body of POST /api/feeds
+type feedRequest struct {
+	URL           string   `json:"url"`
+	Priority      int      `json:"priority"`
+	Keywords      []string `json:"keywords,omitempty"`
+	MatchEntities bool     `json:"match_entities,omitempty"`
+}
+
+// feedPollRequest is the body of POST /api/feeds/poll
+type feedPollRequest struct {
+	Feed string `json:"feed,omitempty"` // URL or name; every feed when empty
+}
+
+// recheckRequest is the body of POST /api/sources/recheck. With a URL only
+// that source is rechecked; otherwise sources not checked within MaxAge
+// are, or every source with All.
+type recheckRequest struct {
+	URL    string `json:"url,omitempty"`
+	All    bool   `json:"all,omitempty"`
+	MaxAge string `json:"max_age,omitempty"` // Go duration, default 24h
+	Limit  int    `json:"limit,omitempty"`   // 0 for no limit
+}
+
+// handleQueueClear removes every item from the queue
+func (s *Server) handleQueueClear(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeAdmin) {
+		return
+	}
+	if err := s.opsFor(r).Queue.ClearQueue(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, successResponse{Success: true})
+}
+
+// handleQueuePriority changes a queued URL's priority
+func (s *Server) handleQueuePriority(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+	var req queuePriorityRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if err := s.opsFor(r).Queue.UpdatePriority(req.URL, req.Priority); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, queueChangeResponse{Status: "updated", URL: req.URL})
+}
+
+// handleQueueStatus moves a queued URL to another state: skipping,
+// deferring or requeueing it, or recording how its ingest went. It returns
+// the item as it now is.
+func (s *Server) handleQueueStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+	var req queueStatusRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	var cause error
+	if req.Error != "" {
+		cause = errors.New(req.Error)
+	}
+	queue := s.opsFor(r).Queue
+	if err := queue.SetStatus(req.URL, req.Status, req.Until, cause); err != nil {
+		writeError(w, err)
+		return
+	}
+	item, err := queue.GetItem(req.URL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+// handleFeeds lists feed subscriptions (GET), subscribes to a feed (POST)
+// or unsubscribes from one given by ?feed= URL or name (DELETE)
+func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
+	scope := ScopeAdmin
+	if r.Method == "GET" {
+		scope = ScopeRead
+	}
+	if !s.authorize(w, r, scope) {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		feeds, err := s.ops.Feed.ListFeeds()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if feeds == nil {
+			feeds = []*operations.FeedSubscription{}
+		}
+		writeJSON(w, http.StatusOK, feeds)
+
+	case "POST":
+		var req feedRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		feed, err := s.ops.Feed.AddFeed(r.Context(), req.URL, req.Priority, req.Keywords, req.MatchEntities)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, feed)
+
+	case "DELETE":
+		if err := s.ops.Feed.RemoveFeed(r.URL.Query().Get("feed")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleFeedPoll polls one feed or all of them and queues new entries
+func (s *Server) handleFeedPoll(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeIngest) {
+		return
+	}
+	var req feedPollRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+
+	results, err := s.opsFor(r).Feed.PollFeeds(r.Context(), req.Feed)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if results == nil {
+		results = []operations.FeedPollResult{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// handleRecheck refetches processed sources and reports what changed. It
+// runs to completion before responding.
+func (s *Server) handleRecheck(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeIngest) {
+		return
+	}
+	var req recheckRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.URL != "" {
+		result, err := s.opsFor(r).Source.RecheckSource(r.Context(), req.URL)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	maxAge := 24 * time.Hour
+	if req.MaxAge != "" {
+		d, err := time.ParseDuration(req.MaxAge)
+		if err != nil || d < 0 {
+			writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "max_age must be a duration such as 24h")
+			return
+		}
+		maxAge = d
+	}
+	report, err := s.opsFor(r).Source.RecheckSources(r.Context(), req.All, maxAge, req.Limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleSnapshots lists the captures recorded for a source, oldest first
+// (GET /api/sources/snapshots?url=)
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Query parameter 'url' is required")
+		return
+	}
+
+	snapshots, err := s.ops.Source.GetSourceSnapshots(url)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []operations.SourceSnapshot{}
+	}
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// handleHighlights lists the highlights made in captures of ?url= so the
+// browser extension can show them on the page again
+func (s *Server) handleHighlights(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "Query parameter 'url' is required")
+		return
+	}
+
+	highlights, err := s.ops.Source.ListHighlights(url)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, highlights)
+}
+
+// handleQuotes lists the quotes and claims recorded from highlights, newest
+// first, optionally only those naming ?entity= or taken from ?url=
+func (s *Server) handleQuotes(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	quotes, err := s.ops.Source.ListQuotes(query.Get("entity"), query.Get("url"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paginate(quotes, offset, limit))
+}
+
+// handlePrompts lists the prompt templates in use and their versions
+func (s *Server) handlePrompts(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") || !s.authorize(w, r, ScopeRead) {
+		return
+	}
+	writeJSON(w, http.StatusOK, prompts.Default().List())
+}
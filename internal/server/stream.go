@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"silvia/internal/chat"
+	"silvia/internal/tools"
+)
+
+// wantsStream reports whether the client asked for server-sent events
+func wantsStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") || r.URL.Query().Get("stream") == "1"
+}
+
+// sseWriter sends server-sent events, flushing after each one
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newSSEWriter starts an event stream on w
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &sseWriter{w: w, flusher: flusher}, nil
+}
+
+// send writes one event with data encoded as JSON
+func (s *sseWriter) send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{"error": err.Error()})
+		event = "error"
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.flusher.Flush()
+}
+
+// sendID writes one event with an ID a reconnecting client sends back as
+// Last-Event-ID
+func (s *sseWriter) sendID(id, event string, data any) {
+	fmt.Fprintf(s.w, "id: %s\n", id)
+	s.send(event, data)
+}
+
+// ping writes a comment so proxies and clients keep an idle stream open
+func (s *sseWriter) ping() {
+	fmt.Fprint(s.w, ": ping\n\n")
+	s.flusher.Flush()
+}
+
+// delta returns a stream callback that sends each piece as a delta event
+func (s *sseWriter) delta() func(string) {
+	return func(content string) {
+		s.send("delta", map[string]string{"content": content})
+	}
+}
+
+// refineRequest is the body of POST /api/entities/refine
+type refineRequest struct {
+	ID       string `json:"id"`
+	Guidance string `json:"guidance,omitempty"`
+}
+
+// chatRequest is the body of POST /api/chat
+type chatRequest struct {
+	Message string `json:"message"`
+}
+
+// chatResponse is the answer to a chat message
+type chatResponse struct {
+	Response string `json:"response"`
+}
+
+// handleRefine refines an entity with the LLM. With Accept: text/event-stream
+// the new content is streamed as delta events, followed by a done event with
+// the saved entity (or an error event).
+func (s *Server) handleRefine(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+
+	var req refineRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.ID == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "id is required")
+		return
+	}
+
+	if !wantsStream(r) {
+		entity, err := s.opsFor(r).Entity.RefineEntity(r.Context(), req.ID, req.Guidance)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entity)
+		return
+	}
+
+	stream, err := newSSEWriter(w)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// The request context ends when the client disconnects, cancelling the LLM call
+	entity, err := s.opsFor(r).Entity.RefineEntityStream(r.Context(), req.ID, req.Guidance, stream.delta())
+	if err != nil {
+		stream.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	stream.send("done", entity)
+}
+
+// handleChat answers a natural language question from the knowledge graph.
+// Each request is a fresh conversation. With Accept: text/event-stream the
+// answer is streamed as delta events, followed by a done event. A reset event
+// means the answer streamed so far was abandoned and the deltas that follow
+// replace it.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") || !s.authorize(w, r, ScopeWrite) {
+		return
+	}
+
+	var req chatRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeErrorStatus(w, http.StatusBadRequest, codeBadRequest, "message is required")
+		return
+	}
+
+	client := s.ops.LLM.Client()
+	if client == nil {
+		writeErrorStatus(w, http.StatusServiceUnavailable, codeUnavailable, "LLM client not available")
+		return
+	}
+	chatInterface := chat.NewChatInterface(tools.NewManager(s.opsFor(r)), client)
+	chatInterface.EnableLogging(false)
+
+	if !wantsStream(r) {
+		response, err := chatInterface.ProcessMessage(r.Context(), req.Message)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, chatResponse{Response: response})
+		return
+	}
+
+	stream, err := newSSEWriter(w)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	chatInterface.Agent().OnReset(func() {
+		stream.send("reset", struct{}{})
+	})
+	response, err := chatInterface.ProcessMessageStream(r.Context(), req.Message, stream.delta())
+	if err != nil {
+		stream.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	stream.send("done", chatResponse{Response: response})
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope is a permission granted to an API token
+type Scope string
+
+// Token scopes. Admin grants every other scope.
+const (
+	ScopeRead   Scope = "read"   // Read entities, search, queue, jobs and events
+	ScopeWrite  Scope = "write"  // Edit entities and the queue, chat, refine and merge
+	ScopeIngest Scope = "ingest" // Ingest sources, process the queue and poll feeds
+	ScopeAdmin  Scope = "admin"  // Manage feeds, clear the queue, rebuild references, read usage
+)
+
+// AllScopes lists every scope, weakest first
+var AllScopes = []Scope{ScopeRead, ScopeWrite, ScopeIngest, ScopeAdmin}
+
+// ParseScopes reads a comma-separated list of scopes
+func ParseScopes(value string) ([]Scope, error) {
+	var scopes []Scope
+	for _, name := range strings.Split(value, ",") {
+		scope := Scope(strings.TrimSpace(strings.ToLower(name)))
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(AllScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q (must be read, write, ingest or admin)", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// Token is a named API token. Only a hash of its secret is stored.
+type Token struct {
+	Name     string    `json:"name"`
+	Scopes   []Scope   `json:"scopes"`
+	Hash     string    `json:"hash"` // SHA-256 of the secret, hex encoded
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used,omitzero"`
+}
+
+// Allows reports whether the token grants scope
+func (t *Token) Allows(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope) || slices.Contains(t.Scopes, ScopeAdmin)
+}
+
+// tokenPrefix starts every secret, so a leaked one is easy to recognize
+const tokenPrefix = "silvia_"
+
+// lastUsedInterval is how stale a token's LastUsed may get before a request
+// rewrites the file
+const lastUsedInterval = time.Hour
+
+var tokenNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]{0,63}$`)
+
+// TokenStore keeps API tokens in data/.silvia/tokens.json. The file is
+// reread when it changes, so tokens created or revoked with `silvia token`
+// take effect on a running server.
+type TokenStore struct {
+	path string
+
+	mu      sync.Mutex
+	tokens  []*Token
+	modTime time.Time
+	size    int64
+}
+
+// NewTokenStore creates a token store in the data directory
+func NewTokenStore(dataDir string) *TokenStore {
+	return &TokenStore{path: filepath.Join(dataDir, ".silvia", "tokens.json")}
+}
+
+// Create adds a token and returns it with its secret, which is shown once
+// and cannot be recovered
+func (s *TokenStore) Create(name string, scopes []Scope) (*Token, string, error) {
+	if !tokenNamePattern.MatchString(name) {
+		return nil, "", fmt.Errorf("invalid token name %q: use letters, digits, '.', '_', '@' or '-'", name)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, "", err
+	}
+	for _, token := range s.tokens {
+		if token.Name == name {
+			return nil, "", fmt.Errorf("a token named %q already exists", name)
+		}
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := tokenPrefix + hex.EncodeToString(random)
+
+	token := &Token{Name: name, Scopes: scopes, Hash: hashSecret(secret), Created: time.Now()}
+	s.tokens = append(s.tokens, token)
+	if err := s.save(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return nil, "", err
+	}
+	copied := *token
+	return &copied, secret, nil
+}
+
+// Revoke deletes the named token
+func (s *TokenStore) Revoke(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(s.tokens, func(t *Token) bool { return t.Name == name })
+	if index < 0 {
+		return fmt.Errorf("no token named %q", name)
+	}
+	s.tokens = slices.Delete(s.tokens, index, index+1)
+	return s.save()
+}
+
+// List returns the tokens sorted by name
+func (s *TokenStore) List() ([]Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]Token, len(s.tokens))
+	for i, token := range s.tokens {
+		tokens[i] = *token
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Name < tokens[j].Name })
+	return tokens, nil
+}
+
+// Configured reports whether any token exists. A tokens file that cannot
+// be read is an error, never a sign that there are no tokens.
+func (s *TokenStore) Configured() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return true, err
+	}
+	return len(s.tokens) > 0, nil
+}
+
+// Authenticate returns the token whose secret this is, or nil
+func (s *TokenStore) Authenticate(secret string) *Token {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil
+	}
+	hash := hashSecret(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil
+	}
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hash)) == 1 {
+			if time.Since(token.LastUsed) > lastUsedInterval {
+				token.LastUsed = time.Now()
+				s.save() // Best effort; failing to record use must not fail the request
+			}
+			copied := *token
+			return &copied
+		}
+	}
+	return nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// load rereads the file if it has changed. Callers hold s.mu.
+func (s *TokenStore) load() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.tokens, s.modTime, s.size = nil, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read tokens: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size && s.tokens != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read tokens: %w", err)
+	}
+	var tokens []*Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	if tokens == nil {
+		tokens = []*Token{}
+	}
+	s.tokens, s.modTime, s.size = tokens, info.ModTime(), info.Size()
+	return nil
+}
+
+// save writes the tokens, readable only by the owner. Callers hold s.mu.
+func (s *TokenStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"silvia/internal/operations"
+	"silvia/internal/tools"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []Scope
+		wantErr string
+	}{
+		{value: "read", want: []Scope{ScopeRead}},
+		{value: "read, Write,ingest", want: []Scope{ScopeRead, ScopeWrite, ScopeIngest}},
+		{value: "admin,admin", want: []Scope{ScopeAdmin}},
+		{value: "read,,write", want: []Scope{ScopeRead, ScopeWrite}},
+		{value: "", wantErr: "at least one scope"},
+		{value: "read,root", wantErr: `unknown scope "root"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseScopes(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseScopes(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseScopes(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	tests := []struct {
+		scopes  []Scope
+		allowed []Scope
+	}{
+		{scopes: []Scope{ScopeRead}, allowed: []Scope{ScopeRead}},
+		// Scopes are not nested: write does not grant read
+		{scopes: []Scope{ScopeWrite}, allowed: []Scope{ScopeWrite}},
+		{scopes: []Scope{ScopeRead, ScopeIngest}, allowed: []Scope{ScopeRead, ScopeIngest}},
+		{scopes: []Scope{ScopeAdmin}, allowed: AllScopes},
+	}
+
+	for _, tt := range tests {
+		token := &Token{Name: "test", Scopes: tt.scopes}
+		for _, scope := range AllScopes {
+			if got, want := token.Allows(scope), slices.Contains(tt.allowed, scope); got != want {
+				t.Errorf("token with %v: Allows(%s) = %v, want %v", tt.scopes, scope, got, want)
+			}
+		}
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewTokenStore(dir)
+	if configured, err := store.Configured(); configured || err != nil {
+		t.Fatalf("Configured() = %v, %v for a new store", configured, err)
+	}
+
+	token, secret, err := store.Create("laptop", []Scope{ScopeRead, ScopeWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		t.Errorf("secret %q lacks the %q prefix", secret, tokenPrefix)
+	}
+	if token.Hash != hashSecret(secret) || token.Hash == secret {
+		t.Errorf("hash = %q, want the SHA-256 of the secret", token.Hash)
+	}
+
+	// Only the hash is written to disk
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Error("tokens.json holds the secret")
+	}
+
+	if _, _, err := store.Create("laptop", []Scope{ScopeRead}); err == nil {
+		t.Error("created a second token with the same name")
+	}
+	if _, _, err := store.Create("bad name", []Scope{ScopeRead}); err == nil {
+		t.Error("created a token with a space in its name")
+	}
+
+	// A second store reads the tokens the first one wrote
+	other := NewTokenStore(dir)
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{secret: secret, want: "laptop"},
+		{secret: secret + "0", want: ""},
+		{secret: strings.TrimPrefix(secret, tokenPrefix), want: ""},
+		{secret: "", want: ""},
+	}
+	for _, tt := range tests {
+		got := other.Authenticate(tt.secret)
+		if name := tokenName(got); name != tt.want {
+			t.Errorf("Authenticate(%q) = %q, want %q", tt.secret, name, tt.want)
+		}
+	}
+
+	if err := store.Revoke("laptop"); err != nil {
+		t.Fatal(err)
+	}
+	if got := other.Authenticate(secret); got != nil {
+		t.Errorf("revoked token still authenticates as %q", got.Name)
+	}
+	if err := store.Revoke("laptop"); err == nil {
+		t.Error("revoked a token that does not exist")
+	}
+}
+
+func tokenName(token *Token) string {
+	if token == nil {
+		return ""
+	}
+	return token.Name
+}
+
+func TestAuthorize(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{token: "shared-secret", tokens: NewTokenStore(dir)}
+	_, reader, err := s.tokens.Create("reader", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		auth   string
+		path   string
+		scope  Scope
+		status int
+	}{
+		{name: "public path", path: "/api/status", scope: "", status: http.StatusOK},
+		{name: "no token", path: "/api/queue", scope: ScopeRead, status: http.StatusUnauthorized},
+		{name: "wrong token", auth: "Bearer silvia_nope", path: "/api/queue", scope: ScopeRead, status: http.StatusUnauthorized},
+		{name: "scope granted", auth: "Bearer " + reader, path: "/api/queue", scope: ScopeRead, status: http.StatusOK},
+		{name: "scope missing", auth: "Bearer " + reader, path: "/api/queue", scope: ScopeWrite, status: http.StatusForbidden},
+		{name: "shared token is admin", auth: "Bearer shared-secret", path: "/api/queue", scope: ScopeAdmin, status: http.StatusOK},
+		{name: "not the API", path: "/", scope: "", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := s.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.scope != "" && !s.authorize(w, r, tt.scope) {
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestUnreadableTokensRequireAuth(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{tokens: NewTokenStore(dir)}
+	if err := os.MkdirAll(filepath.Dir(s.tokens.path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.tokens.path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := s.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, auth := range []string{"", "Bearer silvia_guess"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/queue", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("with %q: status = %d, want %d", auth, rec.Code, http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	s := &Server{hosts: []string{"silvia.lan"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "localhost:8765", want: true},
+		{host: "LOCALHOST:8765", want: true},
+		{host: "127.0.0.1:8765", want: true},
+		{host: "[::1]:8765", want: true},
+		{host: "localhost", want: true},
+		{host: "silvia.lan:8765", want: true},
+		{host: "Silvia.LAN", want: true},
+		{host: "attacker.example:8765", want: false},
+		{host: "localhost.attacker.example", want: false},
+		{host: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := s.hostAllowed(tt.host); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestToolScope(t *testing.T) {
+	registry := tools.NewManager(&operations.Operations{}).Registry()
+	tests := []struct {
+		tool  string
+		scope Scope
+	}{
+		{tool: "search_entities", scope: ScopeRead},
+		{tool: "get_queue", scope: ScopeRead},
+		{tool: "add_to_queue", scope: ScopeWrite},
+		{tool: "ingest_source", scope: ScopeIngest},
+		{tool: "extract_from_html", scope: ScopeIngest},
+		{tool: "merge_entities", scope: ScopeWrite},
+		{tool: "process_next_queue_item", scope: ScopeIngest},
+		{tool: "clear_queue", scope: ScopeAdmin},
+	}
+	for _, tt := range tests {
+		tool, err := registry.Get(tt.tool)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.tool, err)
+		}
+		if got := toolScope(tool); got != tt.scope {
+			t.Errorf("toolScope(%s) = %s, want %s", tt.tool, got, tt.scope)
+		}
+	}
+
+	// Every tool that changes state says what it needs
+	for _, tool := range registry.List() {
+		if _, ok := tool.(tools.AccessDeclarer); tools.IsMutating(tool) && !ok {
+			t.Errorf("%s changes state but does not declare its access", tool.Name())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"silvia/internal/operations"
+)
+
+// webFiles holds the web UI, a single page that talks to the API. It has no
+// external dependencies, so it works offline.
+//
+//go:embed web
+var webFiles embed.FS
+
+// handleUI serves the web UI's page and assets. The UI routes with the URL
+// hash, so any other path is a mistake: unknown API paths get a JSON 404
+// and everything else the page itself.
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeErrorStatus(w, http.StatusNotFound, operations.CodeNotFound, "Unknown endpoint: "+r.URL.Path)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		writeErrorStatus(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	assets, _ := fs.Sub(webFiles, "web")
+	if r.URL.Path != "/" {
+		if _, err := fs.Stat(assets, strings.TrimPrefix(r.URL.Path, "/")); err != nil {
+			r.URL.Path = "/"
+		}
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.FileServer(http.FS(assets)).ServeHTTP(w, r)
+}
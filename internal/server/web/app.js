@@ -0,0 +1,654 @@
+// Silvia web UI: a small single-page app over the local API. Views are
+// chosen by the URL hash, e.g. #/entity/people/ada-lovelace, so the server
+// only ever serves this page.
+
+const view = document.getElementById('view');
+const notice = document.getElementById('notice');
+const pageSize = 50;
+
+// ---- API ----
+
+function token() {
+  return localStorage.getItem('silvia-token') || '';
+}
+
+// api calls the server and returns the decoded body, throwing the error
+// message from the API's JSON error body on failure
+async function api(method, path, body) {
+  const headers = {};
+  if (token()) {
+    headers['Authorization'] = 'Bearer ' + token();
+  }
+  if (body !== undefined) {
+    headers['Content-Type'] = 'application/json';
+  }
+  const response = await fetch(path, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  if (response.status === 204) {
+    return null;
+  }
+  const data = await response.json().catch(() => null);
+  if (!response.ok) {
+    if (response.status === 401) {
+      throw new Error('The server needs an API token; set one with the Token button');
+    }
+    throw new Error((data && data.error) || response.statusText);
+  }
+  return data;
+}
+
+function entityPath(id) {
+  return id.split('/').map(encodeURIComponent).join('/');
+}
+
+// ---- Helpers ----
+
+function escapeHTML(text) {
+  return String(text ?? '').replace(/[&<>"']/g, (c) => ({
+    '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;',
+  })[c]);
+}
+
+function entityLink(id, label) {
+  return `<a href="#/entity/${entityPath(id)}">${escapeHTML(label || id)}</a>`;
+}
+
+function showNotice(message, isError) {
+  notice.textContent = message;
+  notice.className = isError ? 'error' : '';
+  notice.hidden = false;
+  clearTimeout(showNotice.timer);
+  showNotice.timer = setTimeout(() => { notice.hidden = true; }, isError ? 8000 : 4000);
+}
+
+function formatDate(value) {
+  if (!value || value.startsWith('0001-')) {
+    return '';
+  }
+  return new Date(value).toLocaleString();
+}
+
+// ---- Markdown ----
+
+// renderInline formats one line of already-escaped text
+function renderInline(text) {
+  const codes = [];
+  text = text.replace(/`([^`]+)`/g, (_, code) => {
+    codes.push(code);
+    return `\u0000${codes.length - 1}\u0000`;
+  });
+  text = text
+    .replace(/\[\[([^\]|]+)(?:\|([^\]]+))?\]\]/g, (_, target, label) =>
+      `<a class="wiki" href="#/entity/${entityPath(target.trim())}">${label || target}</a>`)
+    .replace(/\[([^\]]+)\]\(([^)\s]+)\)/g, (_, label, url) => {
+      const safe = /^(https?:|mailto:|#)/.test(url) ? url : '#';
+      return `<a href="${safe}" target="_blank" rel="noopener">${label}</a>`;
+    })
+    .replace(/\*\*([^*]+)\*\*/g, '<strong>$1</strong>')
+    .replace(/(^|[^*])\*([^*]+)\*/g, '$1<em>$2</em>')
+    .replace(/(^|[\s(])(https?:\/\/[^\s<)]*[^\s<).,;:!?])/g, '$1<a href="$2" target="_blank" rel="noopener">$2</a>');
+  return text.replace(/\u0000(\d+)\u0000/g, (_, i) => `<code>${codes[i]}</code>`);
+}
+
+// renderMarkdown converts entity markdown to HTML: headings, lists, quotes,
+// code blocks, paragraphs, links and [[wiki-links]]
+function renderMarkdown(source) {
+  const lines = escapeHTML(source).split('\n');
+  const html = [];
+  let paragraph = [];
+  let list = null;
+
+  const flush = () => {
+    if (paragraph.length) {
+      html.push(`<p>${renderInline(paragraph.join(' '))}</p>`);
+      paragraph = [];
+    }
+    if (list) {
+      html.push(`<${list.tag}>${list.items.map((item) => `<li>${renderInline(item)}</li>`).join('')}</${list.tag}>`);
+      list = null;
+    }
+  };
+
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    let match;
+    if (line.startsWith('```')) {
+      flush();
+      const code = [];
+      for (i++; i < lines.length && !lines[i].startsWith('```'); i++) {
+        code.push(lines[i]);
+      }
+      html.push(`<pre><code>${code.join('\n')}</code></pre>`);
+    } else if ((match = line.match(/^(#{1,6})\s+(.*)$/))) {
+      flush();
+      const level = Math.min(match[1].length + 1, 6); // The entity title is the page's h1
+      html.push(`<h${level}>${renderInline(match[2])}</h${level}>`);
+    } else if ((match = line.match(/^\s*[-*]\s+(.*)$/)) || (match = line.match(/^\s*\d+[.)]\s+(.*)$/))) {
+      const tag = /^\s*\d/.test(line) ? 'ol' : 'ul';
+      if (paragraph.length || (list && list.tag !== tag)) {
+        flush();
+      }
+      list = list || { tag, items: [] };
+      list.items.push(match[1]);
+    } else if ((match = line.match(/^&gt;\s?(.*)$/))) {
+      flush();
+      html.push(`<blockquote>${renderInline(match[1])}</blockquote>`);
+    } else if (/^\s*(---|\*\*\*)\s*$/.test(line)) {
+      flush();
+      html.push('<hr>');
+    } else if (line.trim() === '') {
+      flush();
+    } else if (list && /^\s+/.test(line)) {
+      list.items[list.items.length - 1] += ' ' + line.trim();
+    } else {
+      if (list) {
+        flush();
+      }
+      paragraph.push(line.trim());
+    }
+  }
+  flush();
+  return html.join('\n');
+}
+
+// ---- Views ----
+
+async function listView(params) {
+  const type = params.get('type') || '';
+  const offset = Number(params.get('offset') || 0);
+  const [types, page] = await Promise.all([
+    api('GET', '/api/types'),
+    api('GET', `/api/entities?type=${encodeURIComponent(type)}&offset=${offset}&limit=${pageSize}`),
+  ]);
+
+  const options = ['', ...types.entity_types]
+    .map((t) => `<option value="${t}" ${t === type ? 'selected' : ''}>${t || 'All types'}</option>`)
+    .join('');
+  const rows = page.items.map((entity) => `
+    <tr>
+      <td>${entityLink(entity.Metadata.ID, entity.Title || entity.Metadata.ID)}<div class="dim small">${escapeHTML(entity.Metadata.ID)}</div></td>
+      <td>${escapeHTML(entity.Metadata.Type)}</td>
+      <td>${(entity.Relationships || []).length} / ${(entity.BackRefs || []).length}</td>
+      <td class="dim small">${formatDate(entity.Metadata.Updated)}</td>
+    </tr>`).join('');
+
+  const pager = (newOffset, label) =>
+    `<a href="#/?type=${encodeURIComponent(type)}&offset=${newOffset}">${label}</a>`;
+  const end = Math.min(offset + page.items.length, page.total);
+
+  view.innerHTML = `
+    <h1>Entities</h1>
+    <div class="toolbar">
+      <select id="type-filter">${options}</select>
+      <span class="dim">${page.total ? `${offset + 1}–${end} of ${page.total}` : 'No entities'}</span>
+      ${offset > 0 ? pager(Math.max(offset - pageSize, 0), '← Previous') : ''}
+      ${end < page.total ? pager(offset + pageSize, 'Next →') : ''}
+    </div>
+    <table>
+      <thead><tr><th>Entity</th><th>Type</th><th>Links out / in</th><th>Updated</th></tr></thead>
+      <tbody>${rows}</tbody>
+    </table>`;
+
+  document.getElementById('type-filter').addEventListener('change', (event) => {
+    location.hash = `#/?type=${encodeURIComponent(event.target.value)}`;
+  });
+}
+
+async function searchView(params) {
+  const query = params.get('q') || '';
+  document.getElementById('search-input').value = query;
+  const result = await api('GET', `/api/entities/search?q=${encodeURIComponent(query)}&limit=100`);
+  const items = (result.Results || []).map((match) => `
+    <tr>
+      <td>${entityLink(match.Entity.Metadata.ID, match.Entity.Title || match.Entity.Metadata.ID)}
+        <div class="snippet">${escapeHTML(match.Snippet)}</div></td>
+      <td>${escapeHTML(match.Entity.Metadata.Type)}</td>
+    </tr>`).join('');
+
+  view.innerHTML = `
+    <h1>Search</h1>
+    <p class="dim">${result.Total} results for “${escapeHTML(query)}”</p>
+    <table><tbody>${items}</tbody></table>`;
+}
+
+async function entityView(id) {
+  const related = await api('GET', `/api/entities/related?id=${encodeURIComponent(id)}`);
+  const entity = related.Entity;
+  const meta = entity.Metadata;
+  const broken = new Set(related.BrokenLinks || []);
+
+  const tags = (meta.Tags || []).map((tag) => `<span class="tag">${escapeHTML(tag)}</span>`).join('');
+  const aliases = (meta.Aliases || []).length ? `Also known as ${escapeHTML(meta.Aliases.join(', '))}` : '';
+
+  const relationships = (entity.Relationships || []).map((rel) => {
+    const link = broken.has(rel.Target)
+      ? `<a class="missing" href="#/entity/${entityPath(rel.Target)}">${escapeHTML(rel.Target)}</a>`
+      : entityLink(rel.Target);
+    return `<li><span class="dim">${escapeHTML(rel.Type)}</span> ${link}${rel.Note ? ` <span class="dim small">${escapeHTML(rel.Note)}</span>` : ''}</li>`;
+  }).join('');
+  const backRefs = (entity.BackRefs || []).map((ref) =>
+    `<li>${entityLink(ref.Source)} <span class="dim">${escapeHTML(ref.Type)}</span></li>`).join('');
+  const sources = (meta.Sources || []).map((source) => `<li>${entityLink(source)}</li>`).join('');
+
+  view.innerHTML = `
+    <div class="toolbar" style="justify-content: space-between">
+      <div>
+        <h1>${escapeHTML(entity.Title || meta.ID)}</h1>
+        <div class="dim">${escapeHTML(meta.Type)} · ${escapeHTML(meta.ID)} ${tags}</div>
+        <div class="dim small">${aliases}</div>
+      </div>
+      <a href="#/edit/${entityPath(meta.ID)}"><button type="button">Edit</button></a>
+    </div>
+    <div class="entity-layout">
+      <div>
+        <div class="content">${renderMarkdown(entity.Content || '') || '<p class="dim">No content yet.</p>'}</div>
+        <h2>Neighborhood</h2>
+        <svg id="graph"></svg>
+      </div>
+      <aside>
+        <div class="panel"><h3>Relationships</h3>${relationships ? `<ul>${relationships}</ul>` : '<span class="dim small">None</span>'}</div>
+        <div class="panel"><h3>Back-references</h3>${backRefs ? `<ul>${backRefs}</ul>` : '<span class="dim small">None</span>'}</div>
+        ${sources ? `<div class="panel"><h3>Sources</h3><ul>${sources}</ul></div>` : ''}
+        <div class="panel small dim">Created ${formatDate(meta.Created)}<br>Updated ${formatDate(meta.Updated)}</div>
+      </aside>
+    </div>`;
+
+  drawNeighborhood(document.getElementById('graph'), entity, related);
+}
+
+async function editView(id) {
+  const entity = await api('GET', `/api/entities/${entityPath(id)}`);
+  view.innerHTML = `
+    <h1>Edit ${escapeHTML(id)}</h1>
+    <div class="form-row"><input id="edit-title" value="${escapeHTML(entity.Title)}" placeholder="Title"></div>
+    <textarea id="edit-content" class="editor">${escapeHTML(entity.Content)}</textarea>
+    <div class="toolbar">
+      <button id="save" class="primary" type="button">Save</button>
+      <a href="#/entity/${entityPath(id)}">Cancel</a>
+      <span class="dim small">Use [[type/name]] to link to another entity.</span>
+      <button id="delete" type="button" style="margin-left: auto">Delete</button>
+    </div>`;
+
+  document.getElementById('save').addEventListener('click', async (event) => {
+    event.target.disabled = true;
+    try {
+      await api('PUT', `/api/entities/${entityPath(id)}`, {
+        title: document.getElementById('edit-title').value.trim(),
+        content: document.getElementById('edit-content').value,
+      });
+      showNotice('Saved ' + id);
+      location.hash = `#/entity/${entityPath(id)}`;
+    } catch (err) {
+      showNotice(err.message, true);
+      event.target.disabled = false;
+    }
+  });
+
+  document.getElementById('delete').addEventListener('click', async () => {
+    if (!confirm(`Delete ${id}? References to it will become broken links.`)) {
+      return;
+    }
+    try {
+      await api('DELETE', `/api/entities/${entityPath(id)}`);
+      showNotice('Deleted ' + id);
+      location.hash = '#/';
+    } catch (err) {
+      showNotice(err.message, true);
+    }
+  });
+}
+
+async function newView() {
+  const types = await api('GET', '/api/types');
+  const options = types.entity_types.map((t) => `<option value="${t}">${t}</option>`).join('');
+  view.innerHTML = `
+    <h1>New entity</h1>
+    <div class="form-row">
+      <select id="new-type">${options}</select>
+      <input id="new-id" placeholder="ID, e.g. people/ada-lovelace">
+      <input id="new-title" placeholder="Title">
+    </div>
+    <textarea id="new-content" class="editor" placeholder="Markdown content"></textarea>
+    <div class="toolbar"><button id="create" class="primary" type="button">Create</button></div>`;
+
+  document.getElementById('create').addEventListener('click', async () => {
+    const id = document.getElementById('new-id').value.trim();
+    try {
+      await api('POST', '/api/entities', {
+        type: document.getElementById('new-type').value,
+        id,
+        title: document.getElementById('new-title').value.trim(),
+        content: document.getElementById('new-content').value,
+      });
+      showNotice('Created ' + id);
+      location.hash = `#/entity/${entityPath(id)}`;
+    } catch (err) {
+      showNotice(err.message, true);
+    }
+  });
+}
+
+// queueView mirrors the CLI's queue explorer: approving a source claims it
+// and starts an ingest job, which marks it done or failed; skipping and
+// deferring set it aside
+async function queueView() {
+  const queue = await api('GET', '/api/queue?limit=500&status=pending,in_progress,deferred,failed');
+  const priorities = ['low', 'medium', 'high'];
+  const statusLabel = (item) => {
+    switch (item.Status) {
+      case 'in_progress': return 'in progress';
+      case 'deferred': return item.DeferredUntil && !item.DeferredUntil.startsWith('0001')
+        ? `deferred until ${formatDate(item.DeferredUntil)}` : 'deferred';
+      case 'failed': return `failed: ${item.Error}`;
+      default: return item.Status;
+    }
+  };
+  const rows = (queue.Items || []).map((item, i) => `
+    <tr data-index="${i}">
+      <td><a href="${escapeHTML(item.URL)}" target="_blank" rel="noopener">${escapeHTML(item.URL)}</a>
+        <div class="dim small">${escapeHTML(item.Description)}${item.FromSource ? ` · from ${entityLink(item.FromSource)}` : ''}</div></td>
+      <td class="dim small">${escapeHTML(statusLabel(item))}</td>
+      <td><select class="priority">${priorities.map((label, p) =>
+        `<option value="${p}" ${p === item.Priority ? 'selected' : ''}>${label}</option>`).join('')}</select></td>
+      <td class="dim small">${formatDate(item.AddedAt)}</td>
+      <td style="white-space: nowrap">
+        <button class="approve primary" type="button" ${item.Status === 'in_progress' ? 'disabled' : ''}>Approve</button>
+        <button class="defer" type="button">Later</button>
+        <button class="skip" type="button">Skip</button>
+      </td>
+    </tr>`).join('');
+
+  const counts = queue.ByStatus || {};
+  view.innerHTML = `
+    <h1>Queue</h1>
+    <p class="dim">${counts.pending || 0} sources waiting, ${counts.done || 0} done, ${counts.skipped || 0} skipped. Approving one ingests it in the background; Later puts it aside for a day.</p>
+    <table>
+      <thead><tr><th>Source</th><th>Status</th><th>Priority</th><th>Added</th><th></th></tr></thead>
+      <tbody>${rows}</tbody>
+    </table>`;
+
+  view.querySelectorAll('tr[data-index]').forEach((row) => {
+    const item = queue.Items[Number(row.dataset.index)];
+    const setStatus = (status, extra = {}) => api('POST', '/api/queue/status', { url: item.URL, status, ...extra });
+
+    row.querySelector('.approve').addEventListener('click', async () => {
+      try {
+        await setStatus('in_progress');
+      } catch (err) {
+        showNotice(err.message, true);
+        return;
+      }
+      try {
+        const job = await api('POST', '/api/jobs', { kind: 'ingest', url: item.URL });
+        row.remove();
+        showNotice(`Ingesting ${item.URL} as job ${job.id}`);
+      } catch (err) {
+        setStatus('pending').catch(() => {});
+        showNotice(err.message, true);
+      }
+    });
+    row.querySelector('.defer').addEventListener('click', async () => {
+      try {
+        await setStatus('deferred', { until: new Date(Date.now() + 24 * 3600 * 1000).toISOString() });
+        row.remove();
+        showNotice('Deferred ' + item.URL + ' for a day');
+      } catch (err) {
+        showNotice(err.message, true);
+      }
+    });
+    row.querySelector('.skip').addEventListener('click', async () => {
+      try {
+        await setStatus('skipped');
+        row.remove();
+        showNotice('Skipped ' + item.URL);
+      } catch (err) {
+        showNotice(err.message, true);
+      }
+    });
+    row.querySelector('.priority').addEventListener('change', async (event) => {
+      try {
+        await api('POST', '/api/queue/priority', { url: item.URL, priority: Number(event.target.value) });
+      } catch (err) {
+        showNotice(err.message, true);
+      }
+    });
+  });
+}
+
+// ---- Neighborhood graph ----
+
+const typeColors = {
+  person: '#2f6f8f',
+  organization: '#8f5f2f',
+  concept: '#5f8f2f',
+  work: '#7f3f8f',
+  event: '#b3261e',
+  source: '#777',
+};
+
+// drawNeighborhood lays out an entity and its direct neighbors with a small
+// force simulation; nodes can be dragged, and clicking one opens it
+function drawNeighborhood(svg, entity, related) {
+  const width = svg.clientWidth || 800;
+  const height = svg.clientHeight || 420;
+  const svgNS = 'http://www.w3.org/2000/svg';
+
+  const nodes = new Map();
+  const addNode = (e) => {
+    if (!nodes.has(e.Metadata.ID)) {
+      nodes.set(e.Metadata.ID, {
+        id: e.Metadata.ID,
+        label: e.Title || e.Metadata.ID,
+        type: e.Metadata.Type,
+        x: width / 2 + (Math.random() - 0.5) * width / 2,
+        y: height / 2 + (Math.random() - 0.5) * height / 2,
+        vx: 0,
+        vy: 0,
+      });
+    }
+  };
+  addNode(entity);
+  (related.All || []).forEach(addNode);
+
+  // Links between any two nodes shown, not just to the center
+  const links = [];
+  const seen = new Set();
+  [entity, ...(related.All || [])].forEach((e) => {
+    (e.Relationships || []).forEach((rel) => {
+      const key = e.Metadata.ID + '→' + rel.Target;
+      if (nodes.has(rel.Target) && rel.Target !== e.Metadata.ID && !seen.has(key)) {
+        seen.add(key);
+        links.push({ source: nodes.get(e.Metadata.ID), target: nodes.get(rel.Target) });
+      }
+    });
+  });
+
+  const center = nodes.get(entity.Metadata.ID);
+  center.x = width / 2;
+  center.y = height / 2;
+
+  svg.innerHTML = '';
+  const linkEls = links.map(() => {
+    const line = document.createElementNS(svgNS, 'line');
+    line.setAttribute('class', 'link');
+    svg.appendChild(line);
+    return line;
+  });
+
+  let dragging = null;
+  let dragMoved = false;
+  const nodeEls = [...nodes.values()].map((node) => {
+    const g = document.createElementNS(svgNS, 'g');
+    g.setAttribute('class', node === center ? 'node center' : 'node');
+    const circle = document.createElementNS(svgNS, 'circle');
+    circle.setAttribute('r', node === center ? 10 : 7);
+    circle.setAttribute('fill', typeColors[node.type] || '#999');
+    const title = document.createElementNS(svgNS, 'title');
+    title.textContent = node.id;
+    circle.appendChild(title);
+    const text = document.createElementNS(svgNS, 'text');
+    text.textContent = node.label.length > 28 ? node.label.slice(0, 27) + '…' : node.label;
+    text.setAttribute('x', 12);
+    text.setAttribute('y', 4);
+    g.append(circle, text);
+    svg.appendChild(g);
+
+    circle.addEventListener('mousedown', (event) => {
+      dragging = node;
+      dragMoved = false;
+      event.preventDefault();
+    });
+    circle.addEventListener('click', () => {
+      if (!dragMoved && node !== center) {
+        location.hash = `#/entity/${entityPath(node.id)}`;
+      }
+    });
+    return { node, g };
+  });
+
+  const point = (event) => {
+    const rect = svg.getBoundingClientRect();
+    return { x: event.clientX - rect.left, y: event.clientY - rect.top };
+  };
+  svg.onmousemove = (event) => {
+    if (dragging) {
+      dragMoved = true;
+      const p = point(event);
+      dragging.x = p.x;
+      dragging.y = p.y;
+      dragging.vx = dragging.vy = 0;
+      alpha = Math.max(alpha, 0.3);
+      kick();
+    }
+  };
+  svg.onmouseup = svg.onmouseleave = () => { dragging = null; };
+
+  const all = [...nodes.values()];
+  let alpha = 1;
+  let running = false;
+
+  const step = () => {
+    // Repulsion between every pair
+    for (let i = 0; i < all.length; i++) {
+      for (let j = i + 1; j < all.length; j++) {
+        const a = all[i];
+        const b = all[j];
+        let dx = b.x - a.x;
+        let dy = b.y - a.y;
+        const dist2 = Math.max(dx * dx + dy * dy, 25);
+        const force = (2400 / dist2) * alpha;
+        const dist = Math.sqrt(dist2);
+        dx /= dist;
+        dy /= dist;
+        a.vx -= dx * force;
+        a.vy -= dy * force;
+        b.vx += dx * force;
+        b.vy += dy * force;
+      }
+    }
+    // Springs along links
+    links.forEach(({ source, target }) => {
+      const dx = target.x - source.x;
+      const dy = target.y - source.y;
+      const dist = Math.sqrt(dx * dx + dy * dy) || 1;
+      const force = (dist - 110) * 0.02 * alpha;
+      source.vx += (dx / dist) * force;
+      source.vy += (dy / dist) * force;
+      target.vx -= (dx / dist) * force;
+      target.vy -= (dy / dist) * force;
+    });
+    // Pull towards the middle, then move
+    all.forEach((node) => {
+      node.vx += (width / 2 - node.x) * 0.005 * alpha;
+      node.vy += (height / 2 - node.y) * 0.005 * alpha;
+      if (node !== dragging) {
+        node.vx *= 0.6;
+        node.vy *= 0.6;
+        node.x = Math.min(width - 20, Math.max(20, node.x + node.vx));
+        node.y = Math.min(height - 20, Math.max(20, node.y + node.vy));
+      }
+    });
+  };
+
+  const render = () => {
+    links.forEach(({ source, target }, i) => {
+      linkEls[i].setAttribute('x1', source.x);
+      linkEls[i].setAttribute('y1', source.y);
+      linkEls[i].setAttribute('x2', target.x);
+      linkEls[i].setAttribute('y2', target.y);
+    });
+    nodeEls.forEach(({ node, g }) => g.setAttribute('transform', `translate(${node.x},${node.y})`));
+  };
+
+  const tick = () => {
+    if (!svg.isConnected) {
+      running = false;
+      return;
+    }
+    step();
+    render();
+    alpha *= 0.985;
+    if (alpha > 0.01) {
+      requestAnimationFrame(tick);
+    } else {
+      running = false;
+    }
+  };
+  const kick = () => {
+    if (!running) {
+      running = true;
+      requestAnimationFrame(tick);
+    }
+  };
+  kick();
+}
+
+// ---- Routing ----
+
+async function route() {
+  const hash = location.hash.replace(/^#/, '') || '/';
+  const [path, query] = hash.split('?');
+  const params = new URLSearchParams(query || '');
+  notice.hidden = true;
+
+  try {
+    if (path.startsWith('/entity/')) {
+      await entityView(decodeURIComponent(path.slice('/entity/'.length)));
+    } else if (path.startsWith('/edit/')) {
+      await editView(decodeURIComponent(path.slice('/edit/'.length)));
+    } else if (path === '/search') {
+      await searchView(params);
+    } else if (path === '/queue') {
+      await queueView();
+    } else if (path === '/new') {
+      await newView();
+    } else {
+      await listView(params);
+    }
+    window.scrollTo(0, 0);
+  } catch (err) {
+    view.innerHTML = `<h1>Something went wrong</h1><p class="dim">${escapeHTML(err.message)}</p>`;
+  }
+}
+
+document.getElementById('search-form').addEventListener('submit', (event) => {
+  event.preventDefault();
+  const query = document.getElementById('search-input').value.trim();
+  if (query) {
+    location.hash = `#/search?q=${encodeURIComponent(query)}`;
+  }
+});
+
+document.getElementById('token-btn').addEventListener('click', () => {
+  const value = prompt('API token (leave empty if the server has none)', token());
+  if (value !== null) {
+    localStorage.setItem('silvia-token', value.trim());
+    route();
+  }
+});
+
+window.addEventListener('hashchange', route);
+route();
@@ -0,0 +1,134 @@
+package sources
+
+import (
+	"strings"
+)
+
+const (
+	// DefaultChunkSize is the target size of a chunk of source content in characters
+	DefaultChunkSize = 8000
+	// DefaultChunkOverlap is how much trailing content is repeated at the start
+	// of the next chunk so entities spanning a boundary are seen whole
+	DefaultChunkOverlap = 800
+)
+
+// Chunk is a window of source content sent to the LLM in a single request
+type Chunk struct {
+	Index   int    // 1-based position of the chunk
+	Heading string // Nearest heading above the chunk's first paragraph
+	Text    string
+}
+
+// SplitIntoChunks splits markdown content into overlapping windows, breaking
+// on headings and paragraphs. Content that fits in one window is returned as a
+// single chunk. Paragraphs longer than the window are split on sentence or word
+// boundaries.
+func SplitIntoChunks(content string, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = size / 10
+	}
+
+	content = strings.TrimSpace(content)
+	if len(content) <= size {
+		return []Chunk{{Index: 1, Text: content}}
+	}
+
+	blocks := splitBlocks(content, size)
+
+	var chunks []Chunk
+	var current []block
+	currentLen := 0
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		texts := make([]string, len(current))
+		for i, b := range current {
+			texts[i] = b.text
+		}
+		chunks = append(chunks, Chunk{
+			Index:   len(chunks) + 1,
+			Heading: current[0].heading,
+			Text:    strings.Join(texts, "\n\n"),
+		})
+	}
+
+	for _, b := range blocks {
+		if currentLen > 0 && currentLen+len(b.text)+2 > size {
+			flush()
+
+			// Carry trailing blocks into the next chunk as overlap
+			var carried []block
+			carriedLen := 0
+			for i := len(current) - 1; i >= 0; i-- {
+				if carriedLen+len(current[i].text) > overlap {
+					break
+				}
+				carried = append([]block{current[i]}, carried...)
+				carriedLen += len(current[i].text) + 2
+			}
+			current = carried
+			currentLen = carriedLen
+		}
+
+		current = append(current, b)
+		currentLen += len(b.text) + 2
+	}
+	flush()
+
+	return chunks
+}
+
+// block is a paragraph (or heading) together with the heading it falls under
+type block struct {
+	heading string
+	text    string
+}
+
+// splitBlocks breaks content into paragraphs, tracking the current heading
+func splitBlocks(content string, size int) []block {
+	var blocks []block
+	heading := ""
+
+	for _, para := range strings.Split(content, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+
+		if strings.HasPrefix(para, "#") {
+			firstLine, _, _ := strings.Cut(para, "\n")
+			heading = strings.TrimSpace(strings.TrimLeft(firstLine, "#"))
+		}
+
+		for _, piece := range splitLongText(para, size) {
+			blocks = append(blocks, block{heading: heading, text: piece})
+		}
+	}
+
+	return blocks
+}
+
+// splitLongText breaks text longer than size on sentence ends, falling back to spaces
+func splitLongText(text string, size int) []string {
+	var pieces []string
+	for len(text) > size {
+		cut := strings.LastIndex(text[:size], ". ")
+		if cut < size/2 {
+			cut = strings.LastIndex(text[:size], " ")
+		}
+		if cut <= 0 {
+			cut = size - 1
+		}
+		pieces = append(pieces, strings.TrimSpace(text[:cut+1]))
+		text = strings.TrimSpace(text[cut+1:])
+	}
+	if text != "" {
+		pieces = append(pieces, text)
+	}
+	return pieces
+}
@@ -3,11 +3,15 @@ package sources
 import (
 	"regexp"
 	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 // ConvertHTMLToMarkdown is exported for use by extension ingestion
 func (w *WebFetcher) ConvertHTMLToMarkdown(html string) string {
-	return htmlToMarkdown(html)
+	markdown, _ := extractMainContent(html, "")
+	return markdown
 }
 
 // ExtractTitleFromHTML is exported for use by extension ingestion
@@ -52,3 +56,75 @@ func ExtractHTMLLinksWithContext(html string) map[string]string {
 
 	return linkContext
 }
+
+// PageLink is a link on a page and the text it is shown with
+type PageLink struct {
+	URL  string
+	Text string
+}
+
+// invisibleTags hold no text a reader sees
+var invisibleTags = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Iframe: true,
+}
+
+// blockTags start a new line in a page's text
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Article: true, atom.Section: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Header: true, atom.Footer: true, atom.Figcaption: true, atom.Td: true, atom.Th: true,
+}
+
+// PageText returns the visible text of a whole page, one line per block,
+// and its links. Unlike the markdown conversion it keeps navigation and
+// other boilerplate, since names mentioned anywhere on the page count.
+func PageText(rawHTML string) (string, []PageLink) {
+	doc, err := html.Parse(strings.NewReader(rawHTML))
+	if err != nil {
+		return stripHTMLTags(rawHTML), nil
+	}
+
+	var sb strings.Builder
+	var links []PageLink
+	newline := func() {
+		text := sb.String()
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && invisibleTags[n.DataAtom] {
+			return
+		}
+		if n.Type == html.TextNode {
+			text := collapseWhitespace(n.Data)
+			if strings.HasSuffix(sb.String(), "\n") || sb.Len() == 0 {
+				text = strings.TrimLeft(text, " ")
+			}
+			sb.WriteString(text)
+			return
+		}
+		block := n.Type == html.ElementNode && blockTags[n.DataAtom]
+		if block {
+			newline()
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			newline()
+		}
+		if n.DataAtom == atom.A {
+			if href := getAttr(n, "href"); href != "" {
+				if text := strings.Join(strings.Fields(nodeText(n)), " "); text != "" {
+					links = append(links, PageLink{URL: href, Text: text})
+				}
+			}
+		}
+	}
+	walk(doc)
+	return strings.TrimSpace(sb.String()), links
+}
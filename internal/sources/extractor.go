@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
-	"time"
 
 	"silvia/internal/graph"
 	"silvia/internal/llm"
@@ -22,6 +21,7 @@ type ExtractedEntity struct {
 	Content     string // Rich markdown content with sections
 	Aliases     []string
 	WikiLinks   []string // Related entities in [[type/id]] format
+	Chunks      []int    // Chunks of the source the entity was found in
 }
 
 // ExtractedRelationship represents a relationship found in text
@@ -48,6 +48,9 @@ type ExtractionResult struct {
 	LinkedSources []string        // Simple list for backward compatibility
 	Links         []ExtractedLink // Enhanced link information
 	SourceSummary *SourceSummary  // Structured summary of the source
+	ChunkCount    int             // Number of chunks the source was split into
+	FailedChunks  int             // Chunks whose extraction failed, leaving the result partial
+	PromptVersion string          // Version of the extraction prompt used
 }
 
 // SourceSummary represents a structured summary of a source
@@ -62,12 +65,28 @@ type SourceSummary struct {
 	People        []string // Person IDs referenced
 	Organizations []string // Organization IDs referenced
 	Analysis      string   // Analysis and significance
+	PromptVersion string   // Version of the summary prompt used
 }
 
+// ExtractionProgress reports progress through a chunked extraction
+type ExtractionProgress struct {
+	Stage    string // "extract" or "summarize"
+	Chunk    int
+	Total    int
+	Heading  string
+	Entities int  // Entities found in the chunk, set when Done
+	Done     bool // Whether the chunk has finished
+	Err      error
+}
+
+// ProgressFunc receives extraction progress updates
+type ProgressFunc func(ExtractionProgress)
+
 // Extractor uses LLM to extract entities from content
 type Extractor struct {
-	llm   *llm.Client
-	debug bool
+	llm      *llm.Client
+	debug    bool
+	progress ProgressFunc
 }
 
 // NewExtractor creates a new entity extractor
@@ -85,45 +104,52 @@ func (e *Extractor) SetDebug(debug bool) {
 	e.debug = debug
 }
 
+// SetProgressFunc sets a callback for per-chunk progress on long sources
+func (e *Extractor) SetProgressFunc(fn ProgressFunc) {
+	e.progress = fn
+}
+
+func (e *Extractor) reportProgress(p ExtractionProgress) {
+	if e.progress != nil {
+		e.progress(p)
+	}
+}
+
 // GenerateSourceSummary creates a structured summary of a source
 func (e *Extractor) GenerateSourceSummary(ctx context.Context, source *Source, extraction *ExtractionResult) (*SourceSummary, error) {
-	systemPrompt := `You are creating a structured summary of a source document for a knowledge graph system.
-
-Generate a comprehensive summary that includes:
-1. Key themes and topics discussed
-2. Important quotes that capture essential points
-3. Analysis of the document's significance
-4. Connections to entities mentioned
-
-Output JSON with this structure:
-{
-  "title": "article title",
-  "author": "author name if known",
-  "publication": "publication name",
-  "date": "publication date if known",
-  "key_themes": ["theme 1", "theme 2"],
-  "key_quotes": ["important quote 1", "important quote 2"],
-  "analysis": "Multi-paragraph analysis of significance, context, and implications"
-}`
+	systemPrompt, err := prompts.Render(prompts.Summary, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build entity context
 	entityContext := ""
 	if extraction != nil && len(extraction.Entities) > 0 {
 		entityContext = "\n\nKey entities found:\n"
-		for _, entity := range extraction.Entities {
+		for i, entity := range extraction.Entities {
+			if i >= 50 {
+				entityContext += fmt.Sprintf("... and %d more\n", len(extraction.Entities)-50)
+				break
+			}
 			entityContext += fmt.Sprintf("- %s (%s): %s\n", entity.Name, entity.Type, entity.Description)
 		}
 	}
 
-	userPrompt := fmt.Sprintf("Create a structured summary of this source:\n\nTitle: %s\nURL: %s\n\nContent:\n%s%s",
-		source.Title, source.URL, source.Content, entityContext)
-
-	// Limit content length
-	if len(userPrompt) > 10000 {
-		userPrompt = userPrompt[:10000] + "\n[content truncated]"
+	// Long sources are condensed chunk by chunk rather than truncated
+	content := source.Content
+	chunks := SplitIntoChunks(source.Content, DefaultChunkSize, DefaultChunkOverlap)
+	if len(chunks) > 1 {
+		notes, err := e.condenseChunks(ctx, source, chunks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to condense source: %w", err)
+		}
+		content = notes
 	}
 
-	response, err := e.llm.CompleteWithSystem(ctx, systemPrompt, userPrompt, "")
+	userPrompt := fmt.Sprintf("Create a structured summary of this source:\n\nTitle: %s\nURL: %s\n\nContent:\n%s%s",
+		source.Title, source.URL, content, entityContext)
+
+	response, err := e.llm.ForTask(llm.TaskSummary).CompleteWithSystem(ctx, systemPrompt, userPrompt, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate summary: %w", err)
 	}
@@ -189,13 +215,14 @@ Output JSON with this structure:
 
 	// Build summary with entity references
 	summary := &SourceSummary{
-		Title:       result.Title,
-		Author:      result.Author,
-		Publication: result.Publication,
-		Date:        result.Date,
-		KeyThemes:   result.KeyThemes,
-		KeyQuotes:   result.KeyQuotes,
-		Analysis:    result.Analysis,
+		Title:         result.Title,
+		Author:        result.Author,
+		Publication:   result.Publication,
+		Date:          result.Date,
+		KeyThemes:     result.KeyThemes,
+		KeyQuotes:     result.KeyQuotes,
+		Analysis:      result.Analysis,
+		PromptVersion: prompts.Version(prompts.Summary),
 	}
 
 	// Add entity references if available
@@ -216,6 +243,41 @@ Output JSON with this structure:
 	return summary, nil
 }
 
+// condenseChunks writes compact notes for each chunk of a long source so the
+// summary prompt covers the whole document
+func (e *Extractor) condenseChunks(ctx context.Context, source *Source, chunks []Chunk) (string, error) {
+	systemPrompt, err := prompts.Render(prompts.Condense, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var notes strings.Builder
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		e.reportProgress(ExtractionProgress{Stage: "summarize", Chunk: chunk.Index, Total: len(chunks), Heading: chunk.Heading})
+
+		userPrompt := fmt.Sprintf("Source: %s (%s)\nPart %d of %d\n\n%s", source.Title, source.URL, chunk.Index, len(chunks), chunk.Text)
+		response, err := e.llm.ForTask(llm.TaskSummary).CompleteWithSystem(ctx, systemPrompt, userPrompt, "")
+		e.reportProgress(ExtractionProgress{Stage: "summarize", Chunk: chunk.Index, Total: len(chunks), Heading: chunk.Heading, Done: true, Err: err})
+		if err != nil {
+			return "", fmt.Errorf("chunk %d: %w", chunk.Index, err)
+		}
+
+		notes.WriteString(fmt.Sprintf("[Part %d of %d", chunk.Index, len(chunks)))
+		if chunk.Heading != "" {
+			notes.WriteString(": " + chunk.Heading)
+		}
+		notes.WriteString("]\n")
+		notes.WriteString(strings.TrimSpace(response))
+		notes.WriteString("\n\n")
+	}
+
+	return notes.String(), nil
+}
+
 // generateEntityID creates a consistent ID for an entity
 func (e *Extractor) generateEntityID(name string, entityType graph.EntityType) string {
 	// Convert name to ID format
@@ -267,149 +329,104 @@ func (e *Extractor) Extract(ctx context.Context, source *Source) (*ExtractionRes
 		fmt.Printf("[DEBUG] Extract: Source has %d raw links, %d after cleaning\n", len(source.Links), len(cleanedLinks))
 	}
 
-	systemPrompt := `You are an intelligent content analyzer for a knowledge graph system. Analyze the provided article and extract:
-1. Important entities (people, organizations, concepts, works, events)
-2. Relationships between entities
-3. Relevant links from the provided list that would be valuable to explore further
-
-IMPORTANT: You will be provided with a list of links found in the article. Review these links and include the most relevant ones in your response.
-
-` + prompts.GetCitationGuidelines() + `
-
-For EACH entity, provide:
-- A brief one-line description
-- Rich markdown content with proper source attribution that includes:
-  - Overview paragraph with context and significance (citing sources)
-  - Key Activities, Key Themes, or relevant section headers
-  - Important quotes with their sources identified
-  - Relationships to other entities using [[type/name]] wiki-link format
-  
-For events specifically, structure the content with:
-- Opening paragraph describing the event with concise source attribution
-- **Date**: When it occurred with citation if specific
-- **Location**: Where it took place
-- **Participants**: List of people/organizations involved with [[type/name]] links
-- **Significance**: Why this event matters with source
-- **Context**: Background and related events
-- **Outcomes**: What resulted from this event with attribution
-
-For organizations, include:
-- **Leadership**: Key people with [[people/name]] links and source attribution
-- **Mission/Purpose**: What the organization does with citation
-- **Key Activities**: Major initiatives or functions with source reference
-- **Connections**: Related organizations and movements
-
-For people, include:
-- **Role/Position**: Their title or significance with citation
-- **Key Activities**: What they've done relevant to the article with attribution
-- **Affiliations**: Organizations they're connected to with [[organizations/name]] links
-- **Notable Statements**: Important quotes with source identified
-
-IMPORTANT: Use direct, concise citations. Instead of "According to an article by X in Y", write "X (Y, date) states..." or similar. Keep citations brief but complete.
-
-For links provided in the source, evaluate each one and:
-- Include links that could provide valuable context or additional information
-- Look for links to sources, references, related articles, or supporting documentation
-- Categorize each link: reference (cited source), discussion (related topic), resource (tool/data), navigation (site nav), advertisement (promotional)
-- Rate relevance: high (directly related to main topic), medium (provides context), low (tangentially related)
-- You can include up to 20 of the most relevant links
-- Prioritize reference and discussion links over navigation/ads
-
-You MUST output valid JSON and nothing else. Output JSON with this structure:
-{
-  "entities": [
-    {
-      "name": "Entity Name",
-      "type": "person|organization|concept|work|event",
-      "description": "One-line description",
-      "content": "Rich markdown content with sections, context, and wiki-links to related entities",
-      "aliases": ["alternative names mentioned"],
-      "wiki_links": ["people/related-person", "organizations/related-org"]
-    }
-  ],
-  "relationships": [
-    {
-      "source": "Source Entity",
-      "target": "Target Entity",
-      "type": "relationship type",
-      "note": "context from article"
-    }
-  ],
-  "links": [
-    {
-      "url": "full URL",
-      "title": "link text or inferred title",
-      "description": "what this link is about based on article context",
-      "relevance": "high|medium|low",
-      "category": "reference|discussion|resource|navigation|advertisement"
-    }
-  ]
-}
-
-Create rich, interconnected entities that capture the full context and significance from the article, with proper source attribution for all claims.`
-
-	// Include the links in the prompt so the LLM can analyze them
-	linksSection := ""
-	if len(cleanedLinks) > 0 {
-		linksSection = "\n\n=== LINKS TO ANALYZE ===\nPlease review these links and include the most relevant ones in your 'links' array:\n"
-		for i, link := range cleanedLinks {
-			if i >= 30 { // Limit to first 30 to save tokens but get better coverage
-				linksSection += fmt.Sprintf("\n... and %d more links available\n", len(cleanedLinks)-30)
-				break
-			}
-			linksSection += fmt.Sprintf("%d. %s\n", i+1, link)
-		}
-		linksSection += "\n=== END OF LINKS ===\n"
-		if e.debug {
-			fmt.Printf("[DEBUG] Including %d links in LLM prompt (showing first 30)\n", len(cleanedLinks))
-		}
-	} else if e.debug {
-		fmt.Printf("[DEBUG] No links to include in LLM prompt\n")
+	systemPrompt, err := prompts.Render(prompts.Extraction, prompts.ExtractionData{
+		EntityTypes:       graph.EntityTypeNames(),
+		RelationshipTypes: graph.RelationshipTypeNames(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Build source metadata string
-	sourceInfo := fmt.Sprintf("Title: %s\nSource URL: %s", source.Title, source.URL)
-
-	// Generate the source entity ID that will be created
-	sourceEntityID := e.generateSourceEntityID(source.URL)
-	sourceInfo += fmt.Sprintf("\nSource Entity ID: [[%s]]", sourceEntityID)
-
-	if author, ok := source.Metadata["author"]; ok && author != "" {
-		sourceInfo += fmt.Sprintf("\nAuthor: %s", author)
+	// The first 30 links go in the prompt so the LLM can analyze them
+	request := prompts.ExtractionRequestData{
+		Title:          source.Title,
+		URL:            source.URL,
+		SourceEntityID: SourceEntityID(source.URL),
+		Author:         source.Metadata["author"],
+		Date:           source.Metadata["date"],
+		Publication:    source.Metadata["publication"],
 	}
-	if date, ok := source.Metadata["date"]; ok && date != "" {
-		sourceInfo += fmt.Sprintf("\nPublication Date: %s", date)
+	links := cleanedLinks
+	if len(links) > 30 {
+		links = links[:30]
 	}
-	if publication, ok := source.Metadata["publication"]; ok && publication != "" {
-		sourceInfo += fmt.Sprintf("\nPublication: %s", publication)
+	if e.debug {
+		fmt.Printf("[DEBUG] Including %d of %d links in LLM prompt\n", len(links), len(cleanedLinks))
 	}
 
-	userPrompt := fmt.Sprintf("Analyze this content:\n\n%s\n\nIMPORTANT: When citing this source, use the wiki-link format [[%s]] rather than verbose inline citations.\n\nContent:\n%s%s",
-		sourceInfo, sourceEntityID, source.Content, linksSection)
-
-	// Limit content length for API
-	if len(userPrompt) > 10000 {
-		userPrompt = userPrompt[:10000] + "\n[content truncated]"
+	// Passages the user highlighted are what they care about most
+	for _, h := range source.Highlights {
+		request.Highlights = append(request.Highlights, prompts.Highlight{Kind: h.Kind, Text: h.Text, Note: h.Note})
 	}
 
-	// Use structured output for type-safe JSON responses
-	var llmResult LLMExtractionResult
-	if err := e.llm.CompleteWithStructuredOutput(ctx, systemPrompt, userPrompt, &llmResult, ""); err != nil {
-		return nil, fmt.Errorf("LLM extraction failed: %w", err)
+	// Long sources are split into overlapping chunks that are extracted
+	// separately and reconciled, instead of truncating the content
+	chunks := SplitIntoChunks(source.Content, DefaultChunkSize, DefaultChunkOverlap)
+	if e.debug && len(chunks) > 1 {
+		fmt.Printf("[DEBUG] Extract: Split %d characters into %d chunks\n", len(source.Content), len(chunks))
 	}
 
-	if e.debug {
-		fmt.Printf("[DEBUG] LLM returned: %d entities, %d relationships, %d links\n",
-			len(llmResult.Entities), len(llmResult.Relationships), len(llmResult.Links))
-		if len(llmResult.Links) > 0 {
-			fmt.Printf("[DEBUG] First link from LLM: %+v\n", llmResult.Links[0])
+	var parts []chunkExtraction
+	var lastErr error
+	failed := 0
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		e.reportProgress(ExtractionProgress{Stage: "extract", Chunk: chunk.Index, Total: len(chunks), Heading: chunk.Heading})
+
+		request.Part, request.Parts, request.Heading = chunk.Index, len(chunks), chunk.Heading
+		request.Content = chunk.Text
+
+		// The link list is only needed once
+		request.Links, request.MoreLinks = nil, 0
+		if chunk.Index == 1 {
+			request.Links, request.MoreLinks = links, len(cleanedLinks)-len(links)
+		}
+
+		userPrompt, err := prompts.Render(prompts.ExtractionRequest, request)
+		if err != nil {
+			return nil, err
 		}
+
+		// Use structured output for type-safe JSON responses
+		var llmResult LLMExtractionResult
+		if err := e.llm.ForTask(llm.TaskExtraction).CompleteWithStructuredOutput(ctx, systemPrompt, userPrompt, &llmResult, ""); err != nil {
+			lastErr = err
+			failed++
+			// Reported through progress and FailedChunks only; stdout may
+			// be the MCP transport
+			e.reportProgress(ExtractionProgress{Stage: "extract", Chunk: chunk.Index, Total: len(chunks), Heading: chunk.Heading, Done: true, Err: err})
+			continue
+		}
+
+		if e.debug {
+			fmt.Printf("[DEBUG] LLM returned for chunk %d/%d: %d entities, %d relationships, %d links\n",
+				chunk.Index, len(chunks), len(llmResult.Entities), len(llmResult.Relationships), len(llmResult.Links))
+			if len(llmResult.Links) > 0 {
+				fmt.Printf("[DEBUG] First link from LLM: %+v\n", llmResult.Links[0])
+			}
+		}
+
+		e.reportProgress(ExtractionProgress{Stage: "extract", Chunk: chunk.Index, Total: len(chunks), Heading: chunk.Heading, Done: true, Entities: len(llmResult.Entities)})
+		parts = append(parts, chunkExtraction{chunk: chunk.Index, result: &llmResult})
 	}
 
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("LLM extraction failed: %w", lastErr)
+	}
+
+	// Merge entities, relationships and links found across chunks
+	llmResult := reconcileChunks(parts)
+
 	// Convert to our format
 	result := &ExtractionResult{
 		LinkedSources: []string{},
 		Links:         []ExtractedLink{},
+		ChunkCount:    len(chunks),
+		FailedChunks:  failed,
+		PromptVersion: prompts.Version(prompts.Extraction),
 	}
 
 	// Process entities
@@ -427,6 +444,7 @@ Create rich, interconnected entities that capture the full context and significa
 			Content:     content,
 			Aliases:     e.Aliases,
 			WikiLinks:   e.WikiLinks,
+			Chunks:      e.chunks,
 		})
 	}
 
@@ -570,19 +588,6 @@ func (e *Extractor) makeAbsoluteURL(link, baseURL string) string {
 	return base.ResolveReference(rel).String()
 }
 
-// generateSourceEntityID creates a consistent source entity ID from a URL
-func (e *Extractor) generateSourceEntityID(sourceURL string) string {
-	u, err := url.Parse(sourceURL)
-	if err != nil {
-		// Fallback to simple domain extraction
-		return fmt.Sprintf("sources/source-%s", time.Now().Format("2006-01-02"))
-	}
-
-	// Create ID like "sources/domain-date"
-	domain := strings.ReplaceAll(u.Hostname(), ".", "-")
-	return fmt.Sprintf("sources/%s-%s", domain, time.Now().Format("2006-01-02"))
-}
-
 // parseEntityType converts string to EntityType
 func parseEntityType(typeStr string) graph.EntityType {
 	switch strings.ToLower(typeStr) {
@@ -0,0 +1,321 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Feed represents a parsed RSS or Atom feed
+type Feed struct {
+	Title   string
+	Link    string
+	Entries []FeedEntry
+}
+
+// FeedEntry represents a single item in a feed
+type FeedEntry struct {
+	Title     string
+	URL       string
+	Summary   string
+	Author    string
+	Published time.Time
+}
+
+// FeedFetcher retrieves and parses RSS and Atom feeds
+type FeedFetcher struct {
+	client *http.Client
+}
+
+// NewFeedFetcher creates a new feed fetcher
+func NewFeedFetcher() *FeedFetcher {
+	return &FeedFetcher{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// FetchFeed downloads and parses a feed
+func (f *FeedFetcher) FetchFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Silvia/1.0; +https://github.com/silvia)")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := doFetch(ctx, f.client, req, "failed to fetch feed")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &FetchError{
+			URL:        feedURL,
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("HTTP %d: %s", resp.StatusCode, resp.Status),
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	feed, err := ParseFeed(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve relative entry links against the feed URL
+	for i := range feed.Entries {
+		if feed.Entries[i].URL != "" {
+			feed.Entries[i].URL = resolveURL(feed.Entries[i].URL, feedURL)
+		}
+	}
+
+	return feed, nil
+}
+
+// XML structures for RSS 2.0 and RSS 1.0 (RDF)
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+	Items   []rssItem  `xml:"item"` // RSS 1.0 puts items beside the channel
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+// XML structures for Atom
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	ID        string     `xml:"id"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// ParseFeed parses RSS 2.0, RSS 1.0 or Atom feed content
+func ParseFeed(data []byte) (*Feed, error) {
+	root, err := feedRootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "feed":
+		return parseAtom(data)
+	case "rss", "RDF":
+		return parseRSS(data)
+	default:
+		return nil, fmt.Errorf("unrecognized feed format: <%s>", root)
+	}
+}
+
+// feedRootElement returns the local name of the document's root element
+func feedRootElement(data []byte) (string, error) {
+	decoder := newFeedDecoder(data)
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse feed: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func newFeedDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	// Feeds declared as ISO-8859-1 etc. are close enough to UTF-8 for titles and links
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+func parseRSS(data []byte) (*Feed, error) {
+	var doc rssDocument
+	if err := newFeedDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+	}
+
+	feed := &Feed{
+		Title: strings.TrimSpace(doc.Channel.Title),
+		Link:  strings.TrimSpace(doc.Channel.Link),
+	}
+
+	items := append(doc.Channel.Items, doc.Items...)
+	for _, item := range items {
+		link := strings.TrimSpace(item.Link)
+		if link == "" && strings.HasPrefix(item.GUID, "http") {
+			link = strings.TrimSpace(item.GUID)
+		}
+
+		summary := item.Description
+		if summary == "" {
+			summary = item.Content
+		}
+
+		author := item.Creator
+		if author == "" {
+			author = item.Author
+		}
+
+		published := item.PubDate
+		if published == "" {
+			published = item.Date
+		}
+
+		feed.Entries = append(feed.Entries, FeedEntry{
+			Title:     strings.TrimSpace(stripHTMLTags(item.Title)),
+			URL:       link,
+			Summary:   cleanFeedSummary(summary),
+			Author:    strings.TrimSpace(author),
+			Published: parseFeedDate(published),
+		})
+	}
+
+	return feed, nil
+}
+
+func parseAtom(data []byte) (*Feed, error) {
+	var doc atomFeed
+	if err := newFeedDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+	}
+
+	feed := &Feed{
+		Title: strings.TrimSpace(doc.Title),
+		Link:  atomAlternateLink(doc.Links),
+	}
+
+	for _, entry := range doc.Entries {
+		link := atomAlternateLink(entry.Links)
+		if link == "" && strings.HasPrefix(entry.ID, "http") {
+			link = strings.TrimSpace(entry.ID)
+		}
+
+		summary := entry.Summary
+		if summary == "" {
+			summary = entry.Content
+		}
+
+		var author string
+		if len(entry.Authors) > 0 {
+			author = strings.TrimSpace(entry.Authors[0].Name)
+		}
+
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+
+		feed.Entries = append(feed.Entries, FeedEntry{
+			Title:     strings.TrimSpace(stripHTMLTags(entry.Title)),
+			URL:       link,
+			Summary:   cleanFeedSummary(summary),
+			Author:    author,
+			Published: parseFeedDate(published),
+		})
+	}
+
+	return feed, nil
+}
+
+// atomAlternateLink picks the alternate (or unlabeled) link from a list of Atom links
+func atomAlternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// cleanFeedSummary strips markup from a feed summary and collapses whitespace
+func cleanFeedSummary(summary string) string {
+	text := stripHTMLTags(summary)
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > 500 {
+		text = string(runes[:497]) + "..."
+	}
+	return text
+}
+
+// parseFeedDate parses the date formats commonly found in feeds
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	layouts := []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC3339,
+		time.RFC822Z,
+		time.RFC822,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// resolveURL makes a possibly relative link absolute against a base URL
+func resolveURL(link, base string) string {
+	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		return link
+	}
+	return (&Extractor{}).makeAbsoluteURL(link, base)
+}
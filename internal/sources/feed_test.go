@@ -0,0 +1,31 @@
+package sources
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCleanFeedSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+		want    string
+	}{
+		{name: "markup and whitespace", summary: "<p>Council  approves\n<b>transit</b> plan</p>", want: "Council approves transit plan"},
+		{name: "short enough", summary: strings.Repeat("é", 500), want: strings.Repeat("é", 500)},
+		{name: "cut on a character", summary: strings.Repeat("é", 600), want: strings.Repeat("é", 497) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cleanFeedSummary(tt.summary)
+			if !utf8.ValidString(got) {
+				t.Fatalf("cleanFeedSummary() = %q is not valid UTF-8", got)
+			}
+			if got != tt.want {
+				t.Errorf("cleanFeedSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
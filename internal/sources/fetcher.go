@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -15,6 +17,8 @@ type Source struct {
 	RawContent string   // Original HTML/text
 	Links      []string // Extracted links
 	Metadata   map[string]string
+	Capture    *Capture    // Raw HTTP exchange, when available, for WARC archiving
+	Highlights []Highlight // Passages the user selected, for extension captures
 }
 
 // Fetcher interface for different source types
@@ -93,3 +97,60 @@ func ExtractLinks(content string) []string {
 
 	return links
 }
+
+// HasRawHTML reports whether the source carries an HTML document worth archiving
+func (s *Source) HasRawHTML() bool {
+	head := strings.ToLower(s.RawContent[:min(len(s.RawContent), 2048)])
+	return strings.Contains(head, "<html") || strings.Contains(head, "<!doctype html") || strings.Contains(head, "<body")
+}
+
+// SaveRawHTML writes the source's original HTML next to its markdown archive,
+// using the same base name with an .html extension. It returns the written path,
+// or an empty path when the source has no HTML.
+func SaveRawHTML(markdownPath string, source *Source) (string, error) {
+	if !source.HasRawHTML() {
+		return "", nil
+	}
+
+	htmlPath := strings.TrimSuffix(markdownPath, ".md") + ".html"
+	if err := os.WriteFile(htmlPath, []byte(source.RawContent), 0644); err != nil {
+		return "", fmt.Errorf("failed to write raw HTML: %w", err)
+	}
+
+	return htmlPath, nil
+}
+
+// ArchiveCapture writes the source's raw capture as a WARC file under
+// dataDir/sources/warc and records the file and content hash in the source
+// metadata. Sources without a capture but with raw HTML (extension or
+// clipboard captures) are archived as resource records.
+func ArchiveCapture(dataDir string, source *Source) (string, error) {
+	if source.Capture == nil {
+		if !source.HasRawHTML() {
+			return "", nil
+		}
+		source.Capture = NewResourceCapture(source.URL, source.RawContent, "text/html")
+	}
+
+	warcPath, err := WriteWARC(WARCDir(dataDir), source.Capture)
+	if err != nil {
+		return "", err
+	}
+
+	if source.Metadata == nil {
+		source.Metadata = make(map[string]string)
+	}
+	source.Metadata["content_hash"] = source.Capture.ContentHash()
+	if rel, err := filepath.Rel(dataDir, warcPath); err == nil {
+		source.Metadata["warc"] = filepath.ToSlash(rel)
+	} else {
+		source.Metadata["warc"] = warcPath
+	}
+
+	return warcPath, nil
+}
+
+// WARCDir returns the directory holding WARC captures for a data directory
+func WARCDir(dataDir string) string {
+	return filepath.Join(dataDir, "sources", "warc")
+}
@@ -0,0 +1,109 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Kinds of highlight. A quote is kept as the source's own words; a claim is
+// an assertion the user wants checked against other sources.
+const (
+	HighlightQuote = "quote"
+	HighlightClaim = "claim"
+)
+
+// Highlight is a passage the user selected in the browser, with an optional
+// note of their own
+type Highlight struct {
+	ID     string          `json:"id"`
+	Text   string          `json:"text"`
+	Note   string          `json:"note,omitempty"`
+	Kind   string          `json:"kind"` // quote or claim
+	Anchor HighlightAnchor `json:"anchor"`
+}
+
+// HighlightAnchor locates a highlight in the page's DOM so the extension can
+// show it again. Paths are XPaths to the text nodes the selection starts and
+// ends in, and offsets count characters within them. Prefix and suffix hold
+// the text around the selection to find it when the page has changed.
+type HighlightAnchor struct {
+	StartPath   string `json:"start_path,omitempty"`
+	StartOffset int    `json:"start_offset"`
+	EndPath     string `json:"end_path,omitempty"`
+	EndOffset   int    `json:"end_offset"`
+	Prefix      string `json:"prefix,omitempty"`
+	Suffix      string `json:"suffix,omitempty"`
+}
+
+// NormalizeHighlights checks the highlights captured from sourceURL and
+// fills in their kind and ID. The ID is derived from the URL, text and
+// anchor, so capturing the same passage again gives the same ID.
+func NormalizeHighlights(sourceURL string, highlights []Highlight) ([]Highlight, error) {
+	normalized := make([]Highlight, 0, len(highlights))
+	for i, h := range highlights {
+		h.Text = strings.TrimSpace(h.Text)
+		h.Note = strings.TrimSpace(h.Note)
+		if h.Text == "" {
+			return nil, fmt.Errorf("highlight %d has no text", i+1)
+		}
+		switch h.Kind {
+		case "":
+			h.Kind = HighlightQuote
+		case HighlightQuote, HighlightClaim:
+		default:
+			return nil, fmt.Errorf("highlight %d has unknown kind %q (want quote or claim)", i+1, h.Kind)
+		}
+		if h.ID == "" {
+			sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", sourceURL, h.Text, h.Anchor.StartPath, h.Anchor.StartOffset)))
+			h.ID = "h-" + hex.EncodeToString(sum[:])[:12]
+		}
+		normalized = append(normalized, h)
+	}
+	return normalized, nil
+}
+
+// HighlightsPath returns where the highlights of a markdown archive are
+// kept: next to it, with a .highlights.json extension
+func HighlightsPath(markdownPath string) string {
+	return strings.TrimSuffix(markdownPath, ".md") + ".highlights.json"
+}
+
+// SaveHighlights writes the source's highlights next to its markdown
+// archive. It returns the written path, or an empty path when the source
+// has no highlights.
+func SaveHighlights(markdownPath string, source *Source) (string, error) {
+	if len(source.Highlights) == 0 {
+		return "", nil
+	}
+
+	data, err := json.MarshalIndent(source.Highlights, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode highlights: %w", err)
+	}
+	path := HighlightsPath(markdownPath)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write highlights: %w", err)
+	}
+	return path, nil
+}
+
+// LoadHighlights reads the highlights saved with a markdown archive. An
+// archive without highlights has none.
+func LoadHighlights(markdownPath string) ([]Highlight, error) {
+	data, err := os.ReadFile(HighlightsPath(markdownPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var highlights []Highlight
+	if err := json.Unmarshal(data, &highlights); err != nil {
+		return nil, fmt.Errorf("failed to parse highlights: %w", err)
+	}
+	return highlights, nil
+}
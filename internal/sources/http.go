@@ -0,0 +1,74 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"silvia/internal/resilience"
+)
+
+// Fetches are rate limited per host and retried on transient failures. The
+// settings are shared by all fetchers so one site is never hit in parallel
+// by the web and feed fetchers at twice the rate.
+var (
+	fetchRetry  = resilience.DefaultPolicy
+	fetchLimits = resilience.NewLimiterSet(30, 3)
+)
+
+// SetFetchRateLimit limits requests to perMinute per host with the given
+// burst. perMinute <= 0 removes the limit.
+func SetFetchRateLimit(perMinute float64, burst int) {
+	fetchLimits = resilience.NewLimiterSet(perMinute, burst)
+}
+
+// SetFetchRetryPolicy sets how failed fetches are retried
+func SetFetchRetryPolicy(policy resilience.Policy) {
+	fetchRetry = policy
+}
+
+// doFetch sends req, waiting for the host's rate limit and retrying timeouts,
+// dropped connections and retryable statuses. A retryable status that
+// persists is returned as a FetchError; other responses are returned as is.
+func doFetch(ctx context.Context, client *http.Client, req *http.Request, message string) (*http.Response, error) {
+	var resp *http.Response
+	err := resilience.Do(ctx, fetchRetry, func(ctx context.Context) error {
+		if err := fetchLimits.Wait(ctx, req.URL.Host); err != nil {
+			return err
+		}
+		r, err := client.Do(req.Clone(ctx))
+		if err != nil {
+			return err
+		}
+		if resilience.RetryableStatus(r.StatusCode) {
+			io.Copy(io.Discard, io.LimitReader(r.Body, 4096))
+			r.Body.Close()
+			return resilience.NewHTTPError(r, r.Status)
+		}
+		resp = r
+		return nil
+	}, func(attempt int, delay time.Duration, err error) {
+		log.Printf("Fetching %s failed (%v), retry %d in %s", req.URL, err, attempt, delay.Round(time.Millisecond))
+	})
+	if err != nil {
+		var httpErr *resilience.HTTPError
+		if errors.As(err, &httpErr) {
+			return nil, &FetchError{
+				URL:        req.URL.String(),
+				StatusCode: httpErr.StatusCode,
+				Message:    fmt.Sprintf("HTTP %d: %s", httpErr.StatusCode, httpErr.Message),
+				Err:        err,
+			}
+		}
+		return nil, &FetchError{
+			URL:     req.URL.String(),
+			Err:     err,
+			Message: message,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,726 @@
+package sources
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Article is the main content of a page, separated from navigation and other boilerplate
+type Article struct {
+	Title    string
+	Content  string // Markdown
+	Excerpt  string
+	TextSize int // Length of the article's plain text
+}
+
+// minArticleText is the amount of plain text below which an extraction is
+// considered a failure and callers should fall back to the full-page conversion
+const minArticleText = 250
+
+var (
+	// Class/id fragments that usually mark page chrome rather than content
+	unlikelyCandidatesRe = regexp.MustCompile(`(?i)-ad-|ad-break|adbox|advert|banner|breadcrumb|combx|comment|community|cookie|consent|cover-wrap|disqus|extra|footer|gdpr|header|legends|masthead|menu|modal|nav|newsletter|outbrain|pager|pagination|paywall|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skip|social|sponsor|subscribe|taboola|toolbar|tweet|twitter|widget`)
+	// Class/id fragments that usually mark content
+	maybeCandidateRe = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|story|entry|post|text|prose`)
+	positiveWeightRe = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story|prose`)
+	negativeWeightRe = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|newsletter|subscribe`)
+	// Short phrases that are boilerplate wherever they appear
+	boilerplateTextRe = regexp.MustCompile(`(?i)^(skip to (main )?content|skip to navigation|advertisement|share this( article| story)?|sign up for our newsletter|subscribe( now)?|read more|related( articles| stories)?|follow us|copyright .*|all rights reserved.*)$`)
+	// Whitespace tidied up in rendered markdown
+	trailingSpaceRe = regexp.MustCompile(`[ \t]+\n`)
+	extraBlankRe    = regexp.MustCompile(`\n{3,}`)
+)
+
+// Elements that never contain article text
+var removedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Input:    true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Template: true,
+	atom.Nav:      true,
+	atom.Aside:    true,
+	atom.Footer:   true,
+	atom.Dialog:   true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+// Roles that mark page chrome
+var removedRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+	"search":        true,
+	"dialog":        true,
+	"alert":         true,
+	"menu":          true,
+	"menubar":       true,
+}
+
+// ExtractArticle finds the main content of an HTML page and converts it to
+// markdown. pageURL is used to resolve relative links and may be empty.
+// It works on the HTML alone so it can be exercised against saved pages.
+func ExtractArticle(rawHTML, pageURL string) (*Article, error) {
+	doc, err := html.Parse(strings.NewReader(rawHTML))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+
+	removeBoilerplate(body)
+
+	root := selectContentRoot(body)
+	if root == nil {
+		return nil, fmt.Errorf("no article content found")
+	}
+
+	cleanContent(root)
+
+	r := &markdownRenderer{baseURL: pageURL}
+	r.render(root)
+	markdown := r.String()
+
+	text := strings.Join(strings.Fields(nodeText(root)), " ")
+	article := &Article{
+		Title:    extractTitle(rawHTML),
+		Content:  markdown,
+		TextSize: len(text),
+	}
+
+	if len(text) > 200 {
+		article.Excerpt = text[:197] + "..."
+	} else {
+		article.Excerpt = text
+	}
+
+	return article, nil
+}
+
+// extractMainContent returns clean article markdown for a page, falling back
+// to converting the whole document when no article can be identified
+func extractMainContent(rawHTML, pageURL string) (string, bool) {
+	article, err := ExtractArticle(rawHTML, pageURL)
+	if err != nil || article.TextSize < minArticleText {
+		return htmlToMarkdown(rawHTML), false
+	}
+	return article.Content, true
+}
+
+// MainContentMarkdown converts a page to markdown the same way fetched sources
+// are converted, so archived captures can be compared with fresh fetches
+func MainContentMarkdown(rawHTML, pageURL string) string {
+	markdown, _ := extractMainContent(rawHTML, pageURL)
+	return markdown
+}
+
+// removeBoilerplate drops elements that are never part of the article:
+// scripts, navigation, hidden elements and nodes whose class/id look like chrome
+func removeBoilerplate(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode {
+			n.RemoveChild(child)
+		} else if child.Type == html.ElementNode && isBoilerplateElement(child) {
+			n.RemoveChild(child)
+		} else {
+			removeBoilerplate(child)
+		}
+		child = next
+	}
+}
+
+func isBoilerplateElement(n *html.Node) bool {
+	if removedTags[n.DataAtom] {
+		return true
+	}
+
+	if removedRoles[strings.ToLower(getAttr(n, "role"))] {
+		return true
+	}
+
+	if getAttr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+
+	style := strings.ReplaceAll(strings.ToLower(getAttr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	// A page-level <header> is chrome, but an article's own header holds its title
+	if n.DataAtom == atom.Header && !hasAncestor(n, atom.Article) {
+		return true
+	}
+
+	// Never drop the structural containers the content lives in
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+
+	matchString := getAttr(n, "class") + " " + getAttr(n, "id")
+	if strings.TrimSpace(matchString) == "" {
+		return false
+	}
+
+	return unlikelyCandidatesRe.MatchString(matchString) && !maybeCandidateRe.MatchString(matchString)
+}
+
+// selectContentRoot picks the node containing the article, preferring
+// semantic <article>/<main> elements and otherwise scoring paragraphs
+func selectContentRoot(body *html.Node) *html.Node {
+	// A single dominant <article> is the most reliable signal
+	if article := largestByText(findAll(body, atom.Article)); article != nil && textLength(article) >= minArticleText {
+		return article
+	}
+
+	// Next best is <main> or an element with role="main"
+	mains := findAll(body, atom.Main)
+	mains = append(mains, findAllFunc(body, func(n *html.Node) bool {
+		return strings.EqualFold(getAttr(n, "role"), "main")
+	})...)
+	if main := largestByText(mains); main != nil && textLength(main) >= minArticleText {
+		if best := scoreCandidates(main); best != nil && textLength(best) >= textLength(main)/2 {
+			return best
+		}
+		return main
+	}
+
+	if best := scoreCandidates(body); best != nil {
+		return best
+	}
+
+	return body
+}
+
+// scoreCandidates implements readability-style scoring: every paragraph
+// contributes points to its parent and grandparent based on its length and
+// comma count, candidates are weighted by class/id and penalised by link
+// density, and the highest scorer wins
+func scoreCandidates(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+
+	initialize := func(n *html.Node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		scores[n] = tagWeight(n) + classWeight(n)
+		candidates = append(candidates, n)
+	}
+
+	paragraphs := findAllFunc(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+			return true
+		case atom.Div, atom.Section:
+			// Divs holding text directly act like paragraphs
+			return !hasBlockChildren(n)
+		}
+		return false
+	})
+
+	for _, p := range paragraphs {
+		text := strings.TrimSpace(nodeText(p))
+		if len(text) < 25 {
+			continue
+		}
+
+		parent := p.Parent
+		if parent == nil || parent.Type != html.ElementNode {
+			continue
+		}
+
+		score := 1.0
+		score += float64(strings.Count(text, ","))
+		score += min(float64(len(text)/100), 3)
+
+		initialize(parent)
+		scores[parent] += score
+
+		if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+			initialize(grandparent)
+			scores[grandparent] += score / 2
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	for _, c := range candidates {
+		scores[c] *= 1 - linkDensity(c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i]] > scores[candidates[j]]
+	})
+
+	top := candidates[0]
+	if scores[top] <= 0 {
+		return nil
+	}
+
+	// When the top candidate is one of several sibling blocks of content
+	// (e.g. an article split into sections), widen to their common parent
+	if parent := top.Parent; parent != nil && parent.Type == html.ElementNode && parent.DataAtom != atom.Body {
+		threshold := max(10, scores[top]*0.2)
+		strongSiblings := 0
+		for sibling := parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+			if sibling != top && scores[sibling] >= threshold {
+				strongSiblings++
+			}
+		}
+		if strongSiblings > 0 && linkDensity(parent) < 0.25 {
+			return parent
+		}
+	}
+
+	return top
+}
+
+// cleanContent removes link farms, empty blocks and boilerplate phrases
+// from inside the selected content root
+func cleanContent(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.ElementNode && shouldDropFromContent(child) {
+			n.RemoveChild(child)
+		} else {
+			cleanContent(child)
+		}
+		child = next
+	}
+}
+
+func shouldDropFromContent(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Ul, atom.Ol, atom.Div, atom.Section, atom.Table, atom.P, atom.Span:
+	default:
+		return false
+	}
+
+	text := strings.Join(strings.Fields(nodeText(n)), " ")
+	if text == "" {
+		// Keep containers that only hold images or media
+		return findFirst(n, atom.Img) == nil && findFirst(n, atom.Pre) == nil
+	}
+
+	if len(text) < 80 && boilerplateTextRe.MatchString(text) {
+		return true
+	}
+
+	if classWeight(n) < 0 && len(text) < 200 {
+		return true
+	}
+
+	// Lists and blocks made mostly of links are menus, tag clouds or "related" lists
+	density := linkDensity(n)
+	if n.DataAtom != atom.P && density > 0.5 && len(text) < 1000 {
+		return true
+	}
+
+	return false
+}
+
+// tagWeight gives an initial score based on the element type
+func tagWeight(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article:
+		return 10
+	case atom.Div, atom.Main, atom.Section:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+// classWeight scores an element's class and id attributes
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{getAttr(n, "class"), getAttr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeightRe.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeightRe.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := textLength(n)
+	if total == 0 {
+		return 0
+	}
+
+	linkText := 0
+	for _, a := range findAll(n, atom.A) {
+		linkText += textLength(a)
+	}
+
+	return float64(linkText) / float64(total)
+}
+
+func hasBlockChildren(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		switch child.DataAtom {
+		case atom.P, atom.Div, atom.Section, atom.Article, atom.Table, atom.Ul, atom.Ol,
+			atom.Pre, atom.Blockquote, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Figure:
+			return true
+		}
+	}
+	return false
+}
+
+func largestByText(nodes []*html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	for _, n := range nodes {
+		if l := textLength(n); l > bestLen {
+			best, bestLen = n, l
+		}
+	}
+	return best
+}
+
+// DOM helpers
+
+func findFirst(n *html.Node, tag atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == tag {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findFirst(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *html.Node, tag atom.Atom) []*html.Node {
+	return findAllFunc(n, func(node *html.Node) bool {
+		return node.DataAtom == tag
+	})
+}
+
+func findAllFunc(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && match(node) {
+			found = append(found, node)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return found
+}
+
+func hasAncestor(n *html.Node, tag atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.DataAtom == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+			sb.WriteString(" ")
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func textLength(n *html.Node) int {
+	return len(strings.Join(strings.Fields(nodeText(n)), " "))
+}
+
+// markdownRenderer converts a cleaned DOM subtree to markdown
+type markdownRenderer struct {
+	sb        strings.Builder
+	baseURL   string
+	listDepth int
+	tableRows int // Rows written of the current table
+}
+
+func (r *markdownRenderer) String() string {
+	out := trailingSpaceRe.ReplaceAllString(r.sb.String(), "\n")
+	out = extraBlankRe.ReplaceAllString(out, "\n\n")
+	return strings.TrimSpace(out)
+}
+
+func (r *markdownRenderer) block(s string) {
+	r.sb.WriteString("\n\n")
+	r.sb.WriteString(s)
+}
+
+func (r *markdownRenderer) render(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.renderNode(child)
+	}
+}
+
+func (r *markdownRenderer) renderNode(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.sb.WriteString(collapseWhitespace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		r.render(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		if text := inlineText(n); text != "" {
+			r.block(strings.Repeat("#", level) + " " + text)
+			r.sb.WriteString("\n\n")
+		}
+	case atom.P:
+		r.sb.WriteString("\n\n")
+		r.render(n)
+		r.sb.WriteString("\n\n")
+	case atom.Br:
+		r.sb.WriteString("\n")
+	case atom.Hr:
+		r.block("---\n\n")
+	case atom.Strong, atom.B:
+		r.wrapInline(n, "**")
+	case atom.Em, atom.I:
+		r.wrapInline(n, "*")
+	case atom.Code:
+		if n.Parent != nil && n.Parent.DataAtom == atom.Pre {
+			r.render(n)
+		} else {
+			r.wrapInline(n, "`")
+		}
+	case atom.Pre:
+		r.block("```\n" + strings.Trim(nodeTextRaw(n), "\n") + "\n```\n\n")
+	case atom.A:
+		r.renderLink(n)
+	case atom.Img:
+		if alt := strings.TrimSpace(getAttr(n, "alt")); alt != "" {
+			r.sb.WriteString(fmt.Sprintf("![%s](%s)", alt, r.resolve(getAttr(n, "src"))))
+		}
+	case atom.Ul, atom.Ol:
+		r.renderList(n)
+	case atom.Blockquote:
+		inner := &markdownRenderer{baseURL: r.baseURL}
+		inner.render(n)
+		lines := strings.Split(inner.String(), "\n")
+		for i, line := range lines {
+			lines[i] = "> " + line
+		}
+		r.block(strings.Join(lines, "\n") + "\n\n")
+	case atom.Figcaption:
+		if text := inlineText(n); text != "" {
+			r.block("*" + text + "*\n\n")
+		}
+	case atom.Tr:
+		var cells []string
+		for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				cells = append(cells, inlineText(cell))
+			}
+		}
+		if len(cells) == 0 {
+			return
+		}
+		r.sb.WriteString("\n| " + strings.Join(cells, " | ") + " |")
+		// The first row is the header, which markdown marks off
+		if r.tableRows++; r.tableRows == 1 {
+			r.sb.WriteString("\n|" + strings.Repeat(" --- |", len(cells)))
+		}
+	case atom.Table:
+		r.sb.WriteString("\n\n")
+		rows := r.tableRows
+		r.tableRows = 0
+		r.render(n)
+		r.tableRows = rows
+		r.sb.WriteString("\n\n")
+	case atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Figure, atom.Dl, atom.Dd, atom.Dt:
+		r.sb.WriteString("\n")
+		r.render(n)
+		r.sb.WriteString("\n")
+	default:
+		r.render(n)
+	}
+}
+
+func (r *markdownRenderer) wrapInline(n *html.Node, marker string) {
+	text := inlineText(n)
+	if text == "" {
+		return
+	}
+	r.sb.WriteString(marker + text + marker)
+}
+
+func (r *markdownRenderer) renderLink(n *html.Node) {
+	text := inlineText(n)
+	if text == "" {
+		return
+	}
+
+	href := strings.TrimSpace(getAttr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+		r.sb.WriteString(text)
+		return
+	}
+
+	r.sb.WriteString(fmt.Sprintf("[%s](%s)", text, r.resolve(href)))
+}
+
+func (r *markdownRenderer) renderList(n *html.Node) {
+	r.sb.WriteString("\n")
+	if r.listDepth == 0 {
+		r.sb.WriteString("\n")
+	}
+	r.listDepth++
+
+	index := 1
+	for item := n.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		inner := &markdownRenderer{baseURL: r.baseURL, listDepth: r.listDepth}
+		inner.render(item)
+		text := inner.String()
+		if text == "" {
+			continue
+		}
+
+		indent := strings.Repeat("  ", r.listDepth-1)
+		lines := strings.Split(text, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + "  " + strings.TrimLeft(lines[i], " ")
+			}
+		}
+		r.sb.WriteString(indent + marker + strings.Join(lines, "\n") + "\n")
+	}
+
+	r.listDepth--
+	if r.listDepth == 0 {
+		r.sb.WriteString("\n")
+	}
+}
+
+func (r *markdownRenderer) resolve(link string) string {
+	if r.baseURL == "" || link == "" {
+		return link
+	}
+	return resolveURL(link, r.baseURL)
+}
+
+// inlineText renders an element's inline content on a single line
+func inlineText(n *html.Node) string {
+	inner := &markdownRenderer{}
+	inner.render(n)
+	return strings.Join(strings.Fields(inner.String()), " ")
+}
+
+// nodeTextRaw returns text with whitespace preserved, for preformatted blocks
+func nodeTextRaw(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func collapseWhitespace(s string) string {
+	if strings.TrimSpace(s) == "" {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	fields := strings.Fields(s)
+	out := strings.Join(fields, " ")
+	if strings.IndexAny(s[:1], " \t\n\r") == 0 {
+		out = " " + out
+	}
+	if strings.IndexAny(s[len(s)-1:], " \t\n\r") == 0 {
+		out += " "
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package sources
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestExtractArticleGolden converts each saved page in testdata/readability
+// and compares the markdown with the .md file next to it. Run with -update
+// after a deliberate change to the output.
+func TestExtractArticleGolden(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "readability", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("no test pages found")
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".html")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			article, err := ExtractArticle(string(raw), "https://example.com/"+name+"/")
+			if err != nil {
+				t.Fatalf("ExtractArticle: %v", err)
+			}
+
+			golden := strings.TrimSuffix(page, ".html") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(article.Content+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got := article.Content + "\n"; got != string(want) {
+				t.Errorf("markdown differs from %s\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestExtractArticle(t *testing.T) {
+	tests := []struct {
+		page    string
+		title   string
+		keep    []string
+		dropped []string
+	}{
+		{
+			page:  "news_article.html",
+			title: "City Council Approves New Transit Plan",
+			keep: []string{
+				"# City Council Approves New Transit Plan",
+				"## Funding the expansion",
+				"[James Okafor](https://example.com/people/james-okafor)",
+				"**federal matching grants**",
+				"> We have been waiting for this for a decade.",
+			},
+			dropped: []string{"Skip to content", "Advertisement", "Share on Twitter", "Related stories", "newsletter", "All rights reserved", "Sports"},
+		},
+		{
+			page:  "blog_post.html",
+			title: "What I Learned Moving Our Build to Bazel",
+			keep: []string{
+				"## What hurt",
+				"- Remote caching let CI reuse work from developer machines.",
+				"*visible*",
+				"bazel test //services/api:all",
+			},
+			dropped: []string{"We use cookies", "About me", "Great post", "Powered by"},
+		},
+		{
+			page:  "docs_page.html",
+			title: "Configuring Retries",
+			keep: []string{
+				"## Options",
+				"max_retries",
+				"[Errors](https://example.com/errors)",
+				"client = Client(max_retries=5, backoff=1.0)",
+			},
+			dropped: []string{"Installation", "Search docs", "Edit this page", "Previous"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", "readability", tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Relative links resolve against the page itself
+			pageURL := "https://example.com/" + strings.TrimSuffix(tt.page, ".html") + "/"
+			article, err := ExtractArticle(string(raw), pageURL)
+			if err != nil {
+				t.Fatalf("ExtractArticle: %v", err)
+			}
+			if !strings.Contains(article.Title, tt.title) {
+				t.Errorf("title = %q, want it to contain %q", article.Title, tt.title)
+			}
+			if article.TextSize < minArticleText {
+				t.Errorf("text size = %d, below the %d needed to count as an article", article.TextSize, minArticleText)
+			}
+			for _, s := range tt.keep {
+				if !strings.Contains(article.Content, s) {
+					t.Errorf("content is missing %q", s)
+				}
+			}
+			for _, s := range tt.dropped {
+				if strings.Contains(article.Content, s) {
+					t.Errorf("content still has boilerplate %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractArticleTooShort(t *testing.T) {
+	const page = `<html><body><p>Just a line.</p></body></html>`
+	markdown, ok := extractMainContent(page, "")
+	if ok {
+		t.Error("a one-line page counted as an article")
+	}
+	if !strings.Contains(markdown, "Just a line.") {
+		t.Errorf("fallback conversion lost the text: %q", markdown)
+	}
+}
@@ -0,0 +1,228 @@
+package sources
+
+import (
+	"slices"
+	"strings"
+)
+
+// chunkExtraction is the LLM output for a single chunk of a source
+type chunkExtraction struct {
+	chunk  int
+	result *LLMExtractionResult
+}
+
+// reconciledEntity is an entity merged across chunks
+type reconciledEntity struct {
+	LLMExtractedEntity
+	chunks []int
+}
+
+// reconciledResult is the merged extraction for a whole source
+type reconciledResult struct {
+	Entities      []*reconciledEntity
+	Relationships []LLMExtractedRelationship
+	Links         []LLMExtractedLink
+}
+
+// reconcileChunks merges per-chunk extractions. Entities are matched by
+// normalized name or alias; their descriptions and content are merged, and
+// relationships are rewritten to the canonical entity names and deduplicated.
+// Links are deduplicated by URL, keeping the highest relevance.
+func reconcileChunks(parts []chunkExtraction) *reconciledResult {
+	result := &reconciledResult{}
+	index := make(map[string]*reconciledEntity) // normalized name/alias -> entity
+
+	for _, part := range parts {
+		for _, extracted := range part.result.Entities {
+			if strings.TrimSpace(extracted.Name) == "" {
+				continue
+			}
+
+			existing := lookupEntity(index, extracted)
+			if existing == nil {
+				entity := &reconciledEntity{
+					LLMExtractedEntity: extracted,
+					chunks:             []int{part.chunk},
+				}
+				entity.Aliases = dedupeStrings(entity.Aliases, entity.Name)
+				entity.WikiLinks = dedupeStrings(entity.WikiLinks, "")
+				result.Entities = append(result.Entities, entity)
+				indexEntity(index, entity)
+				continue
+			}
+
+			mergeEntity(existing, extracted, part.chunk)
+			indexEntity(index, existing)
+		}
+	}
+
+	// Relationships refer to entities by name; point them at canonical names
+	canonical := func(name string) string {
+		if entity, ok := index[normalizeEntityName(name)]; ok {
+			return entity.Name
+		}
+		return name
+	}
+
+	seenRels := make(map[string]int)
+	for _, part := range parts {
+		for _, rel := range part.result.Relationships {
+			rel.Source = canonical(rel.Source)
+			rel.Target = canonical(rel.Target)
+			key := normalizeEntityName(rel.Source) + "|" + normalizeEntityName(rel.Target) + "|" + strings.ToLower(rel.Type)
+			if i, ok := seenRels[key]; ok {
+				if len(rel.Note) > len(result.Relationships[i].Note) {
+					result.Relationships[i].Note = rel.Note
+				}
+				continue
+			}
+			seenRels[key] = len(result.Relationships)
+			result.Relationships = append(result.Relationships, rel)
+		}
+	}
+
+	seenLinks := make(map[string]int)
+	for _, part := range parts {
+		for _, link := range part.result.Links {
+			if i, ok := seenLinks[link.URL]; ok {
+				if relevanceRank(link.Relevance) > relevanceRank(result.Links[i].Relevance) {
+					result.Links[i] = link
+				}
+				continue
+			}
+			seenLinks[link.URL] = len(result.Links)
+			result.Links = append(result.Links, link)
+		}
+	}
+
+	return result
+}
+
+// lookupEntity finds an already reconciled entity by name or any alias
+func lookupEntity(index map[string]*reconciledEntity, extracted LLMExtractedEntity) *reconciledEntity {
+	if entity, ok := index[normalizeEntityName(extracted.Name)]; ok {
+		return entity
+	}
+	for _, alias := range extracted.Aliases {
+		if entity, ok := index[normalizeEntityName(alias)]; ok {
+			return entity
+		}
+	}
+	return nil
+}
+
+func indexEntity(index map[string]*reconciledEntity, entity *reconciledEntity) {
+	if key := normalizeEntityName(entity.Name); key != "" {
+		index[key] = entity
+	}
+	for _, alias := range entity.Aliases {
+		if key := normalizeEntityName(alias); key != "" {
+			if _, taken := index[key]; !taken {
+				index[key] = entity
+			}
+		}
+	}
+}
+
+// mergeEntity folds another chunk's view of an entity into the reconciled one
+func mergeEntity(entity *reconciledEntity, extracted LLMExtractedEntity, chunk int) {
+	if !slices.Contains(entity.chunks, chunk) {
+		entity.chunks = append(entity.chunks, chunk)
+	}
+
+	// Prefer the fuller description
+	if len(extracted.Description) > len(entity.Description) {
+		entity.Description = extracted.Description
+	}
+
+	entity.Content = mergeContent(entity.Content, extracted.Content)
+
+	aliases := append(entity.Aliases, extracted.Aliases...)
+	if !strings.EqualFold(extracted.Name, entity.Name) {
+		aliases = append(aliases, extracted.Name)
+	}
+	entity.Aliases = dedupeStrings(aliases, entity.Name)
+	entity.WikiLinks = dedupeStrings(append(entity.WikiLinks, extracted.WikiLinks...), "")
+}
+
+// mergeContent appends the paragraphs of addition that are not already in base
+func mergeContent(base, addition string) string {
+	if strings.TrimSpace(addition) == "" {
+		return base
+	}
+	if strings.TrimSpace(base) == "" {
+		return addition
+	}
+
+	seen := make(map[string]bool)
+	for _, para := range strings.Split(base, "\n\n") {
+		seen[normalizeParagraph(para)] = true
+	}
+
+	var added []string
+	for _, para := range strings.Split(addition, "\n\n") {
+		key := normalizeParagraph(para)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		added = append(added, strings.TrimSpace(para))
+	}
+
+	if len(added) == 0 {
+		return base
+	}
+	return strings.TrimRight(base, "\n") + "\n\n" + strings.Join(added, "\n\n")
+}
+
+func normalizeParagraph(para string) string {
+	return strings.ToLower(strings.Join(strings.Fields(para), " "))
+}
+
+// normalizeEntityName lowercases a name and drops punctuation and extra spaces
+func normalizeEntityName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ',', '\'', '"', '’', '(', ')':
+			return -1
+		case '-', '_':
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.TrimPrefix(strings.Join(strings.Fields(name), " "), "the ")
+	return name
+}
+
+// dedupeStrings removes empty and case-insensitive duplicate values, and any value equal to exclude
+func dedupeStrings(values []string, exclude string) []string {
+	seen := make(map[string]bool)
+	if exclude != "" {
+		seen[strings.ToLower(exclude)] = true
+	}
+
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		key := strings.ToLower(v)
+		if v == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+func relevanceRank(relevance string) int {
+	switch relevance {
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+	return 0
+}
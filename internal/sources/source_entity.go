@@ -0,0 +1,108 @@
+package sources
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"silvia/internal/graph"
+	"silvia/internal/prompts"
+)
+
+// SourceEntityID returns the ID of the entity summarizing a source, like
+// "sources/example-com-2006-01-02"
+func SourceEntityID(sourceURL string) string {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		// Fallback to simple domain extraction
+		return fmt.Sprintf("sources/source-%s", time.Now().Format("2006-01-02"))
+	}
+
+	domain := strings.ReplaceAll(u.Hostname(), ".", "-")
+	return fmt.Sprintf("sources/%s-%s", domain, time.Now().Format("2006-01-02"))
+}
+
+// NewSourceEntity builds the entity summarizing a source from its
+// extraction summary. rawPath is where the source itself was archived. The
+// content hash and WARC path recorded by ArchiveCapture go in its
+// frontmatter, so the entity can be checked against the capture.
+func NewSourceEntity(source *Source, summary *SourceSummary, rawPath string) *graph.Entity {
+	entity := graph.NewEntity(SourceEntityID(source.URL), graph.EntityWork)
+	entity.Title = summary.Title
+
+	// Build rich content
+	var content strings.Builder
+
+	// Metadata section
+	if summary.Author != "" {
+		content.WriteString(fmt.Sprintf("**Author**: %s\n", summary.Author))
+	}
+	if summary.Publication != "" {
+		content.WriteString(fmt.Sprintf("**Publication**: %s\n", summary.Publication))
+	}
+	if summary.Date != "" {
+		content.WriteString(fmt.Sprintf("**Date**: %s\n", summary.Date))
+	}
+	content.WriteString(fmt.Sprintf("**Source URL**: %s\n", source.URL))
+	content.WriteString(fmt.Sprintf("**Raw Source**: %s\n\n", rawPath))
+
+	// Key themes
+	if len(summary.KeyThemes) > 0 {
+		content.WriteString("## Key Themes\n\n")
+		for _, theme := range summary.KeyThemes {
+			content.WriteString(fmt.Sprintf("- %s\n", theme))
+		}
+		content.WriteString("\n")
+	}
+
+	// Analysis
+	if summary.Analysis != "" {
+		content.WriteString("## Analysis\n\n")
+		content.WriteString(summary.Analysis)
+		content.WriteString("\n\n")
+	}
+
+	// Key quotes
+	if len(summary.KeyQuotes) > 0 {
+		content.WriteString("## Key Quotes\n\n")
+		for _, quote := range summary.KeyQuotes {
+			content.WriteString(fmt.Sprintf("> %s\n\n", quote))
+		}
+	}
+
+	// Related entities
+	if len(summary.People) > 0 || len(summary.Organizations) > 0 || len(summary.Events) > 0 {
+		content.WriteString("## Related Entities\n\n")
+		if len(summary.People) > 0 {
+			content.WriteString("### People\n")
+			for _, personID := range summary.People {
+				content.WriteString(fmt.Sprintf("- [[%s]]\n", personID))
+			}
+			content.WriteString("\n")
+		}
+		if len(summary.Organizations) > 0 {
+			content.WriteString("### Organizations\n")
+			for _, orgID := range summary.Organizations {
+				content.WriteString(fmt.Sprintf("- [[%s]]\n", orgID))
+			}
+			content.WriteString("\n")
+		}
+		if len(summary.Events) > 0 {
+			content.WriteString("### Events\n")
+			for _, eventID := range summary.Events {
+				content.WriteString(fmt.Sprintf("- [[%s]]\n", eventID))
+			}
+			content.WriteString("\n")
+		}
+	}
+
+	entity.Content = content.String()
+	entity.AddSource(source.URL)
+	entity.RecordPrompt(prompts.Summary, summary.PromptVersion)
+	if source.Metadata != nil {
+		entity.Metadata.ContentHash = source.Metadata["content_hash"]
+		entity.Metadata.Archive = source.Metadata["warc"]
+	}
+	return entity
+}
@@ -0,0 +1,405 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Capture is the raw HTTP exchange behind a fetched source, kept so the
+// source can be preserved exactly as it was served
+type Capture struct {
+	URL             string
+	FetchedAt       time.Time
+	Method          string
+	RequestHeaders  http.Header
+	Proto           string
+	StatusCode      int
+	Status          string
+	ResponseHeaders http.Header
+	Body            []byte
+	// Resource captures (from the browser extension or clipboard) have no
+	// HTTP exchange and are archived as WARC resource records
+	Resource    bool
+	ContentType string
+}
+
+// NewResourceCapture creates a capture for content that was not fetched over
+// HTTP by silvia itself, such as HTML sent by the browser extension
+func NewResourceCapture(sourceURL, content, contentType string) *Capture {
+	return &Capture{
+		URL:         sourceURL,
+		FetchedAt:   time.Now().UTC(),
+		Body:        []byte(content),
+		Resource:    true,
+		ContentType: contentType,
+	}
+}
+
+// ContentHash returns the SHA-256 of the captured body as "sha256:<hex>"
+func (c *Capture) ContentHash() string {
+	sum := sha256.Sum256(c.Body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// WriteWARC writes the capture as a gzipped WARC file in dir and returns its path
+func WriteWARC(dir string, capture *Capture) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create WARC directory: %w", err)
+	}
+
+	domain := strings.ReplaceAll(ExtractDomain(capture.URL), ".", "-")
+	if domain == "" {
+		domain = "source"
+	}
+	stamp := capture.FetchedAt.Format("20060102-150405")
+	filename := fmt.Sprintf("%s-%s.warc.gz", domain, stamp)
+	path := filepath.Join(dir, filename)
+
+	// Captures of one domain within the same second get a random suffix
+	// rather than overwriting each other's archive
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for attempt := 0; errors.Is(err, fs.ErrExist) && attempt < 5; attempt++ {
+		suffix := make([]byte, 3)
+		rand.Read(suffix)
+		filename = fmt.Sprintf("%s-%s-%s.warc.gz", domain, stamp, hex.EncodeToString(suffix))
+		path = filepath.Join(dir, filename)
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create WARC file: %w", err)
+	}
+	defer f.Close()
+
+	date := capture.FetchedAt.UTC().Format(time.RFC3339)
+
+	info := "software: silvia\r\nformat: WARC File Format 1.1\r\nconformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"
+	if err := writeWARCRecord(f, "warcinfo", map[string]string{
+		"WARC-Date":     date,
+		"WARC-Filename": filename,
+		"Content-Type":  "application/warc-fields",
+	}, []byte(info)); err != nil {
+		return "", err
+	}
+
+	payloadDigest := warcDigest(capture.Body)
+
+	if capture.Resource {
+		contentType := capture.ContentType
+		if contentType == "" {
+			contentType = "text/html"
+		}
+		if err := writeWARCRecord(f, "resource", map[string]string{
+			"WARC-Date":           date,
+			"WARC-Target-URI":     capture.URL,
+			"WARC-Payload-Digest": payloadDigest,
+			"Content-Type":        contentType,
+		}, capture.Body); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	responseID := newWARCRecordID()
+	if err := writeWARCRecord(f, "response", map[string]string{
+		"WARC-Record-ID":      responseID,
+		"WARC-Date":           date,
+		"WARC-Target-URI":     capture.URL,
+		"WARC-Payload-Digest": payloadDigest,
+		"Content-Type":        "application/http;msgtype=response",
+	}, capture.responseBlock()); err != nil {
+		return "", err
+	}
+
+	if err := writeWARCRecord(f, "request", map[string]string{
+		"WARC-Date":          date,
+		"WARC-Target-URI":    capture.URL,
+		"WARC-Concurrent-To": responseID,
+		"Content-Type":       "application/http;msgtype=request",
+	}, capture.requestBlock()); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// ReadWARC reads the capture stored in a WARC file written by WriteWARC
+func ReadWARC(path string) (*Capture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WARC file: %w", err)
+	}
+	defer gz.Close()
+
+	reader := bufio.NewReader(gz)
+	capture := &Capture{}
+
+	for {
+		headers, block, err := readWARCRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch headers["WARC-Type"] {
+		case "resource":
+			capture.Resource = true
+			capture.URL = headers["WARC-Target-URI"]
+			capture.ContentType = headers["Content-Type"]
+			capture.Body = block
+			capture.FetchedAt, _ = time.Parse(time.RFC3339, headers["WARC-Date"])
+		case "response":
+			capture.URL = headers["WARC-Target-URI"]
+			capture.FetchedAt, _ = time.Parse(time.RFC3339, headers["WARC-Date"])
+			resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse archived response: %w", err)
+			}
+			capture.Proto = resp.Proto
+			capture.StatusCode = resp.StatusCode
+			capture.Status = resp.Status
+			capture.ResponseHeaders = resp.Header
+			capture.Body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read archived body: %w", err)
+			}
+		case "request":
+			req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(block)))
+			if err == nil {
+				capture.Method = req.Method
+				capture.RequestHeaders = req.Header
+			}
+		}
+	}
+
+	if capture.URL == "" {
+		return nil, fmt.Errorf("no response or resource record in %s", path)
+	}
+
+	return capture, nil
+}
+
+// SourceFromCapture rebuilds a Source from archived HTML without refetching
+func SourceFromCapture(capture *Capture) *Source {
+	html := string(capture.Body)
+	markdown, isArticle := extractMainContent(html, capture.URL)
+
+	metadata := map[string]string{
+		"fetched_at":   capture.FetchedAt.Format(time.RFC3339),
+		"domain":       ExtractDomain(capture.URL),
+		"content_hash": capture.ContentHash(),
+	}
+	if isArticle {
+		metadata["content_extraction"] = "article"
+	} else {
+		metadata["content_extraction"] = "full-page"
+	}
+	if author := extractAuthor(html); author != "" {
+		metadata["author"] = author
+	}
+	if pubDate := extractPublicationDate(html); pubDate != "" {
+		metadata["date"] = pubDate
+	}
+	if publication := extractPublication(html); publication != "" {
+		metadata["publication"] = publication
+	}
+
+	return &Source{
+		URL:        capture.URL,
+		Title:      extractTitle(html),
+		Content:    markdown,
+		RawContent: html,
+		Links:      extractHTMLLinks(html),
+		Metadata:   metadata,
+		Capture:    capture,
+	}
+}
+
+// responseBlock serializes the status line, headers and body of the response
+func (c *Capture) responseBlock() []byte {
+	var buf bytes.Buffer
+	proto := c.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	status := c.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode))
+	}
+	fmt.Fprintf(&buf, "%s %s\r\n", proto, status)
+
+	headers := c.ResponseHeaders.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	// The body is stored decoded and in full
+	headers.Del("Content-Encoding")
+	headers.Del("Transfer-Encoding")
+	headers.Set("Content-Length", strconv.Itoa(len(c.Body)))
+	writeHTTPHeaders(&buf, headers)
+
+	buf.WriteString("\r\n")
+	buf.Write(c.Body)
+	return buf.Bytes()
+}
+
+// requestBlock serializes the request line and headers
+func (c *Capture) requestBlock() []byte {
+	var buf bytes.Buffer
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target := "/"
+	host := ""
+	if req, err := http.NewRequest(method, c.URL, nil); err == nil {
+		target = req.URL.RequestURI()
+		host = req.URL.Host
+	}
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, target)
+
+	headers := c.RequestHeaders.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set("Host", host)
+	writeHTTPHeaders(&buf, headers)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func writeHTTPHeaders(w io.Writer, headers http.Header) {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			fmt.Fprintf(w, "%s: %s\r\n", key, value)
+		}
+	}
+}
+
+// writeWARCRecord writes a single record as its own gzip member
+func writeWARCRecord(w io.Writer, recordType string, headers map[string]string, block []byte) error {
+	gz := gzip.NewWriter(w)
+
+	var buf bytes.Buffer
+	buf.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&buf, "WARC-Type: %s\r\n", recordType)
+	if _, ok := headers["WARC-Record-ID"]; !ok {
+		fmt.Fprintf(&buf, "WARC-Record-ID: %s\r\n", newWARCRecordID())
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, headers[key])
+	}
+	fmt.Fprintf(&buf, "WARC-Block-Digest: %s\r\n", warcDigest(block))
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(block))
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	return nil
+}
+
+// readWARCRecord reads the headers and content block of the next record
+func readWARCRecord(r *bufio.Reader) (map[string]string, []byte, error) {
+	// Skip blank lines between records
+	var line string
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(l) == "" {
+				return nil, nil, io.EOF
+			}
+			return nil, nil, fmt.Errorf("failed to read WARC record: %w", err)
+		}
+		if strings.TrimSpace(l) != "" {
+			line = strings.TrimSpace(l)
+			break
+		}
+	}
+
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, nil, fmt.Errorf("invalid WARC record header: %q", line)
+	}
+
+	headers := make(map[string]string)
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read WARC headers: %w", err)
+		}
+		l = strings.TrimRight(l, "\r\n")
+		if l == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(l, ":"); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	length, err := strconv.Atoi(headers["Content-Length"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid WARC Content-Length: %w", err)
+	}
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return nil, nil, fmt.Errorf("failed to read WARC block: %w", err)
+	}
+
+	return headers, block, nil
+}
+
+// warcDigest returns the base32 SHA-1 digest used in WARC headers
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func newWARCRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -28,6 +28,10 @@ func (e *FetchError) Error() string {
 	return fmt.Sprintf("%s: %s", e.URL, e.Message)
 }
 
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
 // WebFetcher handles generic web URLs
 type WebFetcher struct {
 	client *http.Client
@@ -57,13 +61,9 @@ func (w *WebFetcher) Fetch(ctx context.Context, sourceURL string) (*Source, erro
 	// Set user agent to avoid blocks
 	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Silvia/1.0; +https://github.com/silvia)")
 
-	resp, err := w.client.Do(req)
+	resp, err := doFetch(ctx, w.client, req, "failed to fetch URL")
 	if err != nil {
-		return nil, &FetchError{
-			URL:     sourceURL,
-			Err:     err,
-			Message: "failed to fetch URL",
-		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -95,6 +95,18 @@ func (w *WebFetcher) Fetch(ctx context.Context, sourceURL string) (*Source, erro
 
 	html := string(body)
 
+	capture := &Capture{
+		URL:             sourceURL,
+		FetchedAt:       time.Now().UTC(),
+		Method:          req.Method,
+		RequestHeaders:  req.Header.Clone(),
+		Proto:           resp.Proto,
+		StatusCode:      resp.StatusCode,
+		Status:          resp.Status,
+		ResponseHeaders: resp.Header.Clone(),
+		Body:            body,
+	}
+
 	// Check for common paywall/login indicators in content
 	lowerHTML := strings.ToLower(html)
 	if (strings.Contains(lowerHTML, "please log in") ||
@@ -115,8 +127,8 @@ func (w *WebFetcher) Fetch(ctx context.Context, sourceURL string) (*Source, erro
 	// Extract title
 	title := extractTitle(html)
 
-	// Convert to markdown
-	markdown := htmlToMarkdown(html)
+	// Convert the main article content to markdown, dropping page chrome
+	markdown, isArticle := extractMainContent(html, sourceURL)
 
 	// Extract links
 	links := extractHTMLLinks(html)
@@ -127,6 +139,12 @@ func (w *WebFetcher) Fetch(ctx context.Context, sourceURL string) (*Source, erro
 		"domain":     ExtractDomain(sourceURL),
 	}
 
+	if isArticle {
+		metadata["content_extraction"] = "article"
+	} else {
+		metadata["content_extraction"] = "full-page"
+	}
+
 	// Extract author if available
 	if author := extractAuthor(html); author != "" {
 		metadata["author"] = author
@@ -149,6 +167,7 @@ func (w *WebFetcher) Fetch(ctx context.Context, sourceURL string) (*Source, erro
 		RawContent: html,
 		Links:      links,
 		Metadata:   metadata,
+		Capture:    capture,
 	}
 
 	return source, nil
@@ -382,7 +401,7 @@ func (w *WebFetcher) FetchFromClipboard(sourceURL string) (*Source, error) {
 
 	if isHTML {
 		// Process as HTML
-		markdown = htmlToMarkdown(content)
+		markdown, _ = extractMainContent(content, sourceURL)
 		links = extractHTMLLinks(content)
 
 		// Try to extract title from HTML
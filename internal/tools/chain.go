@@ -0,0 +1,316 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Chain arguments may refer to the results of earlier steps. A string
+// argument that starts with $ is replaced by the value it points to:
+//
+//	$1                  the whole result of step 1 (success, data, error, meta)
+//	$1.data.id          a field of step 1's data
+//	$2.data[0].id       an element of a list; negative indexes count from the end
+//	$1.data[*].id       the id of every element, as a list
+//	$item.id            in a step with for_each, the current element
+//	$$literal           the string "$literal"
+//
+// A step with for_each set to a reference to a list runs its tool once per
+// element, and its result's data is the list of each run's data. Field
+// names match the JSON form of the data, ignoring case.
+
+// ChainError reports which step of a chain failed
+type ChainError struct {
+	Step int    // 1-based step number
+	Tool string // Tool at that step
+	Err  error
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("step %d (%s): %v", e.Step, e.Tool, e.Err)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// itemStep marks a reference to the current for_each element
+const itemStep = 0
+
+// reference is a parsed $-reference
+type reference struct {
+	raw  string
+	step int // 1-based step number, or itemStep
+	path []pathSegment
+}
+
+// pathSegment is one .field, [index] or [*] in a reference
+type pathSegment struct {
+	field string
+	index int
+	list  bool // [index]
+	all   bool // [*]
+}
+
+// isReference reports whether an argument value is a reference
+func isReference(s string) bool {
+	return strings.HasPrefix(s, "$") && !strings.HasPrefix(s, "$$")
+}
+
+// parseReference parses a reference such as $1.data[*].id
+func parseReference(s string) (*reference, error) {
+	ref := &reference{raw: s}
+	rest := strings.TrimPrefix(s, "$")
+
+	switch {
+	case strings.HasPrefix(rest, "item"):
+		ref.step = itemStep
+		rest = strings.TrimPrefix(rest, "item")
+	default:
+		end := 0
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("invalid reference %q: expected $<step> or $item", s)
+		}
+		step, _ := strconv.Atoi(rest[:end])
+		if step < 1 {
+			return nil, fmt.Errorf("invalid reference %q: steps are numbered from 1", s)
+		}
+		ref.step = step
+		rest = rest[end:]
+	}
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := 1
+			for end < len(rest) && rest[end] != '.' && rest[end] != '[' {
+				end++
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("invalid reference %q: empty field name", s)
+			}
+			ref.path = append(ref.path, pathSegment{field: rest[1:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid reference %q: missing ]", s)
+			}
+			inner := rest[1:end]
+			if inner == "*" {
+				ref.path = append(ref.path, pathSegment{all: true})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid reference %q: bad index [%s]", s, inner)
+				}
+				ref.path = append(ref.path, pathSegment{index: index, list: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid reference %q: unexpected %q", s, rest[:1])
+		}
+	}
+	return ref, nil
+}
+
+// resolve follows path through value
+func resolve(value any, path []pathSegment, raw string) (any, error) {
+	for i, segment := range path {
+		switch {
+		case segment.all:
+			items, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: [*] applied to %s, not a list", raw, describe(value))
+			}
+			mapped := make([]any, len(items))
+			for j, item := range items {
+				resolved, err := resolve(item, path[i+1:], raw)
+				if err != nil {
+					return nil, err
+				}
+				mapped[j] = resolved
+			}
+			return mapped, nil
+		case segment.list:
+			items, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: [%d] applied to %s, not a list", raw, segment.index, describe(value))
+			}
+			index := segment.index
+			if index < 0 {
+				index += len(items)
+			}
+			if index < 0 || index >= len(items) {
+				return nil, fmt.Errorf("%s: index %d out of range (%d items)", raw, segment.index, len(items))
+			}
+			value = items[index]
+		default:
+			fields, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: .%s applied to %s, not an object", raw, segment.field, describe(value))
+			}
+			field, ok := fields[segment.field]
+			if !ok {
+				for key, v := range fields {
+					if strings.EqualFold(key, segment.field) {
+						field, ok = v, true
+						break
+					}
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s: no field %q", raw, segment.field)
+			}
+			value = field
+		}
+	}
+	return value, nil
+}
+
+// describe names the JSON type of a value for error messages
+func describe(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "a list"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// normalizeResult converts a result to plain JSON values so references see
+// the same field names a JSON client would
+func normalizeResult(result ToolResult) (any, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("result cannot be referenced: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("result cannot be referenced: %w", err)
+	}
+	return value, nil
+}
+
+// chainState holds the normalized results of the steps run so far
+type chainState struct {
+	results []any
+	item    any
+	hasItem bool
+}
+
+// lookup resolves a parsed reference against the state
+func (s *chainState) lookup(ref *reference) (any, error) {
+	var root any
+	if ref.step == itemStep {
+		if !s.hasItem {
+			return nil, fmt.Errorf("%s: $item is only available in for_each steps", ref.raw)
+		}
+		root = s.item
+	} else {
+		if ref.step > len(s.results) {
+			return nil, fmt.Errorf("%s: step %d has not run yet", ref.raw, ref.step)
+		}
+		root = s.results[ref.step-1]
+	}
+	return resolve(root, ref.path, ref.raw)
+}
+
+// processArguments returns a copy of args with every reference replaced by
+// the value it points to
+func (s *chainState) processArguments(args map[string]any) (map[string]any, error) {
+	processed, err := s.processValue(args)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := processed.(map[string]any)
+	return result, nil
+}
+
+func (s *chainState) processValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+		if !isReference(v) {
+			return v, nil
+		}
+		ref, err := parseReference(v)
+		if err != nil {
+			return nil, err
+		}
+		return s.lookup(ref)
+	case map[string]any:
+		processed := make(map[string]any, len(v))
+		for key, item := range v {
+			resolved, err := s.processValue(item)
+			if err != nil {
+				return nil, err
+			}
+			processed[key] = resolved
+		}
+		return processed, nil
+	case []any:
+		processed := make([]any, len(v))
+		for i, item := range v {
+			resolved, err := s.processValue(item)
+			if err != nil {
+				return nil, err
+			}
+			processed[i] = resolved
+		}
+		return processed, nil
+	default:
+		return value, nil
+	}
+}
+
+// validateReferences checks that every reference in value parses and points
+// at an earlier step, and that $item is only used when allowed
+func validateReferences(value any, step int, allowItem bool) error {
+	switch v := value.(type) {
+	case string:
+		if !isReference(v) {
+			return nil
+		}
+		ref, err := parseReference(v)
+		if err != nil {
+			return err
+		}
+		if ref.step == itemStep && !allowItem {
+			return fmt.Errorf("%s: $item is only available in for_each steps", v)
+		}
+		if ref.step != itemStep && ref.step >= step {
+			return fmt.Errorf("%s: can only refer to earlier steps", v)
+		}
+	case map[string]any:
+		for _, item := range v {
+			if err := validateReferences(item, step, allowItem); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := validateReferences(item, step, allowItem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
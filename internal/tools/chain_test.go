@@ -0,0 +1,277 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		step    int
+		path    []pathSegment
+		wantErr string
+	}{
+		{ref: "$1", step: 1},
+		{ref: "$12.data", step: 12, path: []pathSegment{{field: "data"}}},
+		{ref: "$2.data[0].id", step: 2, path: []pathSegment{{field: "data"}, {index: 0, list: true}, {field: "id"}}},
+		{ref: "$1.data[-1]", step: 1, path: []pathSegment{{field: "data"}, {index: -1, list: true}}},
+		{ref: "$1.data[*].id", step: 1, path: []pathSegment{{field: "data"}, {all: true}, {field: "id"}}},
+		{ref: "$item.id", step: itemStep, path: []pathSegment{{field: "id"}}},
+		{ref: "$item", step: itemStep},
+		{ref: "$x", wantErr: "expected $<step> or $item"},
+		{ref: "$0.data", wantErr: "numbered from 1"},
+		{ref: "$1..id", wantErr: "empty field name"},
+		{ref: "$1.data[0", wantErr: "missing ]"},
+		{ref: "$1.data[x]", wantErr: "bad index"},
+		{ref: "$1-data", wantErr: "unexpected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			ref, err := parseReference(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseReference(%q) error = %v, want %q", tt.ref, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReference(%q): %v", tt.ref, err)
+			}
+			if ref.step != tt.step || !reflect.DeepEqual(ref.path, tt.path) {
+				t.Errorf("parseReference(%q) = step %d path %+v, want step %d path %+v", tt.ref, ref.step, ref.path, tt.step, tt.path)
+			}
+		})
+	}
+}
+
+func TestProcessArguments(t *testing.T) {
+	state := &chainState{
+		results: []any{
+			map[string]any{
+				"success": true,
+				"data": []any{
+					map[string]any{"id": "people/ada", "Name": "Ada"},
+					map[string]any{"id": "people/alan", "Name": "Alan"},
+				},
+			},
+			map[string]any{"success": true, "data": map[string]any{"count": 2.0}},
+		},
+		item:    map[string]any{"id": "people/grace"},
+		hasItem: true,
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "plain values pass through",
+			args: map[string]any{"query": "ada", "limit": 5},
+			want: map[string]any{"query": "ada", "limit": 5},
+		},
+		{
+			name: "field of a list element",
+			args: map[string]any{"id": "$1.data[0].id"},
+			want: map[string]any{"id": "people/ada"},
+		},
+		{
+			name: "negative index counts from the end",
+			args: map[string]any{"id": "$1.data[-1].id"},
+			want: map[string]any{"id": "people/alan"},
+		},
+		{
+			name: "every element",
+			args: map[string]any{"ids": "$1.data[*].id"},
+			want: map[string]any{"ids": []any{"people/ada", "people/alan"}},
+		},
+		{
+			name: "field names ignore case",
+			args: map[string]any{"name": "$1.data[0].name"},
+			want: map[string]any{"name": "Ada"},
+		},
+		{
+			name: "nested arguments",
+			args: map[string]any{"filter": map[string]any{"count": "$2.data.count"}, "ids": []any{"$item.id"}},
+			want: map[string]any{"filter": map[string]any{"count": 2.0}, "ids": []any{"people/grace"}},
+		},
+		{
+			name: "$$ escapes a literal dollar",
+			args: map[string]any{"price": "$$5"},
+			want: map[string]any{"price": "$5"},
+		},
+		{
+			name:    "index out of range",
+			args:    map[string]any{"id": "$1.data[2].id"},
+			wantErr: "out of range",
+		},
+		{
+			name:    "missing field",
+			args:    map[string]any{"id": "$2.data.total"},
+			wantErr: `no field "total"`,
+		},
+		{
+			name:    "field of a list",
+			args:    map[string]any{"id": "$1.data.id"},
+			wantErr: "not an object",
+		},
+		{
+			name:    "step that has not run",
+			args:    map[string]any{"id": "$3.data"},
+			wantErr: "has not run yet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := state.processArguments(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("processArguments() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// echoTool returns its arguments as its data
+type echoTool struct {
+	*BaseTool
+}
+
+func newEchoTool(name string, params ...Parameter) *echoTool {
+	return &echoTool{NewBaseTool(name, "Returns its arguments", params)}
+}
+
+func (t *echoTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
+	return ToolResult{Success: true, Data: args}, nil
+}
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{registry: NewRegistry(), logger: &NullLogger{}}
+	for _, tool := range []Tool{
+		newEchoTool("list", Parameter{Name: "items", Type: "array"}),
+		newEchoTool("get", Parameter{Name: "id", Type: "string", Required: true}),
+	} {
+		if err := m.registry.Register(tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestValidateChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		chain   []ToolCall
+		step    int
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			chain: []ToolCall{{Tool: "list"}, {Tool: "get", Args: map[string]any{"id": "$1.data.items[0]"}}},
+		},
+		{name: "empty", wantErr: "no steps"},
+		{
+			name:    "unknown tool",
+			chain:   []ToolCall{{Tool: "list"}, {Tool: "delete_everything"}},
+			step:    2,
+			wantErr: "not found",
+		},
+		{
+			name:    "missing required argument",
+			chain:   []ToolCall{{Tool: "get"}},
+			step:    1,
+			wantErr: `missing required argument "id"`,
+		},
+		{
+			name:    "reference to a later step",
+			chain:   []ToolCall{{Tool: "get", Args: map[string]any{"id": "$2.data"}}, {Tool: "list"}},
+			step:    1,
+			wantErr: "earlier steps",
+		},
+		{
+			name:    "$item outside for_each",
+			chain:   []ToolCall{{Tool: "get", Args: map[string]any{"id": "$item"}}},
+			step:    1,
+			wantErr: "only available in for_each",
+		},
+		{
+			name:    "for_each that is not a reference",
+			chain:   []ToolCall{{Tool: "list"}, {Tool: "get", ForEach: "items", Args: map[string]any{"id": "$item"}}},
+			step:    2,
+			wantErr: "must be a reference",
+		},
+	}
+
+	m := newTestManager(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.ValidateChain(tt.chain)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateChain: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			var chainErr *ChainError
+			if tt.step > 0 && (!errors.As(err, &chainErr) || chainErr.Step != tt.step) {
+				t.Errorf("error %v is not a ChainError for step %d", err, tt.step)
+			}
+		})
+	}
+}
+
+func TestExecuteChainForEach(t *testing.T) {
+	m := newTestManager(t)
+	results, err := m.ExecuteChain(context.Background(), []ToolCall{
+		{Tool: "list", Args: map[string]any{"items": []any{map[string]any{"id": "a"}, map[string]any{"id": "b"}}}},
+		{Tool: "get", ForEach: "$1.data.items", Args: map[string]any{"id": "$item.id"}},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteChain: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	want := []any{map[string]any{"id": "a"}, map[string]any{"id": "b"}}
+	if !reflect.DeepEqual(results[1].Data, want) {
+		t.Errorf("for_each data = %#v, want %#v", results[1].Data, want)
+	}
+	if results[1].Meta["count"] != 2 {
+		t.Errorf("count = %v, want 2", results[1].Meta["count"])
+	}
+}
+
+func TestExecuteChainStopsAtFailingStep(t *testing.T) {
+	m := newTestManager(t)
+	results, err := m.ExecuteChain(context.Background(), []ToolCall{
+		{Tool: "list", Args: map[string]any{"items": []any{}}},
+		{Tool: "get", Args: map[string]any{"id": "$1.data.items[0]"}},
+		{Tool: "list"},
+	})
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) || chainErr.Step != 2 {
+		t.Fatalf("error = %v, want a ChainError for step 2", err)
+	}
+	if len(results) != 2 || results[1].Success {
+		t.Errorf("results = %+v, want the first and the failed second step", results)
+	}
+}
@@ -37,6 +37,16 @@ func NewMergeEntitiesTool(ops *operations.EntityOps) *MergeEntitiesTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *MergeEntitiesTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *MergeEntitiesTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute merges two entities
 func (t *MergeEntitiesTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	entity1ID := GetString(args, "entity1_id", "")
@@ -95,6 +105,16 @@ func NewRenameEntityTool(ops *operations.EntityOps) *RenameEntityTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *RenameEntityTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *RenameEntityTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute renames an entity
 func (t *RenameEntityTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	oldID := GetString(args, "old_id", "")
@@ -153,6 +173,16 @@ func NewRefineEntityTool(ops *operations.EntityOps) *RefineEntityTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *RefineEntityTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *RefineEntityTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute refines an entity
 func (t *RefineEntityTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	entityID := GetString(args, "entity_id", "")
@@ -205,6 +235,16 @@ func NewDeleteEntityTool(ops *operations.EntityOps) *DeleteEntityTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *DeleteEntityTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *DeleteEntityTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute deletes an entity
 func (t *DeleteEntityTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	entityID := GetString(args, "entity_id", "")
@@ -269,6 +309,16 @@ func NewCreateEntityOpsTool(ops *operations.EntityOps) *CreateEntityOpsTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *CreateEntityOpsTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *CreateEntityOpsTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute creates a new entity
 func (t *CreateEntityOpsTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	entityType := GetString(args, "type", "")
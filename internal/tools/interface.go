@@ -23,6 +23,49 @@ type Tool interface {
 	ValidateArgs(args map[string]any) error
 }
 
+// Mutator is implemented by tools that change the graph or the queue.
+// Agents ask the user before running them.
+type Mutator interface {
+	Mutates() bool
+}
+
+// IsMutating reports whether a tool changes state
+func IsMutating(tool Tool) bool {
+	m, ok := tool.(Mutator)
+	return ok && m.Mutates()
+}
+
+// Access is what a caller must be allowed to do to run a tool. The values
+// match the HTTP server's token scopes.
+type Access string
+
+// Levels of access
+const (
+	AccessRead   Access = "read"   // Reads the graph, the queue or sources
+	AccessWrite  Access = "write"  // Edits entities or the queue
+	AccessIngest Access = "ingest" // Ingests sources, spending LLM budget
+	AccessAdmin  Access = "admin"  // Changes that are hard to undo
+)
+
+// AccessDeclarer is implemented by tools that change state, to say what
+// access running them needs
+type AccessDeclarer interface {
+	Access() Access
+}
+
+// RequiredAccess returns the access a tool needs. A tool that changes state
+// without declaring its access needs admin, so a new tool is never more
+// open than intended.
+func RequiredAccess(tool Tool) Access {
+	if d, ok := tool.(AccessDeclarer); ok {
+		return d.Access()
+	}
+	if IsMutating(tool) {
+		return AccessAdmin
+	}
+	return AccessRead
+}
+
 // Parameter describes a single parameter for a tool
 type Parameter struct {
 	Name        string // Parameter name
@@ -34,10 +77,10 @@ type Parameter struct {
 
 // ToolResult represents the result of executing a tool
 type ToolResult struct {
-	Success bool           // Whether the operation succeeded
-	Data    any            // The actual result data
-	Error   string         // Error message if failed
-	Meta    map[string]any // Additional metadata
+	Success bool           `json:"success"`         // Whether the operation succeeded
+	Data    any            `json:"data,omitempty"`  // The actual result data
+	Error   string         `json:"error,omitempty"` // Error message if failed
+	Meta    map[string]any `json:"meta,omitempty"`  // Additional metadata
 }
 
 // ToolError represents an error that occurred during tool execution
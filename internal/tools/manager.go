@@ -17,8 +17,9 @@ type Manager struct {
 
 // ToolCall represents a single tool invocation in a chain
 type ToolCall struct {
-	Tool string         // Tool name
-	Args map[string]any // Arguments for the tool
+	Tool    string         `json:"tool"`               // Tool name
+	Args    map[string]any `json:"args,omitempty"`     // Arguments, which may refer to earlier results
+	ForEach string         `json:"for_each,omitempty"` // Reference to a list to run the tool over
 }
 
 // NewManager creates a new tool manager with operations-based tools
@@ -76,6 +77,7 @@ func (m *Manager) registerAllTools() {
 	m.registry.Register(NewAddToQueueTool(m.ops.Queue))
 	m.registry.Register(NewRemoveFromQueueTool(m.ops.Queue))
 	m.registry.Register(NewProcessNextQueueItemTool(m.ops.Queue))
+	m.registry.Register(NewSetQueueStatusTool(m.ops.Queue))
 	m.registry.Register(NewUpdateQueuePriorityTool(m.ops.Queue))
 	m.registry.Register(NewClearQueueTool(m.ops.Queue))
 
@@ -88,6 +90,7 @@ func (m *Manager) registerAllTools() {
 	m.registry.Register(NewGetRelatedEntitiesOpsTool(m.ops.Search))
 	m.registry.Register(NewGetEntitiesByTypeTool(m.ops.Search))
 	m.registry.Register(NewSuggestRelatedTool(m.ops.Search))
+	m.registry.Register(NewFindPathTool(m.ops.Search))
 
 	// All tools now use the operations layer - no more GraphOperations
 }
@@ -102,60 +105,142 @@ func (m *Manager) Execute(ctx context.Context, toolName string, args map[string]
 	return m.registry.Execute(ctx, toolName, args)
 }
 
-// ExecuteChain runs multiple tools in sequence, passing results between them
+// ValidateChain checks a chain before it runs: every tool must exist, every
+// required argument must be given, and references must be well formed and
+// point at earlier steps
+func (m *Manager) ValidateChain(chain []ToolCall) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("chain has no steps")
+	}
+
+	for i, call := range chain {
+		step := i + 1
+		tool, err := m.registry.Get(call.Tool)
+		if err != nil {
+			return &ChainError{Step: step, Tool: call.Tool, Err: err}
+		}
+		for _, param := range tool.Parameters() {
+			if _, ok := call.Args[param.Name]; param.Required && !ok {
+				return &ChainError{Step: step, Tool: call.Tool, Err: fmt.Errorf("missing required argument %q", param.Name)}
+			}
+		}
+
+		if call.ForEach != "" {
+			if !isReference(call.ForEach) {
+				return &ChainError{Step: step, Tool: call.Tool, Err: fmt.Errorf("for_each must be a reference such as $1.data, got %q", call.ForEach)}
+			}
+			if err := validateReferences(call.ForEach, step, false); err != nil {
+				return &ChainError{Step: step, Tool: call.Tool, Err: err}
+			}
+		}
+		if err := validateReferences(map[string]any(call.Args), step, call.ForEach != ""); err != nil {
+			return &ChainError{Step: step, Tool: call.Tool, Err: err}
+		}
+	}
+	return nil
+}
+
+// ExecuteChain runs multiple tools in sequence. Arguments can refer to the
+// results of earlier steps, and a step can run once per element of an
+// earlier result; see the reference syntax in chain.go. The chain is
+// validated first and stops at the first failing step, returning the results
+// so far and a *ChainError.
 func (m *Manager) ExecuteChain(ctx context.Context, chain []ToolCall) ([]ToolResult, error) {
+	if err := m.ValidateChain(chain); err != nil {
+		return nil, err
+	}
+
 	m.logger.LogChainStart(len(chain))
 	startTime := time.Now()
 
 	results := make([]ToolResult, 0, len(chain))
-	success := true
+	state := &chainState{}
 
 	for i, call := range chain {
-		// Allow referencing previous results in arguments
-		processedArgs := m.processArguments(call.Args, results)
-
-		result, err := m.Execute(ctx, call.Tool, processedArgs)
+		result, err := m.executeStep(ctx, state, call)
+		if err == nil && !result.Success {
+			err = fmt.Errorf("%s", result.Error)
+		}
 		if err != nil {
-			success = false
+			results = append(results, result)
 			m.logger.LogChainComplete(time.Since(startTime), false)
-			return results, fmt.Errorf("failed at step %d (%s): %w", i+1, call.Tool, err)
+			return results, &ChainError{Step: i + 1, Tool: call.Tool, Err: err}
 		}
 
-		results = append(results, result)
-
-		// Stop chain if a tool fails
-		if !result.Success {
-			success = false
+		normalized, err := normalizeResult(result)
+		if err != nil {
 			m.logger.LogChainComplete(time.Since(startTime), false)
-			return results, fmt.Errorf("tool %s failed: %s", call.Tool, result.Error)
+			return results, &ChainError{Step: i + 1, Tool: call.Tool, Err: err}
 		}
+		results = append(results, result)
+		state.results = append(state.results, normalized)
 	}
 
-	m.logger.LogChainComplete(time.Since(startTime), success)
+	m.logger.LogChainComplete(time.Since(startTime), true)
 	return results, nil
 }
 
-// processArguments processes arguments, replacing references to previous results
-func (m *Manager) processArguments(args map[string]any, previousResults []ToolResult) map[string]any {
-	processed := make(map[string]any)
-
-	for key, value := range args {
-		// Check if value is a reference to a previous result
-		if strVal, ok := value.(string); ok {
-			// Simple reference format: "$1.data.id" references the first result's data.id field
-			// This is a simplified implementation - could be made more sophisticated
-			if len(strVal) > 0 && strVal[0] == '$' {
-				// Parse and resolve reference (simplified for now)
-				processed[key] = value // Keep original for now
-			} else {
-				processed[key] = value
-			}
-		} else {
-			processed[key] = value
+// executeStep runs one step of a chain, once or over each for_each element
+func (m *Manager) executeStep(ctx context.Context, state *chainState, call ToolCall) (ToolResult, error) {
+	if call.ForEach == "" {
+		args, err := state.processArguments(call.Args)
+		if err != nil {
+			return ToolResult{Success: false, Error: err.Error()}, err
+		}
+		return m.Execute(ctx, call.Tool, args)
+	}
+
+	ref, err := parseReference(call.ForEach)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}, err
+	}
+	value, err := state.lookup(ref)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()}, err
+	}
+	items, ok := value.([]any)
+	if !ok {
+		err := fmt.Errorf("for_each %s is %s, not a list", call.ForEach, describe(value))
+		return ToolResult{Success: false, Error: err.Error()}, err
+	}
+
+	data := make([]any, 0, len(items))
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return ToolResult{Success: false, Error: err.Error()}, err
+		}
+
+		itemState := &chainState{results: state.results, item: item, hasItem: true}
+		args, err := itemState.processArguments(call.Args)
+		if err != nil {
+			return ToolResult{Success: false, Error: err.Error()}, err
+		}
+		result, err := m.Execute(ctx, call.Tool, args)
+		if err == nil && !result.Success {
+			err = fmt.Errorf("%s", result.Error)
 		}
+		if err != nil {
+			err = fmt.Errorf("item %d: %w", i, err)
+			return ToolResult{Success: false, Data: data, Error: err.Error()}, err
+		}
+		data = append(data, result.Data)
 	}
 
-	return processed
+	return ToolResult{
+		Success: true,
+		Data:    data,
+		Meta:    map[string]any{"count": len(data)},
+	}, nil
+}
+
+// IsMutating reports whether the named tool changes state. Unknown tools are
+// treated as mutating.
+func (m *Manager) IsMutating(toolName string) bool {
+	tool, err := m.registry.Get(toolName)
+	if err != nil {
+		return true
+	}
+	return IsMutating(tool)
 }
 
 // GetToolHelp returns help text for a specific tool
@@ -231,7 +316,7 @@ func convertTypeToJSONSchema(goType string) string {
 		return "number"
 	case "bool":
 		return "boolean"
-	case "[]string":
+	case "[]string", "array":
 		return "array"
 	case "map":
 		return "object"
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"silvia/internal/operations"
 )
@@ -18,7 +20,14 @@ func NewGetQueueTool(ops *operations.QueueOps) *GetQueueTool {
 		BaseTool: NewBaseTool(
 			"get_queue",
 			"Get the current status of the source queue",
-			[]Parameter{},
+			[]Parameter{
+				{
+					Name:        "status",
+					Type:        "string",
+					Required:    false,
+					Description: "Only list sources in this state: pending, in_progress, done, failed, skipped or deferred (default: those still waiting)",
+				},
+			},
 		),
 		ops: ops,
 	}
@@ -26,7 +35,11 @@ func NewGetQueueTool(ops *operations.QueueOps) *GetQueueTool {
 
 // Execute gets the queue status
 func (t *GetQueueTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
-	status, err := t.ops.GetQueue()
+	var statuses []string
+	if status := GetString(args, "status", ""); status != "" {
+		statuses = append(statuses, status)
+	}
+	status, err := t.ops.GetQueue(statuses...)
 	if err != nil {
 		return ToolResult{Success: false, Error: err.Error()},
 			NewToolError(t.Name(), "failed to get queue", err)
@@ -40,6 +53,7 @@ func (t *GetQueueTool) Execute(ctx context.Context, args map[string]any) (ToolRe
 			"high_priority":   status.ByPriority[2],
 			"medium_priority": status.ByPriority[1],
 			"low_priority":    status.ByPriority[0],
+			"by_status":       status.ByStatus,
 		},
 	}, nil
 }
@@ -87,6 +101,16 @@ func NewAddToQueueTool(ops *operations.QueueOps) *AddToQueueTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *AddToQueueTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *AddToQueueTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute adds to the queue
 func (t *AddToQueueTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	url := GetString(args, "url", "")
@@ -144,6 +168,16 @@ func NewRemoveFromQueueTool(ops *operations.QueueOps) *RemoveFromQueueTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *RemoveFromQueueTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *RemoveFromQueueTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute removes from the queue
 func (t *RemoveFromQueueTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	url := GetString(args, "url", "")
@@ -181,13 +215,23 @@ func NewProcessNextQueueItemTool(ops *operations.QueueOps) *ProcessNextQueueItem
 	return &ProcessNextQueueItemTool{
 		BaseTool: NewBaseTool(
 			"process_next_queue_item",
-			"Get and remove the highest priority item from the queue",
+			"Claim the highest priority pending item in the queue, marking it in progress; ingesting it marks it done or failed",
 			[]Parameter{},
 		),
 		ops: ops,
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *ProcessNextQueueItemTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs ingest access
+func (t *ProcessNextQueueItemTool) Access() Access {
+	return AccessIngest
+}
+
 // Execute processes the next queue item
 func (t *ProcessNextQueueItemTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	item, err := t.ops.ProcessNextItem()
@@ -216,6 +260,114 @@ func (t *ProcessNextQueueItemTool) Execute(ctx context.Context, args map[string]
 	}, nil
 }
 
+// SetQueueStatusTool moves a queued source to another state
+type SetQueueStatusTool struct {
+	*BaseTool
+	ops *operations.QueueOps
+}
+
+// NewSetQueueStatusTool creates a new set queue status tool
+func NewSetQueueStatusTool(ops *operations.QueueOps) *SetQueueStatusTool {
+	return &SetQueueStatusTool{
+		BaseTool: NewBaseTool(
+			"set_queue_status",
+			"Skip, defer, requeue or mark done a queued source",
+			[]Parameter{
+				{
+					Name:        "url",
+					Type:        "string",
+					Required:    true,
+					Description: "The queued URL",
+				},
+				{
+					Name:        "status",
+					Type:        "string",
+					Required:    true,
+					Description: "New state: pending (requeue), in_progress, done, failed, skipped or deferred",
+				},
+				{
+					Name:        "until",
+					Type:        "string",
+					Required:    false,
+					Description: "For deferred: RFC 3339 time or duration (e.g. 24h) after which it is pending again; omit to defer until requeued",
+				},
+				{
+					Name:        "error",
+					Type:        "string",
+					Required:    false,
+					Description: "For failed: why it failed",
+				},
+			},
+		),
+		ops: ops,
+	}
+}
+
+// Mutates reports that this tool changes state
+func (t *SetQueueStatusTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *SetQueueStatusTool) Access() Access {
+	return AccessWrite
+}
+
+// Execute changes a queued source's state
+func (t *SetQueueStatusTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
+	url := GetString(args, "url", "")
+	status := GetString(args, "status", "")
+	if url == "" || status == "" {
+		return ToolResult{Success: false, Error: "url and status are required"},
+			NewToolError(t.Name(), "missing url or status", nil)
+	}
+
+	until, err := parseUntil(GetString(args, "until", ""))
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()},
+			NewToolError(t.Name(), "invalid until", err)
+	}
+	var cause error
+	if message := GetString(args, "error", ""); message != "" {
+		cause = errors.New(message)
+	}
+
+	if err := t.ops.SetStatus(url, status, until, cause); err != nil {
+		return ToolResult{Success: false, Error: err.Error()},
+			NewToolError(t.Name(), "failed to set queue status", err)
+	}
+
+	item, err := t.ops.GetItem(url)
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()},
+			NewToolError(t.Name(), "failed to read queue item", err)
+	}
+	return ToolResult{
+		Success: true,
+		Data:    item,
+		Meta: map[string]any{
+			"url":    url,
+			"status": status,
+		},
+	}, nil
+}
+
+// parseUntil reads a time to defer to, given as RFC 3339 or as a duration
+// from now. Empty means no time.
+func parseUntil(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(d), nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("until must be an RFC 3339 time or a duration such as 24h")
+	}
+	return until, nil
+}
+
 // UpdateQueuePriorityTool updates the priority of an item in the queue
 type UpdateQueuePriorityTool struct {
 	*BaseTool
@@ -247,6 +399,16 @@ func NewUpdateQueuePriorityTool(ops *operations.QueueOps) *UpdateQueuePriorityTo
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *UpdateQueuePriorityTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs write access
+func (t *UpdateQueuePriorityTool) Access() Access {
+	return AccessWrite
+}
+
 // Execute updates queue priority
 func (t *UpdateQueuePriorityTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	url := GetString(args, "url", "")
@@ -297,6 +459,16 @@ func NewClearQueueTool(ops *operations.QueueOps) *ClearQueueTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *ClearQueueTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs admin access
+func (t *ClearQueueTool) Access() Access {
+	return AccessAdmin
+}
+
 // Execute clears the queue
 func (t *ClearQueueTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	err := t.ops.ClearQueue()
@@ -226,3 +226,64 @@ func (t *SuggestRelatedTool) Execute(ctx context.Context, args map[string]any) (
 		},
 	}, nil
 }
+
+// FindPathTool finds how two entities are connected
+type FindPathTool struct {
+	*BaseTool
+	ops *operations.SearchOps
+}
+
+// NewFindPathTool creates a new find path tool
+func NewFindPathTool(ops *operations.SearchOps) *FindPathTool {
+	return &FindPathTool{
+		BaseTool: NewBaseTool(
+			"find_path",
+			"Find the shortest chain of relationships connecting two entities",
+			[]Parameter{
+				{
+					Name:        "from_id",
+					Type:        "string",
+					Required:    true,
+					Description: "The entity to start from",
+				},
+				{
+					Name:        "to_id",
+					Type:        "string",
+					Required:    true,
+					Description: "The entity to reach",
+				},
+				{
+					Name:        "max_depth",
+					Type:        "int",
+					Required:    false,
+					Description: "Maximum number of relationships to follow (default 6)",
+				},
+			},
+		),
+		ops: ops,
+	}
+}
+
+// Execute finds the path
+func (t *FindPathTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
+	fromID := GetString(args, "from_id", "")
+	toID := GetString(args, "to_id", "")
+	if fromID == "" || toID == "" {
+		return ToolResult{Success: false, Error: "from_id and to_id are required"},
+			NewToolError(t.Name(), "missing entity ID", nil)
+	}
+
+	path, err := t.ops.FindPath(fromID, toID, GetInt(args, "max_depth", 0))
+	if err != nil {
+		return ToolResult{Success: false, Error: err.Error()},
+			NewToolError(t.Name(), "failed to find path", err)
+	}
+
+	return ToolResult{
+		Success: true,
+		Data:    path,
+		Meta: map[string]any{
+			"steps": len(path.Steps),
+		},
+	}, nil
+}
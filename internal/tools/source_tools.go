@@ -2,8 +2,10 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 
 	"silvia/internal/operations"
+	"silvia/internal/sources"
 )
 
 // IngestSourceTool ingests a source URL
@@ -37,6 +39,16 @@ func NewIngestSourceTool(ops *operations.SourceOps) *IngestSourceTool {
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *IngestSourceTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs ingest access
+func (t *IngestSourceTool) Access() Access {
+	return AccessIngest
+}
+
 // Execute ingests a source
 func (t *IngestSourceTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	url := GetString(args, "url", "")
@@ -60,6 +72,7 @@ func (t *IngestSourceTool) Execute(ctx context.Context, args map[string]any) (To
 			"url":                url,
 			"entities_extracted": len(result.ExtractedEntities),
 			"links_extracted":    len(result.ExtractedLinks),
+			"failed_chunks":      result.FailedChunks,
 			"processing_time_ms": result.ProcessingTime.Milliseconds(),
 		},
 	}, nil
@@ -102,12 +115,28 @@ func NewExtractFromHTMLTool(ops *operations.SourceOps) *ExtractFromHTMLTool {
 					Required:    false,
 					Description: "Additional metadata about the source",
 				},
+				{
+					Name:        "highlights",
+					Type:        "array",
+					Required:    false,
+					Description: "Passages the user highlighted: objects with text, optional note, kind (quote or claim) and DOM anchor",
+				},
 			},
 		),
 		ops: ops,
 	}
 }
 
+// Mutates reports that this tool changes state
+func (t *ExtractFromHTMLTool) Mutates() bool {
+	return true
+}
+
+// Access reports that running this tool needs ingest access
+func (t *ExtractFromHTMLTool) Access() Access {
+	return AccessIngest
+}
+
 // Execute extracts from HTML
 func (t *ExtractFromHTMLTool) Execute(ctx context.Context, args map[string]any) (ToolResult, error) {
 	url := GetString(args, "url", "")
@@ -129,7 +158,19 @@ func (t *ExtractFromHTMLTool) Execute(ctx context.Context, args map[string]any)
 		}
 	}
 
-	result, err := t.ops.ExtractFromHTML(ctx, url, html, title, metadataStr)
+	var highlights []sources.Highlight
+	if raw, ok := args["highlights"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err == nil {
+			err = json.Unmarshal(data, &highlights)
+		}
+		if err != nil {
+			return ToolResult{Success: false, Error: "highlights must be a list of highlight objects"},
+				NewToolError(t.Name(), "invalid highlights", err)
+		}
+	}
+
+	result, err := t.ops.ExtractFromHTML(ctx, url, html, title, metadataStr, highlights)
 	if err != nil {
 		return ToolResult{Success: false, Error: err.Error()},
 			NewToolError(t.Name(), "failed to extract from HTML", err)
@@ -141,6 +182,7 @@ func (t *ExtractFromHTMLTool) Execute(ctx context.Context, args map[string]any)
 		Meta: map[string]any{
 			"url":                url,
 			"entities_extracted": len(result.ExtractedEntities),
+			"failed_chunks":      result.FailedChunks,
 			"processing_time_ms": result.ProcessingTime.Milliseconds(),
 		},
 	}, nil